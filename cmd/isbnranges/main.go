@@ -0,0 +1,45 @@
+// Команда isbnranges обновляет встроенную в пакет internal/isbn таблицу
+// диапазонов ISBN. Скачайте RangeMessage.xml с
+// https://www.isbn-international.org/range_file_generation и выполните
+// из корня репозитория
+//
+//	go run ./cmd/isbnranges -in RangeMessage.xml
+//
+// Файл проверяется тем же разбором, что и встроенная таблица, и только
+// после этого копируется на место старого. Чтобы обновить дефисы в уже
+// сохраненных книгах, запустите сервер с флагом -reformat-isbn.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/NkvXness/GoBookshelf/internal/isbn"
+)
+
+func main() {
+	in := flag.String("in", "", "путь к скачанному RangeMessage.xml")
+	out := flag.String("out", "internal/isbn/RangeMessage.xml", "куда записать таблицу")
+	flag.Parse()
+
+	log.SetFlags(0)
+	if *in == "" {
+		log.Fatal("Укажите файл таблицы: -in RangeMessage.xml")
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatalf("Ошибка чтения таблицы: %v", err)
+	}
+	ranges, err := isbn.ParseRangeMessage(bytes.NewReader(data))
+	if err != nil {
+		log.Fatalf("Ошибка разбора таблицы: %v", err)
+	}
+
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("Ошибка записи таблицы: %v", err)
+	}
+	log.Printf("Таблица %s (выпуск %s от %s) записана в %s", ranges.Source, ranges.Serial, ranges.Date, *out)
+}
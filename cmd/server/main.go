@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/NkvXness/GoBookshelf/internal/api"
 	"github.com/NkvXness/GoBookshelf/internal/config"
@@ -11,23 +14,64 @@ import (
 )
 
 func main() {
+	migrateDown := flag.Int("migrate-down", 0, "откатить указанное количество миграций и выйти")
+	purgeOnly := flag.Bool("purge-trash", false, "удалить из корзины книги старше срока хранения и выйти")
+	reformatISBN := flag.Bool("reformat-isbn", false, "заново расставить дефисы в ISBN всех книг по таблице диапазонов и выйти")
+	flag.Parse()
+
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	log.Println("Запуск сервера GoBookshelf...")
 
 	// Загружаем конфигурацию
 	cfg := config.LoadConfig()
-	log.Printf("Загружена конфигурация: %+v", cfg)
+	log.Printf("Загружена конфигурация: %s", cfg)
 
 	// Инициализация базы данных
-	db, err := storage.NewDatabase(cfg.DBPath)
+	db, err := storage.NewDatabase(cfg.DSN())
 	if err != nil {
 		log.Fatalf("Ошибка инициализации базы данных: %v", err)
 	}
 	defer db.Close()
 
-	// Создание таблиц
-	if err := initDatabase(db); err != nil {
-		log.Fatalf("Ошибка создания таблиц: %v", err)
+	// Откат миграций по запросу
+	if *migrateDown > 0 {
+		if err := db.MigrateDown(*migrateDown); err != nil {
+			log.Fatalf("Ошибка отката миграций: %v", err)
+		}
+		log.Printf("Откачено миграций: %d", *migrateDown)
+		return
+	}
+
+	// Применение миграций
+	if err := db.Migrate(); err != nil {
+		log.Fatalf("Ошибка применения миграций: %v", err)
+	}
+
+	// Перерасстановка дефисов в ISBN после обновления таблицы диапазонов
+	if *reformatISBN {
+		changed, err := db.ReformatISBNs(context.Background())
+		if err != nil {
+			log.Fatalf("Ошибка перерасстановки дефисов в ISBN: %v", err)
+		}
+		log.Printf("Обновлено ISBN: %d", changed)
+		return
+	}
+
+	// Очистка корзины
+	if *purgeOnly {
+		if cfg.TrashRetention == 0 {
+			log.Fatal("Очистка корзины отключена: TRASH_RETENTION=0")
+		}
+		purgeTrash(db, cfg.TrashRetention)
+		return
+	}
+	if cfg.TrashRetention > 0 {
+		go func() {
+			for {
+				purgeTrash(db, cfg.TrashRetention)
+				time.Sleep(trashPurgeInterval)
+			}
+		}()
 	}
 
 	// Создание маршрутизатора
@@ -37,6 +81,7 @@ func main() {
 	router.Use(api.LoggingMiddleware)
 	router.Use(api.CorsMiddleware)
 	router.Use(api.ContentTypeJSONMiddleware)
+	router.Use(api.ActorMiddleware)
 
 	// Создание обработчика API и регистрация маршрутов
 	handler := api.NewHandler(db)
@@ -56,27 +101,17 @@ func main() {
 	}
 }
 
-func initDatabase(db *storage.Database) error {
-	query := `
-    CREATE TABLE IF NOT EXISTS books (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        title TEXT NOT NULL,
-        author TEXT NOT NULL,
-        isbn TEXT UNIQUE,
-        published DATETIME,
-        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-    );
-
-    CREATE INDEX IF NOT EXISTS idx_books_title ON books(title);
-    CREATE INDEX IF NOT EXISTS idx_books_author ON books(author);
-    CREATE INDEX IF NOT EXISTS idx_books_isbn ON books(isbn);
-    `
-
-	_, err := db.DB.Exec(query)
+// trashPurgeInterval - как часто сервер очищает корзину
+const trashPurgeInterval = time.Hour
+
+// purgeTrash окончательно удаляет книги, пролежавшие в корзине дольше retention
+func purgeTrash(repo storage.BookRepository, retention time.Duration) {
+	purged, err := repo.PurgeTrash(context.Background(), time.Now().Add(-retention))
 	if err != nil {
-		return fmt.Errorf("ошибка создания таблиц: %w", err)
+		log.Printf("Ошибка очистки корзины: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Из корзины окончательно удалено книг: %d", purged)
 	}
-
-	return nil
 }
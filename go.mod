@@ -1,15 +1,24 @@
+// Поиск на SQLite использует FTS5, который go-sqlite3 включает только
+// с тегом сборки: go build -tags sqlite_fts5 ./..., go test -tags
+// sqlite_fts5 ./... Без тега сервер не откроет базу SQLite, а тесты
+// хранилища на SQLite пропускаются.
 module github.com/NkvXness/GoBookshelf
 
 go 1.22.5
 
-require github.com/mattn/go-sqlite3 v1.14.24
+require (
+	github.com/fergusstrange/embedded-postgres v1.34.0
+	github.com/go-playground/validator/v10 v10.22.1
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.24
+)
 
 require (
 	github.com/gabriel-vasile/mimetype v1.4.3 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
-	github.com/go-playground/validator/v10 v10.22.1 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
+	github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8 // indirect
 	golang.org/x/crypto v0.19.0 // indirect
 	golang.org/x/net v0.21.0 // indirect
 	golang.org/x/sys v0.17.0 // indirect
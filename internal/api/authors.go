@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/storage"
+)
+
+// Шаблоны путей к авторам
+const (
+	authorPath      = "/api/authors/{id}"
+	mergeAuthorPath = "/api/authors/{id}/merge"
+)
+
+// ListAuthors возвращает авторов по алфавиту с пагинацией.
+// Параметр name оставляет авторов, в одном из имен которых есть эта строка.
+func (h *Handler) ListAuthors(w http.ResponseWriter, r *http.Request) {
+	var opts storage.AuthorListOptions
+	opts.Page, opts.PageSize = parsePagination(r)
+	opts.Name = strings.TrimSpace(r.URL.Query().Get("name"))
+
+	authors, total, err := h.repo.ListAuthors(r.Context(), opts)
+	if err != nil {
+		log.Printf("Error listing authors: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить список авторов"))
+		return
+	}
+	if authors == nil {
+		authors = []*models.Author{}
+	}
+
+	response := struct {
+		Authors      []*models.Author `json:"authors"`
+		TotalAuthors int              `json:"total_authors"`
+		Page         int              `json:"page"`
+		PageSize     int              `json:"page_size"`
+		TotalPages   int              `json:"total_pages"`
+	}{
+		Authors:      authors,
+		TotalAuthors: total,
+		Page:         opts.Page,
+		PageSize:     opts.PageSize,
+		TotalPages:   (total + opts.PageSize - 1) / opts.PageSize,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAuthor возвращает автора с другими написаниями имени и числом книг
+func (h *Handler) GetAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(pathParam(authorPath, r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID автора"))
+		return
+	}
+
+	author, err := h.repo.GetAuthor(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting author: %v", err)
+		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить информацию об авторе", err))
+		return
+	}
+	if author == nil {
+		errors.WriteErrorResponse(w, errors.NewNotFoundError("Автор не найден"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(author)
+}
+
+// CreateAuthor создает автора. Обычно авторы создаются сами при сохранении
+// книг; заранее созданный автор нужен, чтобы задать другие написания имени.
+func (h *Handler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
+	var author models.Author
+	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректные данные автора"))
+		return
+	}
+
+	author.Normalize()
+	if err := author.Validate(); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.repo.CreateAuthor(r.Context(), &author); err != nil {
+		log.Printf("Error creating author: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось создать автора"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(author)
+}
+
+// UpdateAuthor изменяет имена автора. Без поля alternate_names другие
+// написания остаются прежними, пустой список удаляет их; без sort_name
+// имя для сортировки строится из нового имени.
+func (h *Handler) UpdateAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(pathParam(authorPath, r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID автора"))
+		return
+	}
+
+	var author models.Author
+	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректные данные автора"))
+		return
+	}
+	author.ID = id
+
+	author.Normalize()
+	if err := author.Validate(); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.repo.UpdateAuthor(r.Context(), &author); err != nil {
+		log.Printf("Error updating author: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось обновить автора"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(author)
+}
+
+// DeleteAuthor удаляет автора без книг
+func (h *Handler) DeleteAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(pathParam(authorPath, r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID автора"))
+		return
+	}
+
+	if err := h.repo.DeleteAuthor(r.Context(), id); err != nil {
+		log.Printf("Error deleting author: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось удалить автора"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MergeAuthors переносит все книги автора из пути к автору из тела запроса
+// ({"into": 12}) и удаляет первого. Возвращает автора, в которого
+// выполнено объединение.
+func (h *Handler) MergeAuthors(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(pathParam(mergeAuthorPath, r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID автора"))
+		return
+	}
+
+	var request struct {
+		Into int64 `json:"into"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Into < 1 {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Укажите ID автора, с которым нужно объединить: {\"into\": N}"))
+		return
+	}
+
+	author, err := h.repo.MergeAuthors(r.Context(), id, request.Into)
+	if err != nil {
+		log.Printf("Error merging authors: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось объединить авторов"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(author)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/storage"
+)
+
+// maxBatchSize - максимальное количество операций в одном пакете
+const maxBatchSize = 100
+
+// batchOperation - операция в теле запроса POST /api/books/batch
+type batchOperation struct {
+	Op string `json:"op"`
+	// ID - книга для update и delete
+	ID int64 `json:"id"`
+	// Version - ожидаемая версия книги для update и delete, как в If-Match
+	Version int64        `json:"version"`
+	Book    *models.Book `json:"book"`
+}
+
+// batchResult - результат операции пакета в ответе
+type batchResult struct {
+	Index  int          `json:"index"`
+	Status int          `json:"status"`
+	Book   *models.Book `json:"book,omitempty"`
+	Error  *batchError  `json:"error,omitempty"`
+	// rolledBack - операция отменена из-за ошибки в другой операции
+	rolledBack bool
+}
+
+// batchError - ошибка операции в том же виде, что и ответ с ошибкой API
+type batchError struct {
+	Error   errors.ErrorType `json:"error"`
+	Message string           `json:"message"`
+}
+
+// BatchBooks выполняет операции create, update и delete над несколькими
+// книгами в одной транзакции. С atomic=true (по умолчанию) ошибка любой
+// операции отменяет весь пакет, с atomic=false успешные операции сохраняются.
+func (h *Handler) BatchBooks(w http.ResponseWriter, r *http.Request) {
+	atomic := true
+	if value := r.URL.Query().Get("atomic"); value != "" {
+		var err error
+		if atomic, err = strconv.ParseBool(value); err != nil {
+			errors.WriteErrorResponse(w, errors.NewBadRequestError("Параметр atomic должен быть true или false"))
+			return
+		}
+	}
+
+	var operations []batchOperation
+	if err := json.NewDecoder(r.Body).Decode(&operations); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Тело запроса должно быть массивом операций"))
+		return
+	}
+	if len(operations) == 0 || len(operations) > maxBatchSize {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError(
+			fmt.Sprintf("Пакет должен содержать от 1 до %d операций", maxBatchSize)))
+		return
+	}
+
+	log.Printf("Batch of %d operations, atomic=%v", len(operations), atomic)
+
+	// Некорректные операции не передаются в хранилище
+	results := make([]batchResult, len(operations))
+	var ops []storage.BatchOp
+	var indexes []int
+	invalid := false
+	for i, operation := range operations {
+		results[i].Index = i
+		op, err := prepareBatchOp(operation)
+		if err != nil {
+			setBatchError(&results[i], err)
+			invalid = true
+			continue
+		}
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+	}
+
+	committed := true
+	if atomic && invalid {
+		committed = false
+		for _, i := range indexes {
+			setBatchError(&results[i], storage.ErrRolledBack)
+		}
+	} else if len(ops) > 0 {
+		stored, ok, err := h.repo.Batch(r.Context(), ops, atomic)
+		if err != nil {
+			log.Printf("Error running batch: %v", err)
+			errors.WriteErrorResponse(w, storageError(err, "Не удалось выполнить пакет операций"))
+			return
+		}
+		committed = ok
+
+		for j, result := range stored {
+			i := indexes[j]
+			switch {
+			case result.Err != nil:
+				setBatchError(&results[i], result.Err)
+			case ops[j].Action == storage.BatchCreate:
+				results[i].Status = http.StatusCreated
+				results[i].Book = result.Book
+			case ops[j].Action == storage.BatchUpdate:
+				results[i].Status = http.StatusOK
+				results[i].Book = result.Book
+			default:
+				results[i].Status = http.StatusNoContent
+			}
+		}
+	}
+
+	// Отмененный пакет отвечает статусом первой ошибки, из-за которой
+	// он был отменен
+	status := http.StatusOK
+	if !committed {
+		for _, result := range results {
+			if result.Error != nil && !result.rolledBack {
+				status = result.Status
+				break
+			}
+		}
+	}
+
+	response := struct {
+		Atomic    bool          `json:"atomic"`
+		Committed bool          `json:"committed"`
+		Results   []batchResult `json:"results"`
+	}{
+		Atomic:    atomic,
+		Committed: committed,
+		Results:   results,
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// prepareBatchOp проверяет операцию пакета так же, как POST, PUT и DELETE
+// проверяют одиночные запросы. Новое состояние книги при update
+// дополняется сохраненной книгой, как в PUT, и только затем проверяется;
+// сохраненная книга читается в транзакции пакета.
+func prepareBatchOp(operation batchOperation) (storage.BatchOp, error) {
+	op := storage.BatchOp{Action: storage.BatchAction(operation.Op)}
+
+	switch op.Action {
+	case storage.BatchCreate:
+		if operation.Book == nil {
+			return op, errors.NewBadRequestError("Не указаны данные книги (book)")
+		}
+		operation.Book.Normalize()
+		if err := operation.Book.Validate(); err != nil {
+			return op, errors.NewBadRequestError(err.Error())
+		}
+		op.Book = operation.Book
+
+	case storage.BatchUpdate:
+		if operation.Book == nil {
+			return op, errors.NewBadRequestError("Не указаны данные книги (book)")
+		}
+		if operation.ID < 1 {
+			return op, errors.NewBadRequestError("Некорректный ID книги")
+		}
+		operation.Book.ID = operation.ID
+		operation.Book.Version = operation.Version
+		op.Book = operation.Book
+		op.Merge = func(book, existing *models.Book) error {
+			keepOmitted(book, existing)
+			book.ResolveContributors(existing)
+			book.Normalize()
+			if err := book.Validate(); err != nil {
+				return errors.NewBadRequestError(err.Error())
+			}
+			return nil
+		}
+
+	case storage.BatchDelete:
+		if operation.ID < 1 {
+			return op, errors.NewBadRequestError("Некорректный ID книги")
+		}
+		op.ID = operation.ID
+		op.Version = operation.Version
+
+	default:
+		return op, errors.NewBadRequestError(
+			fmt.Sprintf("Неизвестная операция %q, допустимы: create, update, delete", operation.Op))
+	}
+
+	return op, nil
+}
+
+// setBatchError записывает в результат операции ошибку и ее HTTP-статус
+func setBatchError(result *batchResult, err error) {
+	var appErr errors.AppError
+	switch {
+	case stderrors.As(err, &appErr):
+	case stderrors.Is(err, storage.ErrRolledBack):
+		appErr = errors.NewConflictError("Операция отменена из-за ошибки в другой операции пакета")
+	default:
+		appErr = storageError(err, "Не удалось выполнить операцию")
+	}
+
+	result.Status = appErr.StatusCode()
+	result.Book = nil
+	result.rolledBack = stderrors.Is(err, storage.ErrRolledBack)
+	result.Error = &batchError{Error: appErr.Type, Message: appErr.Message}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// versionConflictMessage возвращается клиенту, изменявшему устаревшую версию книги
+const versionConflictMessage = "Книга была изменена другим пользователем, загрузите актуальную версию"
+
+// etag возвращает ETag книги, построенный по ее версии
+func etag(book *models.Book) string {
+	return fmt.Sprintf(`"%d"`, book.Version)
+}
+
+// setETag передает версию книги в заголовке ETag
+func setETag(w http.ResponseWriter, book *models.Book) {
+	w.Header().Set("ETag", etag(book))
+}
+
+// etagMatches проверяет, соответствует ли книга заголовку If-Match или
+// If-None-Match: списку ETag через запятую или "*". При слабом сравнении
+// (If-None-Match) префикс W/ игнорируется, при строгом (If-Match) слабые
+// теги не совпадают ни с чем.
+func etagMatches(header string, book *models.Book, weak bool) bool {
+	current := etag(book)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedVersion возвращает версию книги, которую клиент собирается изменить
+// согласно заголовку If-Match, или 0, если заголовка нет. Если книга уже
+// изменилась, возвращается ошибка 412 Precondition Failed.
+func expectedVersion(r *http.Request, book *models.Book) (int64, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, nil
+	}
+	if !etagMatches(header, book, false) {
+		return 0, errors.NewPreconditionFailedError(versionConflictMessage)
+	}
+	return book.Version, nil
+}
@@ -2,7 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	stderrors "errors"
+	"io"
 	"log"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,17 +13,19 @@ import (
 
 	"github.com/NkvXness/GoBookshelf/internal/errors"
 	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/patch"
+	"github.com/NkvXness/GoBookshelf/internal/search"
 	"github.com/NkvXness/GoBookshelf/internal/storage"
 )
 
 // Handler содержит обработчики запросов к API
 type Handler struct {
-	db *storage.Database
+	repo storage.BookRepository
 }
 
 // NewHandler создает новый экземпляр обработчика
-func NewHandler(db *storage.Database) *Handler {
-	return &Handler{db: db}
+func NewHandler(repo storage.BookRepository) *Handler {
+	return &Handler{repo: repo}
 }
 
 // RegisterRoutes регистрирует все маршруты API
@@ -28,16 +33,85 @@ func (h *Handler) RegisterRoutes(router *Router) {
 	// Книги - групповые операции
 	router.GET("/api/books", h.ListBooks)
 	router.POST("/api/books", h.HandleBooksPost)
+	router.POST("/api/books/batch", h.BatchBooks)
 
 	// Книги - операции с конкретной книгой
 	router.GET("/api/books/{id}", h.GetBook)
 	router.PUT("/api/books/{id}", h.UpdateBook)
+	router.PATCH("/api/books/{id}", h.PatchBook)
 	router.DELETE("/api/books/{id}", h.DeleteBook)
 
 	// Поиск книг
 	router.GET("/api/books/search", h.SearchBooks)
+
+	// Корзина
+	router.GET("/api/trash", h.ListTrash)
+	router.POST(restoreBookPath, h.RestoreBook)
+
+	// Импорт и выгрузка
+	router.GET("/api/export", h.ExportBooks)
+	router.POST("/api/import", h.ImportBooks)
+
+	// История изменений
+	router.GET(bookHistoryPath, h.BookHistory)
+	router.POST(revertBookPath, h.RevertBook)
+
+	// Авторы
+	router.GET("/api/authors", h.ListAuthors)
+	router.POST("/api/authors", h.CreateAuthor)
+	router.GET(authorPath, h.GetAuthor)
+	router.PUT(authorPath, h.UpdateAuthor)
+	router.DELETE(authorPath, h.DeleteAuthor)
+	router.POST(mergeAuthorPath, h.MergeAuthors)
+
+	// Теги и полки
+	router.GET("/api/tags", h.ListTags)
+	router.GET("/api/shelves", h.ListShelves)
+	router.POST("/api/shelves", h.CreateShelf)
+	router.GET(shelfPath, h.GetShelf)
+	router.PUT(shelfPath, h.UpdateShelf)
+	router.DELETE(shelfPath, h.DeleteShelf)
+	router.GET(shelfBooksPath, h.ShelfBooks)
+	router.POST(shelfBooksPath, h.AddToShelf)
+	router.PUT(shelfBooksPath, h.SetShelfBooks)
+	router.DELETE(shelfBookPath, h.RemoveFromShelf)
+
+	// Серии
+	router.GET("/api/series", h.ListSeries)
+	router.GET(seriesPath, h.GetSeries)
+
+	// Произведения и их издания
+	router.GET("/api/works", h.ListWorks)
+	router.POST("/api/works", h.CreateWork)
+	router.GET(workPath, h.GetWork)
+	router.PUT(workPath, h.UpdateWork)
+	router.DELETE(workPath, h.DeleteWork)
+	router.GET(workEditionsPath, h.WorkEditions)
+	router.POST(workEditionsPath, h.LinkEdition)
+	router.DELETE(workEditionPath, h.UnlinkEdition)
+
+	// OPDS-каталог
+	router.GET(opdsRootPath, h.OPDSRoot)
+	router.GET(opdsNewPath, h.OPDSNew)
+	router.GET(opdsAuthorsPath, h.OPDSAuthors)
+	router.GET(opdsAuthorPath, h.OPDSAuthor)
+	router.GET(opdsSearchPath, h.OPDSSearch)
+	router.GET(opdsOpenSearchPath, h.OPDSOpenSearch)
 }
 
+// Типы тела запроса PATCH
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// Шаблоны путей к действиям над конкретной книгой
+const (
+	restoreBookPath = "/api/books/{id}/restore"
+	bookHistoryPath = "/api/books/{id}/history"
+	revertBookPath  = "/api/books/{id}/revert"
+)
+
 // HandleBooksPost обрабатывает все POST запросы к /api/books
 func (h *Handler) HandleBooksPost(w http.ResponseWriter, r *http.Request) {
 	// Проверяем параметры запроса
@@ -56,9 +130,9 @@ func (h *Handler) HandleBooksPost(w http.ResponseWriter, r *http.Request) {
 		}
 
 		log.Printf("Удаление книги с ID: %d", id)
-		if err := h.db.DeleteBook(id); err != nil {
+		if err := h.repo.DeleteBook(r.Context(), id, 0); err != nil {
 			log.Printf("Ошибка удаления книги: %v", err)
-			errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось удалить книгу", err))
+			errors.WriteErrorResponse(w, storageError(err, "Не удалось удалить книгу"))
 			return
 		}
 
@@ -78,7 +152,7 @@ func (h *Handler) HandleBooksPost(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Получаем существующую книгу для проверки
-		existingBook, err := h.db.GetBook(id)
+		existingBook, err := h.repo.GetBook(r.Context(), id)
 		if err != nil {
 			log.Printf("Ошибка получения книги: %v", err)
 			errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить информацию о книге", err))
@@ -101,13 +175,12 @@ func (h *Handler) HandleBooksPost(w http.ResponseWriter, r *http.Request) {
 		// Устанавливаем ID
 		updatedBook.ID = id
 
-		// Если ISBN не изменился, используем существующий
-		if updatedBook.ISBN == "" {
-			updatedBook.ISBN = existingBook.ISBN
-		}
+		// Поля, которых нет в запросе, остаются прежними
+		keepOmitted(&updatedBook, existingBook)
+		updatedBook.ResolveContributors(existingBook)
 
-		// Форматируем ISBN
-		updatedBook.FormatISBN()
+		// Форматируем ISBN, идентификаторы, участников и теги
+		updatedBook.Normalize()
 
 		// Сохраняем даты создания и обновления
 		updatedBook.CreatedAt = existingBook.CreatedAt
@@ -121,14 +194,14 @@ func (h *Handler) HandleBooksPost(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Обновляем книгу
-		if err := h.db.UpdateBook(&updatedBook); err != nil {
+		if err := h.repo.UpdateBook(r.Context(), &updatedBook); err != nil {
 			log.Printf("Ошибка обновления книги: %v", err)
-			errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось обновить книгу", err))
+			errors.WriteErrorResponse(w, storageError(err, "Не удалось обновить книгу"))
 			return
 		}
 
 		// Получаем обновленную книгу
-		updatedBookFromDB, err := h.db.GetBook(id)
+		updatedBookFromDB, err := h.repo.GetBook(r.Context(), id)
 		if err != nil {
 			log.Printf("Ошибка получения обновленной книги: %v", err)
 			errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить обновленную информацию о книге", err))
@@ -144,37 +217,36 @@ func (h *Handler) HandleBooksPost(w http.ResponseWriter, r *http.Request) {
 	h.CreateBook(w, r)
 }
 
-// ListBooks возвращает список книг с пагинацией
+// ListBooks возвращает список книг с пагинацией, сортировкой и фильтрами
 func (h *Handler) ListBooks(w http.ResponseWriter, r *http.Request) {
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil || page < 1 {
-		page = 1
-	}
-
-	pageSize, err := strconv.Atoi(r.URL.Query().Get("page_size"))
-	if err != nil || pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	opts, err := parseListOptions(r)
+	if err != nil {
+		errors.WriteErrorResponse(w, err)
+		return
 	}
 
-	books, total, err := h.db.ListBooks(page, pageSize)
+	books, info, err := h.repo.ListBooks(r.Context(), opts)
 	if err != nil {
 		log.Printf("Error listing books: %v", err)
-		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить список книг", err))
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить список книг"))
 		return
 	}
 
+	order := "asc"
+	if opts.SortDesc {
+		order = "desc"
+	}
+
 	response := struct {
-		Books      []*models.Book `json:"books"`
-		TotalBooks int            `json:"total_books"`
-		Page       int            `json:"page"`
-		PageSize   int            `json:"page_size"`
-		TotalPages int            `json:"total_pages"`
+		Books []*models.Book `json:"books"`
+		pagination
+		Sort  string `json:"sort"`
+		Order string `json:"order"`
 	}{
 		Books:      books,
-		TotalBooks: total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: (total + pageSize - 1) / pageSize,
+		pagination: newPagination(opts.PageOptions, info),
+		Sort:       string(opts.SortBy),
+		Order:      order,
 	}
 
 	json.NewEncoder(w).Encode(response)
@@ -190,7 +262,16 @@ func (h *Handler) GetBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	book, err := h.db.GetBook(id)
+	// Книга отдается в JSON или, по параметру format и заголовку Accept,
+	// в одном из форматов выгрузки
+	w.Header().Set("Vary", "Accept")
+	format, formatted, err := negotiateFormat(r)
+	if err != nil {
+		errors.WriteErrorResponse(w, err)
+		return
+	}
+
+	book, err := h.repo.GetBook(r.Context(), id)
 	if err != nil {
 		log.Printf("Error getting book: %v", err)
 		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить информацию о книге", err))
@@ -201,6 +282,19 @@ func (h *Handler) GetBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if formatted {
+		writeBooks(w, format, "Не удалось получить информацию о книге", func(yield func([]*models.Book) error) error {
+			return yield([]*models.Book{book})
+		})
+		return
+	}
+
+	setETag(w, book)
+	if header := r.Header.Get("If-None-Match"); header != "" && etagMatches(header, book, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	json.NewEncoder(w).Encode(book)
 }
 
@@ -212,20 +306,21 @@ func (h *Handler) CreateBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Форматируем ISBN перед валидацией
-	book.FormatISBN()
+	// Форматируем ISBN, идентификаторы и участников перед валидацией
+	book.Normalize()
 
 	if err := book.Validate(); err != nil {
 		errors.WriteErrorResponse(w, errors.NewBadRequestError(err.Error()))
 		return
 	}
 
-	if err := h.db.CreateBook(&book); err != nil {
+	if err := h.repo.CreateBook(r.Context(), &book); err != nil {
 		log.Printf("Error creating book: %v", err)
-		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось создать книгу", err))
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось создать книгу"))
 		return
 	}
 
+	setETag(w, &book)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(book)
 }
@@ -241,7 +336,7 @@ func (h *Handler) UpdateBook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Проверяем существование книги
-	existingBook, err := h.db.GetBook(id)
+	existingBook, err := h.repo.GetBook(r.Context(), id)
 	if err != nil {
 		log.Printf("Error getting existing book: %v", err)
 		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить информацию о книге", err))
@@ -252,6 +347,13 @@ func (h *Handler) UpdateBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Проверяем, что клиент изменяет актуальную версию книги
+	version, err := expectedVersion(r, existingBook)
+	if err != nil {
+		errors.WriteErrorResponse(w, err)
+		return
+	}
+
 	// Декодируем данные из запроса
 	var book models.Book
 	if err := json.NewDecoder(r.Body).Decode(&book); err != nil {
@@ -259,19 +361,103 @@ func (h *Handler) UpdateBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Устанавливаем ID из URL
-	book.ID = id
+	// Поля, которых нет в запросе, остаются прежними
+	keepOmitted(&book, existingBook)
 
-	// Если ISBN не указан, используем существующий
-	if book.ISBN == "" {
-		book.ISBN = existingBook.ISBN
+	h.saveBook(w, r, &book, existingBook, version)
+}
+
+// PatchBook частично обновляет книгу. Тело запроса - JSON Merge Patch
+// (application/merge-patch+json, RFC 7396) или JSON Patch
+// (application/json-patch+json, RFC 6902), который применяется к текущему
+// состоянию книги перед валидацией.
+func (h *Handler) PatchBook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(extractIDFromPath(r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID книги"))
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch mediaType {
+	case mergePatchType:
+		apply = patch.Merge
+	case jsonPatchType:
+		apply = patch.Apply
+	default:
+		errors.WriteErrorResponse(w, errors.NewUnsupportedMediaTypeError(
+			"Тело запроса должно иметь тип "+mergePatchType+" или "+jsonPatchType))
+		return
+	}
+
+	existingBook, err := h.repo.GetBook(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting existing book: %v", err)
+		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить информацию о книге", err))
+		return
+	}
+	if existingBook == nil {
+		errors.WriteErrorResponse(w, errors.NewNotFoundError("Книга не найдена"))
+		return
+	}
+
+	// Патч применяется к прочитанному состоянию книги, поэтому сохраняем его
+	// только поверх этой версии, даже если клиент не передал If-Match
+	if _, err := expectedVersion(r, existingBook); err != nil {
+		errors.WriteErrorResponse(w, err)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Не удалось прочитать тело запроса"))
+		return
+	}
+
+	// Применяем патч к JSON-представлению книги
+	current, err := json.Marshal(existingBook)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось подготовить книгу к изменению", err))
+		return
+	}
+	patched, err := apply(current, body)
+	if err != nil {
+		log.Printf("Error applying patch: %v", err)
+		if stderrors.Is(err, patch.ErrTestFailed) {
+			errors.WriteErrorResponse(w, errors.NewConflictError("Условие test в патче не выполнено: "+err.Error()))
+			return
+		}
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный патч: "+err.Error()))
+		return
+	}
+
+	var book models.Book
+	if err := json.Unmarshal(patched, &book); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректные данные книги после применения патча"))
+		return
 	}
 
-	// Форматируем ISBN
-	book.FormatISBN()
+	h.saveBook(w, r, &book, existingBook, existingBook.Version)
+}
+
+// saveBook проверяет и сохраняет новое состояние книги existing и отправляет
+// его клиенту. Служебные поля (id, даты, версия) берутся из existing,
+// version - версия из If-Match или 0.
+func (h *Handler) saveBook(w http.ResponseWriter, r *http.Request, book, existing *models.Book, version int64) {
+	book.ID = existing.ID
+	book.Version = version
+	book.DeletedAt = nil
+
+	// Клиент, который знает только поле author, меняет им авторов книги,
+	// не затрагивая остальных участников
+	book.ResolveContributors(existing)
+
+	// Форматируем ISBN, идентификаторы и участников
+	book.Normalize()
 
 	// Сохраняем текущие значения created_at и updated_at
-	book.CreatedAt = existingBook.CreatedAt
+	book.CreatedAt = existing.CreatedAt
 	book.UpdatedAt = time.Now()
 
 	// Валидируем данные
@@ -281,23 +467,47 @@ func (h *Handler) UpdateBook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Обновляем книгу
-	if err := h.db.UpdateBook(&book); err != nil {
+	if err := h.repo.UpdateBook(r.Context(), book); err != nil {
 		log.Printf("Error updating book: %v", err)
-		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось обновить книгу", err))
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось обновить книгу"))
 		return
 	}
 
 	// Получаем обновленную книгу для ответа
-	updatedBook, err := h.db.GetBook(id)
+	updatedBook, err := h.repo.GetBook(r.Context(), book.ID)
 	if err != nil {
 		log.Printf("Error getting updated book: %v", err)
 		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить обновленную информацию о книге", err))
 		return
 	}
 
+	setETag(w, book)
 	json.NewEncoder(w).Encode(updatedBook)
 }
 
+// keepOmitted оставляет книге прежние значения полей, которых нет в запросе
+// PUT или в операции update пакета: без author и contributors авторы
+// остаются прежними, без isbn - прежний ISBN, без identifiers и tags -
+// прежние идентификаторы и теги, без series - прежняя серия, без publisher,
+// language и format - прежние данные издания. Пустой список identifiers или
+// tags удаляет их, остальные поля можно очистить через PATCH.
+func keepOmitted(book, existing *models.Book) {
+	if book.Author == "" && book.Contributors == nil {
+		book.Author = existing.Author
+	}
+	if book.ISBN == "" {
+		book.ISBN = existing.ISBN
+	}
+	if book.Identifiers == nil {
+		book.Identifiers = existing.Identifiers
+	}
+	if book.Tags == nil {
+		book.Tags = existing.Tags
+	}
+	keepSeries(book, existing)
+	keepEdition(book, existing)
+}
+
 // DeleteBook удаляет книгу
 func (h *Handler) DeleteBook(w http.ResponseWriter, r *http.Request) {
 	// Извлекаем ID из URL
@@ -308,9 +518,28 @@ func (h *Handler) DeleteBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.db.DeleteBook(id); err != nil {
+	// С If-Match удаляется только актуальная версия книги
+	var version int64
+	if r.Header.Get("If-Match") != "" {
+		book, err := h.repo.GetBook(r.Context(), id)
+		if err != nil {
+			log.Printf("Error getting book: %v", err)
+			errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить информацию о книге", err))
+			return
+		}
+		if book == nil {
+			errors.WriteErrorResponse(w, errors.NewNotFoundError("Книга не найдена"))
+			return
+		}
+		if version, err = expectedVersion(r, book); err != nil {
+			errors.WriteErrorResponse(w, err)
+			return
+		}
+	}
+
+	if err := h.repo.DeleteBook(r.Context(), id, version); err != nil {
 		log.Printf("Error deleting book: %v", err)
-		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось удалить книгу", err))
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось удалить книгу"))
 		return
 	}
 
@@ -325,42 +554,212 @@ func (h *Handler) SearchBooks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil || page < 1 {
-		page = 1
+	// В форматах выгрузки отдаются все найденные книги, а не одна страница
+	w.Header().Set("Vary", "Accept")
+	format, formatted, err := negotiateFormat(r)
+	if err != nil {
+		errors.WriteErrorResponse(w, err)
+		return
+	}
+	if formatted {
+		writeBooks(w, format, "Не удалось выполнить поиск книг", func(yield func([]*models.Book) error) error {
+			return storage.ForEachSearchPage(r.Context(), h.repo, query, func(hits []*models.SearchHit) error {
+				books := make([]*models.Book, len(hits))
+				for i, hit := range hits {
+					books[i] = &hit.Book
+				}
+				return yield(books)
+			})
+		})
+		return
 	}
 
-	pageSize, err := strconv.Atoi(r.URL.Query().Get("page_size"))
-	if err != nil || pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	opts, err := parsePageOptions(r)
+	if err != nil {
+		errors.WriteErrorResponse(w, err)
+		return
 	}
 
-	books, total, err := h.db.SearchBooks(query, page, pageSize)
+	// collapse=work оставляет одно издание каждого произведения
+	find := h.repo.SearchBooks
+	switch r.URL.Query().Get("collapse") {
+	case "":
+	case "work":
+		find = h.repo.SearchWorks
+	default:
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Параметр collapse может быть только work"))
+		return
+	}
+
+	books, info, err := find(r.Context(), query, opts)
 	if err != nil {
 		log.Printf("Error searching books: %v", err)
-		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось выполнить поиск книг", err))
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось выполнить поиск книг"))
 		return
 	}
 
 	response := struct {
-		Books      []*models.Book `json:"books"`
-		TotalBooks int            `json:"total_books"`
-		Page       int            `json:"page"`
-		PageSize   int            `json:"page_size"`
-		TotalPages int            `json:"total_pages"`
-		Query      string         `json:"query"`
+		Books []*models.SearchHit `json:"books"`
+		pagination
+		Query string `json:"query"`
 	}{
 		Books:      books,
-		TotalBooks: total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: (total + pageSize - 1) / pageSize,
+		pagination: newPagination(opts, info),
 		Query:      query,
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
+// ListTrash возвращает книги из корзины, сначала удаленные последними
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	opts, err := parsePageOptions(r)
+	if err != nil {
+		errors.WriteErrorResponse(w, err)
+		return
+	}
+
+	books, info, err := h.repo.ListTrash(r.Context(), opts)
+	if err != nil {
+		log.Printf("Error listing trash: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить содержимое корзины"))
+		return
+	}
+
+	response := struct {
+		Books []*models.Book `json:"books"`
+		pagination
+	}{
+		Books:      books,
+		pagination: newPagination(opts, info),
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// RestoreBook возвращает книгу из корзины
+func (h *Handler) RestoreBook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(pathParam(restoreBookPath, r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID книги"))
+		return
+	}
+
+	if err := h.repo.RestoreBook(r.Context(), id); err != nil {
+		log.Printf("Error restoring book: %v", err)
+		if stderrors.Is(err, storage.ErrBookNotFound) {
+			errors.WriteErrorResponse(w, errors.NewNotFoundError("Книга не найдена в корзине"))
+			return
+		}
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось восстановить книгу"))
+		return
+	}
+
+	book, err := h.repo.GetBook(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting restored book: %v", err)
+		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить информацию о книге", err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(book)
+}
+
+// BookHistory возвращает историю изменений книги, начиная с последнего
+func (h *Handler) BookHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(pathParam(bookHistoryPath, r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID книги"))
+		return
+	}
+
+	revisions, err := h.repo.BookHistory(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting book history: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить историю изменений книги"))
+		return
+	}
+
+	response := struct {
+		BookID    int64              `json:"book_id"`
+		Revisions []*models.Revision `json:"revisions"`
+	}{
+		BookID:    id,
+		Revisions: revisions,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevertBook возвращает книгу к состоянию после указанной ревизии.
+// Номер ревизии передается в теле запроса: {"revision": 3}.
+func (h *Handler) RevertBook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(pathParam(revertBookPath, r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID книги"))
+		return
+	}
+
+	var request struct {
+		Revision int `json:"revision"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Revision < 1 {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Укажите номер ревизии: {\"revision\": N}"))
+		return
+	}
+
+	book, err := h.repo.RevertBook(r.Context(), id, request.Revision)
+	if err != nil {
+		log.Printf("Error reverting book: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось откатить изменения книги"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(book)
+}
+
+// storageError преобразует ошибку хранилища в ошибку API
+func storageError(err error, message string) errors.AppError {
+	switch {
+	case stderrors.Is(err, storage.ErrBookNotFound):
+		return errors.NewNotFoundError("Книга не найдена")
+	case stderrors.Is(err, storage.ErrDuplicateISBN):
+		return errors.NewBadRequestError("Книга с таким ISBN уже существует")
+	case stderrors.Is(err, storage.ErrDuplicateIdentifier):
+		return errors.NewBadRequestError("Книга с таким идентификатором уже существует")
+	case stderrors.Is(err, storage.ErrVersionConflict):
+		return errors.NewPreconditionFailedError(versionConflictMessage)
+	case stderrors.Is(err, storage.ErrAuthorNotFound):
+		return errors.NewNotFoundError("Автор не найден")
+	case stderrors.Is(err, storage.ErrDuplicateAuthorName):
+		return errors.NewBadRequestError("Автор с таким именем уже существует")
+	case stderrors.Is(err, storage.ErrAuthorHasBooks):
+		return errors.NewConflictError("У автора есть книги: объедините его с другим автором или удалите книги")
+	case stderrors.Is(err, storage.ErrMergeSameAuthor):
+		return errors.NewBadRequestError("Нельзя объединить автора с самим собой")
+	case stderrors.Is(err, storage.ErrShelfNotFound):
+		return errors.NewNotFoundError("Полка не найдена")
+	case stderrors.Is(err, storage.ErrDuplicateShelfName):
+		return errors.NewBadRequestError("Полка с таким названием уже существует")
+	case stderrors.Is(err, storage.ErrBookNotOnShelf):
+		return errors.NewNotFoundError("Книги нет на этой полке")
+	case stderrors.Is(err, storage.ErrWorkNotFound):
+		return errors.NewNotFoundError("Произведение не найдено")
+	case stderrors.Is(err, storage.ErrBookNotInWork):
+		return errors.NewNotFoundError("Книга не является изданием этого произведения")
+	case stderrors.Is(err, storage.ErrRevisionNotFound):
+		return errors.NewNotFoundError("Ревизия не найдена")
+	case stderrors.Is(err, storage.ErrInvalidCursor):
+		return errors.NewBadRequestError("Некорректный курсор: он поврежден или выдан для другой сортировки")
+	}
+
+	var syntaxErr *search.SyntaxError
+	if stderrors.As(err, &syntaxErr) {
+		return errors.NewBadRequestError(syntaxErr.Error())
+	}
+	return errors.NewInternalServerError(message, err)
+}
+
 // extractIDFromPath извлекает ID из пути запроса
 // Например, из "/api/books/123" извлекает "123"
 func extractIDFromPath(path string) string {
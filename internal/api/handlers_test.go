@@ -2,11 +2,14 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,21 +18,26 @@ import (
 )
 
 func setupTestAPI(t *testing.T) (*Handler, func()) {
-	dbPath := "test.db"
-	db, err := storage.NewDatabase(dbPath)
-	if err != nil {
-		t.Fatalf("Failed to create test database: %v", err)
-	}
-
-	handler := NewHandler(db)
-	cleanup := func() {
-		db.Close()
-		os.Remove(dbPath)
-	}
+	handler := NewHandler(storage.NewMemoryRepository())
+	cleanup := func() {}
 
 	return handler, cleanup
 }
 
+// testISBN генерирует корректный ISBN-13 с контрольной суммой для i-й тестовой книги
+func testISBN(i int) string {
+	prefix := fmt.Sprintf("97804515%04d", i)
+	sum := 0
+	for j, c := range prefix {
+		digit := int(c - '0')
+		if j%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return fmt.Sprintf("%s%d", prefix, (10-sum%10)%10)
+}
+
 func TestCreateBookAPI(t *testing.T) {
 	handler, cleanup := setupTestAPI(t)
 	defer cleanup()
@@ -67,7 +75,7 @@ func TestListBooksAPI(t *testing.T) {
 		book := models.Book{
 			Title:     fmt.Sprintf("Test Book %d", i),
 			Author:    "Test Author",
-			ISBN:      fmt.Sprintf("978045152%04d", i),
+			ISBN:      testISBN(i),
 			Published: time.Now().Add(-24 * time.Hour),
 		}
 		body, _ := json.Marshal(book)
@@ -102,3 +110,1548 @@ func TestListBooksAPI(t *testing.T) {
 		t.Errorf("ListBooks() got total = %d, want 15", response.TotalBooks)
 	}
 }
+
+func TestCreateBookDuplicateISBNAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	book := models.Book{
+		Title:     "Test Book",
+		Author:    "Test Author",
+		ISBN:      "9780451524935",
+		Published: time.Now().Add(-24 * time.Hour),
+	}
+	body, _ := json.Marshal(book)
+
+	for i, want := range []int{http.StatusCreated, http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodPost, "/books", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.CreateBook(w, req)
+
+		if w.Code != want {
+			t.Errorf("CreateBook() attempt %d got status = %v, want %v", i+1, w.Code, want)
+		}
+	}
+}
+
+func TestCreateBookISBN10API(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	// Книга с ISBN-10 хранится под ISBN-13, ISBN-10 остается в ответе
+	body := `{"title":"1984","author":"George Orwell","isbn":"0-451-52493-4","published":"1950-01-01T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreateBook(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateBook() got status = %v, want %v: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var response map[string]any
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["isbn"] != "978-0-451-52493-5" || response["isbn10"] != "0451524934" {
+		t.Errorf("CreateBook() isbn = %v, isbn10 = %v", response["isbn"], response["isbn10"])
+	}
+
+	// Тот же ISBN в форме ISBN-13 считается дубликатом
+	body = `{"title":"1984","author":"George Orwell","isbn":"9780451524935","published":"1950-01-01T00:00:00Z"}`
+	req = httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	handler.CreateBook(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("CreateBook() duplicate got status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/books/search?q=0451524934", nil)
+	w = httptest.NewRecorder()
+	handler.SearchBooks(w, req)
+	if !strings.Contains(w.Body.String(), `"isbn10":"0451524934"`) {
+		t.Errorf("SearchBooks() by ISBN-10 = %s", w.Body)
+	}
+}
+
+func TestBookIdentifiersAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Книга без ISBN сохраняется, идентификаторы приводятся к каноническому виду
+	body := `{"title":"Nature","author":"Various","published":"1950-01-01T00:00:00Z",
+		"identifiers":[{"type":"ISSN","value":"00280836"},{"type":"doi","value":"https://doi.org/10.1038/NATURE"}]}`
+	w := send(http.MethodPost, "/api/books", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateBook() got status = %v, want %v: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var book models.Book
+	json.Unmarshal(w.Body.Bytes(), &book)
+	if book.ISBN != "" || len(book.Identifiers) != 2 || book.Identifiers[0] != (models.Identifier{Type: "issn", Value: "0028-0836"}) ||
+		book.Identifiers[1].Value != "10.1038/nature" {
+		t.Fatalf("CreateBook() = %+v", book)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid value", `{"title":"A","author":"B","published":"1950-01-01T00:00:00Z","identifiers":[{"type":"issn","value":"0028-0837"}]}`},
+		{"unknown type", `{"title":"A","author":"B","published":"1950-01-01T00:00:00Z","identifiers":[{"type":"ean","value":"1"}]}`},
+		{"taken", `{"title":"A","author":"B","published":"1950-01-01T00:00:00Z","identifiers":[{"type":"issn","value":"0028-0836"}]}`},
+	}
+	for _, tt := range tests {
+		if w := send(http.MethodPost, "/api/books", tt.body); w.Code != http.StatusBadRequest {
+			t.Errorf("CreateBook() %s got status = %v, want %v", tt.name, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	// PUT без identifiers сохраняет прежние, пустой список их удаляет
+	url := fmt.Sprintf("/api/books/%d", book.ID)
+	w = send(http.MethodPut, url, `{"title":"Nature","author":"Various","published":"1950-01-01T00:00:00Z"}`)
+	json.Unmarshal(w.Body.Bytes(), &book)
+	if w.Code != http.StatusOK || len(book.Identifiers) != 2 {
+		t.Errorf("UpdateBook() without identifiers got status = %v, identifiers %+v", w.Code, book.Identifiers)
+	}
+
+	if w := send(http.MethodGet, "/api/books/search?q=issn:00280836", ""); !strings.Contains(w.Body.String(), `"0028-0836"`) {
+		t.Errorf("SearchBooks() by ISSN = %s", w.Body)
+	}
+
+	w = send(http.MethodPut, url, `{"title":"Nature","author":"Various","published":"1950-01-01T00:00:00Z","identifiers":[]}`)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "identifiers") {
+		t.Errorf("UpdateBook() with empty identifiers got status = %v: %s", w.Code, w.Body)
+	}
+}
+
+func TestBookContributorsAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"title":"Гамлет","published":"1940-01-01T00:00:00Z","contributors":[
+		{"name":"William Shakespeare","role":"author"},{"name":"Борис Пастернак","role":"translator"}]}`
+	w := send(http.MethodPost, "/api/books", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateBook() got status = %v, want %v: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var book models.Book
+	json.Unmarshal(w.Body.Bytes(), &book)
+	if book.Author != "William Shakespeare" || len(book.Contributors) != 2 || book.Contributors[1].AuthorID == 0 {
+		t.Fatalf("CreateBook() = %+v", book)
+	}
+
+	// Старый клиент меняет автора через поле author, переводчик остается
+	url := fmt.Sprintf("/api/books/%d", book.ID)
+	w = send(http.MethodPut, url, `{"title":"Гамлет","author":"Уильям Шекспир","published":"1940-01-01T00:00:00Z"}`)
+	json.Unmarshal(w.Body.Bytes(), &book)
+	if w.Code != http.StatusOK || book.Author != "Уильям Шекспир" || len(book.Contributors) != 2 ||
+		book.Contributors[0].Name != "Уильям Шекспир" {
+		t.Errorf("UpdateBook() legacy author got status = %v: %s", w.Code, w.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, url, strings.NewReader(`{"author":"W. Shakespeare"}`))
+	req.Header.Set("Content-Type", mergePatchType)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &book)
+	if w.Code != http.StatusOK || len(book.Contributors) != 2 || book.Contributors[0].Name != "W. Shakespeare" {
+		t.Errorf("PatchBook() author got status = %v: %s", w.Code, w.Body)
+	}
+
+	translator := book.Contributors[1].AuthorID
+	w = send(http.MethodGet, fmt.Sprintf("/api/books?author_id=%d&role=translator", translator), "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Гамлет") {
+		t.Errorf("ListBooks(author_id) got status = %v: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name, method, url, body string
+	}{
+		{"unknown role", http.MethodPost, "/api/books", `{"title":"A","published":"1940-01-01T00:00:00Z","contributors":[{"name":"B","role":"composer"}]}`},
+		{"no author", http.MethodPost, "/api/books", `{"title":"A","published":"1940-01-01T00:00:00Z","contributors":[{"name":"B","role":"editor"}]}`},
+		{"role without author_id", http.MethodGet, "/api/books?role=editor", ""},
+		{"bad author_id", http.MethodGet, "/api/books?author_id=x", ""},
+	}
+	for _, tt := range tests {
+		if w := send(tt.method, tt.url, tt.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s got status = %v, want %v", tt.name, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAuthorsAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/api/authors", `{"name":"Лев  Толстой","alternate_names":["Leo Tolstoy"]}`)
+	var lev models.Author
+	json.Unmarshal(w.Body.Bytes(), &lev)
+	if w.Code != http.StatusCreated || lev.Name != "Лев Толстой" || lev.SortName != "Толстой, Лев" {
+		t.Fatalf("CreateAuthor() got status = %v: %s", w.Code, w.Body)
+	}
+
+	w = send(http.MethodPost, "/api/books", `{"title":"Анна Каренина","author":"Л. Толстой","published":"1878-01-01T00:00:00Z"}`)
+	var book models.Book
+	json.Unmarshal(w.Body.Bytes(), &book)
+	short := book.Contributors[0].AuthorID
+	send(http.MethodPost, "/api/books", `{"title":"War and Peace","author":"leo tolstoy","published":"1869-01-01T00:00:00Z"}`)
+
+	w = send(http.MethodGet, "/api/authors?name=толстой", "")
+	var list struct {
+		Authors      []models.Author `json:"authors"`
+		TotalAuthors int             `json:"total_authors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.TotalAuthors != 2 {
+		t.Errorf("ListAuthors() got status = %v: %s", w.Code, w.Body)
+	}
+
+	w = send(http.MethodPost, fmt.Sprintf("/api/authors/%d/merge", short), fmt.Sprintf(`{"into":%d}`, lev.ID))
+	json.Unmarshal(w.Body.Bytes(), &lev)
+	if w.Code != http.StatusOK || lev.BookCount != 2 || len(lev.AlternateNames) != 2 {
+		t.Errorf("MergeAuthors() got status = %v: %s", w.Code, w.Body)
+	}
+	w = send(http.MethodGet, fmt.Sprintf("/api/books/%d", book.ID), "")
+	if !strings.Contains(w.Body.String(), `"author":"Лев Толстой"`) {
+		t.Errorf("GetBook() after merge = %s", w.Body)
+	}
+
+	w = send(http.MethodPut, fmt.Sprintf("/api/authors/%d", lev.ID), `{"name":"Лев Николаевич Толстой","sort_name":"Толстой Л. Н."}`)
+	json.Unmarshal(w.Body.Bytes(), &lev)
+	if w.Code != http.StatusOK || lev.SortName != "Толстой Л. Н." || len(lev.AlternateNames) != 3 {
+		t.Errorf("UpdateAuthor() got status = %v: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name, method, url, body string
+		want                    int
+	}{
+		{"missing author", http.MethodGet, "/api/authors/999", "", http.StatusNotFound},
+		{"bad id", http.MethodGet, "/api/authors/abc", "", http.StatusBadRequest},
+		{"empty name", http.MethodPost, "/api/authors", `{"name":" "}`, http.StatusBadRequest},
+		{"taken name", http.MethodPost, "/api/authors", `{"name":"Л. Толстой"}`, http.StatusBadRequest},
+		{"merge into itself", http.MethodPost, fmt.Sprintf("/api/authors/%d/merge", lev.ID), fmt.Sprintf(`{"into":%d}`, lev.ID), http.StatusBadRequest},
+		{"merge without target", http.MethodPost, fmt.Sprintf("/api/authors/%d/merge", lev.ID), `{}`, http.StatusBadRequest},
+		{"merged author", http.MethodGet, fmt.Sprintf("/api/authors/%d", short), "", http.StatusNotFound},
+		{"author with books", http.MethodDelete, fmt.Sprintf("/api/authors/%d", lev.ID), "", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		if w := send(tt.method, tt.url, tt.body); w.Code != tt.want {
+			t.Errorf("%s got status = %v, want %v: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestTagsAndShelvesAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var dune, solaris models.Book
+	w := send(http.MethodPost, "/api/books", `{"title":"Дюна","author":"Фрэнк Герберт","published":"1965-01-01T00:00:00Z","tags":["Фантастика","любимое"]}`)
+	json.Unmarshal(w.Body.Bytes(), &dune)
+	if w.Code != http.StatusCreated || strings.Join(dune.Tags, ",") != "любимое,фантастика" {
+		t.Fatalf("CreateBook() with tags got status = %v: %s", w.Code, w.Body)
+	}
+	w = send(http.MethodPost, "/api/books", `{"title":"Солярис","author":"Станислав Лем","published":"1961-01-01T00:00:00Z","tags":["фантастика"]}`)
+	json.Unmarshal(w.Body.Bytes(), &solaris)
+
+	// PUT без поля tags оставляет теги
+	w = send(http.MethodPut, fmt.Sprintf("/api/books/%d", dune.ID), `{"title":"Дюна","author":"Фрэнк Герберт","published":"1965-01-01T00:00:00Z"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"tags":["любимое","фантастика"]`) {
+		t.Errorf("UpdateBook() without tags got status = %v: %s", w.Code, w.Body)
+	}
+
+	w = send(http.MethodGet, "/api/tags", "")
+	if w.Code != http.StatusOK || w.Body.String() != `{"tags":[{"name":"любимое","count":1},{"name":"фантастика","count":2}]}`+"\n" {
+		t.Errorf("ListTags() got status = %v: %s", w.Code, w.Body)
+	}
+	w = send(http.MethodGet, "/api/books?tag=Фантастика&tag=любимое", "")
+	if !strings.Contains(w.Body.String(), `"total_books":1`) {
+		t.Errorf("ListBooks(tag) = %s", w.Body)
+	}
+
+	var shelf models.Shelf
+	w = send(http.MethodPost, "/api/shelves", `{"name":" Прочитать ","description":"Летом"}`)
+	json.Unmarshal(w.Body.Bytes(), &shelf)
+	if w.Code != http.StatusCreated || shelf.Name != "Прочитать" {
+		t.Fatalf("CreateShelf() got status = %v: %s", w.Code, w.Body)
+	}
+	books := fmt.Sprintf("/api/shelves/%d/books", shelf.ID)
+
+	for _, body := range []string{fmt.Sprintf(`{"book_id":%d}`, dune.ID), fmt.Sprintf(`{"book_id":%d,"position":0}`, solaris.ID)} {
+		if w := send(http.MethodPost, books, body); w.Code != http.StatusNoContent {
+			t.Errorf("AddToShelf(%s) got status = %v: %s", body, w.Code, w.Body)
+		}
+	}
+	w = send(http.MethodGet, books, "")
+	var list struct {
+		Books      []models.Book `json:"books"`
+		TotalBooks int           `json:"total_books"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.TotalBooks != 2 || list.Books[0].ID != solaris.ID {
+		t.Errorf("ShelfBooks() got status = %v: %s", w.Code, w.Body)
+	}
+	w = send(http.MethodGet, fmt.Sprintf("/api/books/%d", dune.ID), "")
+	if !strings.Contains(w.Body.String(), fmt.Sprintf(`"shelves":[%d]`, shelf.ID)) {
+		t.Errorf("GetBook() shelves = %s", w.Body)
+	}
+
+	w = send(http.MethodPut, books, fmt.Sprintf(`{"book_ids":[%d]}`, dune.ID))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("SetShelfBooks() got status = %v: %s", w.Code, w.Body)
+	}
+	w = send(http.MethodDelete, fmt.Sprintf("%s/%d", books, dune.ID), "")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("RemoveFromShelf() got status = %v: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name, method, url, body string
+		want                    int
+	}{
+		{"missing shelf", http.MethodGet, "/api/shelves/999", "", http.StatusNotFound},
+		{"bad id", http.MethodGet, "/api/shelves/abc/books", "", http.StatusBadRequest},
+		{"empty name", http.MethodPost, "/api/shelves", `{"name":" "}`, http.StatusBadRequest},
+		{"taken name", http.MethodPost, "/api/shelves", `{"name":"Прочитать"}`, http.StatusBadRequest},
+		{"missing book", http.MethodPost, books, `{"book_id":999}`, http.StatusNotFound},
+		{"negative position", http.MethodPost, books, fmt.Sprintf(`{"book_id":%d,"position":-1}`, dune.ID), http.StatusBadRequest},
+		{"repeated books", http.MethodPut, books, fmt.Sprintf(`{"book_ids":[%d,%d]}`, dune.ID, dune.ID), http.StatusBadRequest},
+		{"book not on shelf", http.MethodDelete, fmt.Sprintf("%s/%d", books, dune.ID), "", http.StatusNotFound},
+		{"bad shelf filter", http.MethodGet, "/api/books?shelf=abc", "", http.StatusBadRequest},
+		{"long tag", http.MethodPost, "/api/books", `{"title":"T","author":"A","published":"2000-01-01T00:00:00Z","tags":["` + strings.Repeat("x", 51) + `"]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := send(tt.method, tt.url, tt.body); w.Code != tt.want {
+			t.Errorf("%s got status = %v, want %v: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+
+	if w := send(http.MethodDelete, fmt.Sprintf("/api/shelves/%d", shelf.ID), ""); w.Code != http.StatusNoContent {
+		t.Errorf("DeleteShelf() got status = %v: %s", w.Code, w.Body)
+	}
+}
+
+func TestSeriesAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var second models.Book
+	w := send(http.MethodPost, "/api/books", `{"title":"Две башни","author":"Дж. Р. Р. Толкин","published":"1954-11-11T00:00:00Z","series":"Властелин колец","series_position":2}`)
+	json.Unmarshal(w.Body.Bytes(), &second)
+	if w.Code != http.StatusCreated || second.SeriesID == 0 {
+		t.Fatalf("CreateBook() with series got status = %v: %s", w.Code, w.Body)
+	}
+	send(http.MethodPost, "/api/books", `{"title":"Братство кольца","author":"Дж. Р. Р. Толкин","published":"1954-07-29T00:00:00Z","series":"властелин колец","series_position":1}`)
+
+	// PUT без серии оставляет ее, номер без названия меняет номер
+	w = send(http.MethodPut, fmt.Sprintf("/api/books/%d", second.ID), `{"title":"Две крепости","author":"Дж. Р. Р. Толкин","published":"1954-11-11T00:00:00Z","series_position":2.5}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"series":"Властелин колец"`) || !strings.Contains(w.Body.String(), `"series_position":2.5`) {
+		t.Errorf("UpdateBook() without series got status = %v: %s", w.Code, w.Body)
+	}
+
+	w = send(http.MethodGet, fmt.Sprintf("/api/series/%d", second.SeriesID), "")
+	var series struct {
+		models.Series
+		Books []models.Book `json:"books"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &series)
+	if w.Code != http.StatusOK || series.Name != "Властелин колец" || series.BookCount != 2 ||
+		len(series.Books) != 2 || series.Books[0].Title != "Братство кольца" {
+		t.Errorf("GetSeries() got status = %v: %s", w.Code, w.Body)
+	}
+
+	w = send(http.MethodGet, "/api/books/search?q=series:властелин", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"total_books":2`) {
+		t.Errorf("SearchBooks(series:) got status = %v: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name, method, url, body string
+		want                    int
+	}{
+		{"missing series", http.MethodGet, "/api/series/999", "", http.StatusNotFound},
+		{"bad id", http.MethodGet, "/api/series/abc", "", http.StatusBadRequest},
+		{"position without series", http.MethodPost, "/api/books", `{"title":"T","author":"A","published":"2000-01-01T00:00:00Z","series_position":1}`, http.StatusBadRequest},
+		{"negative position", http.MethodPost, "/api/books", `{"title":"T","author":"A","published":"2000-01-01T00:00:00Z","series":"S","series_position":-1}`, http.StatusBadRequest},
+		{"list", http.MethodGet, "/api/series", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := send(tt.method, tt.url, tt.body); w.Code != tt.want {
+			t.Errorf("%s got status = %v, want %v: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestWorksAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var ids []int64
+	for _, body := range []string{
+		`{"title":"Анна Каренина","author":"Лев Толстой","published":"1878-01-01T00:00:00Z","publisher":"Русский вестник","language":"RU","format":"hardcover"}`,
+		`{"title":"Анна Каренина","author":"Лев Толстой","published":"2012-01-01T00:00:00Z","language":"ru","format":"ebook"}`,
+	} {
+		var book models.Book
+		w := send(http.MethodPost, "/api/books", body)
+		json.Unmarshal(w.Body.Bytes(), &book)
+		if w.Code != http.StatusCreated || book.Language != "ru" {
+			t.Fatalf("CreateBook() with edition got status = %v: %s", w.Code, w.Body)
+		}
+		ids = append(ids, book.ID)
+	}
+
+	var work models.Work
+	w := send(http.MethodPost, "/api/works", `{"title":" Анна  Каренина "}`)
+	json.Unmarshal(w.Body.Bytes(), &work)
+	if w.Code != http.StatusCreated || work.Title != "Анна Каренина" {
+		t.Fatalf("CreateWork() got status = %v: %s", w.Code, w.Body)
+	}
+	for _, id := range ids {
+		if w := send(http.MethodPost, fmt.Sprintf("/api/works/%d/editions", work.ID), fmt.Sprintf(`{"book_id":%d}`, id)); w.Code != http.StatusNoContent {
+			t.Fatalf("LinkEdition() got status = %v: %s", w.Code, w.Body)
+		}
+	}
+
+	w = send(http.MethodGet, fmt.Sprintf("/api/works/%d/editions", work.ID), "")
+	var editions struct {
+		Editions []models.Book `json:"editions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &editions)
+	if w.Code != http.StatusOK || len(editions.Editions) != 2 || editions.Editions[0].Publisher != "Русский вестник" ||
+		editions.Editions[1].WorkID != work.ID {
+		t.Errorf("WorkEditions() got status = %v: %s", w.Code, w.Body)
+	}
+
+	w = send(http.MethodGet, "/api/books/search?q=title:каренина&collapse=work", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"total_books":1`) || !strings.Contains(w.Body.String(), `"editions":2`) {
+		t.Errorf("SearchBooks(collapse=work) got status = %v: %s", w.Code, w.Body)
+	}
+
+	// PUT без сведений об издании оставляет их
+	w = send(http.MethodPut, fmt.Sprintf("/api/books/%d", ids[0]), `{"title":"Анна Каренина","author":"Лев Толстой","published":"1878-01-01T00:00:00Z"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"publisher":"Русский вестник"`) || !strings.Contains(w.Body.String(), fmt.Sprintf(`"work_id":%d`, work.ID)) {
+		t.Errorf("UpdateBook() without edition got status = %v: %s", w.Code, w.Body)
+	}
+
+	editionPath := fmt.Sprintf("/api/works/%d/editions/%d", work.ID, ids[1])
+	tests := []struct {
+		name, method, url, body string
+		want                    int
+	}{
+		{"bad language", http.MethodPost, "/api/books", `{"title":"T","author":"A","published":"2000-01-01T00:00:00Z","language":"russian"}`, http.StatusBadRequest},
+		{"bad format", http.MethodPost, "/api/books", `{"title":"T","author":"A","published":"2000-01-01T00:00:00Z","format":"scroll"}`, http.StatusBadRequest},
+		{"bad collapse", http.MethodGet, "/api/books/search?q=анна&collapse=author", "", http.StatusBadRequest},
+		{"empty title", http.MethodPost, "/api/works", `{"title":" "}`, http.StatusBadRequest},
+		{"rename", http.MethodPut, fmt.Sprintf("/api/works/%d", work.ID), `{"title":"Анна Каренина (роман)"}`, http.StatusOK},
+		{"get", http.MethodGet, fmt.Sprintf("/api/works/%d", work.ID), "", http.StatusOK},
+		{"missing work", http.MethodGet, "/api/works/999", "", http.StatusNotFound},
+		{"bad id", http.MethodGet, "/api/works/abc/editions", "", http.StatusBadRequest},
+		{"link missing book", http.MethodPost, fmt.Sprintf("/api/works/%d/editions", work.ID), `{"book_id":999}`, http.StatusNotFound},
+		{"link without book", http.MethodPost, fmt.Sprintf("/api/works/%d/editions", work.ID), `{}`, http.StatusBadRequest},
+		{"unlink", http.MethodDelete, editionPath, "", http.StatusNoContent},
+		{"unlink twice", http.MethodDelete, editionPath, "", http.StatusNotFound},
+		{"list", http.MethodGet, "/api/works", "", http.StatusOK},
+		{"delete", http.MethodDelete, fmt.Sprintf("/api/works/%d", work.ID), "", http.StatusNoContent},
+		{"delete twice", http.MethodDelete, fmt.Sprintf("/api/works/%d", work.ID), "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := send(tt.method, tt.url, tt.body); w.Code != tt.want {
+			t.Errorf("%s got status = %v, want %v: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestDeleteMissingBookAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/books/42", nil)
+	w := httptest.NewRecorder()
+
+	handler.DeleteBook(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("DeleteBook() got status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestSearchBooksSyntaxErrorAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, `/api/books/search?q=title:war+genre:poetry`, nil)
+	w := httptest.NewRecorder()
+
+	handler.SearchBooks(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("SearchBooks() got status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+
+	var response struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("SearchBooks() returned invalid JSON: %v", err)
+	}
+	if response.Error != "BAD_REQUEST" || !strings.Contains(response.Message, `"genre"`) {
+		t.Errorf("SearchBooks() got error = %+v, want BAD_REQUEST pointing at genre", response)
+	}
+}
+
+func TestListBooksSortAndFilterAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	authors := []string{"Толстой", "Достоевский", "Чехов"}
+	for i, author := range authors {
+		book := models.Book{
+			Title:     fmt.Sprintf("Test Book %d", i),
+			Author:    author,
+			ISBN:      testISBN(i),
+			Published: time.Now().Add(-24 * time.Hour),
+		}
+		body, _ := json.Marshal(book)
+		req := httptest.NewRequest(http.MethodPost, "/books", bytes.NewReader(body))
+		handler.CreateBook(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/books?sort=author", nil)
+	w := httptest.NewRecorder()
+	handler.ListBooks(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ListBooks() got status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	var response struct {
+		Books []*models.Book `json:"books"`
+		Sort  string         `json:"sort"`
+		Order string         `json:"order"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Sort != "author" || response.Order != "asc" {
+		t.Errorf("ListBooks() got sort = %s %s, want author asc", response.Sort, response.Order)
+	}
+	if len(response.Books) != 3 || response.Books[0].Author != "Достоевский" || response.Books[2].Author != "Чехов" {
+		t.Errorf("ListBooks() returned books in wrong order: %+v", response.Books)
+	}
+
+	invalid := []string{
+		"/api/books?sort=isbn",
+		"/api/books?sort=title&order=sideways",
+		"/api/books?published_from=yesterday",
+		"/api/books?published_from=2020-01-02&published_to=2020-01-01",
+	}
+	for _, url := range invalid {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		handler.ListBooks(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("ListBooks(%s) got status = %v, want %v", url, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestListBooksCursorAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		book := models.Book{
+			Title:     fmt.Sprintf("Test Book %d", i),
+			Author:    "Test Author",
+			ISBN:      testISBN(i),
+			Published: time.Now().Add(-24 * time.Hour),
+		}
+		body, _ := json.Marshal(book)
+		req := httptest.NewRequest(http.MethodPost, "/books", bytes.NewReader(body))
+		handler.CreateBook(httptest.NewRecorder(), req)
+	}
+
+	type listResponse struct {
+		Books      []*models.Book `json:"books"`
+		TotalBooks *int           `json:"total_books"`
+		Page       int            `json:"page"`
+		NextCursor string         `json:"next_cursor"`
+		PrevCursor string         `json:"prev_cursor"`
+	}
+	list := func(url string) listResponse {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		handler.ListBooks(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("ListBooks(%s) got status = %v, want %v", url, w.Code, http.StatusOK)
+		}
+		var response listResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	first := list("/api/books?sort=title&page_size=2&include_total=false")
+	if first.TotalBooks != nil {
+		t.Errorf("ListBooks() got total = %d with include_total=false", *first.TotalBooks)
+	}
+	if first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("ListBooks() first page cursors = %q, %q", first.PrevCursor, first.NextCursor)
+	}
+
+	second := list("/api/books?sort=title&page_size=2&cursor=" + first.NextCursor)
+	if second.Page != 0 || second.TotalBooks == nil || *second.TotalBooks != 5 {
+		t.Errorf("ListBooks() by cursor got page = %d, total = %v", second.Page, second.TotalBooks)
+	}
+	if len(second.Books) != 2 || second.Books[0].Title != "Test Book 2" {
+		t.Errorf("ListBooks() by cursor returned %+v, want Test Book 2 and 3", second.Books)
+	}
+
+	back := list("/api/books?sort=title&page_size=2&cursor=" + second.PrevCursor)
+	if len(back.Books) != 2 || back.Books[0].Title != "Test Book 0" {
+		t.Errorf("ListBooks() by prev cursor returned %+v, want Test Book 0 and 1", back.Books)
+	}
+
+	for _, url := range []string{
+		"/api/books?cursor=broken",
+		"/api/books?sort=author&cursor=" + first.NextCursor,
+		"/api/books?include_total=maybe",
+	} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		handler.ListBooks(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("ListBooks(%s) got status = %v, want %v", url, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestTrashAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	book := models.Book{Title: "Test Book", Author: "Test Author", ISBN: testISBN(1), Published: time.Now().Add(-24 * time.Hour)}
+	body, _ := json.Marshal(book)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/books", bytes.NewReader(body)))
+	json.Unmarshal(w.Body.Bytes(), &book)
+
+	steps := []struct {
+		method, url string
+		want        int
+	}{
+		{http.MethodDelete, fmt.Sprintf("/api/books/%d", book.ID), http.StatusNoContent},
+		{http.MethodGet, fmt.Sprintf("/api/books/%d", book.ID), http.StatusNotFound},
+		{http.MethodGet, "/api/trash", http.StatusOK},
+		{http.MethodPost, fmt.Sprintf("/api/books/%d/restore", book.ID), http.StatusOK},
+		{http.MethodGet, fmt.Sprintf("/api/books/%d", book.ID), http.StatusOK},
+		{http.MethodPost, fmt.Sprintf("/api/books/%d/restore", book.ID), http.StatusNotFound},
+		{http.MethodPost, "/api/books/abc/restore", http.StatusBadRequest},
+	}
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(step.method, step.url, nil))
+		if w.Code != step.want {
+			t.Fatalf("%s %s got status = %v, want %v", step.method, step.url, w.Code, step.want)
+		}
+
+		if step.url == "/api/trash" {
+			var response struct {
+				Books []*models.Book `json:"books"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if len(response.Books) != 1 || response.Books[0].DeletedAt == nil {
+				t.Errorf("GET /api/trash = %+v, want trashed book", response.Books)
+			}
+		}
+	}
+}
+
+func TestBookHistoryAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	router.Use(ActorMiddleware)
+	handler.RegisterRoutes(router)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set(actorHeader, " alice ")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	book := models.Book{Title: "Test Book", Author: "Test Author", ISBN: testISBN(1), Published: time.Now().Add(-24 * time.Hour)}
+	body, _ := json.Marshal(book)
+	json.Unmarshal(send(http.MethodPost, "/api/books", string(body)).Body.Bytes(), &book)
+
+	book.Title = "Renamed Book"
+	body, _ = json.Marshal(book)
+	if w := send(http.MethodPut, fmt.Sprintf("/api/books/%d", book.ID), string(body)); w.Code != http.StatusOK {
+		t.Fatalf("UpdateBook got status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	w := send(http.MethodGet, fmt.Sprintf("/api/books/%d/history", book.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("BookHistory got status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var history struct {
+		BookID    int64              `json:"book_id"`
+		Revisions []*models.Revision `json:"revisions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &history)
+	if history.BookID != book.ID || len(history.Revisions) != 2 {
+		t.Fatalf("BookHistory = %+v, want 2 revisions", history)
+	}
+	if rev := history.Revisions[0]; rev.Action != models.ActionUpdate || rev.Actor != "alice" {
+		t.Errorf("BookHistory last revision = %s by %q, want update by alice", rev.Action, rev.Actor)
+	}
+
+	w = send(http.MethodPost, fmt.Sprintf("/api/books/%d/revert", book.ID), `{"revision": 1}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("RevertBook got status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var reverted models.Book
+	json.Unmarshal(w.Body.Bytes(), &reverted)
+	if reverted.Title != "Test Book" {
+		t.Errorf("RevertBook title = %q, want %q", reverted.Title, "Test Book")
+	}
+
+	tests := []struct {
+		method, url, body string
+		want              int
+	}{
+		{http.MethodPost, fmt.Sprintf("/api/books/%d/revert", book.ID), `{"revision": 42}`, http.StatusNotFound},
+		{http.MethodPost, fmt.Sprintf("/api/books/%d/revert", book.ID), `{"revision": 0}`, http.StatusBadRequest},
+		{http.MethodPost, fmt.Sprintf("/api/books/%d/revert", book.ID), `not json`, http.StatusBadRequest},
+		{http.MethodGet, "/api/books/9999/history", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := send(tt.method, tt.url, tt.body); w.Code != tt.want {
+			t.Errorf("%s %s %s got status = %v, want %v", tt.method, tt.url, tt.body, w.Code, tt.want)
+		}
+	}
+}
+
+func TestBookETagAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, url, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	book := models.Book{Title: "Test Book", Author: "Test Author", ISBN: testISBN(1), Published: time.Now().Add(-24 * time.Hour)}
+	body, _ := json.Marshal(book)
+	w := send(http.MethodPost, "/api/books", string(body), nil)
+	json.Unmarshal(w.Body.Bytes(), &book)
+	url := fmt.Sprintf("/api/books/%d", book.ID)
+
+	w = send(http.MethodGet, url, "", nil)
+	if etag := w.Header().Get("ETag"); etag != `"1"` {
+		t.Fatalf("GetBook ETag = %q, want %q", etag, `"1"`)
+	}
+	if w := send(http.MethodGet, url, "", map[string]string{"If-None-Match": `W/"1"`}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("GetBook with current If-None-Match got status = %v, want %v", w.Code, http.StatusNotModified)
+	}
+
+	book.Title = "Updated Book"
+	body, _ = json.Marshal(book)
+	w = send(http.MethodPut, url, string(body), map[string]string{"If-Match": `"1"`})
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("UpdateBook with current If-Match got status = %v, ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+		want   int
+	}{
+		{"stale put", http.MethodPut, map[string]string{"If-Match": `"1"`}, http.StatusPreconditionFailed},
+		{"weak put", http.MethodPut, map[string]string{"If-Match": `W/"2"`}, http.StatusPreconditionFailed},
+		{"stale delete", http.MethodDelete, map[string]string{"If-Match": `"1"`}, http.StatusPreconditionFailed},
+		{"stale get", http.MethodGet, map[string]string{"If-None-Match": `"1"`}, http.StatusOK},
+		{"any put", http.MethodPut, map[string]string{"If-Match": "*"}, http.StatusOK},
+		{"current delete", http.MethodDelete, map[string]string{"If-Match": `"0", "3"`}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if w := send(tt.method, url, string(body), tt.header); w.Code != tt.want {
+			t.Errorf("%s got status = %v, want %v", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestPatchBookAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	published := time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC)
+	book := models.Book{Title: "Test Book", Author: "Test Author", ISBN: testISBN(1), Published: published}
+	body, _ := json.Marshal(book)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/books", bytes.NewReader(body)))
+	json.Unmarshal(w.Body.Bytes(), &book)
+	url := fmt.Sprintf("/api/books/%d", book.ID)
+
+	send := func(contentType, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Merge Patch меняет одно поле, остальные остаются прежними
+	w = send(mergePatchType, `{"title": "Merged Title", "id": 999}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PatchBook merge got status = %v, want %v: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var patched models.Book
+	json.Unmarshal(w.Body.Bytes(), &patched)
+	if patched.ID != book.ID || patched.Title != "Merged Title" || patched.Author != "Test Author" || !patched.Published.Equal(published) {
+		t.Errorf("PatchBook merge = %+v", patched)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("PatchBook ETag = %q, want %q", etag, `"2"`)
+	}
+
+	w = send(jsonPatchType+"; charset=utf-8", `[
+		{"op": "test", "path": "/title", "value": "Merged Title"},
+		{"op": "replace", "path": "/author", "value": "Patched Author"}
+	]`, map[string]string{"If-Match": `"2"`})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PatchBook json patch got status = %v, want %v: %s", w.Code, http.StatusOK, w.Body)
+	}
+	json.Unmarshal(w.Body.Bytes(), &patched)
+	if patched.Title != "Merged Title" || patched.Author != "Patched Author" {
+		t.Errorf("PatchBook json patch = %+v", patched)
+	}
+
+	tests := []struct {
+		name, contentType, body string
+		header                  map[string]string
+		want                    int
+	}{
+		{"plain json", "application/json", `{"title": "x"}`, nil, http.StatusUnsupportedMediaType},
+		{"remove required field", mergePatchType, `{"published": null}`, nil, http.StatusBadRequest},
+		{"broken merge patch", mergePatchType, `{"title":`, nil, http.StatusBadRequest},
+		{"wrong field type", mergePatchType, `{"title": 5}`, nil, http.StatusBadRequest},
+		{"unknown op", jsonPatchType, `[{"op": "swap", "path": "/title"}]`, nil, http.StatusBadRequest},
+		{"failed test", jsonPatchType, `[{"op": "test", "path": "/title", "value": "Other"}]`, nil, http.StatusConflict},
+		{"stale version", mergePatchType, `{"title": "Stale"}`, map[string]string{"If-Match": `"1"`}, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		if w := send(tt.contentType, tt.body, tt.header); w.Code != tt.want {
+			t.Errorf("PatchBook %s got status = %v, want %v", tt.name, w.Code, tt.want)
+		}
+	}
+
+	stored, _ := handler.repo.GetBook(context.Background(), book.ID)
+	if stored.Title != "Merged Title" || stored.Version != 3 {
+		t.Errorf("GetBook() after rejected patches = %q v%d, want %q v3", stored.Title, stored.Version, "Merged Title")
+	}
+}
+
+func TestBatchBooksAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	type response struct {
+		Committed bool `json:"committed"`
+		Results   []struct {
+			Index  int          `json:"index"`
+			Status int          `json:"status"`
+			Book   *models.Book `json:"book"`
+			Error  *struct {
+				Error string `json:"error"`
+			} `json:"error"`
+		} `json:"results"`
+	}
+	send := func(url, body string) (int, response) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, strings.NewReader(body)))
+		var resp response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	book := func(i int) string {
+		return fmt.Sprintf(`{"title": "Book %d", "author": "Test Author", "isbn": %q, "published": "2000-01-01T00:00:00Z"}`, i, testISBN(i))
+	}
+
+	body := fmt.Sprintf(`[{"op": "create", "book": %s}, {"op": "create", "book": %s}]`, book(1), book(2))
+	code, resp := send("/api/books/batch", body)
+	if code != http.StatusOK || !resp.Committed || len(resp.Results) != 2 || resp.Results[1].Status != http.StatusCreated {
+		t.Fatalf("BatchBooks create got status = %v, response %+v", code, resp)
+	}
+	first := resp.Results[0].Book
+
+	// Атомарный пакет с некорректной операцией не применяется
+	body = fmt.Sprintf(`[{"op": "create", "book": %s}, {"op": "delete", "id": %d}, {"op": "create", "book": {"title": ""}}]`, book(3), first.ID)
+	code, resp = send("/api/books/batch", body)
+	if code != http.StatusBadRequest || resp.Committed {
+		t.Errorf("BatchBooks atomic invalid got status = %v, committed = %v", code, resp.Committed)
+	}
+	if resp.Results[0].Status != http.StatusConflict || resp.Results[2].Error == nil || resp.Results[2].Error.Error != "BAD_REQUEST" {
+		t.Errorf("BatchBooks atomic invalid results = %+v", resp.Results)
+	}
+
+	// Ошибка хранилища в атомарном пакете откатывает остальные операции
+	body = fmt.Sprintf(`[{"op": "delete", "id": %d}, {"op": "update", "id": 9999, "book": %s}]`, first.ID, book(4))
+	code, resp = send("/api/books/batch", body)
+	if code != http.StatusNotFound || resp.Committed || resp.Results[1].Status != http.StatusNotFound {
+		t.Errorf("BatchBooks atomic missing book got status = %v, response %+v", code, resp)
+	}
+	if stored, _ := handler.repo.GetBook(context.Background(), first.ID); stored == nil {
+		t.Errorf("BatchBooks rolled back delete removed book %d", first.ID)
+	}
+
+	// Без atomic выполняются все корректные операции
+	body = fmt.Sprintf(`[{"op": "update", "id": %d, "version": 1, "book": %s}, {"op": "delete", "id": %d, "version": 1}, {"op": "rename"}]`, first.ID, book(5), first.ID)
+	code, resp = send("/api/books/batch?atomic=false", body)
+	if code != http.StatusOK || !resp.Committed {
+		t.Fatalf("BatchBooks non-atomic got status = %v, committed = %v", code, resp.Committed)
+	}
+	wantStatus := []int{http.StatusOK, http.StatusPreconditionFailed, http.StatusBadRequest}
+	for i, want := range wantStatus {
+		if resp.Results[i].Index != i || resp.Results[i].Status != want {
+			t.Errorf("BatchBooks non-atomic result %d status = %v, want %v", i, resp.Results[i].Status, want)
+		}
+	}
+	if resp.Results[0].Book == nil || resp.Results[0].Book.Title != "Book 5" {
+		t.Errorf("BatchBooks update result book = %+v", resp.Results[0].Book)
+	}
+
+	// Обновление без author, как и PUT, оставляет прежних авторов
+	body = fmt.Sprintf(`[{"op": "update", "id": %d, "book": {"title": "Book 6", "isbn": %q, "published": "2000-01-01T00:00:00Z"}}]`, first.ID, testISBN(5))
+	code, resp = send("/api/books/batch", body)
+	if code != http.StatusOK || !resp.Committed || resp.Results[0].Book == nil || resp.Results[0].Book.Author != "Test Author" {
+		t.Errorf("BatchBooks update without author got status = %v, response %+v", code, resp)
+	}
+
+	// Ошибка хранилища отменяет атомарный пакет, ответ - статус этой ошибки
+	body = fmt.Sprintf(`[{"op": "create", "book": %s}, {"op": "create", "book": %s}]`, book(7), book(2))
+	code, resp = send("/api/books/batch", body)
+	if code != http.StatusBadRequest || resp.Committed || resp.Results[0].Status != http.StatusConflict {
+		t.Errorf("BatchBooks atomic duplicate ISBN got status = %v, response %+v", code, resp)
+	}
+
+	for _, tt := range []struct{ url, body string }{
+		{"/api/books/batch", `[]`},
+		{"/api/books/batch", `{"op": "create"}`},
+		{"/api/books/batch?atomic=maybe", `[{"op": "delete", "id": 1}]`},
+	} {
+		if code, _ := send(tt.url, tt.body); code != http.StatusBadRequest {
+			t.Errorf("BatchBooks %s %s got status = %v, want %v", tt.url, tt.body, code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestBatchUpdateKeepsOmittedFieldsAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return w
+	}
+
+	var book models.Book
+	w := send(http.MethodPost, "/api/books", fmt.Sprintf(`{"title":"Book","author":"Test Author","isbn":%q,"published":"2000-01-01T00:00:00Z",
+		"identifiers":[{"type":"oclc","value":"12345"}],"tags":["classic"],"series":"Series","series_position":2,
+		"publisher":"Азбука","language":"ru","format":"ebook"}`, testISBN(1)))
+	json.Unmarshal(w.Body.Bytes(), &book)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateBook() got status = %v: %s", w.Code, w.Body)
+	}
+
+	// Операция update, как и PUT, не удаляет поля, которых в ней нет
+	body := fmt.Sprintf(`[{"op":"update","id":%d,"book":{"title":"Renamed","author":"Test Author","published":"2000-01-01T00:00:00Z"}}]`, book.ID)
+	if w := send(http.MethodPost, "/api/books/batch", body); w.Code != http.StatusOK {
+		t.Fatalf("BatchBooks() got status = %v: %s", w.Code, w.Body)
+	}
+	var got models.Book
+	json.Unmarshal(send(http.MethodGet, fmt.Sprintf("/api/books/%d", book.ID), "").Body.Bytes(), &got)
+	if got.Title != "Renamed" || got.ISBN != book.ISBN || len(got.Identifiers) != 1 || len(got.Tags) != 1 ||
+		got.Series != "Series" || got.SeriesPosition == nil || *got.SeriesPosition != 2 ||
+		got.Publisher != "Азбука" || got.Language != "ru" || got.Format != models.EditionEbook {
+		t.Errorf("GetBook() after batch update = %+v", got)
+	}
+}
+
+// multipartCSV собирает тело формы импорта с файлом и, если задано, полем mapping
+func multipartCSV(t *testing.T, csv, mapping string) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "books.csv")
+	if err != nil {
+		t.Fatalf("CreateFormFile() error = %v", err)
+	}
+	file.Write([]byte(csv))
+	if mapping != "" {
+		form.WriteField("mapping", mapping)
+	}
+	form.Close()
+	return &body, form.FormDataContentType()
+}
+
+func TestExportImportAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	for i := 1; i <= 2; i++ {
+		book := models.Book{Title: fmt.Sprintf("Book %d", i), Author: "Test Author", ISBN: testISBN(i), Published: time.Date(2000+i, 1, 1, 0, 0, 0, 0, time.UTC)}
+		body, _ := json.Marshal(book)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/books", bytes.NewReader(body)))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/export?format=csv", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("ExportBooks got status = %v, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	exported := w.Body.String()
+	if lines := strings.Split(strings.TrimSpace(exported), "\n"); len(lines) != 3 || !strings.Contains(lines[1], "Book 1") {
+		t.Fatalf("ExportBooks = %q, want header and 2 books oldest first", exported)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/export?format=xls", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("ExportBooks unknown format got status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+
+	// Импорт выгрузки: книги уже есть, поэтому все строки - дубликаты ISBN
+	csv := exported + fmt.Sprintf("0,New Book,Test Author,%s,2010-05-01,,\n", testISBN(3)) + "0,,Test Author,123,2010,,\n"
+	body, contentType := multipartCSV(t, csv, "")
+	req := httptest.NewRequest(http.MethodPost, "/api/import", body)
+	req.Header.Set("Content-Type", contentType)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ImportBooks got status = %v: %s", w.Code, w.Body)
+	}
+
+	var report struct {
+		Mapping    map[string]string `json:"mapping"`
+		Created    []importedRow     `json:"created"`
+		Duplicates []importedRow     `json:"duplicates"`
+		Invalid    []importedRow     `json:"invalid"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if len(report.Created) != 1 || report.Created[0].Line != 4 || report.Created[0].ID == 0 {
+		t.Errorf("ImportBooks created = %+v, want line 4", report.Created)
+	}
+	if len(report.Duplicates) != 2 || report.Duplicates[0].Line != 2 {
+		t.Errorf("ImportBooks duplicates = %+v, want lines 2 and 3", report.Duplicates)
+	}
+	if len(report.Invalid) != 1 || report.Invalid[0].Line != 5 || report.Invalid[0].Error == "" {
+		t.Errorf("ImportBooks invalid = %+v, want line 5", report.Invalid)
+	}
+	if report.Mapping["isbn"] != "isbn" {
+		t.Errorf("ImportBooks mapping = %v", report.Mapping)
+	}
+
+	// Колонки, которые не распознаются по заголовку, задаются явно
+	csv = fmt.Sprintf("Name,Who,Code,When\nMapped Book,Test Author,%s,2011\n", testISBN(4))
+	for mapping, want := range map[string]int{
+		"":                                 http.StatusBadRequest,
+		`{"author": "Who", "isbn": "Code"`: http.StatusBadRequest,
+		`{"author": "Who", "isbn": "Code", "published": "When"}`: http.StatusOK,
+	} {
+		body, contentType := multipartCSV(t, csv, mapping)
+		req := httptest.NewRequest(http.MethodPost, "/api/import", body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("ImportBooks with mapping %q got status = %v, want %v: %s", mapping, w.Code, want, w.Body)
+		}
+	}
+}
+
+func TestImportGoodreadsDryRunAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	existing := models.Book{Title: "1984", Author: "George Orwell", ISBN: "9780451524935", Published: time.Date(1949, 6, 8, 0, 0, 0, 0, time.UTC)}
+	body, _ := json.Marshal(existing)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/books", bytes.NewReader(body)))
+
+	csv := "Book Id,Title,Author,ISBN,ISBN13,My Rating,Year Published,Date Read,Bookshelves,Exclusive Shelf\n" +
+		`1,The Hunger Games,Suzanne Collins,="0439023483",="9780439023481",5,2008,2023/05/14,favorites,read` + "\n" +
+		`2,1984,George Orwell,="0451524934",="",4,1949,,,read` + "\n" +
+		`3,The Hunger Games,Suzanne Collins,="0439023483",="",0,2008,,,to-read` + "\n"
+
+	importFile := func(query string) (int, importReport) {
+		body, contentType := multipartCSV(t, csv, "")
+		req := httptest.NewRequest(http.MethodPost, "/api/import"+query, body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var report importReport
+		json.Unmarshal(w.Body.Bytes(), &report)
+		return w.Code, report
+	}
+
+	code, report := importFile("?format=goodreads&dry_run=true")
+	if code != http.StatusOK || !report.DryRun || report.Format != "goodreads" {
+		t.Fatalf("ImportBooks dry run got status = %v, report %+v", code, report)
+	}
+	if len(report.Created) != 1 || report.Created[0].ID != 0 || report.Created[0].Reading == nil ||
+		report.Created[0].Reading.Rating != 5 {
+		t.Errorf("ImportBooks dry run created = %+v, want line 2 without ID", report.Created)
+	}
+	// Строка 3 совпадает с книгой в библиотеке и кладет ее на полку read,
+	// строка 4 повторяет строку 2
+	if len(report.Merged) != 1 || report.Merged[0].Line != 3 || report.Merged[0].ExistingID == 0 {
+		t.Errorf("ImportBooks dry run merged = %+v, want line 3", report.Merged)
+	}
+	if len(report.Duplicates) != 1 || report.Duplicates[0].Line != 4 || report.Duplicates[0].ExistingID != 0 {
+		t.Errorf("ImportBooks dry run duplicates = %+v, want line 4", report.Duplicates)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/books", nil))
+	if !strings.Contains(w.Body.String(), `"total_books":1`) {
+		t.Errorf("ImportBooks dry run saved books: %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/shelves", nil))
+	if strings.Contains(w.Body.String(), `"read"`) {
+		t.Errorf("ImportBooks dry run created shelves: %s", w.Body)
+	}
+
+	code, report = importFile("?format=goodreads")
+	if code != http.StatusOK || report.DryRun || len(report.Created) != 1 || report.Created[0].ID == 0 {
+		t.Errorf("ImportBooks got status = %v, created %+v", code, report.Created)
+	}
+	if len(report.Merged) != 1 || report.Merged[0].ID != report.Merged[0].ExistingID {
+		t.Errorf("ImportBooks merged = %+v, want line 3 merged into the existing book", report.Merged)
+	}
+
+	// Полки из выгрузки создаются, книги кладутся на них
+	var shelves struct {
+		Shelves []models.Shelf `json:"shelves"`
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/shelves", nil))
+	json.Unmarshal(w.Body.Bytes(), &shelves)
+	counts := make(map[string]int)
+	for _, shelf := range shelves.Shelves {
+		counts[shelf.Name] = shelf.BookCount
+	}
+	if counts["read"] != 2 || counts["favorites"] != 1 {
+		t.Errorf("ListShelves() after import = %s, want read with 2 books and favorites with 1", w.Body)
+	}
+
+	// Повторный импорт ничего не добавляет
+	if _, report = importFile("?format=goodreads"); len(report.Merged) != 0 || len(report.Duplicates) != 3 {
+		t.Errorf("ImportBooks again merged %+v, duplicates %+v; want 3 duplicates", report.Merged, report.Duplicates)
+	}
+
+	for _, query := range []string{"?format=xls", "?format=goodreads&dry_run=maybe", "?format=librarything"} {
+		if code, _ := importFile(query); code != http.StatusBadRequest {
+			t.Errorf("ImportBooks%s got status = %v, want %v", query, code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestImportMergeTagsAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	handler.RegisterRoutes(router)
+
+	existing := models.Book{Title: "1984", Author: "George Orwell", ISBN: "9780451524935", Tags: []string{"dystopia"},
+		Published: time.Date(1949, 6, 8, 0, 0, 0, 0, time.UTC)}
+	body, _ := json.Marshal(existing)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/books", bytes.NewReader(body)))
+	json.Unmarshal(w.Body.Bytes(), &existing)
+
+	csv := "Title\tPrimary Author\tDate\tISBNs\tTags\tCollections\n" +
+		"1984\tOrwell, George\t1949\t0451524934\tClassic, dystopia\t\n"
+	form, contentType := multipartCSV(t, csv, "")
+	req := httptest.NewRequest(http.MethodPost, "/api/import?format=librarything", form)
+	req.Header.Set("Content-Type", contentType)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var report importReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if w.Code != http.StatusOK || len(report.Merged) != 1 || len(report.Created) != 0 {
+		t.Fatalf("ImportBooks got status = %v: %s", w.Code, w.Body)
+	}
+	book, _ := handler.repo.GetBook(context.Background(), existing.ID)
+	if strings.Join(book.Tags, "|") != "classic|dystopia" || book.Version != 2 {
+		t.Errorf("GetBook() after merge = tags %q, version %d", book.Tags, book.Version)
+	}
+}
+
+func TestCitationFormatsAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	router.Use(ContentTypeJSONMiddleware)
+	handler.RegisterRoutes(router)
+
+	for i, title := range []string{"Война и мир", "Анна Каренина"} {
+		book := models.Book{Title: title, Author: "Лев Толстой", ISBN: testISBN(i + 1), Published: time.Date(1869+i*8, 1, 1, 0, 0, 0, 0, time.UTC)}
+		body, _ := json.Marshal(book)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/books", bytes.NewReader(body)))
+	}
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		accept      string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{"format param", "/api/books/1?format=bibtex", "", http.StatusOK, "application/x-bibtex", "@book{tolstoi1869voina,"},
+		{"accept", "/api/books/1", "application/x-research-info-systems", http.StatusOK, "application/x-research-info-systems", "TY  - BOOK"},
+		{"accept with q", "/api/books/1", "application/json;q=0.5, application/vnd.citationstyles.csl+json", http.StatusOK, "application/vnd.citationstyles.csl+json", `"type":"book"`},
+		{"json preferred", "/api/books/1", "application/x-bibtex;q=0.1, application/json", http.StatusOK, "application/json", `"version":1`},
+		{"wildcard", "/api/books/1", "*/*", http.StatusOK, "application/json", `"version":1`},
+		{"not acceptable", "/api/books/1", "text/html", http.StatusNotAcceptable, "application/json", "NOT_ACCEPTABLE"},
+		{"unknown format", "/api/books/1?format=docx", "", http.StatusBadRequest, "application/json", "BAD_REQUEST"},
+		{"missing book", "/api/books/99?format=ris", "", http.StatusNotFound, "application/json", "NOT_FOUND"},
+		{"search results", "/api/books/search?q=Толстой&format=ris", "", http.StatusOK, "application/x-research-info-systems", "Анна Каренина"},
+		{"whole library", "/api/export?format=csl-json", "", http.StatusOK, "application/vnd.citationstyles.csl+json", "tolstoi1877anna"},
+		{"export by accept", "/api/export", "application/x-bibtex", http.StatusOK, "application/x-bibtex", "@book{tolstoi1877anna,"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(tt.path, tt.accept)
+			if w.Code != tt.status || !strings.HasPrefix(w.Header().Get("Content-Type"), tt.contentType) ||
+				!strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("GET %s got status = %v, content type %q, body %s; want %v, %q with %q",
+					tt.path, w.Code, w.Header().Get("Content-Type"), w.Body, tt.status, tt.contentType, tt.contains)
+			}
+		})
+	}
+
+	// Поиск в формате выгрузки возвращает все результаты, а не страницу
+	w := get("/api/books/search?q=Толстой&page_size=1", "application/x-bibtex")
+	if strings.Count(w.Body.String(), "@book{") != 2 {
+		t.Errorf("search as bibtex = %s, want both books", w.Body)
+	}
+}
+
+// failingRepository - хранилище, в котором пакеты после первого
+// и добавление книг на полки завершаются ошибкой
+type failingRepository struct {
+	storage.BookRepository
+	batches int
+}
+
+func (r *failingRepository) Batch(ctx context.Context, ops []storage.BatchOp, atomic bool) ([]storage.BatchResult, bool, error) {
+	if r.batches++; r.batches > 1 {
+		return nil, false, fmt.Errorf("database is locked")
+	}
+	return r.BookRepository.Batch(ctx, ops, atomic)
+}
+
+func (r *failingRepository) AddToShelf(ctx context.Context, shelfID, bookID int64, position int) error {
+	return fmt.Errorf("database is locked")
+}
+
+func TestImportStorageErrorAPI(t *testing.T) {
+	repo := &failingRepository{BookRepository: storage.NewMemoryRepository()}
+	router := NewRouter()
+	NewHandler(repo).RegisterRoutes(router)
+
+	csv := "Title,Author,ISBN13,Year Published,Exclusive Shelf\n"
+	for i := 1; i <= maxBatchSize+10; i++ {
+		csv += fmt.Sprintf("Book %d,Test Author,%s,2000,read\n", i, testISBN(i))
+	}
+	body, contentType := multipartCSV(t, csv, "")
+	req := httptest.NewRequest(http.MethodPost, "/api/import?format=goodreads", body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ImportBooks got status = %v, want report: %s", w.Code, w.Body)
+	}
+
+	// Первый пакет сохранен без полок, второй попадает в failed
+	var report importReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if len(report.Created) != maxBatchSize || report.Created[0].ID == 0 ||
+		!strings.Contains(report.Created[0].Error, "не добавлена на полки") {
+		t.Errorf("ImportBooks created %d rows, first %+v; want %d saved rows with a shelf error",
+			len(report.Created), report.Created[0], maxBatchSize)
+	}
+	if len(report.Failed) != 10 || report.Failed[0].Line != maxBatchSize+2 || report.Failed[0].ID != 0 ||
+		report.Failed[0].Error == "" {
+		t.Errorf("ImportBooks failed = %+v, want 10 rows from line %d", report.Failed, maxBatchSize+2)
+	}
+
+	books, err := repo.BooksByISBN(context.Background(), []string{testISBN(1), testISBN(maxBatchSize + 1)})
+	if err != nil {
+		t.Fatalf("BooksByISBN() error = %v", err)
+	}
+	if len(books) != 1 {
+		t.Errorf("BooksByISBN() after import = %d books, want only the first batch saved", len(books))
+	}
+}
+
+func TestMARCExportImportAPI(t *testing.T) {
+	source, cleanupSource := setupTestAPI(t)
+	defer cleanupSource()
+	target, cleanupTarget := setupTestAPI(t)
+	defer cleanupTarget()
+
+	sourceRouter, targetRouter := NewRouter(), NewRouter()
+	source.RegisterRoutes(sourceRouter)
+	target.RegisterRoutes(targetRouter)
+
+	for i := 1; i <= 2; i++ {
+		book := models.Book{Title: fmt.Sprintf("Book %d", i), Author: "Test Author", ISBN: testISBN(i), Published: time.Date(2000+i, 1, 1, 0, 0, 0, 0, time.UTC)}
+		body, _ := json.Marshal(book)
+		sourceRouter.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/books", bytes.NewReader(body)))
+	}
+
+	// Записи из одной библиотеки загружаются в другую
+	for _, format := range []string{"marc", "marcxml"} {
+		w := httptest.NewRecorder()
+		sourceRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/export?format="+format, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("ExportBooks %s got status = %v", format, w.Code)
+		}
+
+		body, contentType := multipartCSV(t, w.Body.String(), "")
+		req := httptest.NewRequest(http.MethodPost, "/api/import?format="+format, body)
+		req.Header.Set("Content-Type", contentType)
+		w = httptest.NewRecorder()
+		targetRouter.ServeHTTP(w, req)
+
+		var report importReport
+		json.Unmarshal(w.Body.Bytes(), &report)
+		if w.Code != http.StatusOK || len(report.Invalid) != 0 {
+			t.Fatalf("ImportBooks %s got status = %v: %s", format, w.Code, w.Body)
+		}
+		// Вторая загрузка находит те же книги по ISBN
+		if format == "marc" && len(report.Created) != 2 || format == "marcxml" && len(report.Duplicates) != 2 {
+			t.Errorf("ImportBooks %s report = %+v", format, report)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	targetRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/books/1", nil))
+	var book models.Book
+	json.Unmarshal(w.Body.Bytes(), &book)
+	if book.Title != "Book 1" || book.Author != "Test Author" || strings.ReplaceAll(book.ISBN, "-", "") != testISBN(1) || book.Published.Year() != 2001 {
+		t.Errorf("imported book = %+v", book)
+	}
+
+	body, contentType := multipartCSV(t, "not a marc file", "")
+	req := httptest.NewRequest(http.MethodPost, "/api/import?format=marc", body)
+	req.Header.Set("Content-Type", contentType)
+	w = httptest.NewRecorder()
+	targetRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("ImportBooks broken marc got status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestOPDSAPI(t *testing.T) {
+	handler, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	router := NewRouter()
+	router.Use(ContentTypeJSONMiddleware)
+	handler.RegisterRoutes(router)
+
+	books := []models.Book{
+		{Title: "Война и мир", Author: "Лев Толстой"},
+		{Title: "Анна Каренина", Author: "Лев Толстой"},
+		{Title: "Animal Farm", Author: "George Orwell"},
+	}
+	for i, book := range books {
+		book.ISBN = testISBN(i + 1)
+		book.Published = time.Date(1869+i, 1, 1, 0, 0, 0, 0, time.UTC)
+		body, _ := json.Marshal(book)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/books", bytes.NewReader(body)))
+	}
+
+	type feed struct {
+		Links []struct {
+			Rel  string `xml:"rel,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Total   int `xml:"totalResults"`
+		Entries []struct {
+			Title string `xml:"title"`
+			Links []struct {
+				Rel  string `xml:"rel,attr"`
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	get := func(path, kind string) feed {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Type"), kind) {
+			t.Fatalf("GET %s got status = %v, content type %q: %s", path, w.Code, w.Header().Get("Content-Type"), w.Body)
+		}
+		var f feed
+		if err := xml.Unmarshal(w.Body.Bytes(), &f); err != nil {
+			t.Fatalf("GET %s returned invalid XML: %v", path, err)
+		}
+		return f
+	}
+	link := func(f feed, rel string) string {
+		for _, l := range f.Links {
+			if l.Rel == rel {
+				return l.Href
+			}
+		}
+		return ""
+	}
+
+	root := get("/opds", "kind=navigation")
+	if len(root.Entries) != 2 || link(root, "search") != "/opds/opensearch.xml" {
+		t.Errorf("root feed = %+v", root)
+	}
+
+	// Новые поступления: сначала последние добавленные, страницы по page_size
+	newest := get("/opds/new?page_size=2", "kind=acquisition")
+	if newest.Total != 3 || len(newest.Entries) != 2 || newest.Entries[0].Title != "Animal Farm" {
+		t.Errorf("new feed = %+v", newest)
+	}
+	if next := link(newest, "next"); next != "/opds/new?page=2&page_size=2" {
+		t.Errorf("new feed next link = %q", next)
+	}
+	if last := get("/opds/new?page=2&page_size=2", "kind=acquisition"); len(last.Entries) != 1 || link(last, "previous") == "" {
+		t.Errorf("new feed page 2 = %+v", last)
+	}
+
+	authors := get("/opds/authors", "kind=navigation")
+	if len(authors.Entries) != 2 || authors.Entries[0].Title != "George Orwell" {
+		t.Fatalf("authors feed = %+v", authors)
+	}
+	author := get(authors.Entries[1].Links[0].Href, "kind=acquisition")
+	if len(author.Entries) != 2 || author.Entries[0].Title != "Анна Каренина" {
+		t.Errorf("author feed = %+v", author)
+	}
+
+	found := get("/opds/search?q=farm", "kind=acquisition")
+	if len(found.Entries) != 1 || found.Entries[0].Title != "Animal Farm" {
+		t.Errorf("search feed = %+v", found)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/opds/opensearch.xml", nil))
+	if !strings.Contains(w.Body.String(), `template="/opds/search?q={searchTerms}"`) {
+		t.Errorf("OpenSearch description = %s", w.Body)
+	}
+}
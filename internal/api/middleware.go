@@ -3,9 +3,18 @@ package api
 import (
 	"log"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/storage"
 )
 
+// actorHeader - заголовок, в котором клиент передает имя автора изменений
+const actorHeader = "X-Actor"
+
+// maxActorLength - максимальная длина имени автора изменений в символах
+const maxActorLength = 100
+
 // LoggingMiddleware логирует информацию о запросе
 func LoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -20,8 +29,9 @@ func LoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 func CorsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-Match, If-None-Match, "+actorHeader)
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Content-Disposition")
 
 		// Обработка префлайт запросов
 		if r.Method == "OPTIONS" {
@@ -40,3 +50,17 @@ func ContentTypeJSONMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r)
 	}
 }
+
+// ActorMiddleware сохраняет в контексте запроса автора изменений из заголовка
+// X-Actor, чтобы хранилище записало его в историю книги
+func ActorMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if actor := strings.TrimSpace(r.Header.Get(actorHeader)); actor != "" {
+			if runes := []rune(actor); len(runes) > maxActorLength {
+				actor = string(runes[:maxActorLength])
+			}
+			r = r.WithContext(storage.WithActor(r.Context(), actor))
+		}
+		next(w, r)
+	}
+}
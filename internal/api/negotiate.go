@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/export"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// negotiateFormat выбирает формат ответа для книг: параметр format важнее
+// заголовка Accept. ok == false означает обычный ответ в JSON.
+func negotiateFormat(r *http.Request) (format export.Format, ok bool, err error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		if name == "json" {
+			return export.Format{}, false, nil
+		}
+		format, ok := export.Lookup(name)
+		if !ok {
+			return format, false, errors.NewBadRequestError(fmt.Sprintf(
+				"Неподдерживаемый формат %q, допустимы: json, %s", name, strings.Join(export.Names(), ", ")))
+		}
+		return format, true, nil
+	}
+
+	return acceptedFormat(r.Header.Get("Accept"))
+}
+
+// acceptedFormat выбирает из заголовка Accept тип с наибольшим q, который
+// сервер умеет отдавать. Без заголовка ответ отдается в JSON.
+func acceptedFormat(header string) (format export.Format, ok bool, err error) {
+	if strings.TrimSpace(header) == "" {
+		return export.Format{}, false, nil
+	}
+
+	for _, mediaType := range parseAccept(header) {
+		switch mediaType {
+		case "*/*", "application/*", "application/json":
+			return export.Format{}, false, nil
+		}
+		if format, ok := export.ByMediaType(mediaType); ok {
+			return format, true, nil
+		}
+	}
+
+	return export.Format{}, false, errors.NewNotAcceptableError(fmt.Sprintf(
+		"Ни один из запрошенных типов не поддерживается, допустимы: application/json, %s", strings.Join(mediaTypes(), ", ")))
+}
+
+// parseAccept возвращает типы из заголовка Accept по убыванию q,
+// пропуская типы с q=0
+func parseAccept(header string) []string {
+	type accepted struct {
+		mediaType string
+		q         float64
+	}
+
+	var types []accepted
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			types = append(types, accepted{mediaType, q})
+		}
+	}
+
+	sort.SliceStable(types, func(i, j int) bool { return types[i].q > types[j].q })
+	result := make([]string, len(types))
+	for i, t := range types {
+		result[i] = t.mediaType
+	}
+	return result
+}
+
+// mediaTypes возвращает типы данных форматов выгрузки
+func mediaTypes() []string {
+	var types []string
+	for _, name := range export.Names() {
+		format, _ := export.Lookup(name)
+		types = append(types, format.MediaType)
+	}
+	return types
+}
+
+// writeBooks отправляет книги в формате format. each передает книги в yield
+// по мере чтения; ошибка, случившаяся до начала ответа, отправляется клиенту
+// как ответ с ошибкой, а после начала - только записывается в журнал.
+func writeBooks(w http.ResponseWriter, format export.Format, message string, each func(yield func([]*models.Book) error) error) {
+	w.Header().Set("Content-Type", format.ContentType)
+
+	writer := format.New(w)
+	started := false
+	err := each(func(books []*models.Book) error {
+		started = true
+		for _, book := range books {
+			if err := writer.WriteBook(book); err != nil {
+				return err
+			}
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Printf("Error writing books as %s: %v", format.Name, err)
+		// После начала ответа статус изменить уже нельзя
+		if !started {
+			w.Header().Del("Content-Disposition")
+			errors.WriteErrorResponse(w, storageError(err, message))
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/opds"
+	"github.com/NkvXness/GoBookshelf/internal/storage"
+)
+
+// Адреса лент OPDS-каталога
+const (
+	opdsRootPath       = "/opds"
+	opdsNewPath        = "/opds/new"
+	opdsAuthorsPath    = "/opds/authors"
+	opdsAuthorPath     = "/opds/author"
+	opdsSearchPath     = "/opds/search"
+	opdsOpenSearchPath = "/opds/opensearch.xml"
+)
+
+// opdsTitle - название каталога в приложениях для чтения
+const opdsTitle = "GoBookshelf"
+
+// writeFeed отправляет ленту OPDS с типом typ
+func writeFeed(w http.ResponseWriter, feed *opds.Feed, typ string) {
+	w.Header().Set("Content-Type", typ+";charset=utf-8")
+	if err := feed.Write(w); err != nil {
+		log.Printf("Error writing OPDS feed: %v", err)
+	}
+}
+
+// newOPDSFeed создает ленту со ссылками self, start, up и search
+func newOPDSFeed(r *http.Request, id, title, typ string, updated time.Time) *opds.Feed {
+	feed := opds.NewFeed("urn:gobookshelf:"+id, title, updated)
+	feed.Author = &opds.Person{Name: opdsTitle}
+	feed.AddLink(opds.RelSelf, r.URL.RequestURI(), typ)
+	feed.AddLink(opds.RelStart, opdsRootPath, opds.NavigationType)
+	if r.URL.Path != opdsRootPath {
+		feed.AddLink(opds.RelUp, opdsRootPath, opds.NavigationType)
+	}
+	feed.AddLink(opds.RelSearch, opdsOpenSearchPath, opds.OpenSearchType)
+	return feed
+}
+
+// pageHref возвращает адрес той же ленты с другим номером страницы
+func pageHref(r *http.Request) func(page int) string {
+	return func(page int) string {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(page))
+		return r.URL.Path + "?" + query.Encode()
+	}
+}
+
+// bookEntries создает записи ленты о книгах и возвращает время последнего изменения
+func bookEntries(books []*models.Book) ([]opds.Entry, time.Time) {
+	var updated time.Time
+	entries := make([]opds.Entry, 0, len(books))
+	for _, book := range books {
+		entries = append(entries, opds.BookEntry(book, fmt.Sprintf("/api/books/%d", book.ID)))
+		if book.UpdatedAt.After(updated) {
+			updated = book.UpdatedAt
+		}
+	}
+	return entries, updated
+}
+
+// OPDSRoot возвращает корневую навигационную ленту каталога
+func (h *Handler) OPDSRoot(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	feed := newOPDSFeed(r, "root", opdsTitle, opds.NavigationType, now)
+	feed.AddLink(opds.RelNew, opdsNewPath, opds.AcquisitionType)
+	feed.Entries = []opds.Entry{
+		opds.NavigationEntry("urn:gobookshelf:new", "Новые поступления",
+			"Книги в порядке добавления, сначала новые", opdsNewPath, opds.AcquisitionType, now),
+		opds.NavigationEntry("urn:gobookshelf:authors", "Авторы",
+			"Книги по авторам", opdsAuthorsPath, opds.NavigationType, now),
+	}
+	writeFeed(w, feed, opds.NavigationType)
+}
+
+// OPDSNew возвращает ленту книг, сначала недавно добавленные
+func (h *Handler) OPDSNew(w http.ResponseWriter, r *http.Request) {
+	opts := storage.DefaultListOptions()
+	opts.Page, opts.PageSize = parsePagination(r)
+	h.writeBookFeed(w, r, "new", "Новые поступления", opts)
+}
+
+// OPDSAuthor возвращает ленту книг автора из параметра name по названию
+func (h *Handler) OPDSAuthor(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Не указан автор (параметр name)"))
+		return
+	}
+
+	opts := storage.ListOptions{SortBy: storage.SortByTitle, Author: name}
+	opts.Page, opts.PageSize = parsePagination(r)
+	h.writeBookFeed(w, r, "author:"+url.QueryEscape(name), name, opts)
+}
+
+// writeBookFeed отправляет ленту книг из списка opts
+func (h *Handler) writeBookFeed(w http.ResponseWriter, r *http.Request, id, title string, opts storage.ListOptions) {
+	books, info, err := h.repo.ListBooks(r.Context(), opts)
+	if err != nil {
+		log.Printf("Error listing books for OPDS: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить список книг"))
+		return
+	}
+
+	entries, updated := bookEntries(books)
+	feed := newOPDSFeed(r, id, title, opds.AcquisitionType, updated)
+	feed.AddPaging(opts.Page, opts.PageSize, info.Total, pageHref(r), opds.AcquisitionType)
+	feed.Entries = entries
+	writeFeed(w, feed, opds.AcquisitionType)
+}
+
+// OPDSSearch возвращает ленту результатов поиска по запросу q
+func (h *Handler) OPDSSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Параметр поиска не указан"))
+		return
+	}
+
+	var opts storage.PageOptions
+	opts.Page, opts.PageSize = parsePagination(r)
+	hits, info, err := h.repo.SearchBooks(r.Context(), query, opts)
+	if err != nil {
+		log.Printf("Error searching books for OPDS: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось выполнить поиск книг"))
+		return
+	}
+
+	books := make([]*models.Book, len(hits))
+	for i, hit := range hits {
+		books[i] = &hit.Book
+	}
+	entries, updated := bookEntries(books)
+	feed := newOPDSFeed(r, "search:"+url.QueryEscape(query), "Поиск: "+query, opds.AcquisitionType, updated)
+	feed.AddPaging(opts.Page, opts.PageSize, info.Total, pageHref(r), opds.AcquisitionType)
+	feed.Entries = entries
+	writeFeed(w, feed, opds.AcquisitionType)
+}
+
+// opdsAuthor - автор в навигационной ленте авторов
+type opdsAuthor struct {
+	name    string
+	books   int
+	updated time.Time
+}
+
+// OPDSAuthors возвращает навигационную ленту авторов по алфавиту. Список
+// авторов собирается обходом всех книг.
+func (h *Handler) OPDSAuthors(w http.ResponseWriter, r *http.Request) {
+	var authors []*opdsAuthor
+	byName := make(map[string]*opdsAuthor)
+	err := storage.ForEachPage(r.Context(), h.repo, storage.ListOptions{}, func(books []*models.Book) error {
+		for _, book := range books {
+			for _, name := range book.ContributorNames(models.RoleAuthor) {
+				author, ok := byName[name]
+				if !ok {
+					author = &opdsAuthor{name: name}
+					byName[name] = author
+					authors = append(authors, author)
+				}
+				author.books++
+				if book.UpdatedAt.After(author.updated) {
+					author.updated = book.UpdatedAt
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error listing authors for OPDS: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить список авторов"))
+		return
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		a, b := strings.ToLower(authors[i].name), strings.ToLower(authors[j].name)
+		if a != b {
+			return a < b
+		}
+		return authors[i].name < authors[j].name
+	})
+
+	page, pageSize := parsePagination(r)
+	start := min((page-1)*pageSize, len(authors))
+	end := min(start+pageSize, len(authors))
+
+	var updated time.Time
+	var entries []opds.Entry
+	for _, author := range authors[start:end] {
+		entries = append(entries, opds.NavigationEntry(
+			"urn:gobookshelf:author:"+url.QueryEscape(author.name),
+			author.name,
+			fmt.Sprintf("Книг: %d", author.books),
+			opdsAuthorPath+"?name="+url.QueryEscape(author.name),
+			opds.AcquisitionType,
+			author.updated,
+		))
+		if author.updated.After(updated) {
+			updated = author.updated
+		}
+	}
+
+	feed := newOPDSFeed(r, "authors", "Авторы", opds.NavigationType, updated)
+	feed.AddPaging(page, pageSize, len(authors), pageHref(r), opds.NavigationType)
+	feed.Entries = entries
+	writeFeed(w, feed, opds.NavigationType)
+}
+
+// OPDSOpenSearch возвращает описание поиска OpenSearch для каталога
+func (h *Handler) OPDSOpenSearch(w http.ResponseWriter, r *http.Request) {
+	description := opds.NewOpenSearchDescription(opdsTitle, "Поиск по названию, автору и ISBN",
+		opdsSearchPath+"?q={searchTerms}")
+
+	w.Header().Set("Content-Type", opds.OpenSearchType+";charset=utf-8")
+	if err := description.Write(w); err != nil {
+		log.Printf("Error writing OpenSearch description: %v", err)
+	}
+}
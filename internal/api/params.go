@@ -0,0 +1,182 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/storage"
+)
+
+// sortFields - поля, по которым разрешено сортировать список книг
+var sortFields = map[string]storage.SortField{
+	"title":      storage.SortByTitle,
+	"author":     storage.SortByAuthor,
+	"published":  storage.SortByPublished,
+	"created_at": storage.SortByCreatedAt,
+	"updated_at": storage.SortByUpdatedAt,
+}
+
+// parsePagination читает page и page_size, подставляя значения по умолчанию
+func parsePagination(r *http.Request) (page, pageSize int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err = strconv.Atoi(r.URL.Query().Get("page_size"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return page, pageSize
+}
+
+// parsePageOptions читает параметры страницы: page, page_size, cursor и include_total
+func parsePageOptions(r *http.Request) (storage.PageOptions, error) {
+	query := r.URL.Query()
+
+	var opts storage.PageOptions
+	opts.Page, opts.PageSize = parsePagination(r)
+	opts.Cursor = query.Get("cursor")
+
+	if value := query.Get("include_total"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, errors.NewBadRequestError("Параметр include_total должен быть true или false")
+		}
+		opts.SkipTotal = !include
+	}
+
+	return opts, nil
+}
+
+// pagination - поля ответа, описывающие страницу результата
+type pagination struct {
+	// TotalBooks и TotalPages отсутствуют, если запрошено include_total=false
+	TotalBooks *int `json:"total_books,omitempty"`
+	// Page отсутствует при переходе по курсору
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	TotalPages *int   `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// newPagination заполняет поля страницы для ответа
+func newPagination(opts storage.PageOptions, info storage.PageInfo) pagination {
+	p := pagination{
+		PageSize:   opts.PageSize,
+		NextCursor: info.NextCursor,
+		PrevCursor: info.PrevCursor,
+	}
+	if opts.Cursor == "" {
+		p.Page = opts.Page
+	}
+	if info.Total >= 0 {
+		total := info.Total
+		pages := (total + opts.PageSize - 1) / opts.PageSize
+		p.TotalBooks, p.TotalPages = &total, &pages
+	}
+	return p
+}
+
+// parseListOptions читает параметры страницы, сортировки и фильтрации списка книг.
+// Поддерживаются sort, order, author, author_id, role, tag (можно указать
+// несколько раз), shelf, published_from, published_to и created_after.
+func parseListOptions(r *http.Request) (storage.ListOptions, error) {
+	query := r.URL.Query()
+	opts := storage.DefaultListOptions()
+
+	var err error
+	if opts.PageOptions, err = parsePageOptions(r); err != nil {
+		return opts, err
+	}
+
+	if value := query.Get("sort"); value != "" {
+		field, ok := sortFields[value]
+		if !ok {
+			return opts, errors.NewBadRequestError(fmt.Sprintf(
+				"Недопустимое поле сортировки %q, допустимы: title, author, published, created_at, updated_at", value))
+		}
+		opts.SortBy = field
+		// Текстовые поля по умолчанию сортируются по алфавиту, даты - сначала новые
+		opts.SortDesc = field != storage.SortByTitle && field != storage.SortByAuthor
+	}
+
+	switch strings.ToLower(query.Get("order")) {
+	case "":
+	case "asc":
+		opts.SortDesc = false
+	case "desc":
+		opts.SortDesc = true
+	default:
+		return opts, errors.NewBadRequestError("Параметр order должен быть asc или desc")
+	}
+
+	opts.Author = strings.TrimSpace(query.Get("author"))
+	if value := query.Get("author_id"); value != "" {
+		if opts.AuthorID, err = strconv.ParseInt(value, 10, 64); err != nil || opts.AuthorID < 1 {
+			return opts, errors.NewBadRequestError("Некорректный параметр author_id")
+		}
+	}
+	if opts.Role = strings.ToLower(query.Get("role")); opts.Role != "" && !models.IsContributorRole(opts.Role) {
+		return opts, errors.NewBadRequestError(fmt.Sprintf(
+			"Недопустимая роль %q, допустимы: %s", opts.Role, strings.Join(models.ContributorRoles, ", ")))
+	}
+	if opts.Role != "" && opts.AuthorID == 0 {
+		return opts, errors.NewBadRequestError("Параметр role задается вместе с author_id")
+	}
+
+	for _, value := range query["tag"] {
+		if tag := models.NormalizeTag(value); tag != "" {
+			opts.Tags = append(opts.Tags, tag)
+		}
+	}
+	if value := query.Get("shelf"); value != "" {
+		if opts.ShelfID, err = strconv.ParseInt(value, 10, 64); err != nil || opts.ShelfID < 1 {
+			return opts, errors.NewBadRequestError("Некорректный параметр shelf")
+		}
+	}
+
+	if opts.PublishedFrom, err = parseDateParam(query.Get("published_from"), false); err != nil {
+		return opts, errors.NewBadRequestError("Некорректный параметр published_from: " + err.Error())
+	}
+	if opts.PublishedTo, err = parseDateParam(query.Get("published_to"), true); err != nil {
+		return opts, errors.NewBadRequestError("Некорректный параметр published_to: " + err.Error())
+	}
+	if opts.CreatedAfter, err = parseDateParam(query.Get("created_after"), false); err != nil {
+		return opts, errors.NewBadRequestError("Некорректный параметр created_after: " + err.Error())
+	}
+
+	if !opts.PublishedFrom.IsZero() && !opts.PublishedTo.IsZero() && opts.PublishedFrom.After(opts.PublishedTo) {
+		return opts, errors.NewBadRequestError("published_from не может быть позже published_to")
+	}
+
+	return opts, nil
+}
+
+// parseDateParam разбирает дату в формате ГГГГ-ММ-ДД или RFC 3339.
+// Если endOfDay установлен, дата без времени означает конец этого дня.
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ожидалась дата в формате ГГГГ-ММ-ДД или RFC 3339")
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
@@ -50,6 +50,11 @@ func (r *Router) PUT(path string, handler http.HandlerFunc) {
 	r.HandleFunc(http.MethodPut, path, handler)
 }
 
+// PATCH регистрирует обработчик для PATCH запросов
+func (r *Router) PATCH(path string, handler http.HandlerFunc) {
+	r.HandleFunc(http.MethodPatch, path, handler)
+}
+
 // DELETE регистрирует обработчик для DELETE запросов
 func (r *Router) DELETE(path string, handler http.HandlerFunc) {
 	r.HandleFunc(http.MethodDelete, path, handler)
@@ -63,20 +68,14 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	// Проверяем точное совпадение пути
 	handlers, exists := r.routes[path]
 	if !exists {
-		// Если точного совпадения нет, пробуем найти обработчик
-		// который соответствует более общему пути (без ID в конце)
+		// Если точного совпадения нет, ищем шаблон с параметрами пути
+		// (например, /api/books/{id} или /api/books/{id}/restore)
 		found := false
 		for routePath, routeHandlers := range r.routes {
-			// Проверяем, оканчивается ли путь на {id} (шаблон)
-			if strings.HasSuffix(routePath, "/{id}") {
-				// Удаляем /{id} из конца
-				basePath := routePath[:len(routePath)-4]
-				// Проверяем, начинается ли запрашиваемый путь с этого базового пути
-				if strings.HasPrefix(path, basePath) && len(path) > len(basePath) {
-					handlers = routeHandlers
-					found = true
-					break
-				}
+			if matchPattern(routePath, path) {
+				handlers = routeHandlers
+				found = true
+				break
 			}
 		}
 
@@ -101,6 +100,56 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	handler(w, req)
 }
 
+// matchPattern проверяет, соответствует ли путь шаблону маршрута.
+// Сегмент в фигурных скобках ({id}, {book_id}) совпадает с любым непустым
+// сегментом пути.
+func matchPattern(pattern, path string) bool {
+	if !strings.Contains(pattern, "{") {
+		return false
+	}
+
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(path, "/")
+	if len(patternParts) != len(pathParts) {
+		return false
+	}
+
+	for i, part := range patternParts {
+		if isParam(part) {
+			if pathParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathParam возвращает сегмент пути, соответствующий {id} в шаблоне
+func pathParam(pattern, path string) string {
+	return namedPathParam(pattern, path, "id")
+}
+
+// namedPathParam возвращает сегмент пути, соответствующий {name} в шаблоне
+func namedPathParam(pattern, path, name string) string {
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(path, "/")
+	for i, part := range patternParts {
+		if part == "{"+name+"}" && i < len(pathParts) {
+			return pathParts[i]
+		}
+	}
+	return ""
+}
+
+// isParam проверяет, является ли сегмент шаблона параметром пути
+func isParam(part string) bool {
+	return strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}")
+}
+
 // getAllowedMethods возвращает строку с разрешенными методами
 func getAllowedMethods(handlers map[string]http.HandlerFunc) string {
 	methods := ""
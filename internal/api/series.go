@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// seriesPath - шаблон пути к серии
+const seriesPath = "/api/series/{id}"
+
+// ListSeries возвращает все серии по алфавиту с количеством книг в них
+func (h *Handler) ListSeries(w http.ResponseWriter, r *http.Request) {
+	series, err := h.repo.ListSeries(r.Context())
+	if err != nil {
+		log.Printf("Error listing series: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить список серий"))
+		return
+	}
+	if series == nil {
+		series = []*models.Series{}
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Series []*models.Series `json:"series"`
+	}{Series: series})
+}
+
+// GetSeries возвращает серию вместе с ее книгами в порядке чтения
+func (h *Handler) GetSeries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(pathParam(seriesPath, r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID серии"))
+		return
+	}
+
+	series, err := h.repo.GetSeries(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting series: %v", err)
+		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить информацию о серии", err))
+		return
+	}
+	if series == nil {
+		errors.WriteErrorResponse(w, errors.NewNotFoundError("Серия не найдена"))
+		return
+	}
+
+	books, err := h.repo.SeriesBooks(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting series books: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить книги серии"))
+		return
+	}
+	if books == nil {
+		books = []*models.Book{}
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		*models.Series
+		Books []*models.Book `json:"books"`
+	}{Series: series, Books: books})
+}
+
+// keepSeries оставляет книге прежнюю серию, если в запросе PUT ее нет,
+// как и ISBN. Номер без названия серии меняет номер в прежней серии.
+// Убрать книгу из серии можно через PATCH.
+func keepSeries(book, existing *models.Book) {
+	if book.Series != "" {
+		return
+	}
+	book.Series = existing.Series
+	if book.SeriesPosition == nil {
+		book.SeriesPosition = existing.SeriesPosition
+	}
+}
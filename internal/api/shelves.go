@@ -0,0 +1,254 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/storage"
+)
+
+// Шаблоны путей к полкам
+const (
+	shelfPath      = "/api/shelves/{id}"
+	shelfBooksPath = "/api/shelves/{id}/books"
+	shelfBookPath  = "/api/shelves/{id}/books/{book_id}"
+)
+
+// ListShelves возвращает все полки по алфавиту с количеством книг на них
+func (h *Handler) ListShelves(w http.ResponseWriter, r *http.Request) {
+	shelves, err := h.repo.ListShelves(r.Context())
+	if err != nil {
+		log.Printf("Error listing shelves: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить список полок"))
+		return
+	}
+	if shelves == nil {
+		shelves = []*models.Shelf{}
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Shelves []*models.Shelf `json:"shelves"`
+	}{Shelves: shelves})
+}
+
+// GetShelf возвращает полку без списка книг
+func (h *Handler) GetShelf(w http.ResponseWriter, r *http.Request) {
+	id, ok := shelfID(w, r, shelfPath)
+	if !ok {
+		return
+	}
+
+	shelf, err := h.repo.GetShelf(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting shelf: %v", err)
+		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить информацию о полке", err))
+		return
+	}
+	if shelf == nil {
+		errors.WriteErrorResponse(w, errors.NewNotFoundError("Полка не найдена"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(shelf)
+}
+
+// CreateShelf создает пустую полку
+func (h *Handler) CreateShelf(w http.ResponseWriter, r *http.Request) {
+	var shelf models.Shelf
+	if err := json.NewDecoder(r.Body).Decode(&shelf); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректные данные полки"))
+		return
+	}
+
+	shelf.Normalize()
+	if err := shelf.Validate(); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.repo.CreateShelf(r.Context(), &shelf); err != nil {
+		log.Printf("Error creating shelf: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось создать полку"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shelf)
+}
+
+// UpdateShelf изменяет название и описание полки
+func (h *Handler) UpdateShelf(w http.ResponseWriter, r *http.Request) {
+	id, ok := shelfID(w, r, shelfPath)
+	if !ok {
+		return
+	}
+
+	var shelf models.Shelf
+	if err := json.NewDecoder(r.Body).Decode(&shelf); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректные данные полки"))
+		return
+	}
+	shelf.ID = id
+
+	shelf.Normalize()
+	if err := shelf.Validate(); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.repo.UpdateShelf(r.Context(), &shelf); err != nil {
+		log.Printf("Error updating shelf: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось обновить полку"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(shelf)
+}
+
+// DeleteShelf удаляет полку; книги с нее остаются в библиотеке
+func (h *Handler) DeleteShelf(w http.ResponseWriter, r *http.Request) {
+	id, ok := shelfID(w, r, shelfPath)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteShelf(r.Context(), id); err != nil {
+		log.Printf("Error deleting shelf: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось удалить полку"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ShelfBooks возвращает книги с полки в заданном пользователем порядке
+// с пагинацией
+func (h *Handler) ShelfBooks(w http.ResponseWriter, r *http.Request) {
+	id, ok := shelfID(w, r, shelfBooksPath)
+	if !ok {
+		return
+	}
+
+	var opts storage.PageOptions
+	opts.Page, opts.PageSize = parsePagination(r)
+	books, total, err := h.repo.ShelfBooks(r.Context(), id, opts.Page, opts.PageSize)
+	if err != nil {
+		log.Printf("Error listing shelf books: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить книги с полки"))
+		return
+	}
+	if books == nil {
+		books = []*models.Book{}
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Books []*models.Book `json:"books"`
+		pagination
+	}{
+		Books:      books,
+		pagination: newPagination(opts, storage.PageInfo{Total: total}),
+	})
+}
+
+// AddToShelf кладет книгу на полку: {"book_id": 7, "position": 0}.
+// position - место на полке с 0; без него книга кладется в конец.
+// Книга, которая уже лежит на полке, перемещается на новое место.
+func (h *Handler) AddToShelf(w http.ResponseWriter, r *http.Request) {
+	id, ok := shelfID(w, r, shelfBooksPath)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		BookID   int64 `json:"book_id"`
+		Position *int  `json:"position"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.BookID < 1 {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Укажите ID книги: {\"book_id\": N}"))
+		return
+	}
+	position := -1
+	if request.Position != nil {
+		if *request.Position < 0 {
+			errors.WriteErrorResponse(w, errors.NewBadRequestError("position не может быть отрицательным"))
+			return
+		}
+		position = *request.Position
+	}
+
+	if err := h.repo.AddToShelf(r.Context(), id, request.BookID, position); err != nil {
+		log.Printf("Error adding book to shelf: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось положить книгу на полку"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetShelfBooks заменяет книги на полке списком ID в нужном порядке:
+// {"book_ids": [3, 1, 2]}
+func (h *Handler) SetShelfBooks(w http.ResponseWriter, r *http.Request) {
+	id, ok := shelfID(w, r, shelfBooksPath)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		BookIDs []int64 `json:"book_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.BookIDs == nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Укажите ID книг по порядку: {\"book_ids\": [N, ...]}"))
+		return
+	}
+	sorted := slices.Clone(request.BookIDs)
+	slices.Sort(sorted)
+	if len(slices.Compact(sorted)) != len(request.BookIDs) {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("ID книг в book_ids не должны повторяться"))
+		return
+	}
+
+	if err := h.repo.SetShelfBooks(r.Context(), id, request.BookIDs); err != nil {
+		log.Printf("Error setting shelf books: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось изменить книги на полке"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveFromShelf убирает книгу с полки
+func (h *Handler) RemoveFromShelf(w http.ResponseWriter, r *http.Request) {
+	id, ok := shelfID(w, r, shelfBookPath)
+	if !ok {
+		return
+	}
+	bookID, err := strconv.ParseInt(namedPathParam(shelfBookPath, r.URL.Path, "book_id"), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID книги"))
+		return
+	}
+
+	if err := h.repo.RemoveFromShelf(r.Context(), id, bookID); err != nil {
+		log.Printf("Error removing book from shelf: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось убрать книгу с полки"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// shelfID читает ID полки из пути. Если ID некорректен, отправляет ошибку
+// и возвращает false.
+func shelfID(w http.ResponseWriter, r *http.Request, pattern string) (int64, bool) {
+	id, err := strconv.ParseInt(pathParam(pattern, r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID полки"))
+		return 0, false
+	}
+	return id, true
+}
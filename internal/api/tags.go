@@ -0,0 +1,27 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// ListTags возвращает теги книг вне корзины по алфавиту с количеством книг
+func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.repo.TagCounts(r.Context())
+	if err != nil {
+		log.Printf("Error listing tags: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить список тегов"))
+		return
+	}
+	if tags == nil {
+		tags = []models.TagCount{}
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Tags []models.TagCount `json:"tags"`
+	}{Tags: tags})
+}
@@ -0,0 +1,432 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/export"
+	"github.com/NkvXness/GoBookshelf/internal/importer"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/storage"
+)
+
+// maxImportSize - максимальный размер загружаемого файла импорта
+const maxImportSize = 10 << 20
+
+// ExportBooks выгружает все книги в формате из параметра format. Без
+// параметра формат выбирается по заголовку Accept, по умолчанию - csv.
+// Книги читаются страницами и отправляются клиенту по мере чтения.
+func (h *Handler) ExportBooks(w http.ResponseWriter, r *http.Request) {
+	var format export.Format
+	if name := r.URL.Query().Get("format"); name != "" {
+		var ok bool
+		if format, ok = export.Lookup(name); !ok {
+			errors.WriteErrorResponse(w, errors.NewBadRequestError(fmt.Sprintf(
+				"Неподдерживаемый формат %q, допустимы: %s", name, strings.Join(export.Names(), ", "))))
+			return
+		}
+	} else if accepted, ok, err := acceptedFormat(r.Header.Get("Accept")); err == nil && ok {
+		format = accepted
+	} else {
+		format, _ = export.Lookup("csv")
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="books.%s"`, format.Extension))
+	opts := storage.ListOptions{SortBy: storage.SortByCreatedAt}
+	writeBooks(w, format, "Не удалось выгрузить книги", func(yield func([]*models.Book) error) error {
+		return storage.ForEachPage(r.Context(), h.repo, opts, yield)
+	})
+}
+
+// importReport - результат импорта
+type importReport struct {
+	Format string `json:"format"`
+	// DryRun означает, что книги не сохранялись: created и merged
+	// показывают, какие книги были бы созданы и дополнены
+	DryRun  bool             `json:"dry_run"`
+	Mapping importer.Mapping `json:"mapping,omitempty"`
+	Created []importedRow    `json:"created"`
+	// Merged - строки с ISBN книги из библиотеки, которые добавили к ней
+	// теги, полки или пустые поля издания и серии
+	Merged []importedRow `json:"merged"`
+	// Duplicates - строки, которым нечего добавить к книге с тем же ISBN
+	Duplicates []importedRow `json:"duplicates"`
+	Invalid    []importedRow `json:"invalid"`
+	// Failed - корректные строки, которые не удалось сохранить из-за ошибки
+	// хранилища; их можно импортировать повторно
+	Failed []importedRow `json:"failed"`
+}
+
+// importedRow - строка файла в отчете об импорте
+type importedRow struct {
+	Line  int    `json:"line"`
+	ID    int64  `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	ISBN  string `json:"isbn,omitempty"`
+	// ExistingID - книга библиотеки с тем же ISBN, с которой совпала строка
+	ExistingID int64             `json:"existing_id,omitempty"`
+	Reading    *importer.Reading `json:"reading,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// importFormats - значения параметра format запроса импорта
+var importFormats = []string{"csv", "goodreads", "librarything", "marc", "marcxml"}
+
+// ImportBooks импортирует книги из файла, загруженного в поле file формы
+// multipart/form-data. Параметр format выбирает формат файла: csv (по
+// умолчанию), goodreads, librarything, marc (MARC21) или marcxml. Для csv
+// необязательное поле mapping задает колонки для полей книги в виде JSON:
+// {"title": "Название", "isbn": "ISBN13"}, остальные колонки сопоставляются
+// по заголовкам. С dry_run=true книги
+// не сохраняются, а отчет показывает, что произошло бы при импорте.
+func (h *Handler) ImportBooks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	read, ok := importer.Lookup(format)
+	if !ok && format != "csv" {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError(fmt.Sprintf(
+			"Неподдерживаемый формат %q, допустимы: %s", format, strings.Join(importFormats, ", "))))
+		return
+	}
+
+	dryRun := false
+	if value := query.Get("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			errors.WriteErrorResponse(w, errors.NewBadRequestError("Параметр dry_run должен быть true или false"))
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError(fmt.Sprintf(
+			"Загрузите файл размером до %d МБ в поле file формы multipart/form-data", maxImportSize>>20)))
+		return
+	}
+	defer file.Close()
+
+	var mapping importer.Mapping
+	if value := r.FormValue("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			errors.WriteErrorResponse(w, errors.NewBadRequestError(
+				`Поле mapping должно быть JSON-объектом вида {"title": "колонка", ...}`))
+			return
+		}
+	}
+
+	var rows []importer.Row
+	if format == "csv" {
+		mapping, rows, err = importer.ReadCSV(file, mapping)
+	} else {
+		mapping = nil
+		rows, err = read(file)
+	}
+	if err != nil {
+		log.Printf("Error reading %s import file: %v", format, err)
+		var mappingErr *importer.MappingError
+		if stderrors.As(err, &mappingErr) {
+			errors.WriteErrorResponse(w, errors.NewBadRequestError(
+				"Не удалось сопоставить колонки файла полям книги: "+mappingErr.Error()))
+			return
+		}
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Не удалось прочитать файл импорта"))
+		return
+	}
+
+	report, err := h.importRows(r.Context(), rows, dryRun)
+	if err != nil {
+		log.Printf("Error importing books: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось импортировать книги"))
+		return
+	}
+	report.Format = format
+	report.Mapping = mapping
+
+	log.Printf("Imported %d books from %s (dry run: %v), %d merged, %d duplicates, %d invalid rows, %d failed",
+		len(report.Created), format, dryRun, len(report.Merged), len(report.Duplicates), len(report.Invalid), len(report.Failed))
+	json.NewEncoder(w).Encode(report)
+}
+
+// importRows сохраняет корректные строки импорта пакетами без атомарности.
+// Строка с ISBN книги из библиотеки дополняет эту книгу тегами, полками
+// и пустыми полями издания и серии; если добавить нечего, как и для книги
+// в корзине или ISBN, который встретился в файле выше, строка попадает
+// в дубликаты. Книги без ISBN дубликатами не считаются. С dryRun книги
+// не сохраняются. Ошибка хранилища при сохранении пакета или расстановке
+// по полкам не прерывает импорт, а отмечается на затронутых строках.
+func (h *Handler) importRows(ctx context.Context, rows []importer.Row, dryRun bool) (*importReport, error) {
+	report := &importReport{
+		DryRun:     dryRun,
+		Created:    []importedRow{},
+		Merged:     []importedRow{},
+		Duplicates: []importedRow{},
+		Invalid:    []importedRow{},
+		Failed:     []importedRow{},
+	}
+
+	var valid []importer.Row
+	var isbns []string
+	for _, row := range rows {
+		if row.Err != nil {
+			report.Invalid = append(report.Invalid, importedRow{Line: row.Line, Reading: row.Reading, Error: row.Err.Error()})
+			continue
+		}
+		valid = append(valid, row)
+		if row.Book.ISBN != "" {
+			isbns = append(isbns, row.Book.ISBN)
+		}
+	}
+
+	existing, err := h.repo.BooksByISBN(ctx, isbns)
+	if err != nil {
+		return nil, err
+	}
+	shelves, err := newImportShelves(ctx, h.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []pendingImport
+	seen := make(map[string]bool, len(valid))
+	for _, row := range valid {
+		entry := newImportedRow(row)
+		if row.Book.ISBN != "" && seen[row.Book.ISBN] {
+			report.Duplicates = append(report.Duplicates, entry)
+			continue
+		}
+		seen[row.Book.ISBN] = true
+
+		item := pendingImport{row: row, save: row.Book}
+		if book, ok := existing[row.Book.ISBN]; ok {
+			entry.ExistingID = book.ID
+			item.existing = book
+			item.save = mergeImported(book, row.Book)
+			if book.DeletedAt != nil || (item.save == nil && !shelves.adds(book.Shelves, row.Reading)) {
+				report.Duplicates = append(report.Duplicates, entry)
+				continue
+			}
+		}
+
+		switch {
+		case !dryRun:
+			pending = append(pending, item)
+		case item.existing != nil:
+			report.Merged = append(report.Merged, entry)
+		default:
+			report.Created = append(report.Created, entry)
+		}
+	}
+
+	for start := 0; start < len(pending); start += maxBatchSize {
+		chunk := pending[start:min(start+maxBatchSize, len(pending))]
+
+		// Книга, к которой добавляются только полки, не сохраняется
+		results := make([]storage.BatchResult, len(chunk))
+		var ops []storage.BatchOp
+		var indexes []int
+		for i, item := range chunk {
+			switch {
+			case item.existing == nil:
+				ops = append(ops, storage.BatchOp{Action: storage.BatchCreate, Book: item.save})
+			case item.save != nil:
+				ops = append(ops, storage.BatchOp{Action: storage.BatchUpdate, Book: item.save})
+			default:
+				results[i].Book = item.existing
+				continue
+			}
+			indexes = append(indexes, i)
+		}
+		// Ошибка пакета не прерывает импорт: книги прошлых пакетов уже
+		// сохранены, поэтому строки пакета попадают в failed
+		stored, _, err := h.repo.Batch(ctx, ops, false)
+		failed := make([]bool, len(chunk))
+		for j, i := range indexes {
+			if err != nil {
+				results[i].Err = err
+				failed[i] = true
+				continue
+			}
+			results[i] = stored[j]
+		}
+		if err != nil {
+			log.Printf("Error importing batch of %d books: %v", len(ops), err)
+		}
+
+		for i, result := range results {
+			item := chunk[i]
+			row := newImportedRow(item.row)
+			var onShelves []int64
+			if item.existing != nil {
+				row.ExistingID = item.existing.ID
+				onShelves = item.existing.Shelves
+			}
+			switch {
+			case failed[i]:
+				row.Error = result.Err.Error()
+				report.Failed = append(report.Failed, row)
+			case result.Err == nil:
+				row.ID = result.Book.ID
+				if err := shelves.place(ctx, row.ID, onShelves, item.row.Reading); err != nil {
+					// Книга уже сохранена, поэтому строка остается в отчете
+					// с ошибкой
+					log.Printf("Error placing imported book %d on shelves: %v", row.ID, err)
+					row.Error = "Книга сохранена, но не добавлена на полки: " + err.Error()
+				}
+				if item.existing != nil {
+					report.Merged = append(report.Merged, row)
+				} else {
+					report.Created = append(report.Created, row)
+				}
+			case stderrors.Is(result.Err, storage.ErrDuplicateISBN), stderrors.Is(result.Err, storage.ErrDuplicateIdentifier):
+				// ISBN заняли после проверки или совпал другой идентификатор
+				report.Duplicates = append(report.Duplicates, row)
+			default:
+				row.Error = result.Err.Error()
+				report.Invalid = append(report.Invalid, row)
+			}
+		}
+	}
+
+	sort.Slice(report.Merged, func(i, j int) bool {
+		return report.Merged[i].Line < report.Merged[j].Line
+	})
+	sort.Slice(report.Duplicates, func(i, j int) bool {
+		return report.Duplicates[i].Line < report.Duplicates[j].Line
+	})
+	sort.Slice(report.Invalid, func(i, j int) bool {
+		return report.Invalid[i].Line < report.Invalid[j].Line
+	})
+	return report, nil
+}
+
+// pendingImport - строка импорта, которая создает книгу или дополняет
+// книгу existing
+type pendingImport struct {
+	row      importer.Row
+	existing *models.Book
+	// save - книга для сохранения; nil, если к existing добавляются только
+	// полки
+	save *models.Book
+}
+
+// mergeImported возвращает книгу из библиотеки, дополненную данными
+// импортированной книги: новыми тегами и полями издания и серии, которые
+// у книги пустые. Возвращает nil, если добавить нечего.
+func mergeImported(existing, imported *models.Book) *models.Book {
+	book := *existing
+	book.Tags = append(slices.Clone(existing.Tags), imported.Tags...)
+	book.FormatTags()
+	changed := !slices.Equal(book.Tags, existing.Tags)
+
+	fill := func(field *string, value string) {
+		if *field == "" && value != "" {
+			*field = value
+			changed = true
+		}
+	}
+	fill(&book.Publisher, imported.Publisher)
+	fill(&book.Language, imported.Language)
+	fill(&book.Format, imported.Format)
+	if book.Series == "" && imported.Series != "" {
+		book.Series = imported.Series
+		book.SeriesPosition = imported.SeriesPosition
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return &book
+}
+
+// importShelves - полки библиотеки по названию без учета регистра.
+// Полки из выгрузки, которых нет в библиотеке, создаются при первом
+// размещении на них книги.
+type importShelves struct {
+	repo   storage.BookRepository
+	byName map[string]int64
+}
+
+func newImportShelves(ctx context.Context, repo storage.BookRepository) (*importShelves, error) {
+	list, err := repo.ListShelves(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shelves := &importShelves{repo: repo, byName: make(map[string]int64, len(list))}
+	for _, shelf := range list {
+		shelves.byName[strings.ToLower(shelf.Name)] = shelf.ID
+	}
+	return shelves, nil
+}
+
+// names возвращает корректные названия полок из сведений о чтении
+func (s *importShelves) names(reading *importer.Reading) []string {
+	if reading == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range reading.Shelves {
+		shelf := models.Shelf{Name: name}
+		shelf.Normalize()
+		if shelf.Validate() == nil {
+			names = append(names, shelf.Name)
+		}
+	}
+	return names
+}
+
+// adds проверяет, положит ли импорт книгу на полку, на которой ее нет.
+// onShelves - полки, на которых книга уже лежит.
+func (s *importShelves) adds(onShelves []int64, reading *importer.Reading) bool {
+	for _, name := range s.names(reading) {
+		id, ok := s.byName[strings.ToLower(name)]
+		if !ok || !slices.Contains(onShelves, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// place кладет книгу в конец полок из сведений о чтении, на которых ее
+// еще нет, и создает недостающие полки
+func (s *importShelves) place(ctx context.Context, bookID int64, onShelves []int64, reading *importer.Reading) error {
+	onShelves = slices.Clone(onShelves)
+	for _, name := range s.names(reading) {
+		id, ok := s.byName[strings.ToLower(name)]
+		if !ok {
+			shelf := &models.Shelf{Name: name}
+			if err := s.repo.CreateShelf(ctx, shelf); err != nil {
+				return err
+			}
+			id = shelf.ID
+			s.byName[strings.ToLower(name)] = id
+		}
+		if slices.Contains(onShelves, id) {
+			continue
+		}
+		if err := s.repo.AddToShelf(ctx, id, bookID, -1); err != nil {
+			return err
+		}
+		onShelves = append(onShelves, id)
+	}
+	return nil
+}
+
+func newImportedRow(row importer.Row) importedRow {
+	return importedRow{Line: row.Line, Title: row.Book.Title, ISBN: row.Book.ISBN, Reading: row.Reading}
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/NkvXness/GoBookshelf/internal/errors"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// Шаблоны путей к произведениям
+const (
+	workPath         = "/api/works/{id}"
+	workEditionsPath = "/api/works/{id}/editions"
+	workEditionPath  = "/api/works/{id}/editions/{book_id}"
+)
+
+// ListWorks возвращает все произведения по алфавиту с количеством изданий
+func (h *Handler) ListWorks(w http.ResponseWriter, r *http.Request) {
+	works, err := h.repo.ListWorks(r.Context())
+	if err != nil {
+		log.Printf("Error listing works: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить список произведений"))
+		return
+	}
+	if works == nil {
+		works = []*models.Work{}
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Works []*models.Work `json:"works"`
+	}{Works: works})
+}
+
+// GetWork возвращает произведение без списка изданий
+func (h *Handler) GetWork(w http.ResponseWriter, r *http.Request) {
+	id, ok := workID(w, r, workPath)
+	if !ok {
+		return
+	}
+
+	work, err := h.repo.GetWork(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting work: %v", err)
+		errors.WriteErrorResponse(w, errors.NewInternalServerError("Не удалось получить информацию о произведении", err))
+		return
+	}
+	if work == nil {
+		errors.WriteErrorResponse(w, errors.NewNotFoundError("Произведение не найдено"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(work)
+}
+
+// CreateWork создает произведение без изданий
+func (h *Handler) CreateWork(w http.ResponseWriter, r *http.Request) {
+	var work models.Work
+	if err := json.NewDecoder(r.Body).Decode(&work); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректные данные произведения"))
+		return
+	}
+
+	work.Normalize()
+	if err := work.Validate(); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.repo.CreateWork(r.Context(), &work); err != nil {
+		log.Printf("Error creating work: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось создать произведение"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(work)
+}
+
+// UpdateWork изменяет название произведения
+func (h *Handler) UpdateWork(w http.ResponseWriter, r *http.Request) {
+	id, ok := workID(w, r, workPath)
+	if !ok {
+		return
+	}
+
+	var work models.Work
+	if err := json.NewDecoder(r.Body).Decode(&work); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректные данные произведения"))
+		return
+	}
+	work.ID = id
+
+	work.Normalize()
+	if err := work.Validate(); err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := h.repo.UpdateWork(r.Context(), &work); err != nil {
+		log.Printf("Error updating work: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось обновить произведение"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(work)
+}
+
+// DeleteWork удаляет произведение; его издания остаются отдельными книгами
+func (h *Handler) DeleteWork(w http.ResponseWriter, r *http.Request) {
+	id, ok := workID(w, r, workPath)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteWork(r.Context(), id); err != nil {
+		log.Printf("Error deleting work: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось удалить произведение"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WorkEditions возвращает все издания произведения по дате публикации
+func (h *Handler) WorkEditions(w http.ResponseWriter, r *http.Request) {
+	id, ok := workID(w, r, workEditionsPath)
+	if !ok {
+		return
+	}
+
+	books, err := h.repo.WorkEditions(r.Context(), id)
+	if err != nil {
+		log.Printf("Error listing work editions: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось получить издания произведения"))
+		return
+	}
+	if books == nil {
+		books = []*models.Book{}
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Editions []*models.Book `json:"editions"`
+	}{Editions: books})
+}
+
+// LinkEdition делает книгу изданием произведения: {"book_id": 7}.
+// Издание другого произведения переходит к этому.
+func (h *Handler) LinkEdition(w http.ResponseWriter, r *http.Request) {
+	id, ok := workID(w, r, workEditionsPath)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		BookID int64 `json:"book_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.BookID < 1 {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Укажите ID книги: {\"book_id\": N}"))
+		return
+	}
+
+	if err := h.repo.LinkEdition(r.Context(), id, request.BookID); err != nil {
+		log.Printf("Error linking edition: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось связать издание с произведением"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlinkEdition отвязывает издание от произведения; книга остается
+// в библиотеке
+func (h *Handler) UnlinkEdition(w http.ResponseWriter, r *http.Request) {
+	id, ok := workID(w, r, workEditionPath)
+	if !ok {
+		return
+	}
+	bookID, err := strconv.ParseInt(namedPathParam(workEditionPath, r.URL.Path, "book_id"), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID книги"))
+		return
+	}
+
+	if err := h.repo.UnlinkEdition(r.Context(), id, bookID); err != nil {
+		log.Printf("Error unlinking edition: %v", err)
+		errors.WriteErrorResponse(w, storageError(err, "Не удалось отвязать издание от произведения"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// keepEdition оставляет книге прежние издательство, язык и формат, если
+// в запросе PUT их нет, как и ISBN. Очистить их можно через PATCH.
+func keepEdition(book, existing *models.Book) {
+	if book.Publisher == "" {
+		book.Publisher = existing.Publisher
+	}
+	if book.Language == "" {
+		book.Language = existing.Language
+	}
+	if book.Format == "" {
+		book.Format = existing.Format
+	}
+}
+
+// workID читает ID произведения из пути. Если ID некорректен, отправляет
+// ошибку и возвращает false.
+func workID(w http.ResponseWriter, r *http.Request, pattern string) (int64, bool) {
+	id, err := strconv.ParseInt(pathParam(pattern, r.URL.Path), 10, 64)
+	if err != nil {
+		errors.WriteErrorResponse(w, errors.NewBadRequestError("Некорректный ID произведения"))
+		return 0, false
+	}
+	return id, true
+}
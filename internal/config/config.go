@@ -1,24 +1,56 @@
 package config
 
 import (
+	"fmt"
+	"log"
+	"net/url"
 	"os"
+	"time"
 )
 
+// defaultTrashRetention - срок хранения книг в корзине по умолчанию
+const defaultTrashRetention = 30 * 24 * time.Hour
+
 // Config содержит конфигурацию приложения
 type Config struct {
 	Port   string
 	DBPath string
+	// DatabaseURL - строка подключения к базе данных (например, postgres://...).
+	// Если не задана, используется файл SQLite из DBPath.
+	DatabaseURL string
+	// TrashRetention - сколько книги хранятся в корзине до окончательного
+	// удаления. Ноль отключает очистку корзины.
+	TrashRetention time.Duration
 }
 
 // LoadConfig загружает конфигурацию из переменных окружения
 func LoadConfig() *Config {
 	config := &Config{
-		Port:   getEnv("PORT", "8080"),
-		DBPath: getEnv("DB_PATH", "bookshelf.db"),
+		Port:           getEnv("PORT", "8080"),
+		DBPath:         getEnv("DB_PATH", "bookshelf.db"),
+		DatabaseURL:    getEnv("DATABASE_URL", ""),
+		TrashRetention: getDurationEnv("TRASH_RETENTION", defaultTrashRetention),
 	}
 	return config
 }
 
+// DSN возвращает строку подключения, по схеме которой выбирается хранилище
+func (c *Config) DSN() string {
+	if c.DatabaseURL != "" {
+		return c.DatabaseURL
+	}
+	return c.DBPath
+}
+
+// String возвращает конфигурацию для логов, скрывая пароль в строке подключения
+func (c *Config) String() string {
+	dsn := c.DSN()
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		dsn = u.Redacted()
+	}
+	return fmt.Sprintf("{Port:%s DSN:%s TrashRetention:%s}", c.Port, dsn, c.TrashRetention)
+}
+
 // getEnv возвращает значение переменной окружения или значение по умолчанию
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -27,3 +59,19 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getDurationEnv возвращает длительность из переменной окружения (например, "720h")
+// или значение по умолчанию, если переменная не задана или некорректна
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("Некорректное значение %s=%q, используется %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 )
@@ -8,9 +9,13 @@ import (
 type ErrorType string
 
 const (
-	ErrorTypeNotFound       ErrorType = "NOT_FOUND"
-	ErrorTypeBadRequest     ErrorType = "BAD_REQUEST"
-	ErrorTypeInternalServer ErrorType = "INTERNAL_SERVER_ERROR"
+	ErrorTypeNotFound             ErrorType = "NOT_FOUND"
+	ErrorTypeBadRequest           ErrorType = "BAD_REQUEST"
+	ErrorTypeConflict             ErrorType = "CONFLICT"
+	ErrorTypePreconditionFailed   ErrorType = "PRECONDITION_FAILED"
+	ErrorTypeUnsupportedMediaType ErrorType = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorTypeNotAcceptable        ErrorType = "NOT_ACCEPTABLE"
+	ErrorTypeInternalServer       ErrorType = "INTERNAL_SERVER_ERROR"
 )
 
 type AppError struct {
@@ -37,6 +42,34 @@ func NewBadRequestError(message string) AppError {
 	}
 }
 
+func NewConflictError(message string) AppError {
+	return AppError{
+		Type:    ErrorTypeConflict,
+		Message: message,
+	}
+}
+
+func NewPreconditionFailedError(message string) AppError {
+	return AppError{
+		Type:    ErrorTypePreconditionFailed,
+		Message: message,
+	}
+}
+
+func NewUnsupportedMediaTypeError(message string) AppError {
+	return AppError{
+		Type:    ErrorTypeUnsupportedMediaType,
+		Message: message,
+	}
+}
+
+func NewNotAcceptableError(message string) AppError {
+	return AppError{
+		Type:    ErrorTypeNotAcceptable,
+		Message: message,
+	}
+}
+
 func NewInternalServerError(message string, err error) AppError {
 	return AppError{
 		Type:    ErrorTypeInternalServer,
@@ -45,21 +78,35 @@ func NewInternalServerError(message string, err error) AppError {
 	}
 }
 
+// StatusCode возвращает HTTP-статус, соответствующий типу ошибки
+func (e AppError) StatusCode() int {
+	switch e.Type {
+	case ErrorTypeNotFound:
+		return http.StatusNotFound
+	case ErrorTypeBadRequest:
+		return http.StatusBadRequest
+	case ErrorTypeConflict:
+		return http.StatusConflict
+	case ErrorTypePreconditionFailed:
+		return http.StatusPreconditionFailed
+	case ErrorTypeUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case ErrorTypeNotAcceptable:
+		return http.StatusNotAcceptable
+	}
+	return http.StatusInternalServerError
+}
+
 func WriteErrorResponse(w http.ResponseWriter, err error) {
 	appErr, ok := err.(AppError)
 	if !ok {
 		appErr = NewInternalServerError("An unexpected error occurred", err)
 	}
 
-	statusCode := http.StatusInternalServerError
-	switch appErr.Type {
-	case ErrorTypeNotFound:
-		statusCode = http.StatusNotFound
-	case ErrorTypeBadRequest:
-		statusCode = http.StatusBadRequest
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	fmt.Fprintf(w, `{"error": "%s", "message": "%s"}`, appErr.Type, appErr.Message)
+	w.WriteHeader(appErr.StatusCode())
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   string(appErr.Type),
+		"message": appErr.Message,
+	})
 }
@@ -0,0 +1,322 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// Форматы для менеджеров библиографии: BibTeX, RIS и CSL-JSON. Во всех
+// трех форматах дата публикации выгружается только годом, а имена авторов,
+// переводчиков и редакторов делятся на фамилию и имя.
+
+// SplitName делит имя автора на фамилию и имя по тем же правилам, что
+// и models.DefaultSortName: "Фамилия, Имя" делится по запятой, имя
+// с инициалами в конце ("Толстой Л.Н.") начинается с фамилии, в остальных
+// случаях фамилией считается последнее слово.
+func SplitName(author string) (family, given string) {
+	sortName := models.DefaultSortName(strings.TrimSpace(author))
+	if last, first, found := strings.Cut(sortName, ","); found {
+		return strings.TrimSpace(last), strings.TrimSpace(first)
+	}
+	return sortName, ""
+}
+
+// citationKeys выдает ключи ссылок вида tolstoy1869voina, уникальные
+// в пределах одной выгрузки
+type citationKeys struct {
+	used map[string]bool
+}
+
+// next возвращает ключ книги. При совпадении с уже выданным ключом
+// добавляется буква: tolstoy1869voinab, tolstoy1869voinac.
+func (k *citationKeys) next(book *models.Book) string {
+	if k.used == nil {
+		k.used = make(map[string]bool)
+	}
+
+	var family string
+	if authors := book.ContributorNames(models.RoleAuthor); len(authors) > 0 {
+		family, _ = SplitName(authors[0])
+	}
+	base := keyPart(family)
+	if base == "" {
+		base = "book"
+	}
+	if !book.Published.IsZero() {
+		base += strconv.Itoa(book.Published.Year())
+	}
+	base += titleWord(book.Title)
+
+	key := base
+	for suffix := 'b'; k.used[key]; suffix++ {
+		if suffix > 'z' {
+			key = fmt.Sprintf("%s%d", base, book.ID)
+			break
+		}
+		key = base + string(suffix)
+	}
+	k.used[key] = true
+	return key
+}
+
+// titleWord возвращает для ключа первое слово названия длиной от трех
+// символов (или первое слово, если таких нет)
+func titleWord(title string) string {
+	first := ""
+	for _, word := range strings.Fields(title) {
+		part := keyPart(word)
+		if len(part) >= 3 {
+			return part
+		}
+		if first == "" {
+			first = part
+		}
+	}
+	return first
+}
+
+// translit - транслитерация кириллицы для ключей ссылок
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu",
+	'я': "ia", 'і': "i", 'ї': "i", 'є': "ie", 'ў': "u",
+}
+
+// keyPart оставляет от строки строчные латинские буквы и цифры,
+// транслитерируя кириллицу
+func keyPart(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		default:
+			b.WriteString(translit[r])
+		}
+	}
+	return b.String()
+}
+
+type bibtexWriter struct {
+	w    io.Writer
+	keys citationKeys
+}
+
+// NewBibTeXWriter создает Writer, записывающий книги записями @book
+func NewBibTeXWriter(w io.Writer) Writer {
+	return &bibtexWriter{w: w}
+}
+
+// bibtexEscaper экранирует символы, особые для BibTeX и LaTeX
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"&", `\&`,
+	"%", `\%`,
+	"$", `\$`,
+	"#", `\#`,
+	"_", `\_`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+)
+
+func (b *bibtexWriter) WriteBook(book *models.Book) error {
+	var entry strings.Builder
+	fmt.Fprintf(&entry, "@book{%s,\n", b.keys.next(book))
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&entry, "  %s = {%s},\n", name, bibtexEscaper.Replace(value))
+		}
+	}
+	// Несколько имен в BibTeX перечисляются через "and"
+	field("author", strings.Join(book.ContributorNames(models.RoleAuthor), " and "))
+	field("editor", strings.Join(book.ContributorNames(models.RoleEditor), " and "))
+	field("translator", strings.Join(book.ContributorNames(models.RoleTranslator), " and "))
+	field("title", book.Title)
+	field("series", book.Series)
+	field("number", seriesNumber(book))
+	field("publisher", book.Publisher)
+	field("language", book.Language)
+	if !book.Published.IsZero() {
+		field("year", strconv.Itoa(book.Published.Year()))
+	}
+	field("isbn", book.ISBN)
+	entry.WriteString("}\n\n")
+
+	if _, err := io.WriteString(b.w, entry.String()); err != nil {
+		return fmt.Errorf("failed to write bibtex entry: %w", err)
+	}
+	return nil
+}
+
+func (b *bibtexWriter) Close() error {
+	return nil
+}
+
+type risWriter struct {
+	w io.Writer
+}
+
+// NewRISWriter создает Writer, записывающий книги записями RIS с типом BOOK
+func NewRISWriter(w io.Writer) Writer {
+	return &risWriter{w: w}
+}
+
+func (r *risWriter) WriteBook(book *models.Book) error {
+	var entry strings.Builder
+	// Строки RIS заканчиваются CRLF, значения не должны содержать переводов строк
+	tag := func(name, value string) {
+		if value = strings.Join(strings.Fields(value), " "); value != "" {
+			fmt.Fprintf(&entry, "%s  - %s\r\n", name, value)
+		}
+	}
+	names := func(name, role string) {
+		for _, person := range book.ContributorNames(role) {
+			if family, given := SplitName(person); given != "" {
+				tag(name, family+", "+given)
+			} else {
+				tag(name, family)
+			}
+		}
+	}
+	tag("TY", "BOOK")
+	names("AU", models.RoleAuthor)
+	names("ED", models.RoleEditor)
+	names("A4", models.RoleTranslator)
+	tag("TI", book.Title)
+	tag("PB", book.Publisher)
+	if !book.Published.IsZero() {
+		tag("PY", strconv.Itoa(book.Published.Year()))
+	}
+	tag("SN", book.ISBN)
+	tag("LA", book.Language)
+	if book.ID != 0 {
+		tag("ID", strconv.FormatInt(book.ID, 10))
+	}
+	entry.WriteString("ER  - \r\n")
+
+	if _, err := io.WriteString(r.w, entry.String()); err != nil {
+		return fmt.Errorf("failed to write ris record: %w", err)
+	}
+	return nil
+}
+
+func (r *risWriter) Close() error {
+	return nil
+}
+
+// cslItem - запись CSL-JSON (https://citationstyles.org)
+type cslItem struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Title       string    `json:"title"`
+	Author      []cslName `json:"author,omitempty"`
+	Editor      []cslName `json:"editor,omitempty"`
+	Translator  []cslName `json:"translator,omitempty"`
+	Illustrator []cslName `json:"illustrator,omitempty"`
+	Issued      *cslDate  `json:"issued,omitempty"`
+	ISBN        string    `json:"ISBN,omitempty"`
+	// CollectionTitle и CollectionNumber - серия книги и номер в ней
+	CollectionTitle  string `json:"collection-title,omitempty"`
+	CollectionNumber string `json:"collection-number,omitempty"`
+	Publisher        string `json:"publisher,omitempty"`
+	Language         string `json:"language,omitempty"`
+}
+
+type cslName struct {
+	Family string `json:"family"`
+	Given  string `json:"given,omitempty"`
+}
+
+// cslNames возвращает имена участников книги с ролью role
+func cslNames(book *models.Book, role string) []cslName {
+	var names []cslName
+	for _, person := range book.ContributorNames(role) {
+		if family, given := SplitName(person); family != "" {
+			names = append(names, cslName{Family: family, Given: given})
+		}
+	}
+	return names
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+type cslWriter struct {
+	w       io.Writer
+	keys    citationKeys
+	started bool
+}
+
+// NewCSLWriter создает Writer, записывающий книги массивом CSL-JSON.
+// Идентификаторы записей совпадают с ключами ссылок BibTeX.
+func NewCSLWriter(w io.Writer) Writer {
+	return &cslWriter{w: w}
+}
+
+func (c *cslWriter) WriteBook(book *models.Book) error {
+	item := cslItem{
+		ID:          c.keys.next(book),
+		Type:        "book",
+		Title:       book.Title,
+		Author:      cslNames(book, models.RoleAuthor),
+		Editor:      cslNames(book, models.RoleEditor),
+		Translator:  cslNames(book, models.RoleTranslator),
+		Illustrator: cslNames(book, models.RoleIllustrator),
+		ISBN:        book.ISBN,
+
+		CollectionTitle:  book.Series,
+		CollectionNumber: seriesNumber(book),
+		Publisher:        book.Publisher,
+		Language:         book.Language,
+	}
+	if !book.Published.IsZero() {
+		item.Issued = &cslDate{DateParts: [][]int{{book.Published.Year()}}}
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to encode csl item: %w", err)
+	}
+
+	// Массив пишется по частям, чтобы выгрузка шла потоком
+	prefix := ",\n"
+	if !c.started {
+		prefix = "[\n"
+		c.started = true
+	}
+	if _, err := io.WriteString(c.w, prefix+string(data)); err != nil {
+		return fmt.Errorf("failed to write csl item: %w", err)
+	}
+	return nil
+}
+
+func (c *cslWriter) Close() error {
+	end := "\n]\n"
+	if !c.started {
+		end = "[]\n"
+	}
+	if _, err := io.WriteString(c.w, end); err != nil {
+		return fmt.Errorf("failed to write csl json: %w", err)
+	}
+	return nil
+}
+
+// seriesNumber возвращает номер книги в серии без лишних нулей ("2", "2.5")
+// или пустую строку, если номера нет
+func seriesNumber(book *models.Book) string {
+	if book.SeriesPosition == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*book.SeriesPosition, 'f', -1, 64)
+}
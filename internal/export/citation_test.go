@@ -0,0 +1,191 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func citationBooks() []*models.Book {
+	return []*models.Book{
+		{ID: 1, Title: "Война и мир", Author: "Лев Толстой", ISBN: "978-5-17-090335-2", Published: time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Title: "Война и мир", Author: "Лев Толстой", ISBN: "978-5-389-07435-4", Published: time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 3, Title: "The C & Unix {Guide} 100%", Author: "Orwell, George", ISBN: "978-0-14-044944-8", Published: time.Date(1945, 8, 17, 0, 0, 0, 0, time.UTC)},
+	}
+}
+
+func writeAll(t *testing.T, format string, books []*models.Book) string {
+	t.Helper()
+	f, ok := Lookup(format)
+	if !ok {
+		t.Fatalf("Lookup(%q) not found", format)
+	}
+
+	var buf bytes.Buffer
+	w := f.New(&buf)
+	for _, book := range books {
+		if err := w.WriteBook(book); err != nil {
+			t.Fatalf("WriteBook() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestSplitName(t *testing.T) {
+	tests := map[string][2]string{
+		"Лев Толстой":       {"Толстой", "Лев"},
+		"Orwell, George":    {"Orwell", "George"},
+		"Ursula K. Le Guin": {"Guin", "Ursula K. Le"},
+		"Гомер":             {"Гомер", ""},
+		" Достоевский, Фёдор М. ": {"Достоевский", "Фёдор М."},
+		"Толстой Л.Н.":            {"Толстой", "Л.Н."},
+		"Стругацкий А. Н.":        {"Стругацкий", "А. Н."},
+	}
+	for author, want := range tests {
+		if family, given := SplitName(author); family != want[0] || given != want[1] {
+			t.Errorf("SplitName(%q) = %q, %q; want %q, %q", author, family, given, want[0], want[1])
+		}
+	}
+}
+
+func TestBibTeXWriter(t *testing.T) {
+	got := writeAll(t, "bibtex", citationBooks())
+	want := "@book{tolstoi1869voina,\n" +
+		"  author = {Лев Толстой},\n" +
+		"  title = {Война и мир},\n" +
+		"  year = {1869},\n" +
+		"  isbn = {978-5-17-090335-2},\n" +
+		"}\n\n" +
+		"@book{tolstoi1869voinab,\n" +
+		"  author = {Лев Толстой},\n" +
+		"  title = {Война и мир},\n" +
+		"  year = {1869},\n" +
+		"  isbn = {978-5-389-07435-4},\n" +
+		"}\n\n" +
+		"@book{orwell1945the,\n" +
+		"  author = {Orwell, George},\n" +
+		"  title = {The C \\& Unix \\{Guide\\} 100\\%},\n" +
+		"  year = {1945},\n" +
+		"  isbn = {978-0-14-044944-8},\n" +
+		"}\n\n"
+	if got != want {
+		t.Errorf("bibtex export = %q, want %q", got, want)
+	}
+}
+
+func TestRISWriter(t *testing.T) {
+	got := writeAll(t, "ris", citationBooks()[2:])
+	want := "TY  - BOOK\r\n" +
+		"AU  - Orwell, George\r\n" +
+		"TI  - The C & Unix {Guide} 100%\r\n" +
+		"PY  - 1945\r\n" +
+		"SN  - 978-0-14-044944-8\r\n" +
+		"ID  - 3\r\n" +
+		"ER  - \r\n"
+	if got != want {
+		t.Errorf("ris export = %q, want %q", got, want)
+	}
+}
+
+func TestCSLWriter(t *testing.T) {
+	if got := writeAll(t, "csl-json", nil); got != "[]\n" {
+		t.Errorf("empty csl-json export = %q, want []", got)
+	}
+
+	var items []cslItem
+	if err := json.Unmarshal([]byte(writeAll(t, "csl-json", citationBooks())), &items); err != nil {
+		t.Fatalf("csl-json export is not valid JSON: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("csl-json export has %d items, want 3", len(items))
+	}
+
+	first := items[0]
+	if first.ID != "tolstoi1869voina" || first.Type != "book" || first.ISBN != "978-5-17-090335-2" ||
+		len(first.Author) != 1 || first.Author[0] != (cslName{Family: "Толстой", Given: "Лев"}) ||
+		first.Issued == nil || first.Issued.DateParts[0][0] != 1869 {
+		t.Errorf("csl-json item = %+v", first)
+	}
+	if items[1].ID != "tolstoi1869voinab" {
+		t.Errorf("csl-json duplicate key = %q, want tolstoi1869voinab", items[1].ID)
+	}
+}
+
+func TestCitationContributors(t *testing.T) {
+	book := &models.Book{ID: 4, Title: "Гамлет", Author: "William Shakespeare, John Fletcher",
+		Published: time.Date(1940, 1, 1, 0, 0, 0, 0, time.UTC), Contributors: []models.Contributor{
+			{Name: "William Shakespeare", Role: models.RoleAuthor},
+			{Name: "John Fletcher", Role: models.RoleAuthor},
+			{Name: "Борис Пастернак", Role: models.RoleTranslator},
+		}}
+
+	bibtex := writeAll(t, "bibtex", []*models.Book{book})
+	if !strings.Contains(bibtex, "@book{shakespeare1940gamlet,\n  author = {William Shakespeare and John Fletcher},\n  translator = {Борис Пастернак},\n") {
+		t.Errorf("bibtex export = %q", bibtex)
+	}
+
+	ris := writeAll(t, "ris", []*models.Book{book})
+	if !strings.Contains(ris, "AU  - Shakespeare, William\r\nAU  - Fletcher, John\r\nA4  - Пастернак, Борис\r\n") {
+		t.Errorf("ris export = %q", ris)
+	}
+
+	var items []cslItem
+	json.Unmarshal([]byte(writeAll(t, "csl-json", []*models.Book{book})), &items)
+	if len(items) != 1 || len(items[0].Author) != 2 || len(items[0].Translator) != 1 || items[0].Translator[0].Family != "Пастернак" {
+		t.Errorf("csl-json item = %+v", items)
+	}
+}
+
+func TestByMediaType(t *testing.T) {
+	if f, ok := ByMediaType("Application/X-BibTeX"); !ok || f.Name != "bibtex" {
+		t.Errorf("ByMediaType(bibtex) = %+v, %v", f, ok)
+	}
+	if _, ok := ByMediaType("application/json"); ok {
+		t.Error("ByMediaType(application/json) found a format")
+	}
+}
+
+func TestCitationSeries(t *testing.T) {
+	position := 2.5
+	book := &models.Book{ID: 5, Title: "Стрелок", Author: "Стивен Кинг",
+		Published: time.Date(1982, 1, 1, 0, 0, 0, 0, time.UTC), Series: "Тёмная башня", SeriesPosition: &position}
+
+	bibtex := writeAll(t, "bibtex", []*models.Book{book})
+	if !strings.Contains(bibtex, "  series = {Тёмная башня},\n  number = {2.5},\n") {
+		t.Errorf("bibtex export = %q", bibtex)
+	}
+
+	var items []cslItem
+	json.Unmarshal([]byte(writeAll(t, "csl-json", []*models.Book{book})), &items)
+	if len(items) != 1 || items[0].CollectionTitle != "Тёмная башня" || items[0].CollectionNumber != "2.5" {
+		t.Errorf("csl-json item = %+v", items)
+	}
+}
+
+func TestCitationEdition(t *testing.T) {
+	book := &models.Book{ID: 6, Title: "Anna Karenina", Author: "Leo Tolstoy",
+		Published: time.Date(1901, 1, 1, 0, 0, 0, 0, time.UTC), Publisher: "Thomas Y. Crowell", Language: "en"}
+
+	bibtex := writeAll(t, "bibtex", []*models.Book{book})
+	if !strings.Contains(bibtex, "  publisher = {Thomas Y. Crowell},\n  language = {en},\n") {
+		t.Errorf("bibtex export = %q", bibtex)
+	}
+
+	ris := writeAll(t, "ris", []*models.Book{book})
+	if !strings.Contains(ris, "PB  - Thomas Y. Crowell\r\n") || !strings.Contains(ris, "LA  - en\r\n") {
+		t.Errorf("ris export = %q", ris)
+	}
+
+	var items []cslItem
+	json.Unmarshal([]byte(writeAll(t, "csl-json", []*models.Book{book})), &items)
+	if len(items) != 1 || items[0].Publisher != "Thomas Y. Crowell" || items[0].Language != "en" {
+		t.Errorf("csl-json item = %+v", items)
+	}
+}
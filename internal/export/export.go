@@ -0,0 +1,143 @@
+// Package export записывает книги в форматы обмена данными.
+//
+// Каждый формат реализует Writer, который получает книги по одной, поэтому
+// библиотеку можно выгружать потоком, не загружая ее в память целиком.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// Writer записывает книги в выбранном формате
+type Writer interface {
+	// WriteBook записывает одну книгу
+	WriteBook(book *models.Book) error
+	// Close дописывает окончание документа. Базовый io.Writer не закрывается.
+	Close() error
+}
+
+// Format описывает формат выгрузки
+type Format struct {
+	// Name - значение параметра format
+	Name string
+	// MediaType - тип данных без параметров, по которому формат выбирается
+	// из заголовка Accept
+	MediaType string
+	// ContentType и Extension используются в ответе HTTP
+	ContentType string
+	Extension   string
+	// New создает Writer, пишущий в w
+	New func(w io.Writer) Writer
+}
+
+// formats содержит поддерживаемые форматы по имени
+var formats = map[string]Format{
+	"csv": {Name: "csv", MediaType: "text/csv", ContentType: "text/csv; charset=utf-8", Extension: "csv", New: NewCSVWriter},
+	"bibtex": {Name: "bibtex", MediaType: "application/x-bibtex", ContentType: "application/x-bibtex; charset=utf-8",
+		Extension: "bib", New: NewBibTeXWriter},
+	"ris": {Name: "ris", MediaType: "application/x-research-info-systems",
+		ContentType: "application/x-research-info-systems; charset=utf-8", Extension: "ris", New: NewRISWriter},
+	"csl-json": {Name: "csl-json", MediaType: "application/vnd.citationstyles.csl+json",
+		ContentType: "application/vnd.citationstyles.csl+json; charset=utf-8", Extension: "json", New: NewCSLWriter},
+	"marc": {Name: "marc", MediaType: "application/marc", ContentType: "application/marc", Extension: "mrc", New: NewMARCWriter},
+	"marcxml": {Name: "marcxml", MediaType: "application/marcxml+xml", ContentType: "application/marcxml+xml; charset=utf-8",
+		Extension: "xml", New: NewMARCXMLWriter},
+}
+
+// Lookup возвращает формат по имени
+func Lookup(name string) (Format, bool) {
+	format, ok := formats[name]
+	return format, ok
+}
+
+// ByMediaType возвращает формат по типу данных из заголовка Accept
+func ByMediaType(mediaType string) (Format, bool) {
+	for _, format := range formats {
+		if strings.EqualFold(format.MediaType, mediaType) {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// Names возвращает имена поддерживаемых форматов по алфавиту
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CSVColumns - колонки выгрузки в CSV. Первые пять колонок понимает импорт.
+var CSVColumns = []string{"id", "title", "author", "isbn", "published", "created_at", "updated_at"}
+
+// DateLayout - формат даты публикации в выгрузке
+const DateLayout = "2006-01-02"
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+// NewCSVWriter создает Writer, записывающий книги в CSV с заголовком CSVColumns
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	if err := c.w.Write(CSVColumns); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+	return nil
+}
+
+func (c *csvWriter) WriteBook(book *models.Book) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	record := []string{
+		strconv.FormatInt(book.ID, 10),
+		book.Title,
+		book.Author,
+		book.ISBN,
+		book.Published.UTC().Format(DateLayout),
+		book.CreatedAt.UTC().Format(time.RFC3339),
+		book.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if err := c.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write csv row: %w", err)
+	}
+	return c.flush()
+}
+
+func (c *csvWriter) Close() error {
+	// Пустая выгрузка все равно содержит заголовок
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	return c.flush()
+}
+
+// flush передает строки в базовый io.Writer сразу, чтобы выгрузка шла потоком
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return fmt.Errorf("failed to flush csv: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got, want := buf.String(), "id,title,author,isbn,published,created_at,updated_at\n"; got != want {
+		t.Errorf("empty export = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	w = NewCSVWriter(&buf)
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	book := &models.Book{
+		ID:        7,
+		Title:     `Сказка о "рыбаке", и рыбке`,
+		Author:    "Александр Пушкин",
+		ISBN:      "978-5-17-090335-2",
+		Published: time.Date(1835, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	if err := w.WriteBook(book); err != nil {
+		t.Fatalf("WriteBook() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := "id,title,author,isbn,published,created_at,updated_at\n" +
+		`7,"Сказка о ""рыбаке"", и рыбке",Александр Пушкин,978-5-17-090335-2,1835-01-01,2024-05-01T12:00:00Z,2024-05-01T12:00:00Z` + "\n"
+	if buf.String() != want {
+		t.Errorf("export = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,42 @@
+package export
+
+import (
+	"io"
+
+	"github.com/NkvXness/GoBookshelf/internal/marc"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+type marcWriter struct {
+	w *marc.Writer
+}
+
+// NewMARCWriter создает Writer, записывающий книги записями MARC21 (ISO 2709)
+func NewMARCWriter(w io.Writer) Writer {
+	return &marcWriter{w: marc.NewWriter(w)}
+}
+
+func (m *marcWriter) WriteBook(book *models.Book) error {
+	return m.w.Write(marc.FromBook(book))
+}
+
+func (m *marcWriter) Close() error {
+	return nil
+}
+
+type marcXMLWriter struct {
+	w *marc.XMLWriter
+}
+
+// NewMARCXMLWriter создает Writer, записывающий книги документом MARCXML
+func NewMARCXMLWriter(w io.Writer) Writer {
+	return &marcXMLWriter{w: marc.NewXMLWriter(w)}
+}
+
+func (m *marcXMLWriter) WriteBook(book *models.Book) error {
+	return m.w.Write(marc.FromBook(book))
+}
+
+func (m *marcXMLWriter) Close() error {
+	return m.w.Close()
+}
@@ -0,0 +1,274 @@
+// Package importer читает книги из файлов других программ и выгрузок.
+//
+// Каждая строка файла превращается в Row: книгу, прошедшую Normalize и
+// Validate, или ошибку с номером строки. Сохранение книг выполняет
+// вызывающая сторона.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// Field - поле книги, в которое импортируется колонка
+type Field string
+
+const (
+	FieldTitle     Field = "title"
+	FieldAuthor    Field = "author"
+	FieldISBN      Field = "isbn"
+	FieldPublished Field = "published"
+)
+
+// Fields - поля книги, которые можно сопоставить колонкам CSV
+var Fields = []Field{FieldTitle, FieldAuthor, FieldISBN, FieldPublished}
+
+// Mapping сопоставляет полю книги заголовок колонки CSV
+type Mapping map[Field]string
+
+// fieldAliases - заголовки колонок, которые распознаются без явного
+// сопоставления (сравниваются без учета регистра и пробелов по краям)
+var fieldAliases = map[Field][]string{
+	FieldTitle:     {"title", "name", "book title", "название", "заглавие"},
+	FieldAuthor:    {"author", "authors", "writer", "автор", "авторы"},
+	FieldISBN:      {"isbn", "isbn13", "isbn-13", "isbn 13"},
+	FieldPublished: {"published", "publication date", "date published", "year published", "year", "дата публикации", "год"},
+}
+
+// Row - строка импорта
+type Row struct {
+	// Line - номер строки файла, начиная с 1 (строка 1 - заголовок),
+	// для MARC - номер записи
+	Line int
+	Book *models.Book
+	// Err описывает, почему строку нельзя импортировать
+	Err error
+	// Reading заполняется читателями выгрузок Goodreads и LibraryThing
+	Reading *Reading
+}
+
+// MappingError возвращается, если колонки CSV не удалось сопоставить полям книги
+type MappingError struct {
+	Message string
+	// Header - заголовки колонок файла
+	Header []string
+}
+
+func (e *MappingError) Error() string {
+	return fmt.Sprintf("%s (columns: %s)", e.Message, strings.Join(e.Header, ", "))
+}
+
+// DetectMapping сопоставляет полям книги колонки по их заголовкам
+func DetectMapping(header []string) Mapping {
+	mapping := make(Mapping)
+	for _, field := range Fields {
+		for _, alias := range fieldAliases[field] {
+			if column, ok := findColumn(header, alias); ok {
+				mapping[field] = header[column]
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+// findColumn ищет колонку по заголовку без учета регистра
+func findColumn(header []string, name string) (int, bool) {
+	for i, column := range header {
+		if strings.EqualFold(strings.TrimSpace(column), strings.TrimSpace(name)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// ReadCSV читает книги из CSV с заголовком. Разделитель (запятая, точка
+// с запятой или табуляция) определяется по первой строке. Поля, которых нет
+// в mapping, сопоставляются колонкам по заголовкам (DetectMapping).
+// Возвращает использованное сопоставление и строки файла.
+func ReadCSV(r io.Reader, mapping Mapping) (Mapping, []Row, error) {
+	reader, err := newCSVReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil, &MappingError{Message: "file is empty"}
+		}
+		return nil, nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	// Явно заданные колонки дополняют найденные по заголовкам
+	detected := DetectMapping(header)
+	for field, column := range mapping {
+		detected[field] = column
+	}
+	mapping = detected
+
+	columns, err := resolveMapping(header, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, Row{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+		if isBlank(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		value := func(field Field) string {
+			if column, ok := columns[field]; ok && column < len(record) {
+				return strings.TrimSpace(record[column])
+			}
+			return ""
+		}
+
+		book := &models.Book{
+			Title:  value(FieldTitle),
+			Author: value(FieldAuthor),
+			ISBN:   value(FieldISBN),
+		}
+		rows = append(rows, newRow(line, book, value(FieldPublished)))
+	}
+
+	return mapping, rows, nil
+}
+
+// newRow разбирает дату публикации, форматирует ISBN и проверяет книгу
+func newRow(line int, book *models.Book, published string) Row {
+	if published != "" {
+		date, err := ParseDate(published)
+		if err != nil {
+			return Row{Line: line, Err: err}
+		}
+		book.Published = date
+	}
+
+	book.Normalize()
+	if err := book.Validate(); err != nil {
+		return Row{Line: line, Err: err}
+	}
+	return Row{Line: line, Book: book}
+}
+
+// resolveMapping проверяет сопоставление и возвращает номера колонок полей
+func resolveMapping(header []string, mapping Mapping) (map[Field]int, error) {
+	columns := make(map[Field]int, len(mapping))
+	for field, name := range mapping {
+		if !isField(field) {
+			return nil, &MappingError{Message: fmt.Sprintf("unknown book field %q", field), Header: header}
+		}
+		column, ok := findColumn(header, name)
+		if !ok {
+			return nil, &MappingError{Message: fmt.Sprintf("column %q for %s not found", name, field), Header: header}
+		}
+		columns[field] = column
+	}
+
+	var missing []string
+	for _, field := range Fields {
+		if _, ok := columns[field]; !ok {
+			missing = append(missing, string(field))
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, &MappingError{Message: "no columns for " + strings.Join(missing, ", "), Header: header}
+	}
+	return columns, nil
+}
+
+func isField(field Field) bool {
+	for _, f := range Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// newCSVReader создает csv.Reader, определяя разделитель по первой строке
+// и пропуская метку порядка байтов UTF-8
+func newCSVReader(r io.Reader) (*csv.Reader, error) {
+	buffered := bufio.NewReader(r)
+	if bom, err := buffered.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		buffered.Discard(3)
+	}
+
+	firstLine, err := buffered.Peek(buffered.Size())
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if end := bytes.IndexByte(firstLine, '\n'); end >= 0 {
+		firstLine = firstLine[:end]
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = detectDelimiter(firstLine)
+	reader.FieldsPerRecord = -1
+	return reader, nil
+}
+
+// detectDelimiter выбирает самый частый из поддерживаемых разделителей
+func detectDelimiter(line []byte) rune {
+	delimiter, best := ',', bytes.Count(line, []byte{','})
+	for _, candidate := range []rune{';', '\t'} {
+		if count := bytes.Count(line, []byte(string(candidate))); count > best {
+			delimiter, best = candidate, count
+		}
+	}
+	return delimiter
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// dateLayouts - поддерживаемые форматы даты публикации
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02",
+	"2006/01/02",
+	"02.01.2006",
+	"2006-01",
+	"2006",
+}
+
+// ParseDate разбирает дату публикации. Год без месяца и дня означает 1 января.
+func ParseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid published date %q, expected YYYY-MM-DD", value)
+}
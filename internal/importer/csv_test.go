@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := "\xef\xbb\xbfНазвание;Автор;ISBN;Год\n" +
+		"Война и мир;Лев Толстой;9785170903351;1869\n" +
+		"\n" +
+		"\"Анна; Каренина\";Лев Толстой;978-5-17-090335-2;1877-03-01\n" +
+		"Без даты;Автор;9785170903351;\n" +
+		"Бесы;Фёдор Достоевский;9785170903351;весна\n"
+
+	mapping, rows, err := ReadCSV(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("ReadCSV() error = %v", err)
+	}
+	if mapping[FieldTitle] != "Название" || mapping[FieldPublished] != "Год" {
+		t.Errorf("ReadCSV() mapping = %v", mapping)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("ReadCSV() got %d rows, want 4", len(rows))
+	}
+
+	first := rows[0]
+	if first.Err != nil || first.Line != 2 || first.Book.ISBN != "978-5-17-090335-1" ||
+		!first.Book.Published.Equal(time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ReadCSV() row 0 = %+v, %+v", first, first.Book)
+	}
+
+	// Строка с неверной контрольной суммой, без даты и с неразборчивой датой
+	wantLines := []int{4, 5, 6}
+	for i, row := range rows[1:] {
+		if row.Err == nil || row.Line != wantLines[i] {
+			t.Errorf("ReadCSV() row %d = line %d, error %v; want line %d with error", i+1, row.Line, row.Err, wantLines[i])
+		}
+	}
+}
+
+func TestReadCSVMapping(t *testing.T) {
+	input := "Book,Who,Code,When\nИдиот,Фёдор Достоевский,9785170903351,1869-01-01\n"
+
+	_, _, err := ReadCSV(strings.NewReader(input), nil)
+	var mappingErr *MappingError
+	if !errors.As(err, &mappingErr) || len(mappingErr.Header) != 4 {
+		t.Fatalf("ReadCSV() without mapping error = %v, want *MappingError", err)
+	}
+
+	mapping := Mapping{FieldTitle: "book", FieldISBN: "Code", FieldPublished: "When"}
+	if _, _, err := ReadCSV(strings.NewReader(input), mapping); !errors.As(err, &mappingErr) {
+		t.Errorf("ReadCSV() with partial mapping error = %v, want *MappingError", err)
+	}
+
+	mapping[FieldAuthor] = "Who"
+	_, rows, err := ReadCSV(strings.NewReader(input), mapping)
+	if err != nil {
+		t.Fatalf("ReadCSV() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0].Err != nil || rows[0].Book.Title != "Идиот" {
+		t.Errorf("ReadCSV() rows = %+v", rows)
+	}
+
+	mapping["pages"] = "When"
+	if _, _, err := ReadCSV(strings.NewReader(input), mapping); !errors.As(err, &mappingErr) {
+		t.Errorf("ReadCSV() with unknown field error = %v, want *MappingError", err)
+	}
+}
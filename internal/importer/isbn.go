@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// CleanISBN убирает обертки, которыми выгрузки защищают ISBN от
+// преобразования в число: ="9780439023481" (Goodreads), [0439023483]
+// (LibraryThing), а также пробелы и дефисы. Возвращает только цифры и X.
+func CleanISBN(value string) string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(value, "=")
+	value = strings.Trim(value, `"[] `)
+
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case unicode.IsDigit(r) && r < unicode.MaxASCII:
+			b.WriteRune(r)
+		case r == 'x' || r == 'X':
+			b.WriteRune('X')
+		}
+	}
+	return b.String()
+}
+
+// pickISBN выбирает из значений первый ISBN, который можно привести
+// к ISBN-13. Если такого нет, возвращает первое непустое значение, чтобы
+// валидация сообщила об ошибке.
+func pickISBN(values ...string) string {
+	fallback := ""
+	for _, value := range values {
+		for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+			isbn := CleanISBN(part)
+			if models.ValidISBN13(isbn) {
+				return isbn
+			}
+			if isbn13, ok := models.ISBN10To13(isbn); ok {
+				return isbn13
+			}
+			if fallback == "" {
+				fallback = isbn
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,30 @@
+package importer
+
+import "testing"
+
+func TestCleanISBN(t *testing.T) {
+	tests := map[string]string{
+		`="9780439023481"`:   "9780439023481",
+		`="0439023483"`:      "0439023483",
+		"[080442957x]":       "080442957X",
+		" 978-5-17-090335-1": "9785170903351",
+		`=""`:                "",
+	}
+	for input, want := range tests {
+		if got := CleanISBN(input); got != want {
+			t.Errorf("CleanISBN(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestPickISBN(t *testing.T) {
+	if got := pickISBN(`=""`, `="0451524934"`); got != "9780451524935" {
+		t.Errorf("pickISBN() = %q, want ISBN-13 from the ISBN-10 column", got)
+	}
+	if got := pickISBN("123, 0451524934"); got != "9780451524935" {
+		t.Errorf("pickISBN() list = %q, want first convertible ISBN", got)
+	}
+	if got := pickISBN("123"); got != "123" {
+		t.Errorf("pickISBN() invalid = %q, want value kept for validation", got)
+	}
+}
@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"errors"
+	"io"
+
+	"github.com/NkvXness/GoBookshelf/internal/marc"
+)
+
+// ReadMARC читает записи MARC21 в двоичном формате. Номер строки Row.Line
+// для MARC - номер записи в файле, начиная с 1. Поврежденная запись
+// становится строкой с ошибкой, остальные записи читаются дальше.
+func ReadMARC(r io.Reader) ([]Row, error) {
+	reader := marc.NewReader(r)
+	var rows []Row
+	for line := 1; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		var recordErr *marc.RecordError
+		if errors.As(err, &recordErr) {
+			rows = append(rows, Row{Line: line, Err: recordErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, marcRow(line, rec))
+	}
+}
+
+// ReadMARCXML читает записи MARCXML. Row.Line - номер записи, как в ReadMARC.
+func ReadMARCXML(r io.Reader) ([]Row, error) {
+	reader := marc.NewXMLReader(r)
+	var rows []Row
+	for line := 1; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, marcRow(line, rec))
+	}
+}
+
+// marcRow переводит запись в книгу и проверяет ее так же, как CreateBook
+func marcRow(line int, rec *marc.Record) Row {
+	book := marc.ToBook(rec)
+	book.ISBN = pickISBN(book.ISBN)
+	return newRow(line, book, "")
+}
@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/marc"
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func TestReadMARC(t *testing.T) {
+	books := []*models.Book{
+		{Title: "1984", Author: "George Orwell", ISBN: "0451524934", Published: time.Date(1949, 6, 8, 0, 0, 0, 0, time.UTC)},
+		{Title: "Без года", Author: "Автор", ISBN: "9785170903351"},
+	}
+
+	var binary, xml bytes.Buffer
+	w, xw := marc.NewWriter(&binary), marc.NewXMLWriter(&xml)
+	for _, book := range books {
+		w.Write(marc.FromBook(book))
+		xw.Write(marc.FromBook(book))
+	}
+	xw.Close()
+
+	for name, read := range map[string]func() ([]Row, error){
+		"marc":    func() ([]Row, error) { return ReadMARC(&binary) },
+		"marcxml": func() ([]Row, error) { return ReadMARCXML(&xml) },
+	} {
+		rows, err := read()
+		if err != nil {
+			t.Fatalf("%s: read error = %v", name, err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("%s: got %d rows, want 2", name, len(rows))
+		}
+
+		// ISBN-10 переводится в ISBN-13, книга проверяется как в CreateBook
+		first := rows[0]
+		if first.Err != nil || first.Line != 1 || first.Book.ISBN != "978-0-451-52493-5" ||
+			first.Book.Author != "George Orwell" || first.Book.Published.Year() != 1949 {
+			t.Errorf("%s: row 0 = %+v, %+v", name, first, first.Book)
+		}
+		if rows[1].Err == nil || rows[1].Line != 2 {
+			t.Errorf("%s: record without date = %+v, want error on record 2", name, rows[1])
+		}
+	}
+}
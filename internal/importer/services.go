@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// Reading - сведения о чтении книги из выгрузок Goodreads и LibraryThing.
+// Теги становятся тегами книги, полки - полками библиотеки. Для оценки
+// и даты прочтения в модели книги пока нет полей, они только попадают
+// в отчет.
+type Reading struct {
+	// Rating - оценка читателя от 0.5 до 5, 0 - без оценки
+	Rating   float64    `json:"rating,omitempty"`
+	Shelves  []string   `json:"shelves,omitempty"`
+	Tags     []string   `json:"tags,omitempty"`
+	DateRead *time.Time `json:"date_read,omitempty"`
+}
+
+// Reader читает строки импорта из выгрузки
+type Reader func(r io.Reader) ([]Row, error)
+
+// services содержит читатели выгрузок по имени формата
+var services = map[string]Reader{
+	"goodreads":    ReadGoodreads,
+	"librarything": ReadLibraryThing,
+	"marc":         ReadMARC,
+	"marcxml":      ReadMARCXML,
+}
+
+// Lookup возвращает читатель выгрузки по имени формата
+func Lookup(name string) (Reader, bool) {
+	reader, ok := services[name]
+	return reader, ok
+}
+
+// record - строка таблицы с доступом к колонкам по заголовку
+type record struct {
+	line    int
+	columns map[string]int
+	values  []string
+}
+
+// get возвращает первое непустое значение из колонок names
+func (r record) get(names ...string) string {
+	for _, name := range names {
+		if column, ok := r.columns[strings.ToLower(name)]; ok && column < len(r.values) {
+			if value := strings.TrimSpace(r.values[column]); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// readRecords читает таблицу с заголовком и вызывает parse для каждой
+// непустой строки. Заголовок должен содержать одну из колонок каждой
+// группы required, иначе возвращается *MappingError.
+func readRecords(r io.Reader, service string, required [][]string, parse func(rec record) Row) ([]Row, error) {
+	reader, err := newCSVReader(r)
+	if err != nil {
+		return nil, err
+	}
+	// Goodreads пишет ISBN как ="0439023483" без кавычек вокруг поля
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, &MappingError{Message: "file is empty"}
+		}
+		return nil, fmt.Errorf("failed to read %s header: %w", service, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, group := range required {
+		found := false
+		for _, name := range group {
+			_, ok := columns[strings.ToLower(name)]
+			found = found || ok
+		}
+		if !found {
+			return nil, &MappingError{Message: fmt.Sprintf("not a %s export: no %s column", service, group[0]), Header: header}
+		}
+	}
+
+	var rows []Row
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, Row{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s export: %w", service, err)
+		}
+		if isBlank(values) {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, parse(record{line: line, columns: columns, values: values}))
+	}
+	return rows, nil
+}
+
+// ReadGoodreads читает выгрузку Goodreads (My Books → Export Library).
+// ISBN-10 переводится в ISBN-13, датой публикации считается год издания
+// или, если его нет, год первой публикации.
+func ReadGoodreads(r io.Reader) ([]Row, error) {
+	required := [][]string{{"Title"}, {"Author"}, {"ISBN13", "ISBN"}}
+	return readRecords(r, "Goodreads", required, func(rec record) Row {
+		book := &models.Book{
+			Title:     rec.get("Title"),
+			Author:    rec.get("Author"),
+			ISBN:      pickISBN(rec.get("ISBN13"), rec.get("ISBN")),
+			Publisher: rec.get("Publisher"),
+		}
+
+		reading := &Reading{
+			Shelves: splitList(rec.get("Exclusive Shelf") + "," + rec.get("Bookshelves")),
+		}
+		if rating, err := strconv.ParseFloat(rec.get("My Rating"), 64); err == nil {
+			reading.Rating = rating
+		}
+		if date, err := ParseDate(rec.get("Date Read")); err == nil {
+			reading.DateRead = &date
+		}
+
+		row := newRow(rec.line, book, rec.get("Year Published", "Original Publication Year"))
+		row.Reading = reading
+		return row
+	})
+}
+
+// ReadLibraryThing читает выгрузку LibraryThing в CSV или TSV
+// (Export → Export to CSV/TSV). Имя автора в виде "Фамилия, Имя"
+// переводится в "Имя Фамилия".
+func ReadLibraryThing(r io.Reader) ([]Row, error) {
+	required := [][]string{{"Title"}, {"Primary Author", "AUTHOR (first, last)"}, {"ISBNs", "ISBN"}}
+	return readRecords(r, "LibraryThing", required, func(rec record) Row {
+		author := rec.get("AUTHOR (first, last)")
+		if author == "" {
+			author = invertName(rec.get("Primary Author", "AUTHOR (last, first)"))
+		}
+		book := &models.Book{
+			Title:  rec.get("Title"),
+			Author: author,
+			ISBN:   pickISBN(rec.get("ISBNs"), rec.get("ISBN")),
+		}
+
+		reading := &Reading{
+			Shelves: splitList(rec.get("Collections")),
+			Tags:    splitList(rec.get("Tags")),
+		}
+		book.Tags = reading.Tags
+		if rating, err := strconv.ParseFloat(rec.get("Rating"), 64); err == nil {
+			reading.Rating = math.Round(rating*2) / 2
+		}
+		if date, err := ParseDate(rec.get("Date Read")); err == nil {
+			reading.DateRead = &date
+		}
+
+		row := newRow(rec.line, book, publicationYear(rec.get("Date", "DATE")))
+		row.Reading = reading
+		return row
+	})
+}
+
+// yearPattern находит год в датах вида "c2008" или "2008 [1st ed.]"
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// publicationYear оставляет от даты LibraryThing дату, которую понимает
+// ParseDate, или только год
+func publicationYear(value string) string {
+	if _, err := ParseDate(value); err == nil || value == "" {
+		return value
+	}
+	if year := yearPattern.FindString(strings.TrimPrefix(value, "c")); year != "" {
+		return year
+	}
+	return value
+}
+
+// invertName переводит "Фамилия, Имя" в "Имя Фамилия"
+func invertName(name string) string {
+	last, first, found := strings.Cut(name, ",")
+	if !found {
+		return strings.TrimSpace(name)
+	}
+	return strings.TrimSpace(strings.TrimSpace(first) + " " + strings.TrimSpace(last))
+}
+
+// splitList разбивает список через запятую, убирая пустые значения и повторы
+func splitList(value string) []string {
+	var items []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadGoodreads(t *testing.T) {
+	input := "Book Id,Title,Author,Author l-f,ISBN,ISBN13,My Rating,Publisher,Year Published,Original Publication Year,Date Read,Date Added,Bookshelves,Exclusive Shelf\n" +
+		`2767052,"The Hunger Games (The Hunger Games, #1)",Suzanne Collins,"Collins, Suzanne",="0439023483",="9780439023481",5,Scholastic,2008,2008,2023/05/14,2023/01/02,"favorites, young-adult",read` + "\n" +
+		`5470,1984,George Orwell,"Orwell, George",="0451524934",="",0,Signet,,1949,,2023/01/02,,to-read` + "\n" +
+		`1,No ISBN,Somebody,"Somebody",="",="",0,,2001,,,2023/01/02,,to-read` + "\n"
+
+	rows, err := ReadGoodreads(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadGoodreads() error = %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("ReadGoodreads() got %d rows, want 3", len(rows))
+	}
+
+	first := rows[0]
+	if first.Err != nil || first.Book.ISBN != "978-0-439-02348-1" || first.Book.Author != "Suzanne Collins" || first.Book.Publisher != "Scholastic" ||
+		!first.Book.Published.Equal(time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("ReadGoodreads() row 0 = %+v, %+v", first, first.Book)
+	}
+	reading := first.Reading
+	if reading.Rating != 5 || strings.Join(reading.Shelves, "|") != "read|favorites|young-adult" ||
+		reading.DateRead == nil || !reading.DateRead.Equal(time.Date(2023, 5, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ReadGoodreads() reading = %+v", reading)
+	}
+
+	// ISBN-10 переводится в ISBN-13, год берется из первой публикации
+	second := rows[1]
+	if second.Err != nil || second.Book.ISBN != "978-0-451-52493-5" || second.Book.Published.Year() != 1949 ||
+		second.Reading.Rating != 0 || second.Reading.DateRead != nil {
+		t.Errorf("ReadGoodreads() row 1 = %+v, %+v", second, second.Book)
+	}
+
+	// Книги без ISBN импортируются
+	if rows[2].Err != nil || rows[2].Line != 4 || rows[2].Book.ISBN != "" || rows[2].Reading == nil {
+		t.Errorf("ReadGoodreads() row without ISBN = %+v, want book without ISBN on line 4", rows[2])
+	}
+}
+
+func TestReadLibraryThing(t *testing.T) {
+	input := "Book Id\tTitle\tPrimary Author\tDate\tISBNs\tISBN\tRating\tTags\tCollections\tDate Read\n" +
+		"1\tВойна и мир\tТолстой, Лев\tc1869\t9785170903351\t[9785170903351]\t4.5\tклассика, роман\tYour library, Read\t2022-01-10\n" +
+		"2\t1984\tOrwell, George\t1949 [1st ed.]\t0451524934\t[0451524934]\t\t\tTo read\t\n"
+
+	rows, err := ReadLibraryThing(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadLibraryThing() error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("ReadLibraryThing() got %d rows, want 2", len(rows))
+	}
+
+	first := rows[0]
+	if first.Err != nil || first.Book.Author != "Лев Толстой" || first.Book.ISBN != "978-5-17-090335-1" ||
+		first.Book.Published.Year() != 1869 {
+		t.Fatalf("ReadLibraryThing() row 0 = %+v, %+v", first, first.Book)
+	}
+	reading := first.Reading
+	if reading.Rating != 4.5 || strings.Join(reading.Tags, "|") != "классика|роман" ||
+		strings.Join(reading.Shelves, "|") != "Your library|Read" || reading.DateRead == nil {
+		t.Errorf("ReadLibraryThing() reading = %+v", reading)
+	}
+	if strings.Join(first.Book.Tags, "|") != "классика|роман" {
+		t.Errorf("ReadLibraryThing() tags = %q, want tags from the Tags column", first.Book.Tags)
+	}
+
+	second := rows[1]
+	if second.Err != nil || second.Book.Author != "George Orwell" || second.Book.ISBN != "978-0-451-52493-5" ||
+		second.Book.Published.Year() != 1949 {
+		t.Errorf("ReadLibraryThing() row 1 = %+v, %+v", second, second.Book)
+	}
+}
+
+func TestReadServiceWrongFile(t *testing.T) {
+	input := "Название;Автор;ISBN\nИдиот;Фёдор Достоевский;9785170903351\n"
+
+	var mappingErr *MappingError
+	if _, err := ReadGoodreads(strings.NewReader(input)); !errors.As(err, &mappingErr) {
+		t.Errorf("ReadGoodreads() error = %v, want *MappingError", err)
+	}
+	if _, err := ReadLibraryThing(strings.NewReader(input)); !errors.As(err, &mappingErr) {
+		t.Errorf("ReadLibraryThing() error = %v, want *MappingError", err)
+	}
+	if _, ok := Lookup("goodreads"); !ok {
+		t.Error("Lookup(goodreads) not found")
+	}
+}
@@ -0,0 +1,233 @@
+// Package isbn расставляет дефисы в ISBN-13 по таблице диапазонов
+// ISBN International (RangeMessage.xml).
+//
+// ISBN-13 состоит из префикса EAN (978 или 979), регистрационной группы
+// (страна или язык), номера издателя, номера издания и контрольной цифры.
+// Длины группы и номера издателя переменные и зависят от диапазона, в
+// который попадают следующие за ними цифры, поэтому без таблицы ISBN
+// разделить нельзя.
+//
+// Копия таблицы встроена в пакет. Она сокращенная: в ней только основные
+// регистрационные группы, ISBN остальных групп Split не делит. Чтобы
+// заменить ее полной или более новой, скачайте RangeMessage.xml с сайта
+// ISBN International и выполните
+//
+//	go run ./cmd/isbnranges -in RangeMessage.xml
+package isbn
+
+import (
+	_ "embed"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed RangeMessage.xml
+var rangeMessage string
+
+// rangeDigits - сколько цифр после префикса сравнивается с диапазонами
+const rangeDigits = 7
+
+// rangeMessageXML - структура файла RangeMessage.xml
+type rangeMessageXML struct {
+	Source   string     `xml:"MessageSource"`
+	Serial   string     `xml:"MessageSerialNumber"`
+	Date     string     `xml:"MessageDate"`
+	Prefixes []groupXML `xml:"EAN.UCCPrefixes>EAN.UCC"`
+	Groups   []groupXML `xml:"RegistrationGroups>Group"`
+}
+
+type groupXML struct {
+	Prefix string    `xml:"Prefix"`
+	Agency string    `xml:"Agency"`
+	Rules  []ruleXML `xml:"Rules>Rule"`
+}
+
+type ruleXML struct {
+	Range  string `xml:"Range"`
+	Length int    `xml:"Length"`
+}
+
+// rule - диапазон значений из семи цифр и длина элемента ISBN для него.
+// Длина 0 означает, что диапазон еще не распределен.
+type rule struct {
+	from, to int
+	length   int
+}
+
+// group - префикс EAN или регистрационная группа с правилами для
+// следующего элемента ISBN
+type group struct {
+	agency string
+	rules  []rule
+}
+
+// find возвращает длину элемента, который начинается с digits
+func (g *group) find(digits string) int {
+	value, _ := strconv.Atoi((digits + "0000000")[:rangeDigits])
+	i := sort.Search(len(g.rules), func(i int) bool { return g.rules[i].to >= value })
+	if i < len(g.rules) && g.rules[i].from <= value {
+		return g.rules[i].length
+	}
+	return 0
+}
+
+// Ranges - разобранная таблица диапазонов
+type Ranges struct {
+	// Source, Serial и Date - сведения о выпуске таблицы
+	Source string
+	Serial string
+	Date   string
+
+	// prefixes и groups индексируются префиксом без дефисов: "978", "9785"
+	prefixes map[string]*group
+	groups   map[string]*group
+}
+
+// ParseRangeMessage читает и проверяет таблицу в формате RangeMessage.xml
+func ParseRangeMessage(r io.Reader) (*Ranges, error) {
+	var msg rangeMessageXML
+	if err := xml.NewDecoder(r).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("failed to decode isbn range message: %w", err)
+	}
+	if len(msg.Prefixes) == 0 || len(msg.Groups) == 0 {
+		return nil, fmt.Errorf("isbn range message has no prefixes or registration groups")
+	}
+
+	ranges := &Ranges{
+		Source:   msg.Source,
+		Serial:   msg.Serial,
+		Date:     msg.Date,
+		prefixes: make(map[string]*group, len(msg.Prefixes)),
+		groups:   make(map[string]*group, len(msg.Groups)),
+	}
+	for _, g := range msg.Prefixes {
+		if err := ranges.add(ranges.prefixes, g, 3); err != nil {
+			return nil, err
+		}
+	}
+	for _, g := range msg.Groups {
+		if err := ranges.add(ranges.groups, g, 0); err != nil {
+			return nil, err
+		}
+	}
+	return ranges, nil
+}
+
+// add проверяет группу g и добавляет ее в m. prefixLen - обязательная
+// длина префикса без дефисов (0 - любая).
+func (r *Ranges) add(m map[string]*group, g groupXML, prefixLen int) error {
+	prefix := strings.ReplaceAll(strings.TrimSpace(g.Prefix), "-", "")
+	if _, err := strconv.Atoi(prefix); err != nil || (prefixLen > 0 && len(prefix) != prefixLen) {
+		return fmt.Errorf("invalid isbn prefix %q", g.Prefix)
+	}
+	if _, ok := m[prefix]; ok {
+		return fmt.Errorf("duplicate isbn prefix %q", g.Prefix)
+	}
+
+	parsed := &group{agency: strings.TrimSpace(g.Agency)}
+	for _, ru := range g.Rules {
+		from, to, ok := parseRange(ru.Range)
+		if !ok || ru.Length < 0 || ru.Length > rangeDigits {
+			return fmt.Errorf("invalid rule %q (length %d) for isbn prefix %q", ru.Range, ru.Length, g.Prefix)
+		}
+		if n := len(parsed.rules); n > 0 && parsed.rules[n-1].to >= from {
+			return fmt.Errorf("overlapping or unsorted rule %q for isbn prefix %q", ru.Range, g.Prefix)
+		}
+		parsed.rules = append(parsed.rules, rule{from: from, to: to, length: ru.Length})
+	}
+	m[prefix] = parsed
+	return nil
+}
+
+// parseRange разбирает диапазон вида "0000000-1999999"
+func parseRange(s string) (from, to int, ok bool) {
+	first, last, found := strings.Cut(strings.TrimSpace(s), "-")
+	if !found || len(first) != rangeDigits || len(last) != rangeDigits {
+		return 0, 0, false
+	}
+	from, err1 := strconv.Atoi(first)
+	to, err2 := strconv.Atoi(last)
+	return from, to, err1 == nil && err2 == nil && from <= to
+}
+
+// Parts - элементы ISBN-13
+type Parts struct {
+	Prefix      string
+	Group       string
+	Registrant  string
+	Publication string
+	CheckDigit  string
+	// Agency - агентство регистрационной группы ("German language")
+	Agency string
+}
+
+// String возвращает ISBN с дефисами между элементами
+func (p Parts) String() string {
+	return strings.Join([]string{p.Prefix, p.Group, p.Registrant, p.Publication, p.CheckDigit}, "-")
+}
+
+// Split делит ISBN-13 из 13 цифр на элементы. Возвращает false, если
+// группа или диапазон номера издателя не распределены в таблице.
+func (r *Ranges) Split(isbn13 string) (Parts, bool) {
+	if len(isbn13) != 13 {
+		return Parts{}, false
+	}
+
+	prefix, ok := r.prefixes[isbn13[:3]]
+	if !ok {
+		return Parts{}, false
+	}
+	groupLen := prefix.find(isbn13[3:12])
+	if groupLen == 0 {
+		return Parts{}, false
+	}
+
+	groupEnd := 3 + groupLen
+	group, ok := r.groups[isbn13[:groupEnd]]
+	if !ok {
+		return Parts{}, false
+	}
+	registrantLen := group.find(isbn13[groupEnd:12])
+	if registrantLen == 0 || groupEnd+registrantLen >= 12 {
+		return Parts{}, false
+	}
+
+	registrantEnd := groupEnd + registrantLen
+	return Parts{
+		Prefix:      isbn13[:3],
+		Group:       isbn13[3:groupEnd],
+		Registrant:  isbn13[groupEnd:registrantEnd],
+		Publication: isbn13[registrantEnd:12],
+		CheckDigit:  isbn13[12:],
+		Agency:      group.agency,
+	}, true
+}
+
+// Hyphenate расставляет дефисы в ISBN-13 из 13 цифр
+func (r *Ranges) Hyphenate(isbn13 string) (string, bool) {
+	parts, ok := r.Split(isbn13)
+	if !ok {
+		return "", false
+	}
+	return parts.String(), true
+}
+
+// Default возвращает встроенную таблицу диапазонов
+var Default = sync.OnceValue(func() *Ranges {
+	ranges, err := ParseRangeMessage(strings.NewReader(rangeMessage))
+	if err != nil {
+		// Встроенная таблица проверяется тестами пакета
+		panic(fmt.Sprintf("isbn: invalid embedded range message: %v", err))
+	}
+	return ranges
+})
+
+// Hyphenate расставляет дефисы в ISBN-13 по встроенной таблице
+func Hyphenate(isbn13 string) (string, bool) {
+	return Default().Hyphenate(isbn13)
+}
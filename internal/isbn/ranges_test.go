@@ -0,0 +1,80 @@
+package isbn
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHyphenate(t *testing.T) {
+	tests := []struct {
+		isbn string
+		want string
+		ok   bool
+	}{
+		{"9780451524935", "978-0-451-52493-5", true},
+		{"9780804429573", "978-0-8044-2957-3", true},
+		{"9781846554308", "978-1-84655-430-8", true},
+		{"9785170903351", "978-5-17-090335-1", true},
+		{"9785389074354", "978-5-389-07435-4", true},
+		{"9783161484100", "978-3-16-148410-0", true},
+		{"9791032305690", "979-10-323-0569-0", true},
+		// Группа 978-6 в таблице не распределена
+		{"9786600000001", "", false},
+		// Префикс 977 не относится к ISBN
+		{"9771234567003", "", false},
+		{"978045152493", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Hyphenate(tt.isbn)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Hyphenate(%q) = %q, %v; want %q, %v", tt.isbn, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSplitAgency(t *testing.T) {
+	parts, ok := Default().Split("9785389074354")
+	if !ok {
+		t.Fatal("Split() = false, want parts")
+	}
+	if parts.Group != "5" || parts.Registrant != "389" || parts.Publication != "07435" || parts.Agency == "" {
+		t.Errorf("Split() = %+v, want group 5, registrant 389 and agency", parts)
+	}
+}
+
+func TestParseRangeMessageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{"not xml", "isbn"},
+		{"empty", "<ISBNRangeMessage></ISBNRangeMessage>"},
+		{"bad prefix", rangeXML("97", "0000000-9999999", 1)},
+		{"bad range", rangeXML("978", "0-9", 1)},
+		{"bad length", rangeXML("978", "0000000-9999999", 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRangeMessage(strings.NewReader(tt.xml)); err == nil {
+				t.Error("ParseRangeMessage() error = nil, want error")
+			}
+		})
+	}
+
+	overlapping := `<ISBNRangeMessage><EAN.UCCPrefixes><EAN.UCC><Prefix>978</Prefix><Rules>
+<Rule><Range>0000000-5999999</Range><Length>1</Length></Rule>
+<Rule><Range>5000000-9999999</Range><Length>2</Length></Rule>
+</Rules></EAN.UCC></EAN.UCCPrefixes>
+<RegistrationGroups><Group><Prefix>978-0</Prefix><Rules></Rules></Group></RegistrationGroups></ISBNRangeMessage>`
+	if _, err := ParseRangeMessage(strings.NewReader(overlapping)); err == nil {
+		t.Error("ParseRangeMessage() overlapping rules error = nil, want error")
+	}
+}
+
+// rangeXML собирает таблицу с одним префиксом EAN и одним правилом
+func rangeXML(prefix, rng string, length int) string {
+	return `<ISBNRangeMessage><EAN.UCCPrefixes><EAN.UCC><Prefix>` + prefix + `</Prefix><Rules><Rule><Range>` +
+		rng + `</Range><Length>` + strconv.Itoa(length) + `</Length></Rule></Rules></EAN.UCC></EAN.UCCPrefixes>` +
+		`<RegistrationGroups><Group><Prefix>978-0</Prefix><Rules></Rules></Group></RegistrationGroups></ISBNRangeMessage>`
+}
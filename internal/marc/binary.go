@@ -0,0 +1,242 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Разделители двоичного формата ISO 2709
+const (
+	subfieldDelimiter  = 0x1f
+	fieldTerminator    = 0x1e
+	recordTerminator   = 0x1d
+	leaderLength       = 24
+	directoryEntrySize = 12
+	maxRecordLength    = 99999
+)
+
+// RecordError описывает поврежденную запись. После такой ошибки
+// Reader.Read может читать следующие записи.
+type RecordError struct {
+	// Record - номер записи в файле, начиная с 1
+	Record int
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("marc record %d: %v", e.Record, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Reader читает записи в двоичном формате MARC21
+type Reader struct {
+	r     *bufio.Reader
+	count int
+}
+
+// NewReader создает Reader, читающий записи из r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read возвращает следующую запись или io.EOF, если записей больше нет.
+// Запись в кодировке MARC-8 читается, только если в ней нет символов
+// за пределами ASCII.
+func (r *Reader) Read() (*Record, error) {
+	// Между записями иногда стоят переводы строк
+	for {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != '\n' && b != '\r' && b != ' ' {
+			r.r.UnreadByte()
+			break
+		}
+	}
+	r.count++
+
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(r.r, prefix); err != nil {
+		return nil, fmt.Errorf("failed to read marc record length: %w", err)
+	}
+	length, ok := parseNumber(prefix)
+	if !ok || length < leaderLength+1 {
+		return nil, fmt.Errorf("invalid marc record length %q in record %d", prefix, r.count)
+	}
+
+	data := make([]byte, length)
+	copy(data, prefix)
+	if _, err := io.ReadFull(r.r, data[5:]); err != nil {
+		return nil, fmt.Errorf("failed to read marc record %d: %w", r.count, err)
+	}
+
+	rec, err := parseRecord(data)
+	if err != nil {
+		return nil, &RecordError{Record: r.count, Err: err}
+	}
+	return rec, nil
+}
+
+// parseRecord разбирает запись: маркер, справочник и поля
+func parseRecord(data []byte) (*Record, error) {
+	if data[len(data)-1] != recordTerminator {
+		return nil, errors.New("missing record terminator")
+	}
+
+	leader := string(data[:leaderLength])
+	if leader[9] != 'a' && !isASCII(data) {
+		return nil, errors.New("MARC-8 encoding is not supported, export records in UTF-8")
+	}
+	if !utf8.Valid(data) {
+		return nil, errors.New("record is not valid UTF-8")
+	}
+
+	base, ok := parseNumber(data[12:17])
+	if !ok || base <= leaderLength || base > len(data) {
+		return nil, fmt.Errorf("invalid base address of data %q", leader[12:17])
+	}
+
+	directory := data[leaderLength : base-1]
+	if len(directory)%directoryEntrySize != 0 || data[base-1] != fieldTerminator {
+		return nil, errors.New("invalid directory")
+	}
+
+	rec := &Record{Leader: leader}
+	for entry := directory; len(entry) > 0; entry = entry[directoryEntrySize:] {
+		tag := string(entry[:3])
+		length, ok1 := parseNumber(entry[3:7])
+		start, ok2 := parseNumber(entry[7:12])
+		if !ok1 || !ok2 || length < 1 || base+start+length > len(data)-1 {
+			return nil, fmt.Errorf("invalid directory entry for field %s", tag)
+		}
+
+		value := data[base+start : base+start+length]
+		value = bytes.TrimSuffix(value, []byte{fieldTerminator})
+		rec.Fields = append(rec.Fields, parseField(tag, value))
+	}
+	return rec, nil
+}
+
+// parseNumber разбирает число из цифр ASCII. В отличие от strconv.Atoi
+// не принимает знак и пробелы: длины и смещения в записи не бывают
+// отрицательными.
+func parseNumber(digits []byte) (int, bool) {
+	if len(digits) == 0 {
+		return 0, false
+	}
+	n := 0
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
+
+func parseField(tag string, value []byte) Field {
+	field := Field{Tag: tag}
+	if field.IsControl() {
+		field.Value = string(value)
+		return field
+	}
+
+	field.Indicators = [2]byte{' ', ' '}
+	if len(value) >= 2 {
+		field.Indicators = [2]byte{value[0], value[1]}
+		value = value[2:]
+	}
+	for _, part := range bytes.Split(value, []byte{subfieldDelimiter}) {
+		if len(part) == 0 {
+			continue
+		}
+		field.Subfields = append(field.Subfields, Subfield{Code: part[0], Value: string(part[1:])})
+	}
+	return field
+}
+
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Writer записывает записи в двоичном формате MARC21 в UTF-8
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter создает Writer, пишущий записи в w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write записывает запись. Длина записи, адрес данных и кодировка
+// в маркере записи заполняются заново.
+func (w *Writer) Write(rec *Record) error {
+	var directory, data bytes.Buffer
+	for _, f := range rec.Fields {
+		if len(f.Tag) != 3 {
+			return fmt.Errorf("invalid marc tag %q", f.Tag)
+		}
+
+		start := data.Len()
+		if f.IsControl() {
+			data.WriteString(f.Value)
+		} else {
+			indicators := f.indicators()
+			data.Write(indicators[:])
+			for _, sf := range f.Subfields {
+				data.WriteByte(subfieldDelimiter)
+				data.WriteByte(sf.Code)
+				data.WriteString(sf.Value)
+			}
+		}
+		data.WriteByte(fieldTerminator)
+
+		length := data.Len() - start
+		if length > 9999 {
+			return fmt.Errorf("marc field %s is too long", f.Tag)
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", f.Tag, length, start)
+	}
+	directory.WriteByte(fieldTerminator)
+
+	base := leaderLength + directory.Len()
+	length := base + data.Len() + 1
+	if length > maxRecordLength {
+		return fmt.Errorf("marc record is too long: %d bytes", length)
+	}
+
+	leader := []byte(defaultLeader)
+	if len(rec.Leader) == leaderLength {
+		leader = []byte(rec.Leader)
+	}
+	copy(leader[0:5], fmt.Sprintf("%05d", length))
+	leader[9] = 'a'
+	copy(leader[10:12], "22")
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+	copy(leader[20:24], "4500")
+
+	var record bytes.Buffer
+	record.Write(leader)
+	record.Write(directory.Bytes())
+	record.Write(data.Bytes())
+	record.WriteByte(recordTerminator)
+
+	if _, err := w.w.Write(record.Bytes()); err != nil {
+		return fmt.Errorf("failed to write marc record: %w", err)
+	}
+	return nil
+}
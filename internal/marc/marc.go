@@ -0,0 +1,306 @@
+// Package marc читает и записывает библиографические записи MARC21
+// в двоичном формате (ISO 2709) и в MARCXML.
+//
+// Записи переводятся в книги и обратно по полям 020 (ISBN), 100 (первый
+// автор), 700 (остальные участники с ролью в $e), 245 (название) и 264/260
+// (дата публикации); остальные поля при чтении сохраняются в Record,
+// но в книгу не попадают.
+package marc
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// Record - запись MARC21
+type Record struct {
+	// Leader - маркер записи из 24 символов. Длина записи и адрес данных
+	// в нем при записи вычисляются заново.
+	Leader string
+	Fields []Field
+}
+
+// Field - поле записи. У управляющих полей (001-009) есть только Value,
+// у полей данных - индикаторы и подполя.
+type Field struct {
+	Tag        string
+	Value      string
+	Indicators [2]byte
+	Subfields  []Subfield
+}
+
+// Subfield - подполе поля данных
+type Subfield struct {
+	Code  byte
+	Value string
+}
+
+// IsControl сообщает, является ли поле управляющим (теги 001-009)
+func (f Field) IsControl() bool {
+	return strings.HasPrefix(f.Tag, "00")
+}
+
+// indicators возвращает индикаторы поля, заменяя незаданные пробелами
+func (f Field) indicators() [2]byte {
+	indicators := f.Indicators
+	for i, b := range indicators {
+		if b == 0 {
+			indicators[i] = ' '
+		}
+	}
+	return indicators
+}
+
+// Subfield возвращает значение первого подполя с кодом code
+func (f Field) Subfield(code byte) string {
+	for _, sf := range f.Subfields {
+		if sf.Code == code {
+			return sf.Value
+		}
+	}
+	return ""
+}
+
+// Field возвращает первое поле с тегом tag или nil
+func (r *Record) Field(tag string) *Field {
+	for i := range r.Fields {
+		if r.Fields[i].Tag == tag {
+			return &r.Fields[i]
+		}
+	}
+	return nil
+}
+
+// FieldsByTag возвращает все поля с тегом tag
+func (r *Record) FieldsByTag(tag string) []Field {
+	var fields []Field
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// defaultLeader - маркер записи о книге (nam) в UTF-8 с пунктуацией ISBD
+const defaultLeader = "00000nam a2200000 i 4500"
+
+// FromBook создает запись MARC21 о книге
+func FromBook(book *models.Book) *Record {
+	created := book.CreatedAt
+	if created.IsZero() {
+		created = time.Now()
+	}
+	year := "    "
+	if !book.Published.IsZero() {
+		year = fmt.Sprintf("%04d", book.Published.Year())
+	}
+
+	rec := &Record{Leader: defaultLeader}
+	if book.ID != 0 {
+		rec.Fields = append(rec.Fields, Field{Tag: "001", Value: strconv.FormatInt(book.ID, 10)})
+	}
+	if !book.UpdatedAt.IsZero() {
+		rec.Fields = append(rec.Fields, Field{Tag: "005", Value: book.UpdatedAt.UTC().Format("20060102150405.0")})
+	}
+	// 008: дата создания записи, одна известная дата публикации, остальные
+	// позиции не кодируются
+	rec.Fields = append(rec.Fields, Field{
+		Tag:   "008",
+		Value: created.UTC().Format("060102") + "s" + year + "    xx " + strings.Repeat("|", 17) + "und d",
+	})
+
+	if isbn := strings.ReplaceAll(book.ISBN, "-", ""); isbn != "" {
+		rec.Fields = append(rec.Fields, dataField("020", ' ', ' ', 'a', isbn))
+	}
+	// Первый автор записывается в 100, остальные участники - в 700
+	// с термином роли
+	mainEntry := false
+	for _, c := range bookContributors(book) {
+		if c.Role == models.RoleAuthor && !mainEntry {
+			rec.Fields = append(rec.Fields, dataField("100", '1', ' ', 'a', invertName(c.Name)))
+			mainEntry = true
+			continue
+		}
+		f := dataField("700", '1', ' ', 'a', invertName(c.Name))
+		f.Subfields = append(f.Subfields, Subfield{Code: 'e', Value: c.Role})
+		rec.Fields = append(rec.Fields, f)
+	}
+	rec.Fields = append(rec.Fields, dataField("245", '1', '0', 'a', book.Title))
+	if !book.Published.IsZero() {
+		rec.Fields = append(rec.Fields, dataField("264", ' ', '1', 'c', year))
+	}
+	return rec
+}
+
+func dataField(tag string, ind1, ind2, code byte, value string) Field {
+	return Field{Tag: tag, Indicators: [2]byte{ind1, ind2}, Subfields: []Subfield{{Code: code, Value: value}}}
+}
+
+// ToBook переводит запись MARC21 в книгу. ISBN берется из первого поля 020
+// с ISBN-13 (или из первого поля 020), участники - из 100 (110) и 700
+// (710), год публикации - из 264 с индикатором 1, 260 или 008. Книга
+// не проверяется.
+func ToBook(rec *Record) *models.Book {
+	book := &models.Book{ISBN: recordISBN(rec)}
+
+	if f := rec.Field("245"); f != nil {
+		book.Title = trimISBD(f.Subfield('a'))
+		if subtitle := trimISBD(f.Subfield('b')); subtitle != "" {
+			book.Title += ": " + subtitle
+		}
+	}
+
+	book.Contributors = recordContributors(rec)
+	book.FormatContributors()
+
+	if year, ok := recordYear(rec); ok {
+		book.Published = time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return book
+}
+
+// bookContributors возвращает участников книги; у книги без них автором
+// считается поле Author
+func bookContributors(book *models.Book) []models.Contributor {
+	if len(book.Contributors) > 0 || book.Author == "" {
+		return book.Contributors
+	}
+	return []models.Contributor{{Name: book.Author, Role: models.RoleAuthor}}
+}
+
+// relatorRoles переводит термины ($e) и коды ($4) ролей MARC в роли участников
+var relatorRoles = map[string]string{
+	"author":      models.RoleAuthor,
+	"aut":         models.RoleAuthor,
+	"translator":  models.RoleTranslator,
+	"trl":         models.RoleTranslator,
+	"editor":      models.RoleEditor,
+	"edt":         models.RoleEditor,
+	"illustrator": models.RoleIllustrator,
+	"ill":         models.RoleIllustrator,
+}
+
+// recordContributors собирает участников из полей 100, 110, 700 и 710.
+// Поле 700 без роли считается соавтором, участники с другими ролями
+// (композитор, составитель и т.п.) пропускаются.
+func recordContributors(rec *Record) []models.Contributor {
+	var contributors []models.Contributor
+	for _, tag := range []string{"100", "110", "700", "710"} {
+		for _, f := range rec.FieldsByTag(tag) {
+			name := trimISBD(f.Subfield('a'))
+			if name == "" {
+				continue
+			}
+			if f.Indicators[0] == '1' && !strings.HasSuffix(tag, "10") {
+				name = uninvertName(name)
+			}
+
+			role := models.RoleAuthor
+			if relator := strings.ToLower(trimISBD(f.Subfield('e'))); relator != "" {
+				role = relatorRoles[relator]
+			} else if code := strings.ToLower(f.Subfield('4')); code != "" {
+				role = relatorRoles[code]
+			}
+			c := models.Contributor{Name: name, Role: role}
+			if role != "" && !slices.Contains(contributors, c) {
+				contributors = append(contributors, c)
+			}
+		}
+	}
+	return contributors
+}
+
+// recordISBN выбирает ISBN из полей 020, отбрасывая уточнения вида "(pbk.)"
+func recordISBN(rec *Record) string {
+	first := ""
+	for _, f := range rec.FieldsByTag("020") {
+		isbn := f.Subfield('a')
+		if end := strings.IndexAny(isbn, " ("); end >= 0 {
+			isbn = isbn[:end]
+		}
+		if isbn == "" {
+			continue
+		}
+		if len(strings.ReplaceAll(isbn, "-", "")) == 13 {
+			return isbn
+		}
+		if first == "" {
+			first = isbn
+		}
+	}
+	return first
+}
+
+// recordYear находит год публикации
+func recordYear(rec *Record) (int, bool) {
+	for _, f := range rec.FieldsByTag("264") {
+		if f.Indicators[1] == '1' {
+			if year, ok := findYear(f.Subfield('c')); ok {
+				return year, true
+			}
+		}
+	}
+	for _, f := range rec.FieldsByTag("260") {
+		if year, ok := findYear(f.Subfield('c')); ok {
+			return year, true
+		}
+	}
+	if f := rec.Field("008"); f != nil && len(f.Value) >= 11 {
+		if year, ok := findYear(f.Value[7:11]); ok {
+			return year, true
+		}
+	}
+	return 0, false
+}
+
+// findYear ищет в строке вида "c2008." или "[1869]" первые четыре цифры подряд
+func findYear(s string) (int, bool) {
+	run := 0
+	for i, r := range s {
+		if r < '0' || r > '9' {
+			run = 0
+			continue
+		}
+		run++
+		if run == 4 {
+			year, _ := strconv.Atoi(s[i-3 : i+1])
+			return year, true
+		}
+	}
+	return 0, false
+}
+
+// trimISBD убирает пунктуацию ISBD в конце значения: "Война и мир /" -> "Война и мир"
+func trimISBD(s string) string {
+	return strings.TrimRightFunc(strings.TrimSpace(s), func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("/:;,=.", r)
+	})
+}
+
+// invertName переводит "Лев Толстой" в "Толстой, Лев", как принято в поле 100
+func invertName(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.Contains(name, ",") {
+		return name
+	}
+	if i := strings.LastIndexByte(name, ' '); i >= 0 {
+		return name[i+1:] + ", " + strings.TrimSpace(name[:i])
+	}
+	return name
+}
+
+// uninvertName переводит "Толстой, Лев" в "Лев Толстой"
+func uninvertName(name string) string {
+	last, first, found := strings.Cut(name, ",")
+	if !found {
+		return name
+	}
+	return strings.TrimSpace(strings.TrimSpace(first) + " " + strings.TrimSpace(last))
+}
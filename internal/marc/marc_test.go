@@ -0,0 +1,185 @@
+package marc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testBooks() []*models.Book {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []*models.Book{
+		{ID: 1, Title: "Война и мир", Author: "Лев Толстой", ISBN: "978-5-17-090335-2",
+			Published: time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC), CreatedAt: created, UpdatedAt: created},
+		{ID: 2, Title: "Animal Farm", Author: "George Orwell", ISBN: "978-0-14-044944-8",
+			Published: time.Date(1945, 1, 1, 0, 0, 0, 0, time.UTC), CreatedAt: created, UpdatedAt: created},
+	}
+}
+
+// checkRoundTrip сравнивает книги, прочитанные из записей, с исходными
+func checkRoundTrip(t *testing.T, read func() (*Record, error)) {
+	t.Helper()
+	for _, want := range testBooks() {
+		rec, err := read()
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		got := ToBook(rec)
+		got.FormatISBN()
+		want.FormatISBN()
+		if got.Title != want.Title || got.Author != want.Author || got.ISBN != want.ISBN || !got.Published.Equal(want.Published) {
+			t.Errorf("ToBook() = %+v, want %+v", got, want)
+		}
+		if f := rec.Field("001"); f == nil || f.Value == "" {
+			t.Errorf("record has no control number: %+v", rec)
+		}
+	}
+	if _, err := read(); err != io.EOF {
+		t.Errorf("Read() after last record error = %v, want io.EOF", err)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, book := range testBooks() {
+		if err := w.Write(FromBook(book)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	data := buf.Bytes()
+	if bytes.Count(data, []byte{recordTerminator}) != 2 || !bytes.HasPrefix(data[5:], []byte("nam a22")) {
+		t.Fatalf("binary marc = %q", data)
+	}
+
+	checkRoundTrip(t, NewReader(bytes.NewReader(data)).Read)
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewXMLWriter(&buf)
+	for _, book := range testBooks() {
+		if err := w.Write(FromBook(book)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, `<collection xmlns="http://www.loc.gov/MARC21/slim">`) ||
+		!strings.Contains(out, `<subfield code="a">Толстой, Лев</subfield>`) {
+		t.Fatalf("marcxml = %s", out)
+	}
+
+	checkRoundTrip(t, NewXMLReader(strings.NewReader(out)).Read)
+}
+
+func TestReadCatalogueRecord(t *testing.T) {
+	// Запись в том виде, в котором ее отдает каталог: пунктуация ISBD,
+	// поле 260 вместо 264, ISBN-10 с уточнением и префикс пространства имен
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<marc:collection xmlns:marc="http://www.loc.gov/MARC21/slim">
+  <marc:record>
+    <marc:leader>00000cam a2200000 a 4500</marc:leader>
+    <marc:controlfield tag="001">12345</marc:controlfield>
+    <marc:datafield tag="020" ind1=" " ind2=" ">
+      <marc:subfield code="a">0451524934 (pbk.)</marc:subfield>
+    </marc:datafield>
+    <marc:datafield tag="100" ind1="1" ind2=" ">
+      <marc:subfield code="a">Orwell, George,</marc:subfield>
+      <marc:subfield code="d">1903-1950.</marc:subfield>
+    </marc:datafield>
+    <marc:datafield tag="245" ind1="1" ind2="0">
+      <marc:subfield code="a">Nineteen eighty-four :</marc:subfield>
+      <marc:subfield code="b">a novel /</marc:subfield>
+      <marc:subfield code="c">George Orwell.</marc:subfield>
+    </marc:datafield>
+    <marc:datafield tag="260" ind1=" " ind2=" ">
+      <marc:subfield code="c">c1950.</marc:subfield>
+    </marc:datafield>
+  </marc:record>
+</marc:collection>`
+
+	rec, err := NewXMLReader(strings.NewReader(input)).Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	book := ToBook(rec)
+	if book.Title != "Nineteen eighty-four: a novel" || book.Author != "George Orwell" ||
+		book.ISBN != "0451524934" || book.Published.Year() != 1950 {
+		t.Errorf("ToBook() = %+v", book)
+	}
+}
+
+func TestContributorsRoundTrip(t *testing.T) {
+	book := &models.Book{ID: 3, Title: "Гамлет", Published: time.Date(1940, 1, 1, 0, 0, 0, 0, time.UTC),
+		Contributors: []models.Contributor{
+			{Name: "William Shakespeare", Role: models.RoleAuthor},
+			{Name: "Борис Пастернак", Role: models.RoleTranslator},
+		}}
+
+	rec := FromBook(book)
+	if f := rec.Field("700"); f == nil || f.Subfield('a') != "Пастернак, Борис" || f.Subfield('e') != "translator" {
+		t.Fatalf("FromBook() 700 = %+v", f)
+	}
+
+	// Поле 700 без роли - соавтор, код $4 тоже задает роль
+	rec.Fields = append(rec.Fields,
+		dataField("700", '1', ' ', 'a', "Fletcher, John"),
+		Field{Tag: "700", Indicators: [2]byte{'1', ' '}, Subfields: []Subfield{{Code: 'a', Value: "Лозинский, Михаил."}, {Code: '4', Value: "trl"}}},
+		Field{Tag: "700", Indicators: [2]byte{'1', ' '}, Subfields: []Subfield{{Code: 'a', Value: "Someone"}, {Code: 'e', Value: "composer."}}},
+	)
+	got := ToBook(rec)
+	var names []string
+	for _, c := range got.Contributors {
+		names = append(names, c.Role+":"+c.Name)
+	}
+	want := "author:William Shakespeare|translator:Борис Пастернак|author:John Fletcher|translator:Михаил Лозинский"
+	if strings.Join(names, "|") != want || got.Author != "William Shakespeare, John Fletcher" {
+		t.Errorf("ToBook() contributors = %q, author %q; want %q", strings.Join(names, "|"), got.Author, want)
+	}
+}
+
+func TestReaderSkipsBrokenRecord(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, book := range testBooks() {
+		w.Write(FromBook(book))
+	}
+	data := buf.Bytes()
+
+	// Портим адрес данных первой записи, длина записи остается верной
+	copy(data[12:17], "00001")
+
+	r := NewReader(bytes.NewReader(data))
+	var recordErr *RecordError
+	if _, err := r.Read(); !errors.As(err, &recordErr) || recordErr.Record != 1 {
+		t.Fatalf("Read() broken record error = %v, want *RecordError for record 1", err)
+	}
+	rec, err := r.Read()
+	if err != nil || ToBook(rec).Title != "Animal Farm" {
+		t.Errorf("Read() after broken record = %+v, %v", rec, err)
+	}
+
+	// Отрицательное смещение поля в справочнике - ошибка записи, а не паника
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.Write(FromBook(testBooks()[0]))
+	data = buf.Bytes()
+	copy(data[leaderLength+7:leaderLength+12], "-9999")
+	if _, err := NewReader(bytes.NewReader(data)).Read(); !errors.As(err, &recordErr) {
+		t.Errorf("Read() with negative field start error = %v, want *RecordError", err)
+	}
+
+	if _, err := NewReader(strings.NewReader("abcde")).Read(); err == nil || errors.As(err, &recordErr) {
+		t.Errorf("Read() with invalid length error = %v, want fatal error", err)
+	}
+}
@@ -0,0 +1,165 @@
+package marc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Namespace - пространство имен MARCXML
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// XMLReader читает записи MARCXML: элементы record внутри collection
+// или единственный record
+type XMLReader struct {
+	d *xml.Decoder
+}
+
+// NewXMLReader создает XMLReader, читающий записи из r
+func NewXMLReader(r io.Reader) *XMLReader {
+	return &XMLReader{d: xml.NewDecoder(r)}
+}
+
+// Read возвращает следующую запись или io.EOF, если записей больше нет
+func (r *XMLReader) Read() (*Record, error) {
+	for {
+		token, err := r.d.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("failed to read marcxml: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		var x xmlRecord
+		if err := r.d.DecodeElement(&x, &start); err != nil {
+			return nil, fmt.Errorf("failed to decode marcxml record: %w", err)
+		}
+		return x.record(), nil
+	}
+}
+
+func (x *xmlRecord) record() *Record {
+	rec := &Record{Leader: x.Leader}
+	for _, cf := range x.ControlFields {
+		rec.Fields = append(rec.Fields, Field{Tag: cf.Tag, Value: cf.Value})
+	}
+	for _, df := range x.DataFields {
+		field := Field{Tag: df.Tag, Indicators: [2]byte{indicator(df.Ind1), indicator(df.Ind2)}}
+		for _, sf := range df.Subfields {
+			if sf.Code == "" {
+				continue
+			}
+			field.Subfields = append(field.Subfields, Subfield{Code: sf.Code[0], Value: sf.Value})
+		}
+		rec.Fields = append(rec.Fields, field)
+	}
+	return rec
+}
+
+func indicator(s string) byte {
+	if s == "" {
+		return ' '
+	}
+	return s[0]
+}
+
+// XMLWriter записывает записи в документ MARCXML с корневым элементом collection
+type XMLWriter struct {
+	w       io.Writer
+	enc     *xml.Encoder
+	started bool
+}
+
+// NewXMLWriter создает XMLWriter, пишущий документ в w. Документ
+// завершается вызовом Close.
+func NewXMLWriter(w io.Writer) *XMLWriter {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return &XMLWriter{w: w, enc: enc}
+}
+
+func (w *XMLWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if _, err := io.WriteString(w.w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write marcxml header: %w", err)
+	}
+	root := xml.StartElement{Name: xml.Name{Local: "collection"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Namespace}}}
+	if err := w.enc.EncodeToken(root); err != nil {
+		return fmt.Errorf("failed to write marcxml collection: %w", err)
+	}
+	return nil
+}
+
+// Write записывает запись
+func (w *XMLWriter) Write(rec *Record) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	x := xmlRecord{Leader: rec.Leader}
+	for _, f := range rec.Fields {
+		if f.IsControl() {
+			x.ControlFields = append(x.ControlFields, xmlControlField{Tag: f.Tag, Value: f.Value})
+			continue
+		}
+		indicators := f.indicators()
+		df := xmlDataField{Tag: f.Tag, Ind1: string(indicators[0]), Ind2: string(indicators[1])}
+		for _, sf := range f.Subfields {
+			df.Subfields = append(df.Subfields, xmlSubfield{Code: string(sf.Code), Value: sf.Value})
+		}
+		x.DataFields = append(x.DataFields, df)
+	}
+
+	if err := w.enc.Encode(x); err != nil {
+		return fmt.Errorf("failed to write marcxml record: %w", err)
+	}
+	return nil
+}
+
+// Close закрывает элемент collection. Базовый io.Writer не закрывается.
+func (w *XMLWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if err := w.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "collection"}}); err != nil {
+		return fmt.Errorf("failed to close marcxml collection: %w", err)
+	}
+	if err := w.enc.Flush(); err != nil {
+		return fmt.Errorf("failed to flush marcxml: %w", err)
+	}
+	_, err := io.WriteString(w.w, "\n")
+	return err
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSortName ограничивает длину имени автора для сортировки
+const maxSortName = 200
+
+// Author - человек, участвовавший в создании книг. Один автор может быть
+// записан в разных книгах по-разному ("Л. Толстой", "Leo Tolstoy"): такие
+// варианты хранятся в AlternateNames, и участник книги с любым из них
+// связывается с этим автором.
+type Author struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// SortName - имя для сортировки по алфавиту ("Толстой, Лев"),
+	// по умолчанию строится из Name
+	SortName string `json:"sort_name"`
+	// AlternateNames - другие написания и транслитерации имени
+	AlternateNames []string `json:"alternate_names,omitempty"`
+	// BookCount - количество книг автора вне корзины, заполняется хранилищем
+	BookCount int `json:"book_count"`
+}
+
+// NameKey возвращает ключ, по которому сравниваются имена авторов:
+// имена, отличающиеся только регистром, относятся к одному автору
+func NameKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// Names возвращает основное имя автора и все его варианты
+func (a *Author) Names() []string {
+	return append([]string{a.Name}, a.AlternateNames...)
+}
+
+// Normalize убирает лишние пробелы в именах, отбрасывает варианты,
+// совпадающие с основным именем или друг с другом, и заполняет SortName,
+// если оно не задано
+func (a *Author) Normalize() {
+	a.Name = collapseSpaces(a.Name)
+	a.SortName = collapseSpaces(a.SortName)
+	if a.SortName == "" {
+		a.SortName = DefaultSortName(a.Name)
+	}
+
+	seen := map[string]bool{NameKey(a.Name): true}
+	names := a.AlternateNames[:0]
+	for _, name := range a.AlternateNames {
+		name = collapseSpaces(name)
+		if name != "" && seen[NameKey(name)] {
+			continue
+		}
+		seen[NameKey(name)] = true
+		names = append(names, name)
+	}
+	a.AlternateNames = names
+}
+
+// Validate проверяет имена автора
+func (a *Author) Validate() error {
+	if a.Name == "" || utf8.RuneCountInString(a.Name) > maxContributorName {
+		return fmt.Errorf("name is required and must be between 1 and %d characters", maxContributorName)
+	}
+	if utf8.RuneCountInString(a.SortName) > maxSortName {
+		return fmt.Errorf("sort_name must be at most %d characters", maxSortName)
+	}
+	for _, name := range a.AlternateNames {
+		if name == "" || utf8.RuneCountInString(name) > maxContributorName {
+			return fmt.Errorf("alternate name is required and must be between 1 and %d characters", maxContributorName)
+		}
+	}
+	return nil
+}
+
+// DefaultSortName строит имя для сортировки: фамилия, затем через запятую
+// имя ("Лев Толстой" - "Толстой, Лев"). Имя, которое уже начинается
+// с фамилии ("Толстой Л.Н.") или содержит запятую, только отделяет фамилию.
+func DefaultSortName(name string) string {
+	words := strings.Fields(name)
+	if len(words) < 2 || strings.Contains(name, ",") {
+		return name
+	}
+	if last := words[len(words)-1]; isInitials(last) {
+		return words[0] + ", " + strings.Join(words[1:], " ")
+	}
+	return words[len(words)-1] + ", " + strings.Join(words[:len(words)-1], " ")
+}
+
+// isInitials проверяет, состоит ли слово из инициалов с точками ("Л.Н.", "Дж.")
+func isInitials(word string) bool {
+	if !strings.HasSuffix(word, ".") {
+		return false
+	}
+	return !slices.ContainsFunc(strings.Split(strings.TrimSuffix(word, "."), "."), func(part string) bool {
+		return part == "" || utf8.RuneCountInString(part) > 2
+	})
+}
+
+// collapseSpaces убирает пробелы по краям строки и заменяет повторяющиеся
+// пробелы одним
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultSortName(t *testing.T) {
+	tests := map[string]string{
+		"Лев Толстой":                "Толстой, Лев",
+		"Лев Николаевич Толстой":     "Толстой, Лев Николаевич",
+		"Л. Толстой":                 "Толстой, Л.",
+		"Толстой Л.Н.":               "Толстой, Л.Н.",
+		"Толстой, Лев":               "Толстой, Лев",
+		"Кукрыниксы":                 "Кукрыниксы",
+		"Johann Wolfgang von Goethe": "Goethe, Johann Wolfgang von",
+		"Jr. Smith":                  "Smith, Jr.",
+	}
+	for name, want := range tests {
+		if got := DefaultSortName(name); got != want {
+			t.Errorf("DefaultSortName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAuthorNormalize(t *testing.T) {
+	a := Author{
+		Name:           "  Лев   Толстой ",
+		AlternateNames: []string{"Leo Tolstoy", "лев толстой", " Leo  Tolstoy", "Л. Толстой"},
+	}
+	a.Normalize()
+
+	if a.Name != "Лев Толстой" || a.SortName != "Толстой, Лев" {
+		t.Errorf("Normalize() name = %q, sort name = %q", a.Name, a.SortName)
+	}
+	if got := strings.Join(a.AlternateNames, "|"); got != "Leo Tolstoy|Л. Толстой" {
+		t.Errorf("Normalize() alternate names = %q", got)
+	}
+	if err := a.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	for _, bad := range []Author{
+		{Name: ""},
+		{Name: strings.Repeat("я", maxContributorName+1)},
+		{Name: "Лев Толстой", AlternateNames: []string{" "}},
+	} {
+		bad.Normalize()
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%+v) error = nil", bad)
+		}
+	}
+}
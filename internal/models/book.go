@@ -1,66 +1,97 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
-	"regexp"
-	"strconv"
 	"time"
 
+	"github.com/NkvXness/GoBookshelf/internal/isbn"
 	"github.com/go-playground/validator/v10"
 )
 
 type Book struct {
 	ID        int64     `json:"id"`
 	Title     string    `json:"title" validate:"required,min=1,max=200"`
-	Author    string    `json:"author" validate:"required,min=1,max=100"`
-	ISBN      string    `json:"isbn" validate:"required,isbn13_custom"`
+	Author    string    `json:"author"`
+	ISBN      string    `json:"isbn" validate:"omitempty,isbn_custom"`
 	Published time.Time `json:"published" validate:"required"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// Version увеличивается при каждом изменении книги и передается
+	// клиенту в заголовке ETag
+	Version int64 `json:"version"`
+	// DeletedAt заполнен только у книг в корзине
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Identifiers - идентификаторы книги помимо ISBN (ISSN, LCCN, DOI и др.).
+	// ISBN необязателен: у старых книг, самиздата и электронных изданий
+	// его нет, и книгу можно найти по этим идентификаторам.
+	Identifiers []Identifier `json:"identifiers,omitempty"`
+	// Contributors - авторы, переводчики, редакторы и иллюстраторы книги
+	// по порядку. Author заполняется именами авторов из этого списка
+	// для клиентов, которые не знают о нем.
+	Contributors []Contributor `json:"contributors,omitempty"`
+	// Tags - теги книги в нижнем регистре по алфавиту
+	Tags []string `json:"tags,omitempty"`
+	// Shelves - ID полок, на которых лежит книга. Заполняется хранилищем,
+	// при сохранении книги не учитывается: книги кладутся на полку через ее API.
+	Shelves []int64 `json:"shelves,omitempty"`
+	// Series - название серии, в которую входит книга. Книга связывается
+	// с серией по названию без учета регистра, SeriesID заполняется хранилищем.
+	Series   string `json:"series,omitempty"`
+	SeriesID int64  `json:"series_id,omitempty"`
+	// SeriesPosition - номер книги в серии; может быть дробным (2.5 - повесть
+	// между второй и третьей книгами)
+	SeriesPosition *float64 `json:"series_position,omitempty"`
+	// Publisher, Language и Format описывают издание: издательство, код
+	// языка ISO 639 ("ru") и формат (EditionHardcover и др.)
+	Publisher string `json:"publisher,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Format    string `json:"format,omitempty"`
+	// WorkID - ID произведения, изданием которого является книга. Заполняется
+	// хранилищем: издания связываются с произведением через его API.
+	WorkID int64 `json:"work_id,omitempty"`
 }
 
-var validate *validator.Validate
+// bookFields - поля книги без метода MarshalJSON
+type bookFields Book
 
-func init() {
-	validate = validator.New()
-	// Регистрируем кастомный валидатор для ISBN-13
-	validate.RegisterValidation("isbn13_custom", validateISBN13)
+// bookJSON - книга в JSON вместе с производными полями
+type bookJSON struct {
+	bookFields
+	// ISBN10 - ISBN-10 книги, если у ее ISBN-13 префикс 978
+	ISBN10 string `json:"isbn10,omitempty"`
 }
 
-// validateISBN13 является кастомной функцией валидации для validator/v10
-func validateISBN13(fl validator.FieldLevel) bool {
-	isbn := fl.Field().String()
+func (b Book) toJSON() bookJSON {
+	return bookJSON{bookFields: bookFields(b), ISBN10: b.ISBN10()}
+}
 
-	// Удаляем все не цифровые символы
-	re := regexp.MustCompile(`[^0-9]`)
-	cleanISBN := re.ReplaceAllString(isbn, "")
+// MarshalJSON добавляет к полям книги производное поле isbn10
+func (b Book) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.toJSON())
+}
 
-	if len(cleanISBN) != 13 {
-		return false
-	}
+// ISBN10 возвращает ISBN-10 книги без дефисов или пустую строку, если
+// у ISBN книги нет формы ISBN-10
+func (b *Book) ISBN10() string {
+	isbn10, _ := ISBN13To10(isbnDigits(b.ISBN))
+	return isbn10
+}
 
-	// Проверка контрольной суммы
-	sum := 0
-	for i := 0; i < 12; i++ {
-		digit, err := strconv.Atoi(string(cleanISBN[i]))
-		if err != nil {
-			return false
-		}
-		if i%2 == 0 {
-			sum += digit
-		} else {
-			sum += digit * 3
-		}
-	}
+var validate *validator.Validate
 
-	checkDigit := (10 - (sum % 10)) % 10
-	lastDigit, err := strconv.Atoi(string(cleanISBN[12]))
-	if err != nil {
-		return false
-	}
+func init() {
+	validate = validator.New()
+	// Регистрируем кастомный валидатор для ISBN-10 и ISBN-13
+	validate.RegisterValidation("isbn_custom", validateISBN)
+}
 
-	return checkDigit == lastDigit
+// validateISBN является кастомной функцией валидации для validator/v10.
+// Принимает ISBN-13 и ISBN-10 с дефисами или без них.
+func validateISBN(fl validator.FieldLevel) bool {
+	isbn := isbnDigits(fl.Field().String())
+	return ValidISBN13(isbn) || ValidISBN10(isbn)
 }
 
 // Validate проверяет все поля структуры Book
@@ -74,10 +105,8 @@ func (b *Book) Validate() error {
 				switch e.Field() {
 				case "Title":
 					return fmt.Errorf("title is required and must be between 1 and 200 characters")
-				case "Author":
-					return fmt.Errorf("author is required and must be between 1 and 100 characters")
 				case "ISBN":
-					return fmt.Errorf("invalid ISBN-13 format or checksum")
+					return fmt.Errorf("invalid ISBN format or checksum")
 				case "Published":
 					return fmt.Errorf("published date is required")
 				}
@@ -85,20 +114,52 @@ func (b *Book) Validate() error {
 		}
 		return err
 	}
-	return nil
+	if b.Published.After(time.Now()) {
+		return fmt.Errorf("published date cannot be in the future")
+	}
+	if err := b.validateContributors(); err != nil {
+		return err
+	}
+	if err := b.validateTags(); err != nil {
+		return err
+	}
+	if err := b.validateSeries(); err != nil {
+		return err
+	}
+	if err := b.validateEdition(); err != nil {
+		return err
+	}
+	return b.validateIdentifiers()
 }
 
-// FormatISBN форматирует ISBN с дефисами
+// Normalize приводит ISBN, идентификаторы, участников, теги, серию
+// и сведения об издании к виду, в котором они хранятся. Вызывается перед
+// Validate.
+func (b *Book) Normalize() {
+	b.FormatISBN()
+	b.FormatIdentifiers()
+	b.FormatContributors()
+	b.FormatTags()
+	b.FormatSeries()
+	b.FormatEdition()
+}
+
+// FormatISBN форматирует ISBN с дефисами по таблице диапазонов ISBN
+// International. Корректный ISBN-10 переводится в ISBN-13 с префиксом 978:
+// книги хранятся только с ISBN-13. ISBN из нераспределенного диапазона
+// сохраняется без дефисов: разделить его на элементы невозможно. Так же
+// сохраняется ISBN группы, которой нет во встроенной таблице: угадывать
+// деление нельзя, дефисы расставит -reformat-isbn после обновления таблицы.
 func (b *Book) FormatISBN() {
-	re := regexp.MustCompile(`[^0-9]`)
-	cleanISBN := re.ReplaceAllString(b.ISBN, "")
+	cleanISBN := isbnDigits(b.ISBN)
+	if isbn13, ok := ISBN10To13(cleanISBN); ok {
+		cleanISBN = isbn13
+	}
 
 	if len(cleanISBN) == 13 {
-		b.ISBN = fmt.Sprintf("%s-%s-%s-%s-%s",
-			cleanISBN[0:3],
-			cleanISBN[3:4],
-			cleanISBN[4:7],
-			cleanISBN[7:12],
-			cleanISBN[12:])
+		b.ISBN = cleanISBN
+		if hyphenated, ok := isbn.Hyphenate(cleanISBN); ok {
+			b.ISBN = hyphenated
+		}
 	}
 }
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Роли участников книги
+const (
+	RoleAuthor      = "author"
+	RoleTranslator  = "translator"
+	RoleEditor      = "editor"
+	RoleIllustrator = "illustrator"
+)
+
+// ContributorRoles перечисляет допустимые роли участников в порядке,
+// в котором их принято перечислять
+var ContributorRoles = []string{RoleAuthor, RoleTranslator, RoleEditor, RoleIllustrator}
+
+// maxContributorName ограничивает длину имени участника
+const maxContributorName = 100
+
+// authorSeparator разделяет имена авторов в поле Author
+const authorSeparator = ", "
+
+// Contributor - участник создания книги: автор, переводчик, редактор
+// или иллюстратор. Участники ссылаются на общую таблицу авторов, поэтому
+// все книги одного человека можно найти по AuthorID.
+type Contributor struct {
+	// AuthorID заполняется хранилищем: участник связывается с автором
+	// по имени, при сохранении книги значение из запроса не учитывается
+	AuthorID int64  `json:"author_id,omitempty"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+}
+
+// IsContributorRole проверяет, известна ли роль участника
+func IsContributorRole(role string) bool {
+	return slices.Contains(ContributorRoles, role)
+}
+
+// ContributorNames возвращает имена участников книги с ролью role в порядке
+// их следования. Если участники не заполнены, автором считается значение
+// поля Author.
+func (b *Book) ContributorNames(role string) []string {
+	if len(b.Contributors) == 0 {
+		if role == RoleAuthor && b.Author != "" {
+			return []string{b.Author}
+		}
+		return nil
+	}
+
+	var names []string
+	for _, c := range b.Contributors {
+		if c.Role == role {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// FormatContributors убирает лишние пробелы в именах участников и приводит
+// роли к нижнему регистру. Если среди участников есть авторы, поле Author
+// заполняется их именами через запятую.
+func (b *Book) FormatContributors() {
+	for i, c := range b.Contributors {
+		c.Name = collapseSpaces(c.Name)
+		c.Role = strings.ToLower(strings.TrimSpace(c.Role))
+		b.Contributors[i] = c
+	}
+	if authors := b.ContributorNames(RoleAuthor); len(b.Contributors) > 0 && len(authors) > 0 {
+		b.Author = strings.Join(authors, authorSeparator)
+	}
+}
+
+// ResolveContributors согласует участников книги с полем Author для
+// клиентов, которые знают только его. previous - сохраненное состояние
+// книги или nil для новой книги.
+//
+// Если участники не переданы, остаются участники previous; если при этом
+// (или при неизменных участниках) изменилось поле Author, прежние авторы
+// заменяются им. Книга без авторов среди участников получает автора из
+// поля Author, а само поле заполняется именами авторов.
+func (b *Book) ResolveContributors(previous *Book) {
+	if previous != nil {
+		authorChanged := b.Author != previous.Author
+		switch {
+		case b.Contributors == nil:
+			b.Contributors = slices.Clone(previous.Contributors)
+			if authorChanged {
+				b.Contributors = withoutRole(b.Contributors, RoleAuthor)
+			}
+		case authorChanged && SameContributors(b.Contributors, previous.Contributors):
+			b.Contributors = withoutRole(b.Contributors, RoleAuthor)
+		}
+	}
+
+	if !slices.ContainsFunc(b.Contributors, isAuthor) && b.Author != "" {
+		b.Contributors = slices.Insert(b.Contributors, 0, Contributor{Name: b.Author, Role: RoleAuthor})
+	}
+	b.FormatContributors()
+}
+
+// validateContributors проверяет имена и роли участников. Автор нужен
+// всегда: либо среди участников, либо в поле Author.
+func (b *Book) validateContributors() error {
+	if !slices.ContainsFunc(b.Contributors, isAuthor) {
+		if b.Author == "" || utf8.RuneCountInString(b.Author) > maxContributorName {
+			return fmt.Errorf("author is required and must be between 1 and %d characters", maxContributorName)
+		}
+	}
+
+	seen := make(map[Contributor]bool, len(b.Contributors))
+	for _, c := range b.Contributors {
+		if !IsContributorRole(c.Role) {
+			return fmt.Errorf("unknown contributor role %q, allowed: %s", c.Role, strings.Join(ContributorRoles, ", "))
+		}
+		if c.Name == "" || utf8.RuneCountInString(c.Name) > maxContributorName {
+			return fmt.Errorf("contributor name is required and must be between 1 and %d characters", maxContributorName)
+		}
+		key := Contributor{Name: c.Name, Role: c.Role}
+		if seen[key] {
+			return fmt.Errorf("duplicate %s %q", c.Role, c.Name)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// SameContributors сравнивает участников по именам и ролям: клиент может
+// не передать author_id
+func SameContributors(a, b []Contributor) bool {
+	return slices.EqualFunc(a, b, func(x, y Contributor) bool {
+		return x.Name == y.Name && x.Role == y.Role
+	})
+}
+
+func withoutRole(contributors []Contributor, role string) []Contributor {
+	return slices.DeleteFunc(contributors, func(c Contributor) bool { return c.Role == role })
+}
+
+func isAuthor(c Contributor) bool {
+	return c.Role == RoleAuthor
+}
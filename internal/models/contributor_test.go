@@ -0,0 +1,98 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveContributors(t *testing.T) {
+	translator := Contributor{AuthorID: 7, Name: "Борис Пастернак", Role: RoleTranslator}
+	previous := &Book{Author: "William Shakespeare", Contributors: []Contributor{
+		{AuthorID: 3, Name: "William Shakespeare", Role: RoleAuthor},
+		translator,
+	}}
+
+	tests := []struct {
+		name     string
+		book     Book
+		previous *Book
+		want     string
+	}{
+		{"new book from author", Book{Author: "Лев Толстой"}, nil, "author:Лев Толстой"},
+		{"new book with translator", Book{Author: "Лев Толстой", Contributors: []Contributor{{Name: "Aylmer Maude", Role: RoleTranslator}}}, nil,
+			"author:Лев Толстой|translator:Aylmer Maude"},
+		{"same author", Book{Author: "William Shakespeare"}, previous, "author:William Shakespeare|translator:Борис Пастернак"},
+		{"changed author", Book{Author: "Уильям Шекспир"}, previous, "author:Уильям Шекспир|translator:Борис Пастернак"},
+		{"changed author, same contributors", Book{Author: "Уильям Шекспир", Contributors: previous.Contributors}, previous,
+			"author:Уильям Шекспир|translator:Борис Пастернак"},
+		{"new contributors", Book{Author: "William Shakespeare", Contributors: []Contributor{
+			{Name: "Уильям Шекспир", Role: RoleAuthor}, {Name: "Михаил Лозинский", Role: RoleTranslator},
+		}}, previous, "author:Уильям Шекспир|translator:Михаил Лозинский"},
+	}
+	for _, tt := range tests {
+		book := tt.book
+		book.Contributors = append([]Contributor(nil), book.Contributors...)
+		book.ResolveContributors(tt.previous)
+
+		var got []string
+		for _, c := range book.Contributors {
+			got = append(got, c.Role+":"+c.Name)
+		}
+		if strings.Join(got, "|") != tt.want {
+			t.Errorf("%s: ResolveContributors() = %q, want %q", tt.name, strings.Join(got, "|"), tt.want)
+		}
+		if want := strings.Join(book.ContributorNames(RoleAuthor), ", "); book.Author != want {
+			t.Errorf("%s: Author = %q, want %q", tt.name, book.Author, want)
+		}
+	}
+	if len(previous.Contributors) != 2 {
+		t.Errorf("ResolveContributors() changed previous contributors: %+v", previous.Contributors)
+	}
+}
+
+func TestValidateContributors(t *testing.T) {
+	valid := func() Book {
+		return Book{
+			Title:     "Двенадцать стульев",
+			Published: time.Date(1928, 1, 1, 0, 0, 0, 0, time.UTC),
+			Contributors: []Contributor{
+				{Name: " Илья  Ильф ", Role: "Author"},
+				{Name: "Евгений Петров", Role: RoleAuthor},
+			},
+		}
+	}
+
+	book := valid()
+	book.Normalize()
+	if err := book.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if book.Author != "Илья Ильф, Евгений Петров" || book.Contributors[0].Role != RoleAuthor {
+		t.Errorf("Normalize() = %+v", book)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(b *Book)
+		want   string
+	}{
+		{"no author", func(b *Book) {
+			b.Author = ""
+			b.Contributors = []Contributor{{Name: "Кукрыниксы", Role: RoleIllustrator}}
+		}, "author is required"},
+		{"unknown role", func(b *Book) { b.Contributors[1].Role = "composer" }, "unknown contributor role"},
+		{"empty name", func(b *Book) { b.Contributors[1].Name = "" }, "contributor name is required"},
+		{"long name", func(b *Book) { b.Contributors[1].Name = strings.Repeat("я", 101) }, "contributor name is required"},
+		{"duplicate", func(b *Book) { b.Contributors[1].Name = "Илья Ильф" }, "duplicate author"},
+		{"long legacy author", func(b *Book) { b.Contributors = nil; b.Author = strings.Repeat("я", 101) }, "author is required"},
+	}
+	for _, tt := range tests {
+		book := valid()
+		book.Normalize()
+		tt.modify(&book)
+		if err := book.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Validate() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Типы дополнительных идентификаторов книги
+const (
+	IdentifierISSN    = "issn"
+	IdentifierLCCN    = "lccn"
+	IdentifierOCLC    = "oclc"
+	IdentifierDOI     = "doi"
+	IdentifierASIN    = "asin"
+	IdentifierBarcode = "barcode"
+)
+
+// IdentifierTypes перечисляет допустимые типы идентификаторов
+var IdentifierTypes = []string{
+	IdentifierISSN,
+	IdentifierLCCN,
+	IdentifierOCLC,
+	IdentifierDOI,
+	IdentifierASIN,
+	IdentifierBarcode,
+}
+
+// Identifier - идентификатор книги помимо ISBN: номер периодического
+// издания, каталога библиотеки, магазина или внутренний штрихкод
+type Identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// identifierFormats приводят значение идентификатора к каноническому виду
+// и проверяют его
+var identifierFormats = map[string]func(string) (string, bool){
+	IdentifierISSN:    normalizeISSN,
+	IdentifierLCCN:    normalizeLCCN,
+	IdentifierOCLC:    normalizeOCLC,
+	IdentifierDOI:     normalizeDOI,
+	IdentifierASIN:    normalizeASIN,
+	IdentifierBarcode: normalizeBarcode,
+}
+
+// IsIdentifierType проверяет, известен ли тип идентификатора
+func IsIdentifierType(typ string) bool {
+	_, ok := identifierFormats[typ]
+	return ok
+}
+
+// NormalizeIdentifier приводит значение идентификатора к виду, в котором
+// он хранится: "0028-0836" для ISSN, "n79021164" для LCCN, "10.1000/182"
+// для DOI. Возвращает false для неизвестного типа или некорректного значения.
+func NormalizeIdentifier(typ, value string) (string, bool) {
+	normalize, ok := identifierFormats[typ]
+	if !ok {
+		return "", false
+	}
+	return normalize(strings.TrimSpace(value))
+}
+
+// FormatIdentifiers приводит типы и значения идентификаторов книги
+// к каноническому виду. Некорректные значения остаются как есть, чтобы
+// Validate сообщил о них.
+func (b *Book) FormatIdentifiers() {
+	for i, id := range b.Identifiers {
+		id.Type = strings.ToLower(strings.TrimSpace(id.Type))
+		if value, ok := NormalizeIdentifier(id.Type, id.Value); ok {
+			id.Value = value
+		}
+		b.Identifiers[i] = id
+	}
+}
+
+// validateIdentifiers проверяет типы и значения идентификаторов книги
+// и отсутствие повторов
+func (b *Book) validateIdentifiers() error {
+	seen := make(map[Identifier]bool, len(b.Identifiers))
+	for _, id := range b.Identifiers {
+		if !IsIdentifierType(id.Type) {
+			return fmt.Errorf("unknown identifier type %q, allowed: %s", id.Type, strings.Join(IdentifierTypes, ", "))
+		}
+		if value, ok := NormalizeIdentifier(id.Type, id.Value); !ok || value != id.Value {
+			return fmt.Errorf("invalid %s identifier %q", id.Type, id.Value)
+		}
+		if seen[id] {
+			return fmt.Errorf("duplicate %s identifier %q", id.Type, id.Value)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// normalizeISSN проверяет контрольную цифру ISSN по модулю 11 и
+// возвращает его в виде NNNN-NNNC
+func normalizeISSN(value string) (string, bool) {
+	issn := isbnDigits(value)
+	if len(issn) != 8 || strings.ContainsRune(issn[:7], 'X') {
+		return "", false
+	}
+
+	sum := 0
+	for i := 0; i < 7; i++ {
+		sum += int(issn[i]-'0') * (8 - i)
+	}
+	check := (11 - sum%11) % 11
+	want := byte('0' + check)
+	if check == 10 {
+		want = 'X'
+	}
+	if issn[7] != want {
+		return "", false
+	}
+	return issn[:4] + "-" + issn[4:], true
+}
+
+var lccnRe = regexp.MustCompile(`^[a-z]{0,3}(\d{8}|\d{10})$`)
+
+// normalizeLCCN нормализует LCCN по правилам Library of Congress:
+// пробелы и часть после "/" отбрасываются, номер после дефиса
+// дополняется нулями до шести цифр
+func normalizeLCCN(value string) (string, bool) {
+	lccn := strings.ToLower(strings.Join(strings.Fields(value), ""))
+	lccn, _, _ = strings.Cut(lccn, "/")
+	if prefix, serial, found := strings.Cut(lccn, "-"); found {
+		if serial == "" || len(serial) > 6 || strings.IndexFunc(serial, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+			return "", false
+		}
+		lccn = prefix + strings.Repeat("0", 6-len(serial)) + serial
+	}
+	if !lccnRe.MatchString(lccn) {
+		return "", false
+	}
+	return lccn, true
+}
+
+// Префиксы, с которыми номер OCLC встречается в записях MARC и каталогах,
+// в порядке их следования: "(OCoLC)ocm00012345"
+var oclcPrefixes = []string{"(ocolc)", "ocm", "ocn", "on"}
+
+// normalizeOCLC возвращает номер OCLC без префиксов и ведущих нулей
+func normalizeOCLC(value string) (string, bool) {
+	oclc := strings.ToLower(value)
+	for _, prefix := range oclcPrefixes {
+		if strings.HasPrefix(oclc, prefix) {
+			oclc = strings.TrimSpace(oclc[len(prefix):])
+		}
+	}
+	oclc = strings.TrimLeft(oclc, "0")
+	if oclc == "" || len(oclc) > 12 || strings.IndexFunc(oclc, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return "", false
+	}
+	return oclc, true
+}
+
+// Префиксы, с которыми DOI записывают в ссылках
+var doiPrefixes = []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"}
+
+var doiRe = regexp.MustCompile(`^10\.\d{4,9}/\S+$`)
+
+// normalizeDOI возвращает DOI без префикса ссылки в нижнем регистре:
+// DOI не различают регистр
+func normalizeDOI(value string) (string, bool) {
+	doi := strings.ToLower(value)
+	for _, prefix := range doiPrefixes {
+		if strings.HasPrefix(doi, prefix) {
+			doi = strings.TrimSpace(doi[len(prefix):])
+			break
+		}
+	}
+	if !doiRe.MatchString(doi) {
+		return "", false
+	}
+	return doi, true
+}
+
+var asinRe = regexp.MustCompile(`^[0-9A-Z]{10}$`)
+
+// normalizeASIN проверяет ASIN Amazon: десять латинских букв и цифр
+func normalizeASIN(value string) (string, bool) {
+	asin := strings.ToUpper(value)
+	if !asinRe.MatchString(asin) {
+		return "", false
+	}
+	return asin, true
+}
+
+// maxBarcodeLength ограничивает длину внутреннего штрихкода
+const maxBarcodeLength = 64
+
+// normalizeBarcode проверяет внутренний штрихкод библиотеки: до 64 букв,
+// цифр, дефисов и точек без пробелов
+func normalizeBarcode(value string) (string, bool) {
+	if value == "" || len(value) > maxBarcodeLength {
+		return "", false
+	}
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '.' {
+			return "", false
+		}
+	}
+	return value, true
+}
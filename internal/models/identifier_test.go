@@ -0,0 +1,82 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeIdentifier(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value string
+		want  string
+		ok    bool
+	}{
+		{IdentifierISSN, "0028-0836", "0028-0836", true},
+		{IdentifierISSN, "2434 561x", "2434-561X", true},
+		{IdentifierISSN, "0028-0837", "", false},
+		{IdentifierLCCN, "n79-21164", "n79021164", true},
+		{IdentifierLCCN, "85-2 ", "85000002", true},
+		{IdentifierLCCN, "2001-000002/AC", "2001000002", true},
+		{IdentifierLCCN, "abcd12345678", "", false},
+		{IdentifierOCLC, "(OCoLC)ocm00012345", "12345", true},
+		{IdentifierOCLC, "ocn123456789", "123456789", true},
+		{IdentifierOCLC, "12a", "", false},
+		{IdentifierDOI, "https://doi.org/10.1000/ABC.1", "10.1000/abc.1", true},
+		{IdentifierDOI, "doi:10.1038/nphys1170", "10.1038/nphys1170", true},
+		{IdentifierDOI, "11.1000/182", "", false},
+		{IdentifierASIN, "b000fc1pji", "B000FC1PJI", true},
+		{IdentifierASIN, "B000FC1PJ", "", false},
+		{IdentifierBarcode, "LIB-00042", "LIB-00042", true},
+		{IdentifierBarcode, "LIB 42", "", false},
+		{"upc", "012345678905", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeIdentifier(tt.typ, tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeIdentifier(%q, %q) = %q, %v; want %q, %v", tt.typ, tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBookIdentifiers(t *testing.T) {
+	book := Book{
+		Title:     "Самиздат",
+		Author:    "Неизвестный автор",
+		Published: time.Date(1968, 1, 1, 0, 0, 0, 0, time.UTC),
+		Identifiers: []Identifier{
+			{Type: " ISSN ", Value: "00280836"},
+			{Type: "barcode", Value: "LIB-1"},
+		},
+	}
+
+	// Книга без ISBN допустима, идентификаторы приводятся к каноническому виду
+	book.Normalize()
+	if err := book.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if book.ISBN != "" || book.Identifiers[0] != (Identifier{Type: IdentifierISSN, Value: "0028-0836"}) {
+		t.Errorf("Normalize() = %q, %+v", book.ISBN, book.Identifiers)
+	}
+
+	tests := []struct {
+		name string
+		ids  []Identifier
+		want string
+	}{
+		{"unknown type", []Identifier{{Type: "upc", Value: "1"}}, "unknown identifier type"},
+		{"invalid value", []Identifier{{Type: IdentifierISSN, Value: "0028-0837"}}, "invalid issn identifier"},
+		{"duplicate", []Identifier{{Type: IdentifierASIN, Value: "B000FC1PJI"}, {Type: IdentifierASIN, Value: "b000fc1pji"}}, "duplicate asin identifier"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := book
+			invalid.Identifiers = tt.ids
+			invalid.Normalize()
+			if err := invalid.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+import "strings"
+
+// isbnDigits оставляет в ISBN только цифры и контрольный символ X
+// (в верхнем регистре)
+func isbnDigits(isbn string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9':
+			return r
+		case r == 'X' || r == 'x':
+			return 'X'
+		}
+		return -1
+	}, isbn)
+}
+
+// ValidISBN10 проверяет ISBN-10 без дефисов по модулю 11.
+// Контрольная цифра X означает 10.
+func ValidISBN10(isbn string) bool {
+	if len(isbn) != 10 {
+		return false
+	}
+
+	sum := 0
+	for i, r := range isbn {
+		var digit int
+		switch {
+		case r >= '0' && r <= '9':
+			digit = int(r - '0')
+		case (r == 'X' || r == 'x') && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+// ValidISBN13 проверяет ISBN-13 без дефисов по контрольной цифре
+func ValidISBN13(isbn string) bool {
+	if len(isbn) != 13 {
+		return false
+	}
+	for _, r := range isbn {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return isbn13CheckDigit(isbn[:12]) == isbn[12]
+}
+
+// isbn13CheckDigit вычисляет контрольную цифру по первым 12 цифрам ISBN-13
+func isbn13CheckDigit(digits string) byte {
+	sum := 0
+	for i, r := range digits {
+		digit := int(r - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// ISBN10To13 переводит ISBN-10 без дефисов с корректной контрольной
+// цифрой в ISBN-13 с префиксом 978
+func ISBN10To13(isbn string) (string, bool) {
+	if !ValidISBN10(isbn) {
+		return "", false
+	}
+	digits := "978" + isbn[:9]
+	return digits + string(isbn13CheckDigit(digits)), true
+}
+
+// ISBN13To10 переводит ISBN-13 с префиксом 978 в ISBN-10. У ISBN
+// с префиксом 979 соответствующего ISBN-10 нет.
+func ISBN13To10(isbn string) (string, bool) {
+	if !ValidISBN13(isbn) || !strings.HasPrefix(isbn, "978") {
+		return "", false
+	}
+
+	digits := isbn[3:12]
+	sum := 0
+	for i, r := range digits {
+		sum += int(r-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return digits + "X", true
+	}
+	return digits + string(rune('0'+check)), true
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestISBN10To13(t *testing.T) {
+	tests := []struct {
+		isbn string
+		want string
+		ok   bool
+	}{
+		{"0451524934", "9780451524935", true},
+		{"080442957X", "9780804429573", true},
+		{"080442957x", "9780804429573", true},
+		{"0451524935", "", false},
+		{"04515249", "", false},
+		{"9780439023481", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ISBN10To13(tt.isbn)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ISBN10To13(%q) = %q, %v; want %q, %v", tt.isbn, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestISBN13To10(t *testing.T) {
+	tests := []struct {
+		isbn string
+		want string
+		ok   bool
+	}{
+		{"9780451524935", "0451524934", true},
+		{"9780804429573", "080442957X", true},
+		{"9791032305690", "", false},
+		{"9780451524936", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ISBN13To10(tt.isbn)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ISBN13To10(%q) = %q, %v; want %q, %v", tt.isbn, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBookISBN10(t *testing.T) {
+	book := Book{
+		Title:     "The Eyre Affair",
+		Author:    "Jasper Fforde",
+		ISBN:      "0-8044-2957-X",
+		Published: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := book.Validate(); err != nil {
+		t.Fatalf("Validate() ISBN-10 error = %v", err)
+	}
+
+	book.FormatISBN()
+	if book.ISBN != "978-0-8044-2957-3" {
+		t.Errorf("FormatISBN() = %q, want ISBN-13", book.ISBN)
+	}
+
+	// Группы 978-966 нет во встроенной таблице, 978-6600 не распределен:
+	// дефисы не расставляются
+	for isbn, want := range map[string]string{"9789669935809": "9789669935809", "9786600000001": "9786600000001"} {
+		b := Book{ISBN: isbn}
+		if b.FormatISBN(); b.ISBN != want {
+			t.Errorf("FormatISBN(%s) = %q, want %q", isbn, b.ISBN, want)
+		}
+	}
+
+	data, err := json.Marshal(book)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"isbn":"978-0-8044-2957-3"`) || !strings.Contains(string(data), `"isbn10":"080442957X"`) {
+		t.Errorf("json.Marshal() = %s, want isbn and isbn10", data)
+	}
+
+	hit := SearchHit{Book: book, Score: 1.5}
+	data, err = json.Marshal(hit)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"isbn10":"080442957X"`) || !strings.Contains(string(data), `"score":1.5`) {
+		t.Errorf("json.Marshal(SearchHit) = %s, want book fields and score", data)
+	}
+
+	book.ISBN = "0-8044-2957-4"
+	if err := book.Validate(); err == nil {
+		t.Error("Validate() expected error for ISBN-10 with wrong check digit")
+	}
+}
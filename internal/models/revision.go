@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Действия, которые записываются в историю изменений книги
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionRevert  = "revert"
+	// ActionPurge - последняя ревизия книги, окончательно удаленной
+	// из корзины
+	ActionPurge = "purge"
+)
+
+// FieldChange - изменение одного поля книги. Значения хранятся в том же
+// JSON-представлении, что и в API; null означает отсутствие значения.
+type FieldChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// Revision - запись в истории изменений книги
+type Revision struct {
+	ID     int64 `json:"id"`
+	BookID int64 `json:"book_id"`
+	// Revision - порядковый номер изменения книги, начиная с 1
+	Revision int    `json:"revision"`
+	Action   string `json:"action"`
+	// Changes содержит только изменившиеся поля
+	Changes map[string]FieldChange `json:"changes"`
+	// Actor - кто внес изменение, пусто, если неизвестно
+	Actor string `json:"actor,omitempty"`
+	// RevertedTo - номер ревизии, к которой вернули книгу действием revert
+	RevertedTo int       `json:"reverted_to,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
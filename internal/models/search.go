@@ -0,0 +1,35 @@
+package models
+
+import "encoding/json"
+
+// SearchHit - книга, найденная полнотекстовым поиском
+type SearchHit struct {
+	Book
+	// Score - релевантность результата (bm25), чем больше, тем выше в выдаче
+	Score float64 `json:"score"`
+	// Snippet - фрагмент текста вокруг совпадения с разметкой <mark>
+	Snippet string `json:"snippet,omitempty"`
+	// Highlight содержит поля книги с выделенными совпадениями
+	Highlight *SearchHighlight `json:"highlight,omitempty"`
+	// Editions - количество найденных изданий произведения книги при поиске
+	// с группировкой по произведениям
+	Editions int `json:"editions,omitempty"`
+}
+
+// MarshalJSON нужен, чтобы MarshalJSON встроенной книги не заменял
+// собой весь результат поиска
+func (h SearchHit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		bookJSON
+		Score     float64          `json:"score"`
+		Snippet   string           `json:"snippet,omitempty"`
+		Highlight *SearchHighlight `json:"highlight,omitempty"`
+		Editions  int              `json:"editions,omitempty"`
+	}{h.Book.toJSON(), h.Score, h.Snippet, h.Highlight, h.Editions})
+}
+
+// SearchHighlight содержит поля книги, в которых совпадения обернуты в <mark>
+type SearchHighlight struct {
+	Title  string `json:"title"`
+	Author string `json:"author"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// maxSeriesName ограничивает длину названия серии
+const maxSeriesName = 200
+
+// Series - серия книг: собрание сочинений, трилогия, цикл. Книги серии
+// упорядочены по номеру Book.SeriesPosition.
+type Series struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// BookCount - количество книг серии вне корзины, заполняется хранилищем
+	BookCount int `json:"book_count"`
+}
+
+// FormatSeries убирает лишние пробелы в названии серии
+func (b *Book) FormatSeries() {
+	b.Series = collapseSpaces(b.Series)
+}
+
+// validateSeries проверяет название серии и номер книги в ней
+func (b *Book) validateSeries() error {
+	if utf8.RuneCountInString(b.Series) > maxSeriesName {
+		return fmt.Errorf("series must be at most %d characters", maxSeriesName)
+	}
+	if b.SeriesPosition == nil {
+		return nil
+	}
+	if b.Series == "" {
+		return fmt.Errorf("series_position requires series")
+	}
+	if p := *b.SeriesPosition; p < 0 || math.IsInf(p, 0) || math.IsNaN(p) {
+		return fmt.Errorf("series_position must be a non-negative number")
+	}
+	return nil
+}
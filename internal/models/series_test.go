@@ -0,0 +1,29 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSeries(t *testing.T) {
+	position := func(p float64) *float64 { return &p }
+
+	b := Book{Series: "  Тёмная   башня ", SeriesPosition: position(4.5)}
+	b.FormatSeries()
+	if b.Series != "Тёмная башня" {
+		t.Errorf("FormatSeries() = %q", b.Series)
+	}
+	if err := b.validateSeries(); err != nil {
+		t.Errorf("validateSeries() error = %v", err)
+	}
+
+	for i, bad := range []Book{
+		{SeriesPosition: position(1)},
+		{Series: "Тёмная башня", SeriesPosition: position(-1)},
+		{Series: strings.Repeat("я", maxSeriesName+1)},
+	} {
+		if err := bad.validateSeries(); err == nil {
+			t.Errorf("validateSeries() case %d error = nil", i)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Ограничения на название и описание полки
+const (
+	maxShelfName        = 100
+	maxShelfDescription = 1000
+)
+
+// Shelf - полка (подборка), на которую пользователь складывает книги
+// в выбранном им порядке
+type Shelf struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// BookCount - количество книг на полке вне корзины, заполняется хранилищем
+	BookCount int       `json:"book_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Normalize убирает лишние пробелы в названии и описании полки
+func (s *Shelf) Normalize() {
+	s.Name = collapseSpaces(s.Name)
+	s.Description = strings.TrimSpace(s.Description)
+}
+
+// Validate проверяет название и описание полки
+func (s *Shelf) Validate() error {
+	if s.Name == "" || utf8.RuneCountInString(s.Name) > maxShelfName {
+		return fmt.Errorf("name is required and must be between 1 and %d characters", maxShelfName)
+	}
+	if utf8.RuneCountInString(s.Description) > maxShelfDescription {
+		return fmt.Errorf("description must be at most %d characters", maxShelfDescription)
+	}
+	return nil
+}
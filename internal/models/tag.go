@@ -0,0 +1,47 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTagLength ограничивает длину тега
+const maxTagLength = 50
+
+// TagCount - тег и количество книг с ним вне корзины
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// NormalizeTag приводит тег к виду, в котором он хранится: теги
+// сравниваются без учета регистра и лишних пробелов
+func NormalizeTag(tag string) string {
+	return strings.ToLower(collapseSpaces(tag))
+}
+
+// FormatTags приводит теги книги к хранимому виду, сортирует их и убирает
+// повторы. Пустой список остается пустым, а не nil: он удаляет теги книги.
+func (b *Book) FormatTags() {
+	if b.Tags == nil {
+		return
+	}
+	tags := make([]string, 0, len(b.Tags))
+	for _, tag := range b.Tags {
+		tags = append(tags, NormalizeTag(tag))
+	}
+	slices.Sort(tags)
+	b.Tags = slices.Compact(tags)
+}
+
+// validateTags проверяет длину тегов
+func (b *Book) validateTags() error {
+	for _, tag := range b.Tags {
+		if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+			return fmt.Errorf("tag must be between 1 and %d characters", maxTagLength)
+		}
+	}
+	return nil
+}
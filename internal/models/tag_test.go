@@ -0,0 +1,29 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFormatTags(t *testing.T) {
+	b := Book{Tags: []string{" Фантастика ", "классика", "science  Fiction", "фантастика"}}
+	b.FormatTags()
+	if want := []string{"science fiction", "классика", "фантастика"}; !slices.Equal(b.Tags, want) {
+		t.Errorf("FormatTags() = %q, want %q", b.Tags, want)
+	}
+
+	empty := Book{Tags: []string{}}
+	empty.FormatTags()
+	if empty.Tags == nil {
+		t.Error("FormatTags() turned an empty list into nil")
+	}
+
+	for _, tags := range [][]string{{" "}, {strings.Repeat("я", maxTagLength+1)}} {
+		b := Book{Tags: tags}
+		b.FormatTags()
+		if err := b.validateTags(); err == nil {
+			t.Errorf("validateTags(%q) error = nil", tags)
+		}
+	}
+}
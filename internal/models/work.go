@@ -0,0 +1,87 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Ограничения на название произведения и издательство
+const (
+	maxWorkTitle = 200
+	maxPublisher = 200
+)
+
+// Форматы издания
+const (
+	EditionHardcover = "hardcover"
+	EditionPaperback = "paperback"
+	EditionEbook     = "ebook"
+	EditionAudiobook = "audiobook"
+)
+
+// editionFormats - допустимые значения Book.Format
+var editionFormats = []string{EditionHardcover, EditionPaperback, EditionEbook, EditionAudiobook}
+
+// Work - произведение, объединяющее издания: переводы, переиздания,
+// электронные и аудиоверсии. Каждое издание - отдельная книга со своим
+// ISBN, издательством, языком и форматом.
+type Work struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	// EditionCount - количество изданий вне корзины, заполняется хранилищем
+	EditionCount int       `json:"edition_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Normalize убирает лишние пробелы в названии произведения
+func (w *Work) Normalize() {
+	w.Title = collapseSpaces(w.Title)
+}
+
+// Validate проверяет название произведения
+func (w *Work) Validate() error {
+	if w.Title == "" || utf8.RuneCountInString(w.Title) > maxWorkTitle {
+		return fmt.Errorf("title is required and must be between 1 and %d characters", maxWorkTitle)
+	}
+	return nil
+}
+
+// FormatEdition убирает лишние пробелы в издательстве и приводит язык
+// и формат издания к нижнему регистру
+func (b *Book) FormatEdition() {
+	b.Publisher = collapseSpaces(b.Publisher)
+	b.Language = strings.ToLower(strings.TrimSpace(b.Language))
+	b.Format = strings.ToLower(strings.TrimSpace(b.Format))
+}
+
+// validateEdition проверяет издательство, язык и формат издания
+func (b *Book) validateEdition() error {
+	if utf8.RuneCountInString(b.Publisher) > maxPublisher {
+		return fmt.Errorf("publisher must be at most %d characters", maxPublisher)
+	}
+	if b.Language != "" && !validLanguage(b.Language) {
+		return fmt.Errorf("language must be an ISO 639 code such as \"ru\" or \"eng\"")
+	}
+	if b.Format != "" && !slices.Contains(editionFormats, b.Format) {
+		return fmt.Errorf("format must be one of %s", strings.Join(editionFormats, ", "))
+	}
+	return nil
+}
+
+// validLanguage проверяет, похож ли код на код языка ISO 639-1 или 639-2:
+// две или три латинские буквы
+func validLanguage(code string) bool {
+	if len(code) < 2 || len(code) > 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateEdition(t *testing.T) {
+	b := Book{Publisher: "  Азбука   Классика ", Language: " RU", Format: "Paperback"}
+	b.FormatEdition()
+	if b.Publisher != "Азбука Классика" || b.Language != "ru" || b.Format != EditionPaperback {
+		t.Errorf("FormatEdition() = %q %q %q", b.Publisher, b.Language, b.Format)
+	}
+	if err := b.validateEdition(); err != nil {
+		t.Errorf("validateEdition() error = %v", err)
+	}
+	if err := (&Book{Language: "eng"}).validateEdition(); err != nil {
+		t.Errorf("validateEdition(eng) error = %v", err)
+	}
+
+	for i, bad := range []Book{
+		{Language: "russian"},
+		{Language: "р"},
+		{Language: "e1"},
+		{Format: "scroll"},
+		{Publisher: strings.Repeat("я", maxPublisher+1)},
+	} {
+		if err := bad.validateEdition(); err == nil {
+			t.Errorf("validateEdition() case %d error = nil", i)
+		}
+	}
+}
+
+func TestValidateWork(t *testing.T) {
+	w := Work{Title: "  Анна  Каренина "}
+	w.Normalize()
+	if w.Title != "Анна Каренина" || w.Validate() != nil {
+		t.Errorf("Normalize() = %q, Validate() = %v", w.Title, w.Validate())
+	}
+	for _, title := range []string{"", strings.Repeat("я", maxWorkTitle+1)} {
+		if err := (&Work{Title: title}).Validate(); err == nil {
+			t.Errorf("Validate(%d characters) error = nil", len([]rune(title)))
+		}
+	}
+}
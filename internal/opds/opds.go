@@ -0,0 +1,242 @@
+// Package opds строит каталоги OPDS 1.2 - ленты Atom, которые понимают
+// приложения для чтения на электронных книгах.
+//
+// Навигационные ленты ведут к другим лентам, ленты книг (acquisition)
+// содержат записи о книгах. В библиотеке хранятся только сведения о книгах,
+// без файлов, поэтому записи ссылаются на карточку книги в JSON и в
+// форматах выгрузки.
+package opds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// Типы документов OPDS
+const (
+	NavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	OpenSearchType  = "application/opensearchdescription+xml"
+)
+
+// Отношения ссылок, которые использует каталог
+const (
+	RelSelf       = "self"
+	RelStart      = "start"
+	RelUp         = "up"
+	RelFirst      = "first"
+	RelPrevious   = "previous"
+	RelNext       = "next"
+	RelLast       = "last"
+	RelSearch     = "search"
+	RelSubsection = "subsection"
+	RelAlternate  = "alternate"
+	// RelNew - лента недавно добавленных книг
+	RelNew = "http://opds-spec.org/sort/new"
+)
+
+// Feed - лента Atom
+type Feed struct {
+	XMLName         xml.Name `xml:"feed"`
+	Xmlns           string   `xml:"xmlns,attr"`
+	XmlnsDC         string   `xml:"xmlns:dc,attr"`
+	XmlnsOpenSearch string   `xml:"xmlns:opensearch,attr"`
+
+	ID      string  `xml:"id"`
+	Title   string  `xml:"title"`
+	Updated string  `xml:"updated"`
+	Author  *Person `xml:"author,omitempty"`
+	Links   []Link  `xml:"link"`
+
+	// Сведения о странице для лент с пагинацией
+	TotalResults int `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage int `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   int `xml:"opensearch:startIndex,omitempty"`
+
+	Entries []Entry `xml:"entry"`
+}
+
+// Person - автор ленты или книги
+type Person struct {
+	Name string `xml:"name"`
+}
+
+// Link - ссылка Atom
+type Link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+// Entry - запись ленты: книга или ссылка на другую ленту
+type Entry struct {
+	ID         string   `xml:"id"`
+	Title      string   `xml:"title"`
+	Updated    string   `xml:"updated"`
+	Authors    []Person `xml:"author"`
+	Issued     string   `xml:"dc:issued,omitempty"`
+	Identifier string   `xml:"dc:identifier,omitempty"`
+	Publisher  string   `xml:"dc:publisher,omitempty"`
+	Language   string   `xml:"dc:language,omitempty"`
+	Content    *Content `xml:"content,omitempty"`
+	Links      []Link   `xml:"link"`
+}
+
+// Content - текстовое описание записи
+type Content struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// NewFeed создает пустую ленту
+func NewFeed(id, title string, updated time.Time) *Feed {
+	return &Feed{
+		Xmlns:           "http://www.w3.org/2005/Atom",
+		XmlnsDC:         "http://purl.org/dc/terms/",
+		XmlnsOpenSearch: "http://a9.com/-/spec/opensearch/1.1/",
+		ID:              id,
+		Title:           title,
+		Updated:         formatTime(updated),
+	}
+}
+
+// AddLink добавляет ссылку ленты
+func (f *Feed) AddLink(rel, href, typ string) {
+	f.Links = append(f.Links, Link{Rel: rel, Href: href, Type: typ})
+}
+
+// AddPaging добавляет ссылки first, previous, next и last и сведения
+// OpenSearch о странице page из total результатов. href строит адрес
+// страницы с указанным номером.
+func (f *Feed) AddPaging(page, pageSize, total int, href func(page int) string, typ string) {
+	f.TotalResults = total
+	f.ItemsPerPage = pageSize
+	f.StartIndex = (page-1)*pageSize + 1
+
+	lastPage := (total + pageSize - 1) / pageSize
+	f.AddLink(RelFirst, href(1), typ)
+	if page > 1 {
+		f.AddLink(RelPrevious, href(min(page-1, max(lastPage, 1))), typ)
+	}
+	if page < lastPage {
+		f.AddLink(RelNext, href(page+1), typ)
+	}
+	if lastPage > 1 {
+		f.AddLink(RelLast, href(lastPage), typ)
+	}
+}
+
+// Write записывает ленту в w
+func (f *Feed) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write opds feed: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return fmt.Errorf("failed to encode opds feed: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// NavigationEntry создает запись, ведущую к другой ленте
+func NavigationEntry(id, title, summary, href, typ string, updated time.Time) Entry {
+	return Entry{
+		ID:      id,
+		Title:   title,
+		Updated: formatTime(updated),
+		Content: &Content{Type: "text", Value: summary},
+		Links:   []Link{{Rel: RelSubsection, Href: href, Type: typ}},
+	}
+}
+
+// BookEntry создает запись о книге. bookURL - адрес карточки книги в API,
+// к нему добавляются ссылки на форматы выгрузки.
+func BookEntry(book *models.Book, bookURL string) Entry {
+	entry := Entry{
+		ID:        "urn:gobookshelf:book:" + strconv.FormatInt(book.ID, 10),
+		Title:     book.Title,
+		Updated:   formatTime(book.UpdatedAt),
+		Publisher: book.Publisher,
+		Language:  book.Language,
+		Links: []Link{
+			{Rel: RelAlternate, Href: bookURL, Type: "application/json", Title: "JSON"},
+			{Rel: RelAlternate, Href: bookURL + "?format=marcxml", Type: "application/marcxml+xml", Title: "MARCXML"},
+			{Rel: RelAlternate, Href: bookURL + "?format=bibtex", Type: "application/x-bibtex", Title: "BibTeX"},
+		},
+	}
+	for _, name := range book.ContributorNames(models.RoleAuthor) {
+		entry.Authors = append(entry.Authors, Person{Name: name})
+	}
+
+	var summary []string
+	if book.Author != "" {
+		summary = append(summary, book.Author)
+	}
+	if !book.Published.IsZero() {
+		entry.Issued = strconv.Itoa(book.Published.Year())
+		summary = append(summary, entry.Issued)
+	}
+	if isbn := strings.ReplaceAll(book.ISBN, "-", ""); isbn != "" {
+		entry.Identifier = "urn:isbn:" + isbn
+		summary = append(summary, "ISBN "+book.ISBN)
+	}
+	entry.Content = &Content{Type: "text", Value: strings.Join(summary, ", ")}
+	return entry
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// OpenSearchDescription - описание поиска OpenSearch 1.1
+type OpenSearchDescription struct {
+	XMLName     xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns       string          `xml:"xmlns,attr"`
+	ShortName   string          `xml:"ShortName"`
+	Description string          `xml:"Description"`
+	InputEncode string          `xml:"InputEncoding"`
+	URLs        []OpenSearchURL `xml:"Url"`
+}
+
+// OpenSearchURL - шаблон адреса поиска, {searchTerms} заменяется запросом
+type OpenSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// NewOpenSearchDescription создает описание поиска по шаблону адреса template
+func NewOpenSearchDescription(name, description, template string) *OpenSearchDescription {
+	return &OpenSearchDescription{
+		Xmlns:       "http://a9.com/-/spec/opensearch/1.1/",
+		ShortName:   name,
+		Description: description,
+		InputEncode: "UTF-8",
+		URLs:        []OpenSearchURL{{Type: AcquisitionType, Template: template}},
+	}
+}
+
+// Write записывает описание поиска в w
+func (d *OpenSearchDescription) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write opensearch description: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return fmt.Errorf("failed to encode opensearch description: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,68 @@
+package opds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func TestFeedWrite(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	feed := NewFeed("urn:test", "Книги", updated)
+	feed.AddLink(RelSelf, "/opds/new?page=2", AcquisitionType)
+	feed.AddPaging(2, 10, 25, func(page int) string { return fmt.Sprintf("/opds/new?page=%d", page) }, AcquisitionType)
+	feed.Entries = append(feed.Entries, BookEntry(&models.Book{
+		ID: 7, Title: "Война и мир", Author: "Лев Толстой", ISBN: "978-5-17-090335-2",
+		Published: time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC), UpdatedAt: updated,
+		Publisher: "АСТ", Language: "ru",
+	}, "/api/books/7"))
+
+	var buf bytes.Buffer
+	if err := feed.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/terms/" xmlns:opensearch="http://a9.com/-/spec/opensearch/1.1/">`,
+		`<opensearch:totalResults>25</opensearch:totalResults>`,
+		`<opensearch:startIndex>11</opensearch:startIndex>`,
+		`<link rel="previous" href="/opds/new?page=1"`,
+		`<link rel="next" href="/opds/new?page=3"`,
+		`<link rel="last" href="/opds/new?page=3"`,
+		`<id>urn:gobookshelf:book:7</id>`,
+		`<dc:identifier>urn:isbn:9785170903352</dc:identifier>`,
+		`<dc:issued>1869</dc:issued>`,
+		`<dc:publisher>АСТ</dc:publisher>`,
+		`<dc:language>ru</dc:language>`,
+		`<updated>2024-05-01T12:00:00Z</updated>`,
+		`<author>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feed does not contain %s:\n%s", want, out)
+		}
+	}
+
+	// Лента должна разбираться как обычный XML
+	var parsed struct {
+		Entries []struct {
+			Title string `xml:"title"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &parsed); err != nil || len(parsed.Entries) != 1 || parsed.Entries[0].Title != "Война и мир" {
+		t.Errorf("xml.Unmarshal() = %+v, %v", parsed, err)
+	}
+}
+
+func TestAddPagingFirstPage(t *testing.T) {
+	feed := NewFeed("urn:test", "Книги", time.Now())
+	feed.AddPaging(1, 10, 5, func(page int) string { return fmt.Sprint(page) }, AcquisitionType)
+	if len(feed.Links) != 1 || feed.Links[0].Rel != RelFirst {
+		t.Errorf("AddPaging() links = %+v, want only first", feed.Links)
+	}
+}
@@ -0,0 +1,382 @@
+// Package patch применяет к JSON-документам частичные изменения в форматах
+// JSON Merge Patch (RFC 7396) и JSON Patch (RFC 6902).
+//
+// Документ и патч передаются в виде JSON, результат также возвращается
+// в виде JSON. Числа сохраняются без потери точности.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrTestFailed возвращается, если операция test из JSON Patch не прошла.
+// В этом случае документ не изменяется.
+var ErrTestFailed = errors.New("patch test operation failed")
+
+// Error описывает некорректный патч или операцию, которую нельзя применить
+// к документу
+type Error struct {
+	// Op - номер операции JSON Patch (с 0) или -1 для Merge Patch
+	Op      int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Op < 0 {
+		return fmt.Sprintf("invalid merge patch: %s", e.Message)
+	}
+	return fmt.Sprintf("invalid json patch operation %d: %s", e.Op, e.Message)
+}
+
+// Merge применяет JSON Merge Patch: объекты объединяются рекурсивно,
+// null удаляет поле, любое другое значение заменяет его целиком
+func Merge(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	value, err := decode(patch)
+	if err != nil {
+		return nil, &Error{Op: -1, Message: err.Error()}
+	}
+
+	return json.Marshal(mergeValue(target, value))
+}
+
+func mergeValue(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergeValue(targetObject[name], value)
+	}
+	return targetObject
+}
+
+// operation - операция JSON Patch
+type operation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// Apply применяет JSON Patch: операции add, remove, replace, move, copy и
+// test выполняются по порядку. Если хотя бы одна операция не применилась,
+// возвращается ошибка и документ не изменяется.
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	var ops []operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, &Error{Op: -1, Message: "json patch must be an array of operations"}
+	}
+
+	for i, op := range ops {
+		target, err = applyOperation(target, op)
+		if err != nil {
+			if errors.Is(err, ErrTestFailed) {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			return nil, &Error{Op: i, Message: err.Error()}
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func applyOperation(doc any, op operation) (any, error) {
+	if op.Path == nil {
+		return nil, errors.New(`missing "path"`)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf(`missing "value" for %s`, op.Op)
+		}
+		value, err := decode(*op.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if _, err := get(doc, path); err != nil {
+				return nil, err
+			}
+			if len(path) == 0 {
+				return value, nil
+			}
+			if doc, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, fmt.Errorf("%w: value at %q differs", ErrTestFailed, *op.Path)
+			}
+			return doc, nil
+		}
+
+	case "remove":
+		return remove(doc, path)
+
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf(`missing "from" for %s`, op.Op)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, errors.New("cannot move a value into one of its children")
+			}
+			if doc, err = remove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			// Копия не должна разделять вложенные объекты с исходным значением
+			data, _ := json.Marshal(value)
+			value, _ = decode(data)
+		}
+		return add(doc, path, value)
+	}
+
+	return nil, fmt.Errorf("unknown op %q", op.Op)
+}
+
+// parsePointer разбирает JSON Pointer (RFC 6901) на сегменты
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must start with /", pointer)
+	}
+
+	parts := strings.Split(pointer[1:], "/")
+	for i, part := range parts {
+		parts[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+	}
+	return parts, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, part := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[part]
+			if !ok {
+				return nil, fmt.Errorf("path member %q not found", part)
+			}
+			doc = value
+		case []any:
+			index, err := arrayIndex(part, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("cannot traverse %q in a scalar value", part)
+		}
+	}
+	return doc, nil
+}
+
+// add вставляет value по пути path и возвращает измененный документ
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+		return doc, nil
+	case []any:
+		index := len(node)
+		if last != "-" {
+			if index, err = arrayIndex(last, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		node = append(node, nil)
+		copy(node[index+1:], node[index:])
+		node[index] = value
+		return replaceChild(doc, path[:len(path)-1], node)
+	}
+	return nil, fmt.Errorf("cannot add %q to a scalar value", last)
+}
+
+// remove удаляет значение по пути path и возвращает измененный документ
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		if _, ok := node[last]; !ok {
+			return nil, fmt.Errorf("path member %q not found", last)
+		}
+		delete(node, last)
+		return doc, nil
+	case []any:
+		index, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node[:index:index], node[index+1:]...)
+		return replaceChild(doc, path[:len(path)-1], node)
+	}
+	return nil, fmt.Errorf("cannot remove %q from a scalar value", last)
+}
+
+// replaceChild подменяет массив по пути path: после вставки или удаления
+// элемента срез может указывать на новый массив
+func replaceChild(doc any, path []string, value []any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+	case []any:
+		index, _ := strconv.Atoi(last)
+		node[index] = value
+	}
+	return doc, nil
+}
+
+// arrayIndex разбирает индекс элемента массива, не превышающий max
+func arrayIndex(part string, max int) (int, error) {
+	if part == "" || (len(part) > 1 && part[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", part)
+	}
+	index, err := strconv.Atoi(part)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %q", part)
+	}
+	if index > max {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equal сравнивает значения JSON; числа сравниваются по значению
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, errA := strconv.ParseFloat(string(a), 64)
+		y, errB := strconv.ParseFloat(string(b), 64)
+		return errA == nil && errB == nil && x == y
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, value := range a {
+			other, ok := b[name]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// decode разбирает JSON, сохраняя числа в виде json.Number
+func decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return value, nil
+}
@@ -0,0 +1,218 @@
+// Package search реализует язык запросов для поиска книг.
+//
+// Запрос состоит из слов, фраз в кавычках и условий по полям:
+//
+//	author:"Толстой" published:>=1860 title:war -isbn:978-5*
+//
+// Поля issn, lccn, oclc, doi, asin и barcode ищут книгу по идентификатору
+// соответствующего типа, поля translator, editor и illustrator - по именам
+// участников с этой ролью. Поле tag ищет книги с тегом, shelf - книги
+// на полке с указанным ID, series - книги серии по словам ее названия.
+//
+// Условия объединяются через AND (по умолчанию), OR и NOT (или "-" перед
+// условием), порядок задается скобками. Разобранный запрос (AST) можно
+// скомпилировать в параметризованное условие WHERE или проверить на книге
+// в памяти.
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// Field - поле книги, по которому выполняется поиск
+type Field string
+
+const (
+	// FieldAny означает поиск по названию, автору и ISBN одновременно
+	FieldAny       Field = ""
+	FieldTitle     Field = "title"
+	FieldAuthor    Field = "author"
+	FieldISBN      Field = "isbn"
+	FieldPublished Field = "published"
+	FieldTag       Field = "tag"
+	FieldShelf     Field = "shelf"
+	FieldSeries    Field = "series"
+)
+
+// knownFields содержит поля, допустимые в запросе. Поля идентификаторов
+// и участников называются так же, как их типы и роли, и добавляются в init.
+var knownFields = map[string]Field{
+	"title":     FieldTitle,
+	"author":    FieldAuthor,
+	"isbn":      FieldISBN,
+	"published": FieldPublished,
+	"tag":       FieldTag,
+	"shelf":     FieldShelf,
+	"series":    FieldSeries,
+}
+
+// fieldNames перечисляет допустимые поля для сообщений об ошибках
+var fieldNames = "title, author, isbn, published, tag, shelf, series, " + strings.Join(models.IdentifierTypes, ", ") +
+	", " + strings.Join(models.ContributorRoles[1:], ", ")
+
+func init() {
+	for _, typ := range models.IdentifierTypes {
+		knownFields[typ] = Field(typ)
+	}
+	// Авторы ищутся по полю author, в котором перечислены их имена
+	for _, role := range models.ContributorRoles {
+		if role != models.RoleAuthor {
+			knownFields[role] = Field(role)
+		}
+	}
+}
+
+// isIdentifier проверяет, ищет ли поле по идентификатору книги
+func (f Field) isIdentifier() bool {
+	return models.IsIdentifierType(string(f))
+}
+
+// isContributor проверяет, ищет ли поле по участникам книги с ролью,
+// совпадающей с именем поля
+func (f Field) isContributor() bool {
+	return f != FieldAuthor && models.IsContributorRole(string(f))
+}
+
+// Op - оператор сравнения для поля published
+type Op string
+
+const (
+	OpEq Op = "="
+	OpGt Op = ">"
+	OpGe Op = ">="
+	OpLt Op = "<"
+	OpLe Op = "<="
+)
+
+// Node - узел дерева запроса
+type Node interface {
+	fmt.Stringer
+	node()
+}
+
+// And истинен, если истинны все вложенные условия
+type And struct {
+	Nodes []Node
+}
+
+// Or истинен, если истинно хотя бы одно вложенное условие
+type Or struct {
+	Nodes []Node
+}
+
+// Not инвертирует вложенное условие
+type Not struct {
+	Node Node
+}
+
+// Term - элементарное условие поиска
+type Term struct {
+	Field Field
+	// Value - искомое значение без кавычек и завершающей звездочки
+	Value string
+	// Phrase - значение было в кавычках и ищется как фраза целиком
+	Phrase bool
+	// Prefix - значение заканчивалось на * и ищется по префиксу
+	Prefix bool
+	// Op, From и To заполняются только для поля published.
+	// Дата из запроса задает полуинтервал [From, To): год, месяц или день.
+	Op   Op
+	From time.Time
+	To   time.Time
+	// Pos - позиция условия в исходном запросе
+	Pos int
+}
+
+func (And) node()   {}
+func (Or) node()    {}
+func (Not) node()   {}
+func (*Term) node() {}
+
+func (n And) String() string {
+	return joinNodes(n.Nodes, " AND ")
+}
+
+func (n Or) String() string {
+	return joinNodes(n.Nodes, " OR ")
+}
+
+func (n Not) String() string {
+	return "NOT " + n.Node.String()
+}
+
+func (t *Term) String() string {
+	value := t.Value
+	if t.Phrase {
+		value = fmt.Sprintf("%q", value)
+	}
+	if t.Prefix {
+		value += "*"
+	}
+	if t.Field == FieldPublished && t.Op != OpEq {
+		value = string(t.Op) + value
+	}
+	if t.Field != FieldAny {
+		value = string(t.Field) + ":" + value
+	}
+	return value
+}
+
+func joinNodes(nodes []Node, sep string) string {
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		parts = append(parts, n.String())
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+// PlainWords возвращает слова запроса, если он состоит только из слов без
+// полей, фраз, OR и NOT. Такой запрос можно выполнить обычным полнотекстовым
+// поиском с ранжированием.
+func PlainWords(n Node) ([]string, bool) {
+	switch n := n.(type) {
+	case *Term:
+		if n.Field != FieldAny || n.Phrase {
+			return nil, false
+		}
+		return []string{n.Value}, true
+	case And:
+		var words []string
+		for _, child := range n.Nodes {
+			w, ok := PlainWords(child)
+			if !ok {
+				return nil, false
+			}
+			words = append(words, w...)
+		}
+		return words, true
+	}
+	return nil, false
+}
+
+// HighlightTerms возвращает значения условий по тексту, которые стоит
+// подсветить в результатах (условия под NOT не учитываются)
+func HighlightTerms(n Node) []string {
+	switch n := n.(type) {
+	case *Term:
+		if n.Field == FieldAny || n.Field == FieldTitle || n.Field == FieldAuthor {
+			return []string{n.Value}
+		}
+	case And:
+		return collectHighlightTerms(n.Nodes)
+	case Or:
+		return collectHighlightTerms(n.Nodes)
+	}
+	return nil
+}
+
+func collectHighlightTerms(nodes []Node) []string {
+	var terms []string
+	for _, child := range nodes {
+		terms = append(terms, HighlightTerms(child)...)
+	}
+	return terms
+}
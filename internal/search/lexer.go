@@ -0,0 +1,112 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokColon
+	tokOp
+	tokLParen
+	tokRParen
+	tokMinus
+)
+
+// token - лексема запроса. Pos - позиция первого символа (в символах, с 1)
+type token struct {
+	kind  tokenKind
+	text  string
+	value string
+	pos   int
+}
+
+// isKeyword проверяет, является ли лексема ключевым словом AND, OR или NOT.
+// Ключевые слова распознаются только в верхнем регистре.
+func (t token) isKeyword(keyword string) bool {
+	return t.kind == tokWord && t.text == keyword
+}
+
+// lex разбивает запрос на лексемы
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+
+	prevKind := tokEOF
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: pos})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: pos})
+			i++
+
+		case r == ':':
+			tokens = append(tokens, token{kind: tokColon, text: ":", pos: pos})
+			i++
+
+		case r == '"':
+			var value strings.Builder
+			j := i + 1
+			closed := false
+			for j < len(runes) {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					value.WriteRune(runes[j+1])
+					j += 2
+					continue
+				}
+				if runes[j] == '"' {
+					closed = true
+					break
+				}
+				value.WriteRune(runes[j])
+				j++
+			}
+			if !closed {
+				return nil, &SyntaxError{Pos: pos, Token: string(runes[i:]), Message: "не закрыта кавычка"}
+			}
+			tokens = append(tokens, token{kind: tokString, text: string(runes[i : j+1]), value: value.String(), pos: pos})
+			i = j + 1
+
+		case r == '-' && prevKind != tokColon && prevKind != tokOp && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			tokens = append(tokens, token{kind: tokMinus, text: "-", pos: pos})
+			i++
+
+		case (r == '>' || r == '<' || r == '=') && prevKind == tokColon:
+			op := string(r)
+			if r != '=' && i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: pos})
+			i += len(op)
+
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune(`():"`, runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			tokens = append(tokens, token{kind: tokWord, text: word, value: word, pos: pos})
+			i = j
+		}
+
+		prevKind = tokens[len(tokens)-1].kind
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(runes) + 1})
+	return tokens, nil
+}
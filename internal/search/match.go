@@ -0,0 +1,121 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// Match проверяет, подходит ли книга под запрос.
+// Используется хранилищами без SQL, поэтому слова ищутся как подстроки
+// без учета регистра.
+func Match(n Node, book *models.Book) bool {
+	switch n := n.(type) {
+	case And:
+		for _, child := range n.Nodes {
+			if !Match(child, book) {
+				return false
+			}
+		}
+		return true
+	case Or:
+		for _, child := range n.Nodes {
+			if Match(child, book) {
+				return true
+			}
+		}
+		return false
+	case Not:
+		return !Match(n.Node, book)
+	case *Term:
+		return matchTerm(n, book)
+	}
+	panic(fmt.Sprintf("search: unknown node type %T", n))
+}
+
+func matchTerm(t *Term, book *models.Book) bool {
+	isbn := ISBNDigits(book.ISBN)
+
+	switch t.Field {
+	case FieldPublished:
+		switch t.Op {
+		case OpGt:
+			return !book.Published.Before(t.To)
+		case OpGe:
+			return !book.Published.Before(t.From)
+		case OpLt:
+			return book.Published.Before(t.From)
+		case OpLe:
+			return book.Published.Before(t.To)
+		}
+		return !book.Published.Before(t.From) && book.Published.Before(t.To)
+
+	case FieldISBN:
+		if t.Prefix {
+			return strings.HasPrefix(isbn, t.Value)
+		}
+		return isbn == t.Value
+
+	case FieldTag:
+		return slices.ContainsFunc(book.Tags, func(tag string) bool {
+			return tag == t.Value || t.Prefix && strings.HasPrefix(tag, t.Value)
+		})
+
+	case FieldShelf:
+		id, _ := strconv.ParseInt(t.Value, 10, 64)
+		return slices.Contains(book.Shelves, id)
+	}
+
+	if t.Field.isIdentifier() {
+		for _, id := range book.Identifiers {
+			value := strings.ToLower(id.Value)
+			if id.Type == string(t.Field) && (value == t.Value || t.Prefix && strings.HasPrefix(value, t.Value)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var haystacks []string
+	switch {
+	case t.Field == FieldTitle:
+		haystacks = []string{book.Title}
+	case t.Field == FieldAuthor:
+		haystacks = []string{book.Author}
+	case t.Field.isContributor():
+		haystacks = book.ContributorNames(string(t.Field))
+	case t.Field == FieldSeries:
+		haystacks = []string{book.Series}
+	default:
+		haystacks = []string{book.Title, book.Author, isbn}
+		for _, id := range book.Identifiers {
+			haystacks = append(haystacks, strings.ReplaceAll(id.Value, "-", ""))
+		}
+	}
+
+	patterns := []string{t.Value}
+	if !t.Phrase {
+		patterns = textWords(t.Value)
+	}
+
+	for _, pattern := range patterns {
+		if !containsAny(haystacks, pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAny проверяет вхождение подстроки хотя бы в одну из строк без учета регистра
+func containsAny(haystacks []string, needle string) bool {
+	needle = strings.ToLower(needle)
+	for _, h := range haystacks {
+		if strings.Contains(strings.ToLower(h), needle) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// SyntaxError описывает ошибку в запросе и указывает на лексему, в которой она найдена
+type SyntaxError struct {
+	// Pos - позиция лексемы в запросе (в символах, начиная с 1)
+	Pos     int
+	Token   string
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("ошибка в запросе на позиции %d: %s", e.Pos, e.Message)
+	}
+	return fmt.Sprintf("ошибка в запросе на позиции %d (%q): %s", e.Pos, e.Token, e.Message)
+}
+
+// Parse разбирает запрос и возвращает его дерево
+func Parse(query string) (Node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, &SyntaxError{Pos: 1, Message: "пустой запрос"}
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorAt(tok, "неожиданная лексема")
+	}
+
+	return node, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorAt(tok token, message string) error {
+	return &SyntaxError{Pos: tok.pos, Token: tok.text, Message: message}
+}
+
+// parseOr: and ("OR" and)*
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Node{left}
+	for p.peek().isKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, right)
+	}
+
+	if len(nodes) == 1 {
+		return left, nil
+	}
+	return Or{Nodes: nodes}, nil
+}
+
+// parseAnd: unary (["AND"] unary)*
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Node{left}
+	for {
+		tok := p.peek()
+		if tok.isKeyword("AND") {
+			p.next()
+		} else if !p.startsOperand(tok) {
+			break
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, right)
+	}
+
+	if len(nodes) == 1 {
+		return left, nil
+	}
+	return And{Nodes: nodes}, nil
+}
+
+// startsOperand проверяет, может ли с лексемы начинаться следующее условие
+// (для неявного AND между условиями)
+func (p *parser) startsOperand(tok token) bool {
+	switch tok.kind {
+	case tokWord:
+		return !tok.isKeyword("OR")
+	case tokString, tokMinus, tokLParen:
+		return true
+	}
+	return false
+}
+
+// parseUnary: ("NOT" | "-") unary | primary
+func (p *parser) parseUnary() (Node, error) {
+	tok := p.peek()
+	if tok.isKeyword("NOT") || tok.kind == tokMinus {
+		p.next()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Node: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary: "(" or ")" | field ":" [op] value | value
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, p.errorAt(tok, "не закрыта скобка")
+		}
+		p.next()
+		return node, nil
+
+	case tokWord:
+		if tok.isKeyword("AND") || tok.isKeyword("OR") || tok.isKeyword("NOT") {
+			return nil, p.errorAt(tok, "ожидалось условие поиска")
+		}
+		if p.peek().kind == tokColon {
+			p.next()
+			return p.parseFieldTerm(tok)
+		}
+		return newTextTerm(FieldAny, tok)
+
+	case tokString:
+		return newTextTerm(FieldAny, tok)
+
+	case tokEOF:
+		return nil, p.errorAt(tok, "неожиданный конец запроса")
+	}
+
+	return nil, p.errorAt(tok, "ожидалось условие поиска")
+}
+
+// parseFieldTerm разбирает значение после "поле:"
+func (p *parser) parseFieldTerm(fieldTok token) (Node, error) {
+	field, ok := knownFields[strings.ToLower(fieldTok.text)]
+	if !ok {
+		return nil, p.errorAt(fieldTok, "неизвестное поле, допустимы "+fieldNames)
+	}
+
+	op := OpEq
+	if tok := p.peek(); tok.kind == tokOp {
+		p.next()
+		if field != FieldPublished {
+			return nil, p.errorAt(tok, "операторы сравнения допустимы только для поля published")
+		}
+		op = Op(tok.text)
+	}
+
+	tok := p.next()
+	if tok.kind != tokWord && tok.kind != tokString {
+		return nil, p.errorAt(tok, fmt.Sprintf("ожидалось значение поля %s", field))
+	}
+
+	if field == FieldPublished {
+		return newDateTerm(op, tok, fieldTok.pos)
+	}
+
+	term, err := newTextTerm(field, tok)
+	if err != nil {
+		return nil, err
+	}
+	term.Pos = fieldTok.pos
+	return term, nil
+}
+
+// newTextTerm создает условие по текстовому полю из слова или фразы
+func newTextTerm(field Field, tok token) (*Term, error) {
+	term := &Term{Field: field, Value: tok.value, Phrase: tok.kind == tokString, Pos: tok.pos}
+
+	if !term.Phrase {
+		if strings.HasSuffix(term.Value, "*") {
+			term.Prefix = true
+			term.Value = strings.TrimSuffix(term.Value, "*")
+		}
+		if strings.Contains(term.Value, "*") {
+			return nil, &SyntaxError{Pos: tok.pos, Token: tok.text, Message: "звездочка допустима только в конце слова"}
+		}
+	}
+
+	if !strings.ContainsFunc(term.Value, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsNumber(r)
+	}) {
+		return nil, &SyntaxError{Pos: tok.pos, Token: tok.text, Message: "значение не содержит букв или цифр"}
+	}
+
+	if field == FieldISBN {
+		term.Value = ISBNDigits(term.Value)
+		if term.Value == "" {
+			return nil, &SyntaxError{Pos: tok.pos, Token: tok.text, Message: "ISBN должен содержать цифры"}
+		}
+		if !term.Prefix {
+			term.Value = isbn10To13(term.Value)
+		}
+	}
+
+	switch field {
+	case FieldTag:
+		term.Value = models.NormalizeTag(term.Value)
+	case FieldShelf:
+		if id, err := strconv.ParseInt(term.Value, 10, 64); err != nil || id < 1 || term.Prefix {
+			return nil, &SyntaxError{Pos: tok.pos, Token: tok.text, Message: "ожидался ID полки"}
+		}
+	}
+
+	// Идентификаторы сравниваются без учета регистра; полное значение
+	// приводится к виду, в котором оно хранится
+	if field.isIdentifier() {
+		if value, ok := models.NormalizeIdentifier(string(field), term.Value); ok && !term.Prefix {
+			term.Value = value
+		}
+		term.Value = strings.ToLower(term.Value)
+	}
+
+	return term, nil
+}
+
+// Форматы дат, допустимые в условии published
+var dateLayouts = []struct {
+	layout string
+	next   func(time.Time) time.Time
+}{
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// newDateTerm создает условие по дате публикации
+func newDateTerm(op Op, tok token, pos int) (*Term, error) {
+	for _, d := range dateLayouts {
+		from, err := time.Parse(d.layout, tok.value)
+		if err != nil {
+			continue
+		}
+		return &Term{
+			Field: FieldPublished,
+			Value: tok.value,
+			Op:    op,
+			From:  from,
+			To:    d.next(from),
+			Pos:   pos,
+		}, nil
+	}
+
+	return nil, &SyntaxError{Pos: tok.pos, Token: tok.text, Message: "ожидалась дата в формате ГГГГ, ГГГГ-ММ или ГГГГ-ММ-ДД"}
+}
+
+// isbn10To13 заменяет полный ISBN-10 на ISBN-13, под которым книга
+// хранится. Остальные значения возвращаются без изменений.
+func isbn10To13(digits string) string {
+	if isbn13, ok := models.ISBN10To13(digits); ok {
+		return isbn13
+	}
+	return digits
+}
+
+// ISBNDigits оставляет в ISBN только цифры и контрольный символ X (в нижнем регистре)
+func ISBNDigits(isbn string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9':
+			return r
+		case r == 'X' || r == 'x':
+			return 'x'
+		}
+		return -1
+	}, isbn)
+}
@@ -0,0 +1,234 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`war`, `war`},
+		{`war peace`, `(war AND peace)`},
+		{`war AND peace OR anna`, `((war AND peace) OR anna)`},
+		{`war AND (peace OR anna)`, `(war AND (peace OR anna))`},
+		{`author:"Толстой" published:>=1860 title:war -isbn:978-5*`,
+			`(author:"Толстой" AND published:>=1860 AND title:war AND NOT isbn:9785*)`},
+		{`NOT title:war`, `NOT title:war`},
+		{`AUTHOR:tolstoy`, `author:tolstoy`},
+		{`Jean-Paul`, `Jean-Paul`},
+		{`published:1869-03`, `published:1869-03`},
+		{`isbn:0-8044-2957-x`, `isbn:9780804429573`},
+		{`isbn:0804*`, `isbn:0804*`},
+		{`issn:00280836`, `issn:0028-0836`},
+		{`doi:"https://doi.org/10.1000/ABC"`, `doi:"10.1000/abc"`},
+		{`asin:b000*`, `asin:b000*`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			node, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := node.String(); got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantPos   int
+		wantToken string
+	}{
+		{`genre:poetry`, 1, "genre"},
+		{`title:war (peace`, 11, "("},
+		{`title:war)`, 10, ")"},
+		{`title:"war`, 7, `"war`},
+		{`published:>=next-year`, 13, "next-year"},
+		{`title:>war`, 7, ">"},
+		{`war AND`, 8, ""},
+		{`war OR OR peace`, 8, "OR"},
+		{`ti*tle`, 1, "ti*tle"},
+		{`title:`, 7, ""},
+		{`shelf:favorites`, 7, "favorites"},
+		{`shelf:1*`, 7, "1*"},
+		{`   `, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse() error = %v, want *SyntaxError", err)
+			}
+			if syntaxErr.Pos != tt.wantPos || syntaxErr.Token != tt.wantToken {
+				t.Errorf("Parse() error at %d %q, want %d %q (%v)", syntaxErr.Pos, syntaxErr.Token, tt.wantPos, tt.wantToken, err)
+			}
+		})
+	}
+}
+
+func TestToSQL(t *testing.T) {
+	node, err := Parse(`author:"Лев Толстой" published:>=1860 -isbn:978-5*`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	where, args := ToSQL(node, SQLOptions{Like: "ILIKE"})
+	wantWhere := `((author ILIKE ? ESCAPE '\') AND (published >= ?) AND NOT (lower(replace(isbn, '-', '')) LIKE ? ESCAPE '\'))`
+	if where != wantWhere {
+		t.Errorf("ToSQL() where = %s, want %s", where, wantWhere)
+	}
+	if len(args) != 3 || args[0] != "%Лев Толстой%" || args[2] != "9785%" {
+		t.Errorf("ToSQL() args = %v", args)
+	}
+	if published, ok := args[1].(time.Time); !ok || published.Year() != 1860 {
+		t.Errorf("ToSQL() published arg = %v, want 1860-01-01", args[1])
+	}
+	if strings.Count(where, "?") != len(args) {
+		t.Errorf("ToSQL() placeholders = %d, args = %d", strings.Count(where, "?"), len(args))
+	}
+
+	where, args = ToSQL(node, SQLOptions{Like: "LIKE", FullTextTable: "books_fts"})
+	if !strings.Contains(where, "books_fts.author MATCH ?") || args[0] != `"Лев Толстой"` {
+		t.Errorf("ToSQL() with full text = %s %v", where, args)
+	}
+
+	node, err = Parse(`doi:10.1000/182 OR 0028-0836`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	where, args = ToSQL(node, SQLOptions{Like: "ILIKE"})
+	if !strings.Contains(where, "type = ? AND lower(value) = ?") || !strings.Contains(where, "replace(value, '-', '') ILIKE ?") ||
+		len(args) != 6 || args[0] != "doi" || args[1] != "10.1000/182" || args[5] != "%00280836%" {
+		t.Errorf("ToSQL() with identifiers = %s %v", where, args)
+	}
+
+	node, err = Parse(`translator:"Борис Пастернак"`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	where, args = ToSQL(node, SQLOptions{Like: "LIKE", FullTextTable: "books_fts", Lower: "unicode_lower"})
+	if !strings.Contains(where, "bc.role = ? AND unicode_lower(a.name) LIKE ?") || strings.Contains(where, "books_fts") ||
+		len(args) != 2 || args[0] != "translator" || args[1] != "%борис пастернак%" {
+		t.Errorf("ToSQL() with translator = %s %v", where, args)
+	}
+
+	node, err = Parse(`tag:"Science Fiction" shelf:3`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	where, args = ToSQL(node, SQLOptions{Like: "LIKE"})
+	if !strings.Contains(where, "t.name = ?") || !strings.Contains(where, "shelf_id = ?") ||
+		len(args) != 2 || args[0] != "science fiction" || args[1] != int64(3) {
+		t.Errorf("ToSQL() with tag and shelf = %s %v", where, args)
+	}
+
+	node, err = Parse(`series:"Тёмная Башня"`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	where, args = ToSQL(node, SQLOptions{Like: "LIKE", Lower: "unicode_lower"})
+	if !strings.Contains(where, "unicode_lower(s.name) LIKE ?") || len(args) != 1 || args[0] != "%тёмная башня%" {
+		t.Errorf("ToSQL() with series = %s %v", where, args)
+	}
+
+	node, err = Parse(`tag:sci_* title:"100%"`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	where, args = ToSQL(node, SQLOptions{Like: "LIKE"})
+	if strings.Count(where, `ESCAPE '\'`) != 2 || len(args) != 2 || args[0] != `sci\_%` || args[1] != `%100\%%` {
+		t.Errorf("ToSQL() with LIKE wildcards = %s %v", where, args)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got := EscapeLike(`50%_off\`); got != `50\%\_off\\` {
+		t.Errorf("EscapeLike() = %s", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	book := &models.Book{
+		Title:     "Война и мир",
+		Author:    "Лев Толстой",
+		ISBN:      "978-5-17-090335-2",
+		Published: time.Date(1869, 3, 1, 0, 0, 0, 0, time.UTC),
+		Identifiers: []models.Identifier{
+			{Type: models.IdentifierLCCN, Value: "n79021164"},
+			{Type: models.IdentifierDOI, Value: "10.1000/war-and-peace"},
+		},
+		Contributors: []models.Contributor{
+			{Name: "Лев Толстой", Role: models.RoleAuthor},
+			{Name: "Aylmer Maude", Role: models.RoleTranslator},
+			{Name: "Louise Maude", Role: models.RoleTranslator},
+		},
+		Tags:    []string{"classics", "historical fiction"},
+		Shelves: []int64{2, 5},
+		Series:  "Собрание сочинений в 22 томах",
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{`толстой`, true},
+		{`author:толстой title:мир`, true},
+		{`author:"лев толстой"`, true},
+		{`author:"толстой лев"`, false},
+		{`title:толстой`, false},
+		{`published:1869`, true},
+		{`published:>1869`, false},
+		{`published:>=1869-03-01 published:<1870`, true},
+		{`published:<=1868`, false},
+		{`isbn:978-5*`, true},
+		{`isbn:9785170903352`, true},
+		{`isbn:978-0*`, false},
+		{`-isbn:978-5*`, false},
+		{`lccn:n79-21164`, true},
+		{`doi:10.1000/WAR*`, true},
+		{`doi:10.1000/182`, false},
+		{`issn:n79021164`, false},
+		{`translator:maude`, true},
+		{`translator:"louise maude"`, true},
+		{`translator:толстой`, false},
+		{`editor:maude`, false},
+		{`tag:Classics`, true},
+		{`tag:"historical fiction"`, true},
+		{`tag:hist*`, true},
+		{`tag:fiction`, false},
+		{`shelf:5`, true},
+		{`shelf:3`, false},
+		{`series:собрание`, true},
+		{`series:"22 томах"`, true},
+		{`series:трилогия`, false},
+		{`maude`, false},
+		{`warandpeace`, true},
+		{`достоевский OR толстой`, true},
+		{`NOT (достоевский OR толстой)`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			node, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := Match(node, book); got != tt.want {
+				t.Errorf("Match(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SQLOptions описывает особенности СУБД, под которую компилируется запрос
+type SQLOptions struct {
+	// Like - оператор регистронезависимого сравнения по шаблону (LIKE или ILIKE)
+	Like string
+	// FullTextTable - таблица FTS5 с колонками title, author, isbn и
+	// identifiers, у которой rowid совпадает с id книги. Если не задана,
+	// текст ищется через Like.
+	FullTextTable string
+	// Lower - функция перевода строки в нижний регистр с учетом Unicode,
+	// по умолчанию lower
+	Lower string
+}
+
+// ToSQL компилирует запрос в условие WHERE по таблице books.
+// Значения передаются только через плейсхолдеры ?, порядок аргументов
+// соответствует порядку плейсхолдеров.
+func ToSQL(n Node, opts SQLOptions) (string, []any) {
+	c := &compiler{opts: opts}
+	where := c.compile(n)
+	return where, c.args
+}
+
+type compiler struct {
+	opts SQLOptions
+	args []any
+}
+
+func (c *compiler) compile(n Node) string {
+	switch n := n.(type) {
+	case And:
+		return c.compileList(n.Nodes, " AND ")
+	case Or:
+		return c.compileList(n.Nodes, " OR ")
+	case Not:
+		return "NOT " + c.compile(n.Node)
+	case *Term:
+		return c.compileTerm(n)
+	}
+	panic(fmt.Sprintf("search: unknown node type %T", n))
+}
+
+func (c *compiler) compileList(nodes []Node, sep string) string {
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		parts = append(parts, c.compile(n))
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+func (c *compiler) compileTerm(t *Term) string {
+	switch t.Field {
+	case FieldPublished:
+		return c.compileDate(t)
+
+	case FieldISBN:
+		if t.Prefix {
+			c.args = append(c.args, EscapeLike(t.Value)+"%")
+			return `(lower(replace(isbn, '-', '')) LIKE ? ESCAPE '\')`
+		}
+		c.args = append(c.args, t.Value)
+		return "(lower(replace(isbn, '-', '')) = ?)"
+
+	case FieldTag:
+		if t.Prefix {
+			c.args = append(c.args, EscapeLike(t.Value)+"%")
+			return `(id IN (SELECT bt.book_id FROM book_tags bt JOIN tags t ON t.id = bt.tag_id WHERE t.name LIKE ? ESCAPE '\'))`
+		}
+		c.args = append(c.args, t.Value)
+		return "(id IN (SELECT bt.book_id FROM book_tags bt JOIN tags t ON t.id = bt.tag_id WHERE t.name = ?))"
+
+	case FieldShelf:
+		id, _ := strconv.ParseInt(t.Value, 10, 64)
+		c.args = append(c.args, id)
+		return "(id IN (SELECT book_id FROM shelf_books WHERE shelf_id = ?))"
+	}
+
+	if t.Field.isIdentifier() {
+		if t.Prefix {
+			c.args = append(c.args, string(t.Field), EscapeLike(t.Value)+"%")
+			return `(id IN (SELECT book_id FROM book_identifiers WHERE type = ? AND lower(value) LIKE ? ESCAPE '\'))`
+		}
+		c.args = append(c.args, string(t.Field), t.Value)
+		return "(id IN (SELECT book_id FROM book_identifiers WHERE type = ? AND lower(value) = ?))"
+	}
+
+	if t.Field.isContributor() {
+		return c.compileNames(t,
+			`id IN (SELECT bc.book_id FROM book_contributors bc JOIN authors a ON a.id = bc.author_id WHERE bc.role = ? AND %s(a.name) LIKE ? ESCAPE '\')`,
+			string(t.Field))
+	}
+	if t.Field == FieldSeries {
+		return c.compileNames(t, `id IN (SELECT bs.book_id FROM book_series bs JOIN series s ON s.id = bs.series_id WHERE %s(s.name) LIKE ? ESCAPE '\')`)
+	}
+
+	if c.opts.FullTextTable != "" {
+		column := c.opts.FullTextTable
+		if t.Field != FieldAny {
+			column += "." + string(t.Field)
+		}
+		c.args = append(c.args, FullTextExpr(t))
+		return fmt.Sprintf("(id IN (SELECT rowid FROM %s WHERE %s MATCH ?))", c.opts.FullTextTable, column)
+	}
+
+	// Условия с единственным плейсхолдером для шаблона
+	columns := []string{fmt.Sprintf(`%s %s ? ESCAPE '\'`, t.Field, c.opts.Like)}
+	if t.Field == FieldAny {
+		columns = []string{
+			fmt.Sprintf(`title %s ? ESCAPE '\'`, c.opts.Like),
+			fmt.Sprintf(`author %s ? ESCAPE '\'`, c.opts.Like),
+			fmt.Sprintf(`replace(isbn, '-', '') %s ? ESCAPE '\'`, c.opts.Like),
+			IdentifierCondition(c.opts.Like),
+		}
+	}
+
+	patterns := []string{t.Value}
+	if !t.Phrase {
+		patterns = textWords(t.Value)
+	}
+
+	conditions := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		alternatives := make([]string, 0, len(columns))
+		for _, column := range columns {
+			alternatives = append(alternatives, column)
+			c.args = append(c.args, "%"+EscapeLike(pattern)+"%")
+		}
+		if len(alternatives) > 1 {
+			conditions = append(conditions, "("+strings.Join(alternatives, " OR ")+")")
+		} else {
+			conditions = append(conditions, alternatives[0])
+		}
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")"
+}
+
+// compileNames ищет слова условия в именах, которые выбирает подзапрос
+// condition: каждое слово должно встретиться в одном из имен. Вместо %s
+// в condition подставляется функция перевода в нижний регистр, последний
+// плейсхолдер получает шаблон слова, предыдущие - значения args.
+func (c *compiler) compileNames(t *Term, condition string, args ...any) string {
+	lower := c.opts.Lower
+	if lower == "" {
+		lower = "lower"
+	}
+
+	patterns := []string{t.Value}
+	if !t.Phrase {
+		patterns = textWords(t.Value)
+	}
+
+	conditions := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		conditions = append(conditions, fmt.Sprintf(condition, lower))
+		c.args = append(append(c.args, args...), "%"+EscapeLike(strings.ToLower(pattern))+"%")
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")"
+}
+
+func (c *compiler) compileDate(t *Term) string {
+	switch t.Op {
+	case OpGt:
+		c.args = append(c.args, t.To)
+		return "(published >= ?)"
+	case OpGe:
+		c.args = append(c.args, t.From)
+		return "(published >= ?)"
+	case OpLt:
+		c.args = append(c.args, t.From)
+		return "(published < ?)"
+	case OpLe:
+		c.args = append(c.args, t.To)
+		return "(published < ?)"
+	}
+	c.args = append(c.args, t.From, t.To)
+	return "(published >= ? AND published < ?)"
+}
+
+// IdentifierCondition возвращает условие по таблице books, истинное, если
+// один из идентификаторов книги без дефисов подходит под шаблон
+// в единственном плейсхолдере. like - оператор сравнения по шаблону,
+// шаблон экранируется через EscapeLike.
+func IdentifierCondition(like string) string {
+	return fmt.Sprintf(`id IN (SELECT book_id FROM book_identifiers WHERE replace(value, '-', '') %s ? ESCAPE '\')`, like)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// EscapeLike экранирует в s обратной косой чертой символы %, _ и \, чтобы
+// в шаблоне LIKE ... ESCAPE '\' они совпадали только сами с собой
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var isbnLikeRe = regexp.MustCompile(`^[0-9Xx\-]*[0-9][0-9Xx\-]*$`)
+
+// textWords разбивает значение условия на слова. Значение, похожее на
+// ISBN ("978-5-17"), считается одним словом из цифр, так как ISBN
+// индексируется без дефисов. Полный ISBN-10 заменяется на ISBN-13.
+func textWords(value string) []string {
+	if isbnLikeRe.MatchString(value) {
+		return []string{isbn10To13(ISBNDigits(value))}
+	}
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// FullTextExpr возвращает выражение MATCH для FTS5: фраза ищется целиком,
+// отдельные слова - по префиксу и все одновременно
+func FullTextExpr(t *Term) string {
+	words := textWords(t.Value)
+	if t.Phrase {
+		return `"` + strings.Join(words, " ") + `"`
+	}
+
+	parts := make([]string, 0, len(words))
+	for _, w := range words {
+		parts = append(parts, `"`+w+`"*`)
+	}
+	return strings.Join(parts, " ")
+}
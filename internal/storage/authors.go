@@ -0,0 +1,614 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/search"
+)
+
+// AuthorListOptions задает страницу и фильтр списка авторов
+type AuthorListOptions struct {
+	Page     int
+	PageSize int
+	// Name оставляет авторов, в имени или другом написании имени которых
+	// встречается эта строка (без учета регистра)
+	Name string
+}
+
+// authorQuery выбирает авторов вместе с количеством их книг вне корзины
+const authorQuery = `
+    SELECT a.id, a.name, a.sort_name, (
+        SELECT COUNT(*) FROM books b
+        WHERE b.deleted_at IS NULL
+          AND b.id IN (SELECT book_id FROM book_contributors WHERE author_id = a.id)
+    )
+    FROM authors a`
+
+func (d *Database) ListAuthors(ctx context.Context, opts AuthorListOptions) ([]*models.Author, int, error) {
+	var conditions []string
+	var args []any
+	if opts.Name != "" {
+		pattern := "%" + search.EscapeLike(models.NameKey(opts.Name)) + "%"
+		conditions = append(conditions, fmt.Sprintf(
+			`(%[1]s(a.name) LIKE ? ESCAPE '\' OR a.id IN (SELECT author_id FROM author_names WHERE %[1]s(name) LIKE ? ESCAPE '\'))`, d.dialect.lower))
+		args = append(args, pattern, pattern)
+	}
+
+	var total int
+	if err := d.queryRow(ctx, "SELECT COUNT(*) FROM authors a"+whereClause(conditions), args...).Scan(&total); err != nil {
+		log.Printf("Error getting total author count: %v", err)
+		return nil, 0, fmt.Errorf("failed to get total author count: %w", err)
+	}
+
+	// Авторы, созданные до появления sort_name, сортируются по имени
+	order := fmt.Sprintf(d.dialect.noCase, "CASE WHEN a.sort_name = '' THEN a.name ELSE a.sort_name END")
+	query := authorQuery + whereClause(conditions) + " ORDER BY " + order + ", a.id LIMIT ? OFFSET ?"
+	rows, err := d.query(ctx, query, append(args, opts.PageSize, (opts.Page-1)*opts.PageSize)...)
+	if err != nil {
+		log.Printf("Error querying authors: %v", err)
+		return nil, 0, fmt.Errorf("failed to query authors: %w", err)
+	}
+	authors, err := scanAuthors(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := loadAlternateNames(ctx, d, authors); err != nil {
+		return nil, 0, err
+	}
+	return authors, total, nil
+}
+
+func (d *Database) GetAuthor(ctx context.Context, id int64) (*models.Author, error) {
+	return getAuthor(ctx, d, id)
+}
+
+// getAuthor читает автора; возвращает nil без ошибки, если его нет
+func getAuthor(ctx context.Context, q querier, id int64) (*models.Author, error) {
+	rows, err := q.query(ctx, authorQuery+" WHERE a.id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get author: %w", err)
+	}
+	authors, err := scanAuthors(rows)
+	if err != nil || len(authors) == 0 {
+		return nil, err
+	}
+	if err := loadAlternateNames(ctx, q, authors); err != nil {
+		return nil, err
+	}
+	return authors[0], nil
+}
+
+// scanAuthors читает авторов из результата authorQuery и закрывает rows
+func scanAuthors(rows *sql.Rows) ([]*models.Author, error) {
+	defer rows.Close()
+
+	var authors []*models.Author
+	for rows.Next() {
+		var a models.Author
+		if err := rows.Scan(&a.ID, &a.Name, &a.SortName, &a.BookCount); err != nil {
+			return nil, fmt.Errorf("failed to scan author row: %w", err)
+		}
+		if a.SortName == "" {
+			a.SortName = models.DefaultSortName(a.Name)
+		}
+		authors = append(authors, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating author rows: %w", err)
+	}
+	return authors, nil
+}
+
+// loadAlternateNames заполняет другие написания имен авторов
+func loadAlternateNames(ctx context.Context, q querier, authors []*models.Author) error {
+	if len(authors) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*models.Author, len(authors))
+	args := make([]any, len(authors))
+	for i, a := range authors {
+		a.AlternateNames = nil
+		byID[a.ID] = a
+		args[i] = a.ID
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(authors)), ", ")
+	rows, err := q.query(ctx, fmt.Sprintf(
+		"SELECT author_id, name FROM author_names WHERE author_id IN (%s) ORDER BY author_id, position", placeholders), args...)
+	if err != nil {
+		return fmt.Errorf("failed to load author names: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return fmt.Errorf("failed to scan author name: %w", err)
+		}
+		if a, ok := byID[id]; ok {
+			a.AlternateNames = append(a.AlternateNames, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load author names: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) CreateAuthor(ctx context.Context, author *models.Author) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		if err := tx.checkAuthorNames(ctx, author); err != nil {
+			return err
+		}
+
+		err := tx.queryRow(ctx, "INSERT INTO authors (name, sort_name) VALUES (?, ?) RETURNING id",
+			author.Name, author.SortName).Scan(&author.ID)
+		if err != nil {
+			if tx.dialect.isUniqueViolation(err) {
+				return ErrDuplicateAuthorName
+			}
+			return fmt.Errorf("failed to create author: %w", err)
+		}
+		author.BookCount = 0
+
+		log.Printf("Created author %d %q", author.ID, author.Name)
+		return tx.saveAlternateNames(ctx, author)
+	})
+}
+
+func (d *Database) UpdateAuthor(ctx context.Context, author *models.Author) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		existing, err := getAuthor(ctx, tx, author.ID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return ErrAuthorNotFound
+		}
+		keepPreviousName(author, existing)
+		if err := tx.checkAuthorNames(ctx, author); err != nil {
+			return err
+		}
+
+		// Книги читаются до переименования, чтобы записать в их историю прежнее имя
+		books, err := authorBooks(ctx, tx, author.ID)
+		if err != nil {
+			return err
+		}
+
+		// Сначала освобождаются другие написания: прежнее имя может стать одним из них
+		if _, err := tx.exec(ctx, "DELETE FROM author_names WHERE author_id = ?", author.ID); err != nil {
+			return fmt.Errorf("failed to delete author names: %w", err)
+		}
+		_, err = tx.exec(ctx, "UPDATE authors SET name = ?, sort_name = ? WHERE id = ?", author.Name, author.SortName, author.ID)
+		if err != nil {
+			if tx.dialect.isUniqueViolation(err) {
+				return ErrDuplicateAuthorName
+			}
+			return fmt.Errorf("failed to update author: %w", err)
+		}
+		if err := tx.saveAlternateNames(ctx, author); err != nil {
+			return err
+		}
+		author.BookCount = existing.BookCount
+
+		if author.Name == existing.Name {
+			return nil
+		}
+		log.Printf("Renamed author %d from %q to %q", author.ID, existing.Name, author.Name)
+		return tx.relinkBooks(ctx, books, author.ID, author)
+	})
+}
+
+func (d *Database) DeleteAuthor(ctx context.Context, id int64) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		var count int
+		err := tx.queryRow(ctx, "SELECT COUNT(*) FROM book_contributors WHERE author_id = ?", id).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to count author books: %w", err)
+		}
+		if count > 0 {
+			return ErrAuthorHasBooks
+		}
+
+		if _, err := tx.exec(ctx, "DELETE FROM author_names WHERE author_id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete author names: %w", err)
+		}
+		result, err := tx.exec(ctx, "DELETE FROM authors WHERE id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete author: %w", err)
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get delete result: %w", err)
+		}
+		if deleted == 0 {
+			return ErrAuthorNotFound
+		}
+
+		log.Printf("Deleted author %d", id)
+		return nil
+	})
+}
+
+func (d *Database) MergeAuthors(ctx context.Context, sourceID, targetID int64) (*models.Author, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeSameAuthor
+	}
+	log.Printf("Attempting to merge author %d into %d", sourceID, targetID)
+
+	var merged *models.Author
+	err := d.inTx(ctx, func(tx *dbTx) error {
+		source, err := getAuthor(ctx, tx, sourceID)
+		if err != nil {
+			return err
+		}
+		target, err := getAuthor(ctx, tx, targetID)
+		if err != nil {
+			return err
+		}
+		if source == nil || target == nil {
+			return ErrAuthorNotFound
+		}
+
+		books, err := authorBooks(ctx, tx, sourceID)
+		if err != nil {
+			return err
+		}
+		if err := tx.relinkBooks(ctx, books, sourceID, target); err != nil {
+			return err
+		}
+
+		if _, err := tx.exec(ctx, "DELETE FROM author_names WHERE author_id IN (?, ?)", sourceID, targetID); err != nil {
+			return fmt.Errorf("failed to delete author names: %w", err)
+		}
+		if _, err := tx.exec(ctx, "DELETE FROM authors WHERE id = ?", sourceID); err != nil {
+			return fmt.Errorf("failed to delete merged author: %w", err)
+		}
+		target.AlternateNames = append(target.AlternateNames, source.Names()...)
+		target.Normalize()
+		if err := tx.saveAlternateNames(ctx, target); err != nil {
+			return err
+		}
+
+		merged, err = getAuthor(ctx, tx, targetID)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error merging authors: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Successfully merged author %d into %d", sourceID, targetID)
+	return merged, nil
+}
+
+// findAuthor ищет автора по имени или другому написанию имени без учета
+// регистра и возвращает его ID и основное имя. Если автора нет, ID равен 0.
+func (t *dbTx) findAuthor(ctx context.Context, name string) (int64, string, error) {
+	query := fmt.Sprintf(`
+        SELECT id, name FROM authors WHERE %[1]s(name) = ?
+        UNION ALL
+        SELECT a.id, a.name FROM author_names n JOIN authors a ON a.id = n.author_id WHERE %[1]s(n.name) = ?`,
+		t.dialect.lower)
+	key := models.NameKey(name)
+
+	var id int64
+	err := t.queryRow(ctx, query, key, key).Scan(&id, &name)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to find author: %w", err)
+	}
+	return id, name, nil
+}
+
+// checkAuthorNames проверяет, что имена автора не принадлежат другим авторам
+func (t *dbTx) checkAuthorNames(ctx context.Context, author *models.Author) error {
+	for _, name := range author.Names() {
+		id, _, err := t.findAuthor(ctx, name)
+		if err != nil {
+			return err
+		}
+		if id != 0 && id != author.ID {
+			return ErrDuplicateAuthorName
+		}
+	}
+	return nil
+}
+
+// saveAlternateNames заменяет другие написания имени автора
+func (t *dbTx) saveAlternateNames(ctx context.Context, author *models.Author) error {
+	if _, err := t.exec(ctx, "DELETE FROM author_names WHERE author_id = ?", author.ID); err != nil {
+		return fmt.Errorf("failed to delete author names: %w", err)
+	}
+	for i, name := range author.AlternateNames {
+		_, err := t.exec(ctx, "INSERT INTO author_names (author_id, position, name) VALUES (?, ?, ?)", author.ID, i, name)
+		if err != nil {
+			if t.dialect.isUniqueViolation(err) {
+				return ErrDuplicateAuthorName
+			}
+			return fmt.Errorf("failed to save author name: %w", err)
+		}
+	}
+	return nil
+}
+
+// authorBooks возвращает все книги, в которых участвует автор, включая
+// книги в корзине
+func authorBooks(ctx context.Context, q querier, authorID int64) ([]*models.Book, error) {
+	query := `
+        SELECT id, title, author, COALESCE(isbn, ''), published, created_at, updated_at, version, deleted_at
+        FROM books
+        WHERE id IN (SELECT book_id FROM book_contributors WHERE author_id = ?)
+        ORDER BY id`
+	rows, err := q.query(ctx, query, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query author books: %w", err)
+	}
+	defer rows.Close()
+
+	var books []*models.Book
+	for rows.Next() {
+		var book models.Book
+		err := rows.Scan(
+			&book.ID,
+			&book.Title,
+			&book.Author,
+			&book.ISBN,
+			&book.Published,
+			&book.CreatedAt,
+			&book.UpdatedAt,
+			&book.Version,
+			&book.DeletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		books = append(books, &book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book rows: %w", err)
+	}
+
+	if err := loadRelations(ctx, q, books); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// relinkBooks заменяет в книгах автора fromID на автора to, обновляет поле
+// author и версию книг и записывает изменение в их историю
+func (t *dbTx) relinkBooks(ctx context.Context, books []*models.Book, fromID int64, to *models.Author) error {
+	now := time.Now().UTC()
+	for _, old := range books {
+		book := relinkedBook(old, fromID, to)
+		if fromID != to.ID {
+			if err := t.saveContributors(ctx, book); err != nil {
+				return err
+			}
+		}
+
+		_, err := t.exec(ctx, "UPDATE books SET author = ?, updated_at = ?, version = version + 1 WHERE id = ?",
+			book.Author, now, book.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update author of book %d: %w", book.ID, err)
+		}
+
+		rev := &models.Revision{BookID: book.ID, Action: models.ActionUpdate, Changes: diffBooks(old, book)}
+		if len(rev.Changes) > 0 {
+			if err := t.recordRevision(ctx, rev); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// relinkedBook возвращает копию книги, в которой автор fromID заменен
+// автором to
+func relinkedBook(old *models.Book, fromID int64, to *models.Author) *models.Book {
+	book := *old
+	book.Contributors = slices.Clone(old.Contributors)
+	for i, c := range book.Contributors {
+		if c.AuthorID == fromID {
+			book.Contributors[i].AuthorID = to.ID
+			book.Contributors[i].Name = to.Name
+		}
+	}
+	book.Contributors = uniqueContributors(book.Contributors)
+	book.FormatContributors()
+	return &book
+}
+
+// keepPreviousName добавляет прежнее имя переименованного автора к другим
+// написаниям: книги, сохраненные с ним позже (например, при откате
+// к старой ревизии), будут связаны с этим автором
+func keepPreviousName(author, previous *models.Author) {
+	if author.AlternateNames == nil {
+		author.AlternateNames = slices.Clone(previous.AlternateNames)
+	}
+	author.AlternateNames = append(author.AlternateNames, previous.Name)
+	author.Normalize()
+}
+
+func (m *MemoryRepository) ListAuthors(ctx context.Context, opts AuthorListOptions) ([]*models.Author, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key := models.NameKey(opts.Name)
+	var authors []*models.Author
+	for _, a := range m.authors {
+		if slices.ContainsFunc(a.Names(), func(name string) bool {
+			return strings.Contains(models.NameKey(name), key)
+		}) {
+			authors = append(authors, m.authorWithCount(a))
+		}
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if c := compareNoCase(authors[i].SortName, authors[j].SortName); c != 0 {
+			return c < 0
+		}
+		return authors[i].ID < authors[j].ID
+	})
+
+	start := min((opts.Page-1)*opts.PageSize, len(authors))
+	end := min(start+opts.PageSize, len(authors))
+	return authors[start:end], len(authors), nil
+}
+
+func (m *MemoryRepository) GetAuthor(ctx context.Context, id int64) (*models.Author, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a, exists := m.authors[id]
+	if !exists {
+		return nil, nil
+	}
+	return m.authorWithCount(a), nil
+}
+
+func (m *MemoryRepository) CreateAuthor(ctx context.Context, author *models.Author) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	author.ID = 0
+	if m.authorNamesTaken(author) {
+		return ErrDuplicateAuthorName
+	}
+	author.ID = m.nextAuthorID
+	author.BookCount = 0
+	m.nextAuthorID++
+	m.authors[author.ID] = cloneAuthor(author)
+	return nil
+}
+
+func (m *MemoryRepository) UpdateAuthor(ctx context.Context, author *models.Author) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.authors[author.ID]
+	if !exists {
+		return ErrAuthorNotFound
+	}
+	keepPreviousName(author, existing)
+	if m.authorNamesTaken(author) {
+		return ErrDuplicateAuthorName
+	}
+
+	m.authors[author.ID] = cloneAuthor(author)
+	author.BookCount = m.authorWithCount(author).BookCount
+	if author.Name != existing.Name {
+		m.relinkBooks(ctx, author.ID, author)
+	}
+	return nil
+}
+
+func (m *MemoryRepository) DeleteAuthor(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.authors[id]; !exists {
+		return ErrAuthorNotFound
+	}
+	for _, b := range m.books {
+		if slices.ContainsFunc(b.Contributors, func(c models.Contributor) bool { return c.AuthorID == id }) {
+			return ErrAuthorHasBooks
+		}
+	}
+	delete(m.authors, id)
+	return nil
+}
+
+func (m *MemoryRepository) MergeAuthors(ctx context.Context, sourceID, targetID int64) (*models.Author, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeSameAuthor
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	source, sourceExists := m.authors[sourceID]
+	target, targetExists := m.authors[targetID]
+	if !sourceExists || !targetExists {
+		return nil, ErrAuthorNotFound
+	}
+
+	m.relinkBooks(ctx, sourceID, target)
+	delete(m.authors, sourceID)
+	target.AlternateNames = append(target.AlternateNames, source.Names()...)
+	target.Normalize()
+	return m.authorWithCount(target), nil
+}
+
+// findAuthor ищет автора по имени или другому написанию имени без учета
+// регистра (вызывается под блокировкой)
+func (m *MemoryRepository) findAuthor(name string) *models.Author {
+	key := models.NameKey(name)
+	for _, a := range m.authors {
+		if slices.ContainsFunc(a.Names(), func(n string) bool { return models.NameKey(n) == key }) {
+			return a
+		}
+	}
+	return nil
+}
+
+// authorNamesTaken проверяет, принадлежит ли одно из имен автора другому
+// автору (вызывается под блокировкой)
+func (m *MemoryRepository) authorNamesTaken(author *models.Author) bool {
+	for _, name := range author.Names() {
+		if a := m.findAuthor(name); a != nil && a.ID != author.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// authorWithCount возвращает копию автора с количеством его книг вне
+// корзины (вызывается под блокировкой)
+func (m *MemoryRepository) authorWithCount(author *models.Author) *models.Author {
+	result := cloneAuthor(author)
+	result.BookCount = 0
+	for _, b := range m.books {
+		if b.DeletedAt == nil && slices.ContainsFunc(b.Contributors, func(c models.Contributor) bool { return c.AuthorID == author.ID }) {
+			result.BookCount++
+		}
+	}
+	return result
+}
+
+// relinkBooks заменяет в книгах автора fromID на автора to так же, как
+// dbTx.relinkBooks (вызывается под блокировкой)
+func (m *MemoryRepository) relinkBooks(ctx context.Context, fromID int64, to *models.Author) {
+	now := time.Now()
+	for id, old := range m.books {
+		if !slices.ContainsFunc(old.Contributors, func(c models.Contributor) bool { return c.AuthorID == fromID }) {
+			continue
+		}
+
+		book := relinkedBook(old, fromID, to)
+		book.UpdatedAt = now
+		book.Version++
+		m.books[id] = book
+		if changes := diffBooks(old, book); len(changes) > 0 {
+			m.record(ctx, &models.Revision{BookID: id, Action: models.ActionUpdate, Changes: changes})
+		}
+	}
+}
+
+func cloneAuthor(a *models.Author) *models.Author {
+	clone := *a
+	clone.AlternateNames = slices.Clone(a.AlternateNames)
+	return &clone
+}
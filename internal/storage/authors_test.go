@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testAuthors(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	published := time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newBook := func(title string, contributors ...models.Contributor) *models.Book {
+		t.Helper()
+		book := &models.Book{Title: title, Published: published, Contributors: contributors}
+		book.Normalize()
+		if err := repo.CreateBook(ctx, book); err != nil {
+			t.Fatalf("CreateBook(%q) error = %v", title, err)
+		}
+		return book
+	}
+	author := func(name string) models.Contributor {
+		return models.Contributor{Name: name, Role: models.RoleAuthor}
+	}
+	findAuthor := func(name string) *models.Author {
+		t.Helper()
+		authors, total, err := repo.ListAuthors(ctx, AuthorListOptions{Page: 1, PageSize: 10, Name: name})
+		if err != nil || total != 1 || len(authors) != 1 {
+			t.Fatalf("ListAuthors(%q) = %+v, %d, %v; want one author", name, authors, total, err)
+		}
+		return authors[0]
+	}
+
+	war := newBook("Война и мир", author("Лев Толстой"))
+	anna := newBook("Анна Каренина", author("Л. Толстой"), models.Contributor{Name: "Aylmer Maude", Role: models.RoleTranslator})
+	newBook("Детство", author("Толстой Л.Н."))
+
+	authors, total, err := repo.ListAuthors(ctx, AuthorListOptions{Page: 1, PageSize: 2})
+	if err != nil || total != 4 || len(authors) != 2 || authors[0].SortName != "Maude, Aylmer" {
+		t.Fatalf("ListAuthors() = %+v, %d, %v; want 4 authors, Maude first", authors, total, err)
+	}
+
+	// Другие написания имени связывают участников книг с автором
+	lev := findAuthor("лев")
+	if lev.SortName != "Толстой, Лев" || lev.BookCount != 1 {
+		t.Errorf("author = %+v, want derived sort name and 1 book", lev)
+	}
+	lev.AlternateNames = []string{"Leo Tolstoy"}
+	if err := repo.UpdateAuthor(ctx, lev); err != nil {
+		t.Fatalf("UpdateAuthor() error = %v", err)
+	}
+	resurrection := newBook("Воскресение", author("leo  tolstoy"))
+	if resurrection.Author != "Лев Толстой" || resurrection.Contributors[0].AuthorID != lev.ID {
+		t.Errorf("CreateBook() by alternate name = %+v, want linked to %d", resurrection.Contributors, lev.ID)
+	}
+
+	if err := repo.CreateAuthor(ctx, &models.Author{Name: "LEO TOLSTOY", SortName: "Tolstoy, Leo"}); !errors.Is(err, ErrDuplicateAuthorName) {
+		t.Errorf("CreateAuthor() with taken name error = %v, want ErrDuplicateAuthorName", err)
+	}
+	if err := repo.DeleteAuthor(ctx, lev.ID); !errors.Is(err, ErrAuthorHasBooks) {
+		t.Errorf("DeleteAuthor() with books error = %v, want ErrAuthorHasBooks", err)
+	}
+
+	// Объединение переносит книги и имена
+	short := findAuthor("Л. Толстой")
+	merged, err := repo.MergeAuthors(ctx, short.ID, lev.ID)
+	if err != nil {
+		t.Fatalf("MergeAuthors() error = %v", err)
+	}
+	if merged.BookCount != 3 || !slices.Equal(merged.AlternateNames, []string{"Leo Tolstoy", "Л. Толстой"}) {
+		t.Errorf("MergeAuthors() = %+v, want 3 books and both alternate names", merged)
+	}
+	if gone, err := repo.GetAuthor(ctx, short.ID); gone != nil || err != nil {
+		t.Errorf("GetAuthor(merged) = %+v, %v; want nil", gone, err)
+	}
+	got, _ := repo.GetBook(ctx, anna.ID)
+	if got.Author != "Лев Толстой" || got.Contributors[0].AuthorID != lev.ID || got.Contributors[1].Name != "Aylmer Maude" || got.Version != 2 {
+		t.Errorf("GetBook() after merge = %+v", got)
+	}
+	history, _ := repo.BookHistory(ctx, anna.ID)
+	if len(history) != 2 || history[0].Changes["author"].New == nil {
+		t.Errorf("BookHistory() after merge = %+v, want update with new author", history)
+	}
+
+	for _, ids := range [][2]int64{{lev.ID, lev.ID}, {short.ID, lev.ID}} {
+		if _, err := repo.MergeAuthors(ctx, ids[0], ids[1]); err == nil {
+			t.Errorf("MergeAuthors(%d, %d) error = nil", ids[0], ids[1])
+		}
+	}
+
+	// Разные написания одного автора в книге сливаются в одного участника
+	childhood := newBook("Отрочество", author("Лев Толстой"), author("Л. Толстой"))
+	if len(childhood.Contributors) != 1 || childhood.Author != "Лев Толстой" {
+		t.Errorf("CreateBook() with two spellings = %+v", childhood.Contributors)
+	}
+
+	// Переименование меняет имя во всех книгах и сохраняет прежнее
+	lev = &models.Author{ID: lev.ID, Name: "Лев Николаевич Толстой"}
+	lev.Normalize()
+	if err := repo.UpdateAuthor(ctx, lev); err != nil {
+		t.Fatalf("UpdateAuthor() rename error = %v", err)
+	}
+	if lev.SortName != "Толстой, Лев Николаевич" || !slices.Contains(lev.AlternateNames, "Лев Толстой") || lev.BookCount != 4 {
+		t.Errorf("UpdateAuthor() = %+v, want previous name kept", lev)
+	}
+	got, _ = repo.GetBook(ctx, war.ID)
+	if got.Author != "Лев Николаевич Толстой" || got.Contributors[0].Name != "Лев Николаевич Толстой" {
+		t.Errorf("GetBook() after rename = %+v", got)
+	}
+	hits, _, err := repo.SearchBooks(ctx, `author:"Лев Николаевич"`, firstPage)
+	if err != nil || len(hits) != 4 {
+		t.Errorf("SearchBooks() after rename = %d hits, %v; want 4", len(hits), err)
+	}
+
+	// Книги в корзине не учитываются в количестве, но удалить автора не дают
+	if err := repo.DeleteBook(ctx, war.ID, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+	if got, _ := repo.GetAuthor(ctx, lev.ID); got.BookCount != 3 {
+		t.Errorf("GetAuthor().BookCount = %d, want 3", got.BookCount)
+	}
+
+	nobody := &models.Author{Name: "Аноним"}
+	nobody.Normalize()
+	if err := repo.CreateAuthor(ctx, nobody); err != nil || nobody.ID == 0 {
+		t.Fatalf("CreateAuthor() = %+v, %v", nobody, err)
+	}
+	if err := repo.DeleteAuthor(ctx, nobody.ID); err != nil {
+		t.Errorf("DeleteAuthor() error = %v", err)
+	}
+	if err := repo.DeleteAuthor(ctx, nobody.ID); !errors.Is(err, ErrAuthorNotFound) {
+		t.Errorf("DeleteAuthor() twice error = %v, want ErrAuthorNotFound", err)
+	}
+	if err := repo.UpdateAuthor(ctx, nobody); !errors.Is(err, ErrAuthorNotFound) {
+		t.Errorf("UpdateAuthor() deleted author error = %v, want ErrAuthorNotFound", err)
+	}
+}
+
+func TestAuthors(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testAuthors(t, db)
+	})
+}
+
+func TestMemoryAuthors(t *testing.T) {
+	testAuthors(t, NewMemoryRepository())
+}
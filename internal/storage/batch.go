@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// ErrRolledBack возвращается для операций пакета, которые выполнились
+// успешно, но были отменены из-за ошибки в другой операции атомарного пакета
+var ErrRolledBack = errors.New("operation rolled back")
+
+// errBatchFailed откатывает транзакцию атомарного пакета с ошибками
+var errBatchFailed = errors.New("batch failed")
+
+// BatchAction - действие операции пакета
+type BatchAction string
+
+const (
+	BatchCreate BatchAction = "create"
+	BatchUpdate BatchAction = "update"
+	BatchDelete BatchAction = "delete"
+)
+
+// BatchOp - одна операция пакета
+type BatchOp struct {
+	Action BatchAction
+	// Book - новая книга для create или новое состояние книги для update.
+	// При update Book.ID и Book.Version задают книгу и ожидаемую версию.
+	Book *models.Book
+	// ID и Version задают удаляемую книгу и ее ожидаемую версию для delete
+	// (0 - без проверки версии)
+	ID      int64
+	Version int64
+	// Merge, если задана, дополняет Book при update сохраненной книгой,
+	// которая читается в транзакции пакета, и проверяет результат. Ошибка
+	// Merge становится ошибкой операции.
+	Merge func(book, existing *models.Book) error
+}
+
+// BatchResult - результат операции пакета: сохраненная книга (для create
+// и update) или ошибка
+type BatchResult struct {
+	Book *models.Book
+	Err  error
+}
+
+// Batch выполняет операции пакета в одной транзакции. Каждая операция
+// выполняется в своей точке сохранения, поэтому ошибка одной из них не
+// отменяет остальные. Если atomic равен true и хотя бы одна операция
+// завершилась ошибкой, транзакция откатывается целиком, а успешные операции
+// получают ErrRolledBack. committed сообщает, зафиксирована ли транзакция.
+func (d *Database) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, bool, error) {
+	log.Printf("Attempting to run batch of %d operations (atomic=%v)", len(ops), atomic)
+
+	results := make([]BatchResult, len(ops))
+	failed := false
+	err := d.inTx(ctx, func(tx *dbTx) error {
+		for i, op := range ops {
+			results[i] = tx.batchOp(ctx, i, op)
+			failed = failed || results[i].Err != nil
+		}
+		if atomic && failed {
+			return errBatchFailed
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchFailed) {
+		log.Printf("Error running batch: %v", err)
+		return nil, false, err
+	}
+
+	if atomic && failed {
+		rollBack(results)
+		log.Printf("Rolled back batch of %d operations", len(ops))
+		return results, false, nil
+	}
+	log.Printf("Finished batch of %d operations", len(ops))
+	return results, true, nil
+}
+
+// batchOp выполняет операцию i пакета в отдельной точке сохранения
+func (t *dbTx) batchOp(ctx context.Context, i int, op BatchOp) BatchResult {
+	savepoint := fmt.Sprintf("batch_op_%d", i)
+	if _, err := t.exec(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return BatchResult{Err: fmt.Errorf("failed to create savepoint: %w", err)}
+	}
+
+	var err error
+	switch op.Action {
+	case BatchCreate:
+		err = t.createBook(ctx, op.Book)
+	case BatchUpdate:
+		if err = t.mergeBatchOp(ctx, op); err == nil {
+			err = t.updateBook(ctx, op.Book, &models.Revision{Action: models.ActionUpdate})
+		}
+	case BatchDelete:
+		err = t.deleteBook(ctx, op.ID, op.Version)
+	default:
+		err = fmt.Errorf("unknown batch action %q", op.Action)
+	}
+
+	if err != nil {
+		if _, rollbackErr := t.exec(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rollbackErr != nil {
+			return BatchResult{Err: fmt.Errorf("failed to roll back to savepoint: %w", rollbackErr)}
+		}
+		return BatchResult{Err: err}
+	}
+	if _, err := t.exec(ctx, "RELEASE SAVEPOINT "+savepoint); err != nil {
+		return BatchResult{Err: fmt.Errorf("failed to release savepoint: %w", err)}
+	}
+
+	if op.Action == BatchDelete {
+		return BatchResult{}
+	}
+	return BatchResult{Book: op.Book}
+}
+
+// mergeBatchOp дополняет книгу операции update сохраненной книгой,
+// прочитанной в транзакции
+func (t *dbTx) mergeBatchOp(ctx context.Context, op BatchOp) error {
+	if op.Merge == nil {
+		return nil
+	}
+	existing, err := getBook(ctx, t, op.Book.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrBookNotFound
+	}
+	return op.Merge(op.Book, existing)
+}
+
+func (m *MemoryRepository) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var saved *MemoryRepository
+	if atomic {
+		saved = m.snapshot()
+	}
+
+	results := make([]BatchResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		var err error
+		switch op.Action {
+		case BatchCreate:
+			err = m.create(ctx, op.Book)
+		case BatchUpdate:
+			if err = m.mergeBatchOp(op); err == nil {
+				err = m.update(ctx, op.Book, &models.Revision{Action: models.ActionUpdate})
+			}
+		case BatchDelete:
+			err = m.remove(ctx, op.ID, op.Version)
+		default:
+			err = fmt.Errorf("unknown batch action %q", op.Action)
+		}
+
+		switch {
+		case err != nil:
+			results[i] = BatchResult{Err: err}
+			failed = true
+		case op.Action != BatchDelete:
+			results[i] = BatchResult{Book: op.Book}
+		}
+	}
+
+	if atomic && failed {
+		m.books, m.nextID = saved.books, saved.nextID
+		m.revisions, m.nextRevisionID = saved.revisions, saved.nextRevisionID
+		m.authors, m.nextAuthorID = saved.authors, saved.nextAuthorID
+		m.series, m.nextSeriesID = saved.series, saved.nextSeriesID
+		m.works, m.nextWorkID = saved.works, saved.nextWorkID
+		m.shelves, m.nextShelfID = saved.shelves, saved.nextShelfID
+		rollBack(results)
+		return results, false, nil
+	}
+	return results, true, nil
+}
+
+// snapshot копирует состояние хранилища для отката пакета
+// (вызывается под блокировкой)
+func (m *MemoryRepository) snapshot() *MemoryRepository {
+	saved := &MemoryRepository{
+		books:          make(map[int64]*models.Book, len(m.books)),
+		nextID:         m.nextID,
+		revisions:      make(map[int64][]*models.Revision, len(m.revisions)),
+		nextRevisionID: m.nextRevisionID,
+		authors:        make(map[int64]*models.Author, len(m.authors)),
+		nextAuthorID:   m.nextAuthorID,
+		series:         make(map[int64]*models.Series, len(m.series)),
+		nextSeriesID:   m.nextSeriesID,
+		works:          make(map[int64]*models.Work, len(m.works)),
+		nextWorkID:     m.nextWorkID,
+		shelves:        make(map[int64]*memoryShelf, len(m.shelves)),
+		nextShelfID:    m.nextShelfID,
+	}
+	for id, b := range m.books {
+		book := *b
+		saved.books[id] = &book
+	}
+	for id, history := range m.revisions {
+		saved.revisions[id] = append([]*models.Revision(nil), history...)
+	}
+	for id, a := range m.authors {
+		saved.authors[id] = cloneAuthor(a)
+	}
+	for id, s := range m.series {
+		series := *s
+		saved.series[id] = &series
+	}
+	for id, w := range m.works {
+		work := *w
+		saved.works[id] = &work
+	}
+	for id, sh := range m.shelves {
+		saved.shelves[id] = &memoryShelf{shelf: sh.shelf, books: slices.Clone(sh.books)}
+	}
+	return saved
+}
+
+// mergeBatchOp дополняет книгу операции update сохраненной книгой
+// (вызывается под блокировкой)
+func (m *MemoryRepository) mergeBatchOp(op BatchOp) error {
+	if op.Merge == nil {
+		return nil
+	}
+	existing, exists := m.books[op.Book.ID]
+	if !exists || existing.DeletedAt != nil {
+		return ErrBookNotFound
+	}
+	book := *existing
+	return op.Merge(op.Book, &book)
+}
+
+// rollBack помечает успешные операции отмененного пакета
+func rollBack(results []BatchResult) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = BatchResult{Err: ErrRolledBack}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testBatch(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	newBook := func(title, isbn string) *models.Book {
+		return &models.Book{Title: title, Author: "Test Author", ISBN: isbn, Published: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+
+	existing := newBook("Existing", "978-0-00-000001-0")
+	if err := repo.CreateBook(ctx, existing); err != nil {
+		t.Fatalf("Failed to create test book: %v", err)
+	}
+
+	// В атомарном пакете ошибка отменяет все операции
+	renamed := *existing
+	renamed.Title = "Renamed"
+	results, committed, err := repo.Batch(ctx, []BatchOp{
+		{Action: BatchCreate, Book: newBook("First", "978-0-00-000002-0")},
+		{Action: BatchUpdate, Book: &renamed},
+		{Action: BatchCreate, Book: newBook("Duplicate", existing.ISBN)},
+		{Action: BatchDelete, ID: 9999},
+	}, true)
+	if err != nil || committed {
+		t.Fatalf("Batch() committed = %v, error = %v; want rolled back", committed, err)
+	}
+	wantErrs := []error{ErrRolledBack, ErrRolledBack, ErrDuplicateISBN, ErrBookNotFound}
+	for i, want := range wantErrs {
+		if !errors.Is(results[i].Err, want) {
+			t.Errorf("Batch() atomic result %d error = %v, want %v", i, results[i].Err, want)
+		}
+	}
+	if _, info, _ := repo.ListBooks(ctx, DefaultListOptions()); info.Total != 1 {
+		t.Errorf("ListBooks() after rolled back batch total = %d, want 1", info.Total)
+	}
+	if book, _ := repo.GetBook(ctx, existing.ID); book.Title != "Existing" || book.Version != 1 {
+		t.Errorf("GetBook() after rolled back batch = %q v%d, want Existing v1", book.Title, book.Version)
+	}
+	if history, _ := repo.BookHistory(ctx, existing.ID); len(history) != 1 {
+		t.Errorf("BookHistory() after rolled back batch has %d revisions, want 1", len(history))
+	}
+
+	// Без atomic успешные операции сохраняются
+	renamed = *existing
+	renamed.Title = "Renamed"
+	results, committed, err = repo.Batch(ctx, []BatchOp{
+		{Action: BatchCreate, Book: newBook("First", "978-0-00-000002-0")},
+		{Action: BatchCreate, Book: newBook("Duplicate", existing.ISBN)},
+		{Action: BatchUpdate, Book: &renamed},
+		{Action: BatchDelete, ID: existing.ID, Version: 1},
+	}, false)
+	if err != nil || !committed {
+		t.Fatalf("Batch() committed = %v, error = %v; want committed", committed, err)
+	}
+	if results[0].Err != nil || results[0].Book == nil || results[0].Book.ID == 0 {
+		t.Errorf("Batch() create result = %+v, want created book", results[0])
+	}
+	if !errors.Is(results[1].Err, ErrDuplicateISBN) {
+		t.Errorf("Batch() duplicate result error = %v, want ErrDuplicateISBN", results[1].Err)
+	}
+	if results[2].Err != nil || results[2].Book.Version != 2 {
+		t.Errorf("Batch() update result = %+v, want version 2", results[2])
+	}
+	if !errors.Is(results[3].Err, ErrVersionConflict) {
+		t.Errorf("Batch() stale delete error = %v, want ErrVersionConflict", results[3].Err)
+	}
+
+	if book, _ := repo.GetBook(ctx, existing.ID); book == nil || book.Title != "Renamed" {
+		t.Errorf("GetBook() after batch = %+v, want Renamed", book)
+	}
+	if book, _ := repo.GetBook(ctx, results[0].Book.ID); book == nil || book.Title != "First" {
+		t.Errorf("GetBook() created in batch = %+v, want First", book)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testBatch(t, db)
+	})
+}
+
+func TestMemoryBatch(t *testing.T) {
+	testBatch(t, NewMemoryRepository())
+}
+
+func testBatchMerge(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	existing := &models.Book{Title: "Existing", Author: "Test Author", ISBN: "978-0-00-000001-0", Published: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := repo.CreateBook(ctx, existing); err != nil {
+		t.Fatalf("Failed to create test book: %v", err)
+	}
+	shelf := &models.Shelf{Name: "Favorites"}
+	if err := repo.CreateShelf(ctx, shelf); err != nil {
+		t.Fatalf("CreateShelf() error = %v", err)
+	}
+	if err := repo.AddToShelf(ctx, shelf.ID, existing.ID, -1); err != nil {
+		t.Fatalf("AddToShelf() error = %v", err)
+	}
+	existing, err := repo.GetBook(ctx, existing.ID)
+	if err != nil || existing == nil {
+		t.Fatalf("GetBook() = %+v, %v", existing, err)
+	}
+
+	// Merge получает книгу в том виде, в каком ее оставили предыдущие
+	// операции пакета
+	errMerge := errors.New("merge failed")
+	ops := func() []BatchOp {
+		renamed := *existing
+		renamed.Title = "Renamed"
+		return []BatchOp{
+			{Action: BatchUpdate, Book: &renamed},
+			{Action: BatchUpdate, Book: &models.Book{ID: existing.ID}, Merge: func(book, stored *models.Book) error {
+				*book = *stored
+				book.Title = stored.Title + " again"
+				return nil
+			}},
+			{Action: BatchUpdate, Book: &models.Book{ID: existing.ID}, Merge: func(book, stored *models.Book) error {
+				return errMerge
+			}},
+			{Action: BatchUpdate, Book: &models.Book{ID: 9999}, Merge: func(book, stored *models.Book) error {
+				return nil
+			}},
+		}
+	}
+
+	// Откат атомарного пакета оставляет книгу на полке
+	if _, committed, err := repo.Batch(ctx, ops(), true); err != nil || committed {
+		t.Fatalf("Batch() committed = %v, error = %v; want rolled back", committed, err)
+	}
+	if books, total, err := repo.ShelfBooks(ctx, shelf.ID, 1, 10); err != nil || total != 1 || books[0].ID != existing.ID {
+		t.Errorf("ShelfBooks() after rolled back batch = %d books, %v; want the existing book", total, err)
+	}
+	if book, _ := repo.GetBook(ctx, existing.ID); book == nil || book.Title != "Existing" {
+		t.Errorf("GetBook() after rolled back batch = %+v, want Existing", book)
+	}
+
+	results, committed, err := repo.Batch(ctx, ops(), false)
+	if err != nil || !committed {
+		t.Fatalf("Batch() committed = %v, error = %v; want committed", committed, err)
+	}
+	if results[1].Err != nil || results[1].Book.Title != "Renamed again" || results[1].Book.Version != existing.Version+2 {
+		t.Errorf("Batch() merged update = %+v, %v; want Renamed again after the rename", results[1].Book, results[1].Err)
+	}
+	if !errors.Is(results[2].Err, errMerge) {
+		t.Errorf("Batch() failed merge error = %v, want %v", results[2].Err, errMerge)
+	}
+	if !errors.Is(results[3].Err, ErrBookNotFound) {
+		t.Errorf("Batch() merge of missing book error = %v, want ErrBookNotFound", results[3].Err)
+	}
+	if book, _ := repo.GetBook(ctx, existing.ID); book == nil || book.Title != "Renamed again" || len(book.Shelves) != 1 {
+		t.Errorf("GetBook() after batch = %+v, want Renamed again on its shelf", book)
+	}
+}
+
+func TestBatchMerge(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testBatchMerge(t, db)
+	})
+}
+
+func TestMemoryBatchMerge(t *testing.T) {
+	testBatchMerge(t, NewMemoryRepository())
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// resolveAuthors связывает участников книги с авторами по имени или другому
+// написанию имени, создавая авторов, которых еще нет, и заменяет имена
+// участников основными именами авторов. Поле Author обновляется.
+func (t *dbTx) resolveAuthors(ctx context.Context, book *models.Book) error {
+	for i, c := range book.Contributors {
+		id, name, err := t.findAuthor(ctx, c.Name)
+		if err != nil {
+			return err
+		}
+		if id == 0 {
+			name = c.Name
+			err := t.queryRow(ctx, "INSERT INTO authors (name, sort_name) VALUES (?, ?) RETURNING id",
+				name, models.DefaultSortName(name)).Scan(&id)
+			if err != nil {
+				return fmt.Errorf("failed to create author: %w", err)
+			}
+		}
+		book.Contributors[i].AuthorID = id
+		book.Contributors[i].Name = name
+	}
+	book.Contributors = uniqueContributors(book.Contributors)
+	book.FormatContributors()
+	return nil
+}
+
+// saveContributors заменяет участников книги на book.Contributors.
+// Участники должны быть связаны с авторами через resolveAuthors.
+func (t *dbTx) saveContributors(ctx context.Context, book *models.Book) error {
+	if _, err := t.exec(ctx, "DELETE FROM book_contributors WHERE book_id = ?", book.ID); err != nil {
+		return fmt.Errorf("failed to delete book contributors: %w", err)
+	}
+
+	for i, c := range book.Contributors {
+		_, err := t.exec(ctx, "INSERT INTO book_contributors (book_id, position, author_id, role) VALUES (?, ?, ?, ?)",
+			book.ID, i, c.AuthorID, c.Role)
+		if err != nil {
+			return fmt.Errorf("failed to save book contributor: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadContributors заполняет участников книг
+func loadContributors(ctx context.Context, q querier, books []*models.Book) error {
+	for _, b := range books {
+		b.Contributors = nil
+	}
+
+	query := `
+        SELECT bc.book_id, bc.author_id, a.name, bc.role
+        FROM book_contributors bc
+        JOIN authors a ON a.id = bc.author_id
+        WHERE bc.book_id IN (%s)
+        ORDER BY bc.book_id, bc.position`
+	err := queryByBooks(ctx, q, books, query, func(rows *sql.Rows, byID map[int64]*models.Book) error {
+		var bookID int64
+		var c models.Contributor
+		if err := rows.Scan(&bookID, &c.AuthorID, &c.Name, &c.Role); err != nil {
+			return err
+		}
+		if b, ok := byID[bookID]; ok {
+			b.Contributors = append(b.Contributors, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load book contributors: %w", err)
+	}
+	return nil
+}
+
+// resolveAuthors связывает участников книги с авторами так же, как
+// dbTx.resolveAuthors (вызывается под блокировкой)
+func (m *MemoryRepository) resolveAuthors(book *models.Book) {
+	for i, c := range book.Contributors {
+		author := m.findAuthor(c.Name)
+		if author == nil {
+			author = &models.Author{ID: m.nextAuthorID, Name: c.Name, SortName: models.DefaultSortName(c.Name)}
+			m.nextAuthorID++
+			m.authors[author.ID] = author
+		}
+		book.Contributors[i].AuthorID = author.ID
+		book.Contributors[i].Name = author.Name
+	}
+	book.Contributors = uniqueContributors(book.Contributors)
+	book.FormatContributors()
+}
+
+// uniqueContributors убирает повторы одного автора в одной роли, которые
+// появляются, когда разные написания имени относятся к одному автору
+func uniqueContributors(contributors []models.Contributor) []models.Contributor {
+	seen := make(map[models.Contributor]bool, len(contributors))
+	return slices.DeleteFunc(contributors, func(c models.Contributor) bool {
+		key := models.Contributor{AuthorID: c.AuthorID, Role: c.Role}
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+		return false
+	})
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testContributors(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	published := time.Date(1937, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	twelve := &models.Book{Title: "Двенадцать стульев", Published: published, Contributors: []models.Contributor{
+		{Name: "Илья Ильф", Role: models.RoleAuthor},
+		{Name: "Евгений Петров", Role: models.RoleAuthor},
+		{Name: "Кукрыниксы", Role: models.RoleIllustrator},
+	}}
+	hamlet := &models.Book{Title: "Гамлет", Published: published, Contributors: []models.Contributor{
+		{Name: "William Shakespeare", Role: models.RoleAuthor},
+		{Name: "Борис Пастернак", Role: models.RoleTranslator},
+	}}
+	// Клиент, который знает только поле author
+	faust := &models.Book{Title: "Фауст", Author: "Johann Wolfgang von Goethe", Published: published}
+	for _, book := range []*models.Book{twelve, hamlet, faust} {
+		book.Normalize()
+		if err := repo.CreateBook(ctx, book); err != nil {
+			t.Fatalf("CreateBook(%q) error = %v", book.Title, err)
+		}
+	}
+
+	got, err := repo.GetBook(ctx, twelve.ID)
+	if err != nil || got.Author != "Илья Ильф, Евгений Петров" || len(got.Contributors) != 3 ||
+		got.Contributors[1].Name != "Евгений Петров" || got.Contributors[2].Role != models.RoleIllustrator {
+		t.Fatalf("GetBook() = %+v, %v; want authors in order and illustrator", got, err)
+	}
+	got, _ = repo.GetBook(ctx, faust.ID)
+	if len(got.Contributors) != 1 || got.Contributors[0].Name != "Johann Wolfgang von Goethe" || got.Contributors[0].AuthorID == 0 {
+		t.Errorf("GetBook() legacy author contributors = %+v", got.Contributors)
+	}
+
+	// Один и тот же человек связан с одним автором во всех книгах
+	faust, _ = repo.GetBook(ctx, faust.ID)
+	faust.Contributors = append(faust.Contributors, models.Contributor{Name: "Борис Пастернак", Role: models.RoleTranslator})
+	if err := repo.UpdateBook(ctx, faust); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	pasternak := faust.Contributors[1].AuthorID
+	hamlet, _ = repo.GetBook(ctx, hamlet.ID)
+	if pasternak == 0 || hamlet.Contributors[1].AuthorID != pasternak {
+		t.Errorf("translator author IDs = %d, %d; want the same author", pasternak, hamlet.Contributors[1].AuthorID)
+	}
+
+	opts := DefaultListOptions()
+	opts.AuthorID = pasternak
+	books, _, err := repo.ListBooks(ctx, opts)
+	if err != nil || len(books) != 2 {
+		t.Errorf("ListBooks(AuthorID) = %d books, %v; want 2", len(books), err)
+	}
+	opts.Role = models.RoleAuthor
+	if books, _, _ := repo.ListBooks(ctx, opts); len(books) != 0 {
+		t.Errorf("ListBooks(AuthorID, Role=author) = %d books, want 0", len(books))
+	}
+
+	// Изменение только поля author заменяет авторов, переводчик остается
+	faust.Contributors = nil
+	faust.Author = "Иоганн Вольфганг Гёте"
+	if err := repo.UpdateBook(ctx, faust); err != nil {
+		t.Fatalf("UpdateBook() legacy author error = %v", err)
+	}
+	got, _ = repo.GetBook(ctx, faust.ID)
+	if got.Author != "Иоганн Вольфганг Гёте" || len(got.Contributors) != 2 ||
+		got.Contributors[0].Name != "Иоганн Вольфганг Гёте" || got.Contributors[1].AuthorID != pasternak {
+		t.Errorf("GetBook() after legacy author update = %+v", got.Contributors)
+	}
+
+	for query, want := range map[string]int{
+		"translator:пастернак":         2,
+		`translator:"борис пастернак"`: 2,
+		"illustrator:кукрыниксы":       1,
+		"translator:ильф":              0,
+		"author:петров":                1,
+	} {
+		hits, _, err := repo.SearchBooks(ctx, query, firstPage)
+		if err != nil || len(hits) != want {
+			t.Errorf("SearchBooks(%q) = %d hits, %v; want %d", query, len(hits), err, want)
+		}
+	}
+
+	// Откат к созданию возвращает прежних участников
+	reverted, err := repo.RevertBook(ctx, faust.ID, 1)
+	if err != nil || reverted.Author != "Johann Wolfgang von Goethe" || len(reverted.Contributors) != 1 {
+		t.Errorf("RevertBook() = %+v, %v; want original author only", reverted, err)
+	}
+}
+
+func TestContributors(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testContributors(t, db)
+	})
+}
+
+func TestMemoryContributors(t *testing.T) {
+	testContributors(t, NewMemoryRepository())
+}
+
+func TestMigrateContributors(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		ctx := context.Background()
+		// Откатываем схему до миграции 007, которая добавила участников
+		version, err := db.SchemaVersion()
+		if err != nil {
+			t.Fatalf("SchemaVersion() error = %v", err)
+		}
+		if err := db.MigrateDown(version - 6); err != nil {
+			t.Fatalf("MigrateDown() error = %v", err)
+		}
+
+		// Книги, сохраненные до появления участников: в поле author имена
+		// авторов через запятую, регистр имени может отличаться. Запятая
+		// внутри одного имени не делит его на авторов.
+		legacy := []struct{ title, author, isbn string }{
+			{"Война и мир", "Лев Толстой", "978-5-17-090335-2"},
+			{"Анна Каренина", "лев толстой", "978-5-389-07435-4"},
+			{"Двенадцать стульев", "Илья Ильф, Евгений Петров, Илья Ильф", "978-5-17-090838-2"},
+			{"Воскресение", "Толстой, Лев", "978-0-451-52493-5"},
+			{"Hearts in Atlantis", "Stephen King, Jr.", "978-0-439-02348-1"},
+		}
+		for _, b := range legacy {
+			_, err := db.exec(ctx, "INSERT INTO books (title, author, isbn, published, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+				b.title, b.author, b.isbn, time.Now().UTC(), time.Now().UTC(), time.Now().UTC())
+			if err != nil {
+				t.Fatalf("insert book error = %v", err)
+			}
+		}
+		if err := db.Migrate(); err != nil {
+			t.Fatalf("Migrate() error = %v", err)
+		}
+
+		list, _, err := db.ListBooks(ctx, DefaultListOptions())
+		if err != nil || len(list) != len(legacy) {
+			t.Fatalf("ListBooks() = %d books, %v", len(list), err)
+		}
+		books := make(map[string]*models.Book, len(list))
+		for _, b := range list {
+			books[b.Title] = b
+		}
+		tolstoy := books["Война и мир"].Contributors
+		if len(tolstoy) != 1 || tolstoy[0].Name != "Лев Толстой" {
+			t.Fatalf("migrated contributors = %+v, want Лев Толстой", tolstoy)
+		}
+		if got := books["Анна Каренина"].Contributors; len(got) != 1 || got[0].AuthorID != tolstoy[0].AuthorID {
+			t.Errorf("migrated contributors = %+v, want the same author regardless of case", got)
+		}
+		if got := books["Двенадцать стульев"].Contributors; len(got) != 2 || got[0].Name != "Илья Ильф" || got[1].Name != "Евгений Петров" {
+			t.Errorf("migrated contributors = %+v, want Ильф and Петров in order", got)
+		}
+		for title, want := range map[string]string{"Воскресение": "Толстой, Лев", "Hearts in Atlantis": "Stephen King, Jr."} {
+			if got := books[title].Contributors; len(got) != 1 || got[0].Name != want {
+				t.Errorf("migrated contributors of %q = %+v, want a single author %q", title, got, want)
+			}
+		}
+
+		// Имя для сортировки у перенесенных авторов строится из имени
+		author, err := db.GetAuthor(ctx, tolstoy[0].AuthorID)
+		if err != nil || author.SortName != "Толстой, Лев" || author.BookCount != 2 {
+			t.Errorf("GetAuthor() = %+v, %v; want derived sort name and 2 books", author, err)
+		}
+	})
+}
@@ -1,69 +1,138 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"slices"
 	"time"
 
 	"github.com/NkvXness/GoBookshelf/internal/models"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type Database struct {
-	DB *sql.DB
+	DB      *sql.DB
+	dialect dialect
 }
 
-func NewDatabase(dbPath string) (*Database, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+// NewDatabase открывает базу данных по строке подключения.
+// postgres://... открывает PostgreSQL, любое другое значение - файл SQLite.
+func NewDatabase(dsn string) (*Database, error) {
+	dialect, source := dialectForDSN(dsn)
+	if dialect.fullText && !fts5Enabled {
+		return nil, ErrNoFTS5
+	}
+
+	db, err := sql.Open(dialect.driver, source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &Database{DB: db}, nil
+	return &Database{DB: db, dialect: dialect}, nil
+}
+
+// Dialect возвращает имя используемой СУБД ("sqlite" или "postgres")
+func (d *Database) Dialect() string {
+	return d.dialect.name
 }
 
 func (d *Database) Close() error {
 	return d.DB.Close()
 }
 
-func (d *Database) CreateBook(book *models.Book) error {
+func (d *Database) CreateBook(ctx context.Context, book *models.Book) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		return tx.createBook(ctx, book)
+	})
+}
+
+func (t *dbTx) createBook(ctx context.Context, book *models.Book) error {
+	book.ResolveContributors(nil)
+	if err := t.resolveAuthors(ctx, book); err != nil {
+		return err
+	}
+	if err := t.resolveSeries(ctx, book); err != nil {
+		return err
+	}
+
 	query := `
         INSERT INTO books (title, author, isbn, published, created_at, updated_at)
         VALUES (?, ?, ?, ?, ?, ?)
+        RETURNING id
     `
-	now := time.Now()
-	result, err := d.DB.Exec(query, book.Title, book.Author, book.ISBN, book.Published, now, now)
+	// Время хранится в UTC: SQLite сравнивает даты как строки, и значения
+	// из фильтров и курсоров должны быть в том же часовом поясе
+	now := time.Now().UTC()
+	var id int64
+	err := t.queryRow(ctx, query, book.Title, book.Author, nullableISBN(book.ISBN), book.Published.UTC(), now, now).Scan(&id)
 	if err != nil {
+		if t.dialect.isUniqueViolation(err) {
+			return ErrDuplicateISBN
+		}
 		return fmt.Errorf("failed to create book: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
-
 	book.ID = id
 	book.CreatedAt = now
 	book.UpdatedAt = now
+	book.Version = 1
+	book.Shelves = nil
+	book.WorkID = 0
 
-	return nil
+	if len(book.Identifiers) > 0 {
+		if err := t.saveIdentifiers(ctx, book); err != nil {
+			return err
+		}
+	}
+	if len(book.Tags) > 0 {
+		if err := t.saveTags(ctx, book); err != nil {
+			return err
+		}
+	}
+	if book.SeriesID != 0 {
+		if err := t.saveSeries(ctx, book); err != nil {
+			return err
+		}
+	}
+	if !sameEdition(book, &models.Book{}) {
+		if err := t.saveEdition(ctx, book); err != nil {
+			return err
+		}
+	}
+	if err := t.saveContributors(ctx, book); err != nil {
+		return err
+	}
+
+	return t.recordRevision(ctx, &models.Revision{
+		BookID:  id,
+		Action:  models.ActionCreate,
+		Changes: diffBooks(nil, book),
+	})
 }
 
-func (d *Database) GetBook(id int64) (*models.Book, error) {
+func (d *Database) GetBook(ctx context.Context, id int64) (*models.Book, error) {
+	return getBook(ctx, d, id)
+}
+
+// getBook читает книгу вне корзины; возвращает nil без ошибки, если ее нет
+func getBook(ctx context.Context, q querier, id int64) (*models.Book, error) {
 	log.Printf("Attempting to get book with ID: %d", id)
 
 	query := `
-        SELECT id, title, author, isbn, published, created_at, updated_at
+        SELECT id, title, author, COALESCE(isbn, ''), published, created_at, updated_at, version
         FROM books
-        WHERE id = ?
+        WHERE id = ? AND deleted_at IS NULL
     `
 	var book models.Book
-	err := d.DB.QueryRow(query, id).Scan(
+	err := q.queryRow(ctx, query, id).Scan(
 		&book.ID,
 		&book.Title,
 		&book.Author,
@@ -71,6 +140,7 @@ func (d *Database) GetBook(id int64) (*models.Book, error) {
 		&book.Published,
 		&book.CreatedAt,
 		&book.UpdatedAt,
+		&book.Version,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -80,28 +150,43 @@ func (d *Database) GetBook(id int64) (*models.Book, error) {
 		log.Printf("Error querying book: %v", err)
 		return nil, fmt.Errorf("failed to get book: %w", err)
 	}
+	if err := loadRelations(ctx, q, []*models.Book{&book}); err != nil {
+		return nil, err
+	}
 
 	log.Printf("Successfully retrieved book: %+v", book)
 	return &book, nil
 }
 
-func (d *Database) DeleteBook(id int64) error {
+func (d *Database) DeleteBook(ctx context.Context, id int64, version int64) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		return tx.deleteBook(ctx, id, version)
+	})
+}
+
+func (t *dbTx) deleteBook(ctx context.Context, id int64, version int64) error {
 	log.Printf("Attempting to delete book with ID: %d", id)
 
 	// Проверяем существование книги перед удалением
-	existingBook, err := d.GetBook(id)
+	existingBook, err := getBook(ctx, t, id)
 	if err != nil {
 		log.Printf("Error checking book existence: %v", err)
 		return fmt.Errorf("failed to check book existence: %w", err)
 	}
 	if existingBook == nil {
 		log.Printf("Book with ID %d not found", id)
-		return fmt.Errorf("book not found")
+		return ErrBookNotFound
+	}
+	if version != 0 && version != existingBook.Version {
+		log.Printf("Book %d has version %d, expected %d", id, existingBook.Version, version)
+		return ErrVersionConflict
 	}
 
-	// Удаляем книгу
-	query := "DELETE FROM books WHERE id = ?"
-	result, err := d.DB.Exec(query, id)
+	// Перемещаем книгу в корзину. Условие на версию защищает от изменений,
+	// сделанных после чтения книги.
+	now := time.Now().UTC()
+	query := "UPDATE books SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL AND version = ?"
+	result, err := t.exec(ctx, query, now, id, existingBook.Version)
 	if err != nil {
 		log.Printf("Error executing delete query: %v", err)
 		return fmt.Errorf("failed to delete book: %w", err)
@@ -116,32 +201,55 @@ func (d *Database) DeleteBook(id int64) error {
 
 	if rowsAffected == 0 {
 		log.Printf("No rows were affected when deleting book %d", id)
-		return fmt.Errorf("book not found or already deleted")
+		return ErrVersionConflict
 	}
 
-	log.Printf("Successfully deleted book %d", id)
-	return nil
+	log.Printf("Successfully moved book %d to trash", id)
+	return t.recordRevision(ctx, &models.Revision{
+		BookID:  id,
+		Action:  models.ActionDelete,
+		Changes: deletedAtChange(nil, &now),
+	})
 }
 
-func (d *Database) UpdateBook(book *models.Book) error {
+func (d *Database) UpdateBook(ctx context.Context, book *models.Book) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		return tx.updateBook(ctx, book, &models.Revision{Action: models.ActionUpdate})
+	})
+}
+
+// updateBook сохраняет книгу и записывает в историю ревизию rev
+// с измененными полями (если поля не изменились, ревизия не записывается)
+func (t *dbTx) updateBook(ctx context.Context, book *models.Book, rev *models.Revision) error {
 	log.Printf("Attempting to update book: %+v", book)
 
 	// Убедимся, что книга с таким ID существует
-	existingBook, err := d.GetBook(book.ID)
+	existingBook, err := getBook(ctx, t, book.ID)
 	if err != nil {
 		log.Printf("Error checking book existence: %v", err)
 		return fmt.Errorf("failed to check book existence: %w", err)
 	}
 	if existingBook == nil {
 		log.Printf("Book with ID %d not found", book.ID)
-		return fmt.Errorf("book not found")
+		return ErrBookNotFound
+	}
+	if book.Version != 0 && book.Version != existingBook.Version {
+		log.Printf("Book %d has version %d, expected %d", book.ID, existingBook.Version, book.Version)
+		return ErrVersionConflict
+	}
+	book.ResolveContributors(existingBook)
+	if err := t.resolveAuthors(ctx, book); err != nil {
+		return err
+	}
+	if err := t.resolveSeries(ctx, book); err != nil {
+		return err
 	}
 
 	// Проверка на изменение ISBN
-	if book.ISBN != existingBook.ISBN {
+	if book.ISBN != existingBook.ISBN && book.ISBN != "" {
 		// Проверяем существование книги с таким же ISBN, но другим ID
 		var count int
-		err := d.DB.QueryRow("SELECT COUNT(*) FROM books WHERE isbn = ? AND id != ?", book.ISBN, book.ID).Scan(&count)
+		err := t.queryRow(ctx, "SELECT COUNT(*) FROM books WHERE replace(isbn, '-', '') = ? AND id != ?", isbnDigits(book.ISBN), book.ID).Scan(&count)
 		if err != nil {
 			log.Printf("Error checking ISBN uniqueness: %v", err)
 			return fmt.Errorf("failed to check ISBN uniqueness: %w", err)
@@ -149,27 +257,32 @@ func (d *Database) UpdateBook(book *models.Book) error {
 
 		if count > 0 {
 			log.Printf("Book with ISBN %s already exists", book.ISBN)
-			return fmt.Errorf("книга с таким ISBN уже существует")
+			return ErrDuplicateISBN
 		}
 	}
 
-	// Выполняем обновление книги
+	// Выполняем обновление книги. Условие на прочитанную версию не дает
+	// затереть изменения, сохраненные параллельно.
 	query := `
         UPDATE books
-        SET title = ?, author = ?, isbn = ?, published = ?, updated_at = ?
-        WHERE id = ?
+        SET title = ?, author = ?, isbn = ?, published = ?, updated_at = ?, version = version + 1
+        WHERE id = ? AND deleted_at IS NULL AND version = ?
     `
-	now := time.Now()
-	result, err := d.DB.Exec(query,
+	now := time.Now().UTC()
+	result, err := t.exec(ctx, query,
 		book.Title,
 		book.Author,
-		book.ISBN,
-		book.Published,
+		nullableISBN(book.ISBN),
+		book.Published.UTC(),
 		now,
 		book.ID,
+		existingBook.Version,
 	)
 	if err != nil {
 		log.Printf("Error executing update query: %v", err)
+		if t.dialect.isUniqueViolation(err) {
+			return ErrDuplicateISBN
+		}
 		return fmt.Errorf("failed to update book: %w", err)
 	}
 
@@ -181,39 +294,84 @@ func (d *Database) UpdateBook(book *models.Book) error {
 
 	if rowsAffected == 0 {
 		log.Printf("No rows were affected when updating book %d", book.ID)
-		return fmt.Errorf("book not found or no changes made")
+		return ErrVersionConflict
+	}
+
+	if !slices.Equal(book.Identifiers, existingBook.Identifiers) {
+		if err := t.saveIdentifiers(ctx, book); err != nil {
+			return err
+		}
+	}
+	if models.SameContributors(book.Contributors, existingBook.Contributors) {
+		book.Contributors = existingBook.Contributors
+	} else if err := t.saveContributors(ctx, book); err != nil {
+		return err
+	}
+	if !slices.Equal(book.Tags, existingBook.Tags) {
+		if err := t.saveTags(ctx, book); err != nil {
+			return err
+		}
+	}
+	if !sameSeries(book, existingBook) {
+		if err := t.saveSeries(ctx, book); err != nil {
+			return err
+		}
+	}
+	book.WorkID = existingBook.WorkID
+	if !sameEdition(book, existingBook) {
+		if err := t.saveEdition(ctx, book); err != nil {
+			return err
+		}
 	}
 
+	book.Shelves = existingBook.Shelves
+	book.CreatedAt = existingBook.CreatedAt
 	book.UpdatedAt = now
+	book.Version = existingBook.Version + 1
 	log.Printf("Successfully updated book: %+v", book)
-	return nil
+
+	rev.BookID = book.ID
+	rev.Changes = diffBooks(existingBook, book)
+	if len(rev.Changes) == 0 {
+		return nil
+	}
+	return t.recordRevision(ctx, rev)
 }
 
-func (d *Database) ListBooks(page, pageSize int) ([]*models.Book, int, error) {
-	log.Printf("Attempting to list books with page=%d, pageSize=%d", page, pageSize)
+func (d *Database) ListBooks(ctx context.Context, opts ListOptions) ([]*models.Book, PageInfo, error) {
+	log.Printf("Attempting to list books with options %+v", opts)
 
-	offset := (page - 1) * pageSize
+	conditions, args := d.listWhere(opts)
 
 	// Получаем общее количество книг
-	var total int
-	err := d.DB.QueryRow("SELECT COUNT(*) FROM books").Scan(&total)
+	total := -1
+	if !opts.SkipTotal {
+		err := d.queryRow(ctx, "SELECT COUNT(*) FROM books"+whereClause(conditions), args...).Scan(&total)
+		if err != nil {
+			log.Printf("Error getting total book count: %v", err)
+			return nil, PageInfo{}, fmt.Errorf("failed to get total book count: %w", err)
+		}
+		log.Printf("Total books count: %d", total)
+	}
+
+	key := opts.sortKey()
+	page, err := d.pageQuery(key, string(opts.SortBy), "id", opts.PageOptions)
 	if err != nil {
-		log.Printf("Error getting total book count: %v", err)
-		return nil, 0, fmt.Errorf("failed to get total book count: %w", err)
+		return nil, PageInfo{}, err
+	}
+	if page.where != "" {
+		conditions = append(conditions, page.where)
+		args = append(args, page.args...)
 	}
-	log.Printf("Total books count: %d", total)
 
 	// Получаем книги для текущей страницы
 	query := `
-        SELECT id, title, author, isbn, published, created_at, updated_at
-        FROM books
-        ORDER BY created_at DESC
-        LIMIT ? OFFSET ?
-    `
-	rows, err := d.DB.Query(query, pageSize, offset)
+        SELECT id, title, author, COALESCE(isbn, ''), published, created_at, updated_at, version, deleted_at
+        FROM books` + whereClause(conditions) + page.tail
+	rows, err := d.query(ctx, query, append(args, page.tailArgs...)...)
 	if err != nil {
 		log.Printf("Error querying books: %v", err)
-		return nil, 0, fmt.Errorf("failed to query books: %w", err)
+		return nil, PageInfo{}, fmt.Errorf("failed to query books: %w", err)
 	}
 	defer rows.Close()
 
@@ -228,19 +386,85 @@ func (d *Database) ListBooks(page, pageSize int) ([]*models.Book, int, error) {
 			&book.Published,
 			&book.CreatedAt,
 			&book.UpdatedAt,
+			&book.Version,
+			&book.DeletedAt,
 		)
 		if err != nil {
 			log.Printf("Error scanning book row: %v", err)
-			return nil, 0, fmt.Errorf("failed to scan book row: %w", err)
+			return nil, PageInfo{}, fmt.Errorf("failed to scan book row: %w", err)
 		}
 		books = append(books, &book)
 	}
 
 	if err := rows.Err(); err != nil {
 		log.Printf("Error iterating book rows: %v", err)
-		return nil, 0, fmt.Errorf("error iterating book rows: %w", err)
+		return nil, PageInfo{}, fmt.Errorf("error iterating book rows: %w", err)
+	}
+
+	books, info := finishPage(books, key, opts.PageOptions, page.cursor, bookKey)
+	if err := loadRelations(ctx, d, books); err != nil {
+		return nil, PageInfo{}, err
 	}
+	info.Total = total
 
 	log.Printf("Successfully retrieved %d books", len(books))
-	return books, total, nil
+	return books, info, nil
+}
+
+// exec выполняет запрос, переписывая плейсхолдеры под диалект
+func (d *Database) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return d.DB.ExecContext(ctx, d.dialect.rebind(query), args...)
+}
+
+// query выполняет запрос, возвращающий строки, с учетом диалекта
+func (d *Database) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.DB.QueryContext(ctx, d.dialect.rebind(query), args...)
+}
+
+// queryRow выполняет запрос, возвращающий одну строку, с учетом диалекта
+func (d *Database) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return d.DB.QueryRowContext(ctx, d.dialect.rebind(query), args...)
+}
+
+// querier выполняет запросы с учетом диалекта; его реализуют *Database и *dbTx
+type querier interface {
+	exec(ctx context.Context, query string, args ...any) (sql.Result, error)
+	query(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	queryRow(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// dbTx - транзакция, переписывающая плейсхолдеры под диалект
+type dbTx struct {
+	tx      *sql.Tx
+	dialect dialect
+}
+
+// inTx выполняет fn в транзакции и фиксирует ее, если fn не вернула ошибку
+func (d *Database) inTx(ctx context.Context, fn func(tx *dbTx) error) error {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(&dbTx{tx: tx, dialect: d.dialect}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (t *dbTx) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, t.dialect.rebind(query), args...)
+}
+
+func (t *dbTx) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, t.dialect.rebind(query), args...)
+}
+
+func (t *dbTx) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.tx.QueryRowContext(ctx, t.dialect.rebind(query), args...)
 }
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// dialect описывает различия между поддерживаемыми СУБД
+type dialect struct {
+	// name совпадает с именем каталога миграций
+	name string
+	// driver - имя драйвера database/sql
+	driver string
+	// numberedPlaceholders включает замену ? на $1, $2, ...
+	numberedPlaceholders bool
+	// like - оператор регистронезависимого сравнения по шаблону
+	like string
+	// lower - функция перевода строки в нижний регистр с учетом Unicode
+	lower string
+	// fullText включает поиск через полнотекстовый индекс books_fts
+	fullText bool
+	// noCase - шаблон выражения для сортировки текста без учета регистра
+	noCase string
+	// isUniqueViolation проверяет, нарушено ли ограничение уникальности
+	isUniqueViolation func(err error) bool
+}
+
+var sqliteDialect = dialect{
+	name:     "sqlite",
+	driver:   sqliteDriverName,
+	like:     "LIKE",
+	lower:    unicodeLower,
+	fullText: true,
+	noCase:   "%s COLLATE " + unicodeNoCase,
+	isUniqueViolation: func(err error) bool {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) {
+			return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+		}
+		return false
+	},
+}
+
+var postgresDialect = dialect{
+	name:                 "postgres",
+	driver:               "postgres",
+	numberedPlaceholders: true,
+	like:                 "ILIKE",
+	lower:                "lower",
+	noCase:               "lower(%s)",
+	isUniqueViolation: func(err error) bool {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			return pqErr.Code == "23505"
+		}
+		return false
+	},
+}
+
+// dialectForDSN определяет диалект по строке подключения.
+// Строки со схемой postgres:// или postgresql:// открываются через PostgreSQL,
+// sqlite://путь и все остальные значения считаются путем к файлу SQLite.
+func dialectForDSN(dsn string) (dialect, string) {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return postgresDialect, dsn
+	case strings.HasPrefix(dsn, "sqlite://"):
+		return sqliteDialect, strings.TrimPrefix(dsn, "sqlite://")
+	}
+	return sqliteDialect, dsn
+}
+
+// rebind переписывает плейсхолдеры ? в формат, понятный диалекту.
+// Знаки вопроса внутри строковых литералов не затрагиваются.
+func (d dialect) rebind(query string) string {
+	if !d.numberedPlaceholders || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	inString := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+			b.WriteRune(r)
+		case r == '?' && !inString:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package storage
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect dialect
+		query   string
+		want    string
+	}{
+		{
+			name:    "sqlite keeps placeholders",
+			dialect: sqliteDialect,
+			query:   "SELECT * FROM books WHERE id = ? AND isbn = ?",
+			want:    "SELECT * FROM books WHERE id = ? AND isbn = ?",
+		},
+		{
+			name:    "postgres numbers placeholders",
+			dialect: postgresDialect,
+			query:   "SELECT * FROM books WHERE id = ? AND isbn = ?",
+			want:    "SELECT * FROM books WHERE id = $1 AND isbn = $2",
+		},
+		{
+			name:    "postgres skips string literals",
+			dialect: postgresDialect,
+			query:   "SELECT '?' FROM books WHERE title = ?",
+			want:    "SELECT '?' FROM books WHERE title = $1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.rebind(tt.query); got != tt.want {
+				t.Errorf("rebind() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialectForDSN(t *testing.T) {
+	tests := []struct {
+		dsn        string
+		wantName   string
+		wantSource string
+	}{
+		{"bookshelf.db", "sqlite", "bookshelf.db"},
+		{"sqlite:///var/lib/bookshelf.db", "sqlite", "/var/lib/bookshelf.db"},
+		{"postgres://user@localhost/bookshelf", "postgres", "postgres://user@localhost/bookshelf"},
+		{"postgresql://user@localhost/bookshelf", "postgres", "postgresql://user@localhost/bookshelf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			d, source := dialectForDSN(tt.dsn)
+			if d.name != tt.wantName || source != tt.wantSource {
+				t.Errorf("dialectForDSN() = %s, %q, want %s, %q", d.name, source, tt.wantName, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+var isbnQueryRe = regexp.MustCompile(`^[0-9Xx\- ]*[0-9][0-9Xx\- ]*$`)
+
+// searchTerms разбивает пользовательский запрос на слова.
+// Запрос, похожий на ISBN ("978-5-17"), превращается в одно слово из цифр,
+// так как ISBN индексируется без дефисов. Полный ISBN-10 заменяется
+// на ISBN-13, под которым книга хранится.
+func searchTerms(query string) []string {
+	if isbnQueryRe.MatchString(query) {
+		digits := strings.Map(func(r rune) rune {
+			if r == '-' || r == ' ' {
+				return -1
+			}
+			return unicode.ToLower(r)
+		}, query)
+		if isbn13, ok := models.ISBN10To13(digits); ok {
+			digits = isbn13
+		}
+		return []string{digits}
+	}
+
+	return strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// ftsQuery преобразует пользовательский запрос в выражение MATCH:
+// все слова должны встретиться, каждое ищется по префиксу ("слово"*)
+func ftsQuery(query string) string {
+	terms := searchTerms(query)
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		parts = append(parts, `"`+term+`"*`)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Метки начала и конца совпадения до экранирования HTML. Символы из области
+// для частного использования Unicode не встречаются в названиях и именах,
+// поэтому после html.EscapeString их можно заменить на <mark>.
+const (
+	markOpen  = "\uE000"
+	markClose = "\uE001"
+)
+
+// ftsMarks - аргументы snippet() и highlight() с метками совпадения
+const ftsMarks = "'" + markOpen + "', '" + markClose + "'"
+
+var markReplacer = strings.NewReplacer(markOpen, "<mark>", markClose, "</mark>")
+
+// markHTML экранирует HTML в тексте с метками совпадений и заменяет метки
+// на <mark>: пользовательский текст не может добавить в ответ свою разметку
+func markHTML(text string) string {
+	return markReplacer.Replace(html.EscapeString(text))
+}
+
+// highlightMatches оборачивает в <mark> вхождения слов запроса без учета
+// регистра, экранируя остальной текст. Используется хранилищами, в которых
+// нет встроенной подсветки совпадений.
+func highlightMatches(text, query string) string {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return html.EscapeString(text)
+	}
+
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, regexp.QuoteMeta(term))
+	}
+	re, err := regexp.Compile(`(?i)(` + strings.Join(quoted, "|") + `)`)
+	if err != nil {
+		return html.EscapeString(text)
+	}
+	return markHTML(re.ReplaceAllString(text, markOpen+"$1"+markClose))
+}
+
+// highlightHits заполняет подсветку названия и автора для найденных книг
+func highlightHits(hits []*models.SearchHit, query string) {
+	for _, hit := range hits {
+		hit.Highlight = &models.SearchHighlight{
+			Title:  highlightMatches(hit.Title, query),
+			Author: highlightMatches(hit.Author, query),
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build sqlite_fts5
+
+package storage
+
+// fts5Enabled сообщает, собран ли SQLite с FTS5, на котором построен индекс
+// books_fts. go-sqlite3 включает FTS5 только с тегом сборки sqlite_fts5.
+const fts5Enabled = true
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// saveIdentifiers заменяет идентификаторы книги на book.Identifiers
+func (t *dbTx) saveIdentifiers(ctx context.Context, book *models.Book) error {
+	if _, err := t.exec(ctx, "DELETE FROM book_identifiers WHERE book_id = ?", book.ID); err != nil {
+		return fmt.Errorf("failed to delete book identifiers: %w", err)
+	}
+
+	for i, id := range book.Identifiers {
+		_, err := t.exec(ctx, "INSERT INTO book_identifiers (book_id, position, type, value) VALUES (?, ?, ?, ?)",
+			book.ID, i, id.Type, id.Value)
+		if err != nil {
+			if t.dialect.isUniqueViolation(err) {
+				return ErrDuplicateIdentifier
+			}
+			return fmt.Errorf("failed to save book identifier: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadIdentifiers заполняет идентификаторы книг
+func loadIdentifiers(ctx context.Context, q querier, books []*models.Book) error {
+	for _, b := range books {
+		b.Identifiers = nil
+	}
+
+	query := `
+        SELECT book_id, type, value
+        FROM book_identifiers
+        WHERE book_id IN (%s)
+        ORDER BY book_id, position`
+	err := queryByBooks(ctx, q, books, query, func(rows *sql.Rows, byID map[int64]*models.Book) error {
+		var bookID int64
+		var id models.Identifier
+		if err := rows.Scan(&bookID, &id.Type, &id.Value); err != nil {
+			return err
+		}
+		if b, ok := byID[bookID]; ok {
+			b.Identifiers = append(b.Identifiers, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load book identifiers: %w", err)
+	}
+	return nil
+}
+
+// nullableISBN возвращает NULL вместо пустого ISBN: ограничение UNIQUE
+// не распространяется на NULL, и книг без ISBN может быть сколько угодно
+func nullableISBN(isbn string) any {
+	if isbn == "" {
+		return nil
+	}
+	return isbn
+}
+
+// identifierTaken проверяет, занят ли идентификатор другой книгой, в том
+// числе книгой в корзине (вызывается под блокировкой)
+func (m *MemoryRepository) identifierTaken(ids []models.Identifier, exceptID int64) bool {
+	for id, b := range m.books {
+		if id == exceptID {
+			continue
+		}
+		for _, identifier := range ids {
+			if slices.Contains(b.Identifiers, identifier) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testIdentifiers(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	published := time.Date(1968, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Книг без ISBN может быть несколько
+	zine := &models.Book{Title: "Хроника текущих событий", Author: "Самиздат", Published: published,
+		Identifiers: []models.Identifier{
+			{Type: models.IdentifierISSN, Value: "0028-0836"},
+			{Type: models.IdentifierBarcode, Value: "LIB-1"},
+		}}
+	paper := &models.Book{Title: "A Paper", Author: "Somebody", Published: published,
+		Identifiers: []models.Identifier{{Type: models.IdentifierDOI, Value: "10.1000/182"}}}
+	for _, book := range []*models.Book{zine, paper} {
+		if err := repo.CreateBook(ctx, book); err != nil {
+			t.Fatalf("CreateBook() error = %v", err)
+		}
+	}
+
+	got, err := repo.GetBook(ctx, zine.ID)
+	if err != nil || got.ISBN != "" || len(got.Identifiers) != 2 || got.Identifiers[1].Value != "LIB-1" {
+		t.Fatalf("GetBook() = %+v, %v; want book without ISBN and 2 identifiers", got, err)
+	}
+
+	// Идентификатор того же типа с тем же значением занят
+	duplicate := &models.Book{Title: "Copy", Author: "Somebody", Published: published,
+		Identifiers: []models.Identifier{{Type: models.IdentifierDOI, Value: "10.1000/182"}}}
+	if err := repo.CreateBook(ctx, duplicate); !errors.Is(err, ErrDuplicateIdentifier) {
+		t.Errorf("CreateBook() duplicate identifier error = %v, want ErrDuplicateIdentifier", err)
+	}
+	got.Identifiers = append(got.Identifiers, models.Identifier{Type: models.IdentifierDOI, Value: "10.1000/182"})
+	if err := repo.UpdateBook(ctx, got); !errors.Is(err, ErrDuplicateIdentifier) {
+		t.Errorf("UpdateBook() duplicate identifier error = %v, want ErrDuplicateIdentifier", err)
+	}
+
+	// Обновление заменяет идентификаторы и попадает в историю
+	got, _ = repo.GetBook(ctx, zine.ID)
+	got.Identifiers = []models.Identifier{{Type: models.IdentifierLCCN, Value: "n79021164"}}
+	if err := repo.UpdateBook(ctx, got); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	got, _ = repo.GetBook(ctx, zine.ID)
+	if len(got.Identifiers) != 1 || got.Identifiers[0].Type != models.IdentifierLCCN {
+		t.Errorf("GetBook() after update identifiers = %+v", got.Identifiers)
+	}
+	history, err := repo.BookHistory(ctx, zine.ID)
+	if err != nil || len(history) != 2 || history[0].Changes["identifiers"].New == nil {
+		t.Errorf("BookHistory() = %+v, %v; want identifiers change", history, err)
+	}
+
+	// Откат к созданию возвращает прежние идентификаторы
+	reverted, err := repo.RevertBook(ctx, zine.ID, 1)
+	if err != nil || len(reverted.Identifiers) != 2 || reverted.Identifiers[0].Value != "0028-0836" {
+		t.Errorf("RevertBook() = %+v, %v; want identifiers from revision 1", reverted, err)
+	}
+
+	// Книга находится по идентификатору
+	for _, query := range []string{"00280836", "issn:0028-0836", "doi:10.1000/182", "barcode:lib*"} {
+		hits, _, err := repo.SearchBooks(ctx, query, firstPage)
+		if err != nil || len(hits) != 1 || len(hits[0].Identifiers) == 0 {
+			t.Errorf("SearchBooks(%q) = %+v, %v; want one book with identifiers", query, hits, err)
+		}
+	}
+
+	books, _, err := repo.ListBooks(ctx, DefaultListOptions())
+	if err != nil || len(books) != 2 || len(books[0].Identifiers) != 1 || books[0].Identifiers[0].Type != models.IdentifierDOI {
+		t.Errorf("ListBooks() = %+v, %v; want books with identifiers", books, err)
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testIdentifiers(t, db)
+	})
+}
+
+func TestMemoryIdentifiers(t *testing.T) {
+	testIdentifiers(t, NewMemoryRepository())
+}
+
+func TestPurgeTrashReleasesIdentifiers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		ctx := context.Background()
+		ids := []models.Identifier{{Type: models.IdentifierASIN, Value: "B000FC1PJI"}}
+		book := &models.Book{Title: "Kindle", Author: "Somebody", Published: time.Now().Add(-time.Hour), Identifiers: ids}
+		if err := db.CreateBook(ctx, book); err != nil {
+			t.Fatalf("CreateBook() error = %v", err)
+		}
+		if err := db.DeleteBook(ctx, book.ID, 0); err != nil {
+			t.Fatalf("DeleteBook() error = %v", err)
+		}
+		if _, err := db.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("PurgeTrash() error = %v", err)
+		}
+
+		again := &models.Book{Title: "Kindle", Author: "Somebody", Published: time.Now().Add(-time.Hour), Identifiers: ids}
+		if err := db.CreateBook(ctx, again); err != nil {
+			t.Errorf("CreateBook() after purge error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// isbnChunkSize ограничивает число параметров в одном запросе BooksByISBN
+const isbnChunkSize = 500
+
+// isbnDigits возвращает ISBN без дефисов. ISBN сравниваются по цифрам:
+// дефисы в сохраненных книгах могут быть расставлены по старой таблице
+// диапазонов.
+func isbnDigits(isbn string) string {
+	return strings.ReplaceAll(isbn, "-", "")
+}
+
+// BooksByISBN возвращает книги с указанными ISBN, включая книги в корзине.
+// Книга попадает в ответ под тем ISBN, под которым ее искали.
+func (d *Database) BooksByISBN(ctx context.Context, isbns []string) (map[string]*models.Book, error) {
+	books := make(map[string]*models.Book, len(isbns))
+	wanted := isbnsByDigits(isbns)
+
+	for start := 0; start < len(isbns); start += isbnChunkSize {
+		chunk := isbns[start:min(start+isbnChunkSize, len(isbns))]
+		args := make([]any, len(chunk))
+		for i, isbn := range chunk {
+			args[i] = isbnDigits(isbn)
+		}
+
+		query := `
+        SELECT id, title, author, isbn, published, created_at, updated_at, version, deleted_at
+        FROM books
+        WHERE replace(isbn, '-', '') IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ") + `)`
+		rows, err := d.query(ctx, query, args...)
+		if err != nil {
+			log.Printf("Error querying books by ISBN: %v", err)
+			return nil, fmt.Errorf("failed to query books by isbn: %w", err)
+		}
+
+		for rows.Next() {
+			var book models.Book
+			err := rows.Scan(
+				&book.ID,
+				&book.Title,
+				&book.Author,
+				&book.ISBN,
+				&book.Published,
+				&book.CreatedAt,
+				&book.UpdatedAt,
+				&book.Version,
+				&book.DeletedAt,
+			)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan book row: %w", err)
+			}
+			for _, isbn := range wanted[isbnDigits(book.ISBN)] {
+				books[isbn] = &book
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating book rows: %w", err)
+		}
+	}
+
+	list := make([]*models.Book, 0, len(books))
+	loaded := make(map[int64]bool, len(books))
+	for _, book := range books {
+		if !loaded[book.ID] {
+			loaded[book.ID] = true
+			list = append(list, book)
+		}
+	}
+	if err := loadRelations(ctx, d, list); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// BooksByISBN возвращает книги с указанными ISBN, включая книги в корзине
+func (m *MemoryRepository) BooksByISBN(ctx context.Context, isbns []string) (map[string]*models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wanted := isbnsByDigits(isbns)
+	books := make(map[string]*models.Book)
+	for _, b := range m.books {
+		if b.ISBN == "" {
+			continue
+		}
+		book := *b
+		for _, isbn := range wanted[isbnDigits(b.ISBN)] {
+			books[isbn] = &book
+		}
+	}
+	return books, nil
+}
+
+// isbnsByDigits группирует искомые ISBN по цифрам
+func isbnsByDigits(isbns []string) map[string][]string {
+	wanted := make(map[string][]string, len(isbns))
+	for _, isbn := range isbns {
+		digits := isbnDigits(isbn)
+		wanted[digits] = append(wanted[digits], isbn)
+	}
+	return wanted
+}
+
+// ReformatISBNs заново расставляет дефисы в ISBN всех книг, включая книги
+// в корзине, и возвращает число измененных книг. Нужна после обновления
+// таблицы диапазонов ISBN, чтобы старые записи выглядели так же, как новые;
+// дубликаты ISBN проверяются по цифрам и без нее. Версии книг и история не
+// меняются - сам ISBN остается прежним.
+func (d *Database) ReformatISBNs(ctx context.Context) (int, error) {
+	var changed int
+	err := d.inTx(ctx, func(tx *dbTx) error {
+		rows, err := tx.query(ctx, "SELECT id, isbn FROM books WHERE isbn IS NOT NULL")
+		if err != nil {
+			return fmt.Errorf("failed to query book isbns: %w", err)
+		}
+
+		updates := make(map[int64]string)
+		for rows.Next() {
+			var book models.Book
+			if err := rows.Scan(&book.ID, &book.ISBN); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan book isbn: %w", err)
+			}
+			isbn := book.ISBN
+			book.FormatISBN()
+			if book.ISBN != isbn {
+				updates[book.ID] = book.ISBN
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("error iterating book rows: %w", err)
+		}
+
+		for id, isbn := range updates {
+			if _, err := tx.exec(ctx, "UPDATE books SET isbn = ? WHERE id = ?", isbn, id); err != nil {
+				return fmt.Errorf("failed to update isbn of book %d: %w", id, err)
+			}
+		}
+		changed = len(updates)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error reformatting ISBNs: %v", err)
+		return 0, err
+	}
+	return changed, nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testBooksByISBN(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	listFixtures(t, repo)
+
+	books, _, err := repo.ListBooks(ctx, DefaultListOptions())
+	if err != nil {
+		t.Fatalf("ListBooks() error = %v", err)
+	}
+	trashed := books[0]
+	if err := repo.DeleteBook(ctx, trashed.ID, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+
+	// Книги из корзины тоже находятся, неизвестные ISBN пропускаются
+	found, err := repo.BooksByISBN(ctx, []string{trashed.ISBN, "978-5-17-090335-2", "978-0-00-000000-2"})
+	if err != nil {
+		t.Fatalf("BooksByISBN() error = %v", err)
+	}
+	if len(found) != 2 || found[trashed.ISBN].ID != trashed.ID || found["978-5-17-090335-2"].Title != "Бесы" {
+		t.Errorf("BooksByISBN() = %+v, want trashed book and Бесы", found)
+	}
+
+	if found, err := repo.BooksByISBN(ctx, nil); err != nil || len(found) != 0 {
+		t.Errorf("BooksByISBN(nil) = %+v, %v, want empty", found, err)
+	}
+}
+
+func TestBooksByISBN(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testBooksByISBN(t, db)
+	})
+}
+
+func TestMemoryBooksByISBN(t *testing.T) {
+	testBooksByISBN(t, NewMemoryRepository())
+}
+
+func testISBNDigits(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+
+	// Книга с дефисами по старой таблице диапазонов
+	old := &models.Book{Title: "Бесы", Author: "Федор Достоевский", ISBN: "978-5-170-90335-2", Published: time.Date(1872, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := repo.CreateBook(ctx, old); err != nil {
+		t.Fatalf("CreateBook() error = %v", err)
+	}
+
+	// ISBN сравниваются по цифрам, а не по расстановке дефисов
+	book := &models.Book{Title: "Бесы", Author: "Федор Достоевский", ISBN: "978-5-17-090335-2", Published: old.Published}
+	if err := repo.CreateBook(ctx, book); !errors.Is(err, ErrDuplicateISBN) {
+		t.Errorf("CreateBook() with the same digits error = %v, want ErrDuplicateISBN", err)
+	}
+	book.ISBN = "978-0-00-000000-2"
+	if err := repo.CreateBook(ctx, book); err != nil {
+		t.Fatalf("CreateBook() error = %v", err)
+	}
+	book.ISBN = "978-5-17-090335-2"
+	if err := repo.UpdateBook(ctx, book); !errors.Is(err, ErrDuplicateISBN) {
+		t.Errorf("UpdateBook() with the same digits error = %v, want ErrDuplicateISBN", err)
+	}
+
+	found, err := repo.BooksByISBN(ctx, []string{"978-5-17-090335-2"})
+	if err != nil || len(found) != 1 || found["978-5-17-090335-2"].ID != old.ID {
+		t.Errorf("BooksByISBN() = %+v, %v; want book %d", found, err, old.ID)
+	}
+}
+
+func TestISBNDigits(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testISBNDigits(t, db)
+	})
+}
+
+func TestMemoryISBNDigits(t *testing.T) {
+	testISBNDigits(t, NewMemoryRepository())
+}
+
+func TestReformatISBNs(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		ctx := context.Background()
+		listFixtures(t, db)
+
+		// Запись, сохраненная до появления таблицы диапазонов
+		if _, err := db.exec(ctx, "UPDATE books SET isbn = ? WHERE isbn = ?", "978-5-170-90335-2", "978-5-17-090335-2"); err != nil {
+			t.Fatalf("exec() error = %v", err)
+		}
+
+		changed, err := db.ReformatISBNs(ctx)
+		if err != nil || changed != 1 {
+			t.Fatalf("ReformatISBNs() = %d, %v; want 1", changed, err)
+		}
+		found, err := db.BooksByISBN(ctx, []string{"978-5-17-090335-2"})
+		if err != nil || len(found) != 1 || found["978-5-17-090335-2"].Version != 1 {
+			t.Errorf("BooksByISBN() = %+v, %v; want reformatted book with version 1", found, err)
+		}
+	})
+}
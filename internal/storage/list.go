@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/search"
+)
+
+// SortField - поле, по которому сортируется список книг
+type SortField string
+
+const (
+	SortByTitle     SortField = "title"
+	SortByAuthor    SortField = "author"
+	SortByPublished SortField = "published"
+	SortByCreatedAt SortField = "created_at"
+	SortByUpdatedAt SortField = "updated_at"
+
+	// sortByDeletedAt используется только для списка книг в корзине
+	sortByDeletedAt SortField = "deleted_at"
+)
+
+// isText возвращает true для полей, которые сортируются без учета регистра
+func (f SortField) isText() bool {
+	return f == SortByTitle || f == SortByAuthor
+}
+
+// ListOptions задает страницу, сортировку и фильтры списка книг
+type ListOptions struct {
+	PageOptions
+
+	// SortBy - поле сортировки, по умолчанию created_at
+	SortBy SortField
+	// SortDesc включает сортировку по убыванию
+	SortDesc bool
+
+	// Author оставляет книги, в имени автора которых встречается эта фраза
+	Author string
+	// AuthorID оставляет книги, в создании которых участвовал автор с этим
+	// ID; Role дополнительно ограничивает его роль
+	AuthorID int64
+	Role     string
+	// PublishedFrom и PublishedTo ограничивают дату публикации (включительно)
+	PublishedFrom time.Time
+	PublishedTo   time.Time
+	// CreatedAfter оставляет книги, добавленные позже указанного момента
+	CreatedAfter time.Time
+	// Tags оставляет книги, у которых есть все эти теги
+	Tags []string
+	// ShelfID оставляет книги с полки с этим ID
+	ShelfID int64
+
+	// trash выбирает книги из корзины вместо обычного списка
+	trash bool
+}
+
+// DefaultListOptions возвращает параметры списка по умолчанию: первая
+// страница из 10 книг, сначала новые
+func DefaultListOptions() ListOptions {
+	return ListOptions{
+		PageOptions: PageOptions{Page: 1, PageSize: 10},
+		SortBy:      SortByCreatedAt,
+		SortDesc:    true,
+	}
+}
+
+// sortKey возвращает ключ сортировки списка
+func (o ListOptions) sortKey() sortKey {
+	return sortKey{field: o.SortBy, desc: o.SortDesc}
+}
+
+// authorTerm возвращает условие поиска по автору для фильтра Author
+func (o ListOptions) authorTerm() *search.Term {
+	return &search.Term{Field: search.FieldAuthor, Value: o.Author, Phrase: true}
+}
+
+// collectionTerms возвращает условия поиска для фильтров Tags и ShelfID
+func (o ListOptions) collectionTerms() []*search.Term {
+	var terms []*search.Term
+	for _, tag := range o.Tags {
+		terms = append(terms, &search.Term{Field: search.FieldTag, Value: tag})
+	}
+	if o.ShelfID != 0 {
+		terms = append(terms, &search.Term{Field: search.FieldShelf, Value: strconv.FormatInt(o.ShelfID, 10)})
+	}
+	return terms
+}
+
+// listWhere собирает условия WHERE для фильтров списка
+func (d *Database) listWhere(opts ListOptions) ([]string, []any) {
+	conditions := []string{"deleted_at IS NULL"}
+	if opts.trash {
+		conditions[0] = "deleted_at IS NOT NULL"
+	}
+	var args []any
+
+	if opts.Author != "" {
+		where, termArgs := search.ToSQL(opts.authorTerm(), d.searchOptions())
+		conditions = append(conditions, where)
+		args = append(args, termArgs...)
+	}
+	if opts.AuthorID != 0 {
+		condition := "id IN (SELECT book_id FROM book_contributors WHERE author_id = ?"
+		args = append(args, opts.AuthorID)
+		if opts.Role != "" {
+			condition += " AND role = ?"
+			args = append(args, opts.Role)
+		}
+		conditions = append(conditions, condition+")")
+	}
+	for _, term := range opts.collectionTerms() {
+		where, termArgs := search.ToSQL(term, d.searchOptions())
+		conditions = append(conditions, where)
+		args = append(args, termArgs...)
+	}
+	if !opts.PublishedFrom.IsZero() {
+		conditions = append(conditions, "published >= ?")
+		args = append(args, opts.PublishedFrom.UTC())
+	}
+	if !opts.PublishedTo.IsZero() {
+		conditions = append(conditions, "published <= ?")
+		args = append(args, opts.PublishedTo.UTC())
+	}
+	if !opts.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at > ?")
+		args = append(args, opts.CreatedAfter.UTC())
+	}
+
+	return conditions, args
+}
+
+// trashListOptions возвращает параметры списка книг в корзине
+func trashListOptions(page PageOptions) ListOptions {
+	return ListOptions{PageOptions: page, SortBy: sortByDeletedAt, SortDesc: true, trash: true}
+}
+
+// whereClause объединяет условия через AND
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// matches проверяет, проходит ли книга фильтры списка
+func (o ListOptions) matches(b *models.Book) bool {
+	if (b.DeletedAt != nil) != o.trash {
+		return false
+	}
+	if o.Author != "" && !search.Match(o.authorTerm(), b) {
+		return false
+	}
+	if o.AuthorID != 0 && !slices.ContainsFunc(b.Contributors, func(c models.Contributor) bool {
+		return c.AuthorID == o.AuthorID && (o.Role == "" || c.Role == o.Role)
+	}) {
+		return false
+	}
+	for _, term := range o.collectionTerms() {
+		if !search.Match(term, b) {
+			return false
+		}
+	}
+	if !o.PublishedFrom.IsZero() && b.Published.Before(o.PublishedFrom) {
+		return false
+	}
+	if !o.PublishedTo.IsZero() && b.Published.After(o.PublishedTo) {
+		return false
+	}
+	if !o.CreatedAfter.IsZero() && !b.CreatedAt.After(o.CreatedAfter) {
+		return false
+	}
+	return true
+}
+
+// sortBooks сортирует книги так же, как pageQuery в базе данных
+func sortBooks(books []*models.Book, by SortField, desc bool) {
+	sort.SliceStable(books, func(i, j int) bool {
+		a, b := books[i], books[j]
+
+		c := 0
+		switch by {
+		case SortByTitle:
+			c = compareNoCase(a.Title, b.Title)
+		case SortByAuthor:
+			c = compareNoCase(a.Author, b.Author)
+		case SortByPublished:
+			c = a.Published.Compare(b.Published)
+		case SortByUpdatedAt:
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		case sortByDeletedAt:
+			c = deletedAt(a).Compare(deletedAt(b))
+		default:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		if c == 0 {
+			c = compareIDs(a.ID, b.ID)
+		}
+
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// deletedAt возвращает момент удаления книги или нулевое время
+func deletedAt(b *models.Book) time.Time {
+	if b.DeletedAt == nil {
+		return time.Time{}
+	}
+	return *b.DeletedAt
+}
+
+func compareIDs(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
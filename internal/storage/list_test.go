@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// listFixtures создает книги с разными авторами и датами публикации
+func listFixtures(t *testing.T, repo BookRepository) {
+	books := []*models.Book{
+		{Title: "Анна Каренина", Author: "лев Толстой", ISBN: "978-5-389-07435-4", Published: time.Date(1878, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Title: "Бесы", Author: "Фёдор Достоевский", ISBN: "978-5-17-090335-2", Published: time.Date(1872, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Title: "Война и мир", Author: "Лев Толстой", ISBN: "978-5-17-982216-0", Published: time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Title: "animal Farm", Author: "George Orwell", ISBN: "978-0-14-044944-8", Published: time.Date(1945, 8, 17, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, book := range books {
+		if err := repo.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("Failed to create test book: %v", err)
+		}
+	}
+}
+
+func listTitles(t *testing.T, repo BookRepository, opts ListOptions) ([]string, int) {
+	if opts.Page == 0 {
+		opts.Page, opts.PageSize = 1, 10
+	}
+	if opts.SortBy == "" {
+		opts.SortBy = SortByCreatedAt
+	}
+
+	books, info, err := repo.ListBooks(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("ListBooks() error = %v", err)
+	}
+
+	titles := make([]string, 0, len(books))
+	for _, b := range books {
+		titles = append(titles, b.Title)
+	}
+	return titles, info.Total
+}
+
+func testListSortingAndFilters(t *testing.T, repo BookRepository) {
+	listFixtures(t, repo)
+
+	tests := []struct {
+		name      string
+		opts      ListOptions
+		want      []string
+		wantTotal int
+	}{
+		{
+			name: "author ascending ignores case",
+			opts: ListOptions{SortBy: SortByAuthor},
+			// Для одинаковых авторов порядок определяется id
+			want:      []string{"animal Farm", "Анна Каренина", "Война и мир", "Бесы"},
+			wantTotal: 4,
+		},
+		{
+			name:      "title descending",
+			opts:      ListOptions{SortBy: SortByTitle, SortDesc: true},
+			want:      []string{"Война и мир", "Бесы", "Анна Каренина", "animal Farm"},
+			wantTotal: 4,
+		},
+		{
+			name:      "published ascending",
+			opts:      ListOptions{SortBy: SortByPublished},
+			want:      []string{"Война и мир", "Бесы", "Анна Каренина", "animal Farm"},
+			wantTotal: 4,
+		},
+		{
+			name:      "author filter",
+			opts:      ListOptions{SortBy: SortByTitle, Author: "ТОЛСТОЙ"},
+			want:      []string{"Анна Каренина", "Война и мир"},
+			wantTotal: 2,
+		},
+		{
+			name: "published range",
+			opts: ListOptions{
+				SortBy:        SortByPublished,
+				PublishedFrom: time.Date(1870, 1, 1, 0, 0, 0, 0, time.UTC),
+				PublishedTo:   time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			want:      []string{"Бесы", "Анна Каренина"},
+			wantTotal: 2,
+		},
+		{
+			name:      "created after",
+			opts:      ListOptions{CreatedAfter: time.Now().Add(time.Hour)},
+			want:      []string{},
+			wantTotal: 0,
+		},
+		{
+			name:      "page of sorted list",
+			opts:      ListOptions{PageOptions: PageOptions{Page: 2, PageSize: 3}, SortBy: SortByTitle},
+			want:      []string{"Война и мир"},
+			wantTotal: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			titles, total := listTitles(t, repo, tt.opts)
+			if total != tt.wantTotal {
+				t.Errorf("ListBooks() total = %d, want %d", total, tt.wantTotal)
+			}
+			if len(titles) != len(tt.want) {
+				t.Fatalf("ListBooks() = %v, want %v", titles, tt.want)
+			}
+			for i := range titles {
+				if titles[i] != tt.want[i] {
+					t.Fatalf("ListBooks() = %v, want %v", titles, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestListBooksSortingAndFilters(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testListSortingAndFilters(t, db)
+	})
+}
+
+func TestMemoryListBooksSortingAndFilters(t *testing.T) {
+	testListSortingAndFilters(t, NewMemoryRepository())
+}
@@ -0,0 +1,348 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/search"
+)
+
+// MemoryRepository хранит книги в памяти процесса.
+// Используется в тестах и там, где не нужна постоянная база данных.
+type MemoryRepository struct {
+	mu     sync.RWMutex
+	books  map[int64]*models.Book
+	nextID int64
+	// revisions хранит историю изменений книг по возрастанию номеров
+	revisions      map[int64][]*models.Revision
+	nextRevisionID int64
+	// authors хранит авторов, на которых ссылаются участники книг
+	authors      map[int64]*models.Author
+	nextAuthorID int64
+	// shelves хранит полки вместе с порядком книг на них
+	shelves     map[int64]*memoryShelf
+	nextShelfID int64
+	// series хранит серии, на которые ссылаются книги
+	series       map[int64]*models.Series
+	nextSeriesID int64
+	// works хранит произведения; издания ссылаются на них через Book.WorkID
+	works      map[int64]*models.Work
+	nextWorkID int64
+}
+
+// NewMemoryRepository создает пустое хранилище книг в памяти
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		books:          make(map[int64]*models.Book),
+		nextID:         1,
+		revisions:      make(map[int64][]*models.Revision),
+		nextRevisionID: 1,
+		authors:        make(map[int64]*models.Author),
+		nextAuthorID:   1,
+		shelves:        make(map[int64]*memoryShelf),
+		nextShelfID:    1,
+		series:         make(map[int64]*models.Series),
+		nextSeriesID:   1,
+		works:          make(map[int64]*models.Work),
+		nextWorkID:     1,
+	}
+}
+
+func (m *MemoryRepository) CreateBook(ctx context.Context, book *models.Book) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.create(ctx, book)
+}
+
+// create сохраняет новую книгу (вызывается под блокировкой)
+func (m *MemoryRepository) create(ctx context.Context, book *models.Book) error {
+	book.ResolveContributors(nil)
+	if m.isbnTaken(book.ISBN, 0) {
+		return ErrDuplicateISBN
+	}
+	if m.identifierTaken(book.Identifiers, 0) {
+		return ErrDuplicateIdentifier
+	}
+	m.resolveAuthors(book)
+	m.resolveSeries(book)
+
+	now := time.Now()
+	book.ID = m.nextID
+	book.CreatedAt = now
+	book.UpdatedAt = now
+	book.Version = 1
+	book.DeletedAt = nil
+	book.Shelves = nil
+	book.WorkID = 0
+	m.nextID++
+
+	stored := *book
+	stored.Identifiers = slices.Clone(book.Identifiers)
+	stored.Contributors = slices.Clone(book.Contributors)
+	stored.Tags = slices.Clone(book.Tags)
+	stored.SeriesPosition = clonePosition(book.SeriesPosition)
+	m.books[book.ID] = &stored
+	m.record(ctx, &models.Revision{BookID: book.ID, Action: models.ActionCreate, Changes: diffBooks(nil, book)})
+	return nil
+}
+
+func (m *MemoryRepository) GetBook(ctx context.Context, id int64) (*models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	book, exists := m.books[id]
+	if !exists || book.DeletedAt != nil {
+		return nil, nil
+	}
+
+	result := *book
+	return &result, nil
+}
+
+func (m *MemoryRepository) UpdateBook(ctx context.Context, book *models.Book) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.update(ctx, book, &models.Revision{Action: models.ActionUpdate})
+}
+
+// update сохраняет книгу и записывает ревизию rev (вызывается под блокировкой)
+func (m *MemoryRepository) update(ctx context.Context, book *models.Book, rev *models.Revision) error {
+	existing, exists := m.books[book.ID]
+	if !exists || existing.DeletedAt != nil {
+		return ErrBookNotFound
+	}
+	if book.Version != 0 && book.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	book.ResolveContributors(existing)
+	if m.isbnTaken(book.ISBN, book.ID) {
+		return ErrDuplicateISBN
+	}
+	if m.identifierTaken(book.Identifiers, book.ID) {
+		return ErrDuplicateIdentifier
+	}
+	m.resolveAuthors(book)
+	m.resolveSeries(book)
+
+	book.CreatedAt = existing.CreatedAt
+	book.UpdatedAt = time.Now()
+	book.Version = existing.Version + 1
+	book.DeletedAt = nil
+	book.Shelves = existing.Shelves
+	book.WorkID = existing.WorkID
+
+	rev.BookID = book.ID
+	rev.Changes = diffBooks(existing, book)
+
+	stored := *book
+	stored.Identifiers = slices.Clone(book.Identifiers)
+	stored.Contributors = slices.Clone(book.Contributors)
+	stored.Tags = slices.Clone(book.Tags)
+	stored.SeriesPosition = clonePosition(book.SeriesPosition)
+	m.books[book.ID] = &stored
+	if len(rev.Changes) > 0 {
+		m.record(ctx, rev)
+	}
+	return nil
+}
+
+func (m *MemoryRepository) DeleteBook(ctx context.Context, id int64, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.remove(ctx, id, version)
+}
+
+// remove перемещает книгу в корзину (вызывается под блокировкой)
+func (m *MemoryRepository) remove(ctx context.Context, id int64, version int64) error {
+	book, exists := m.books[id]
+	if !exists || book.DeletedAt != nil {
+		return ErrBookNotFound
+	}
+	if version != 0 && version != book.Version {
+		return ErrVersionConflict
+	}
+
+	now := time.Now()
+	book.DeletedAt = &now
+	m.record(ctx, &models.Revision{BookID: id, Action: models.ActionDelete, Changes: deletedAtChange(nil, &now)})
+	return nil
+}
+
+func (m *MemoryRepository) ListBooks(ctx context.Context, opts ListOptions) ([]*models.Book, PageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	books := m.sorted(opts.matches)
+	sortBooks(books, opts.SortBy, opts.SortDesc)
+
+	return pageBooks(books, opts.sortKey(), opts.PageOptions)
+}
+
+func (m *MemoryRepository) SearchBooks(ctx context.Context, query string, opts PageOptions) ([]*models.SearchHit, PageInfo, error) {
+	node, err := search.Parse(query)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := m.sorted(func(b *models.Book) bool {
+		return b.DeletedAt == nil && search.Match(node, b)
+	})
+	var editions map[int64]int
+	if opts.collapseWorks {
+		matches, editions = collapseWorks(matches)
+	}
+
+	books, info, err := pageBooks(matches, sortKey{field: SortByCreatedAt, desc: true}, opts)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	hits := make([]*models.SearchHit, 0, len(books))
+	for _, b := range books {
+		hit := &models.SearchHit{Book: *b}
+		if opts.collapseWorks {
+			hit.Editions = max(editions[b.WorkID], 1)
+		}
+		hits = append(hits, hit)
+	}
+	highlightHits(hits, strings.Join(search.HighlightTerms(node), " "))
+
+	return hits, info, nil
+}
+
+func (m *MemoryRepository) SearchWorks(ctx context.Context, query string, opts PageOptions) ([]*models.SearchHit, PageInfo, error) {
+	opts.collapseWorks = true
+	return m.SearchBooks(ctx, query, opts)
+}
+
+func (m *MemoryRepository) ListTrash(ctx context.Context, opts PageOptions) ([]*models.Book, PageInfo, error) {
+	return m.ListBooks(ctx, trashListOptions(opts))
+}
+
+func (m *MemoryRepository) RestoreBook(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, exists := m.books[id]
+	if !exists || book.DeletedAt == nil {
+		return ErrBookNotFound
+	}
+
+	m.record(ctx, &models.Revision{BookID: id, Action: models.ActionRestore, Changes: deletedAtChange(book.DeletedAt, nil)})
+	book.DeletedAt = nil
+	return nil
+}
+
+func (m *MemoryRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purged := 0
+	for id, b := range m.books {
+		if b.DeletedAt != nil && b.DeletedAt.Before(before) {
+			m.record(ctx, &models.Revision{BookID: id, Action: models.ActionPurge, Changes: map[string]models.FieldChange{}})
+			delete(m.books, id)
+			for _, s := range m.shelves {
+				s.books = withoutBook(s.books, id)
+			}
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (m *MemoryRepository) BookHistory(ctx context.Context, id int64) ([]*models.Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revisions := m.revisions[id]
+	if _, exists := m.books[id]; !exists && len(revisions) == 0 {
+		return nil, ErrBookNotFound
+	}
+
+	result := make([]*models.Revision, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		rev := *revisions[i]
+		result = append(result, &rev)
+	}
+	return result, nil
+}
+
+func (m *MemoryRepository) RevertBook(ctx context.Context, id int64, revision int) (*models.Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, exists := m.books[id]
+	if !exists || current.DeletedAt != nil {
+		return nil, ErrBookNotFound
+	}
+
+	book, err := bookAtRevision(current, m.revisions[id], revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.update(ctx, book, &models.Revision{Action: models.ActionRevert, RevertedTo: revision}); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// record добавляет ревизию в историю книги (вызывается под блокировкой)
+func (m *MemoryRepository) record(ctx context.Context, rev *models.Revision) {
+	history := m.revisions[rev.BookID]
+
+	rev.ID = m.nextRevisionID
+	rev.Revision = len(history) + 1
+	rev.Actor = ActorFromContext(ctx)
+	rev.CreatedAt = time.Now()
+	m.nextRevisionID++
+
+	m.revisions[rev.BookID] = append(history, rev)
+}
+
+// isbnTaken проверяет, занят ли ISBN другой книгой (вызывается под блокировкой)
+func (m *MemoryRepository) isbnTaken(isbn string, exceptID int64) bool {
+	if isbn == "" {
+		return false
+	}
+	for id, b := range m.books {
+		if id != exceptID && isbnDigits(b.ISBN) == isbnDigits(isbn) {
+			return true
+		}
+	}
+	return false
+}
+
+// sorted возвращает копии книг, подходящих под фильтр, в порядке created_at DESC
+func (m *MemoryRepository) sorted(filter func(*models.Book) bool) []*models.Book {
+	result := make([]*models.Book, 0, len(m.books))
+	for _, b := range m.books {
+		if filter != nil && !filter(b) {
+			continue
+		}
+		book := *b
+		result = append(result, &book)
+	}
+
+	sortBooks(result, SortByCreatedAt, true)
+	return result
+}
+
+// clonePosition копирует номер книги в серии, чтобы сохраненная книга
+// не менялась вместе с переданной
+func clonePosition(position *float64) *float64 {
+	if position == nil {
+		return nil
+	}
+	p := *position
+	return &p
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func TestMemoryRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	book := &models.Book{
+		Title:     "Test Book",
+		Author:    "Test Author",
+		ISBN:      "9780451524935",
+		Published: time.Now().Add(-24 * time.Hour),
+	}
+	if err := repo.CreateBook(ctx, book); err != nil {
+		t.Fatalf("CreateBook() error = %v", err)
+	}
+	if book.ID == 0 {
+		t.Fatal("CreateBook() did not set book ID")
+	}
+
+	duplicate := *book
+	if err := repo.CreateBook(ctx, &duplicate); !errors.Is(err, ErrDuplicateISBN) {
+		t.Errorf("CreateBook() duplicate error = %v, want ErrDuplicateISBN", err)
+	}
+
+	book.Title = "Updated Test Book"
+	if err := repo.UpdateBook(ctx, book); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+
+	retrieved, err := repo.GetBook(ctx, book.ID)
+	if err != nil {
+		t.Fatalf("GetBook() error = %v", err)
+	}
+	if retrieved == nil || retrieved.Title != "Updated Test Book" {
+		t.Errorf("GetBook() got = %+v, want updated title", retrieved)
+	}
+
+	// Изменение полученной копии не должно затрагивать хранилище
+	retrieved.Title = "Changed outside"
+	again, _ := repo.GetBook(ctx, book.ID)
+	if again.Title != "Updated Test Book" {
+		t.Error("GetBook() returned a shared pointer to stored book")
+	}
+
+	if err := repo.DeleteBook(ctx, book.ID, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+	if err := repo.DeleteBook(ctx, book.ID, 0); !errors.Is(err, ErrBookNotFound) {
+		t.Errorf("DeleteBook() second call error = %v, want ErrBookNotFound", err)
+	}
+}
+
+func TestMemoryRepositoryListAndSearch(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	for i := 0; i < 15; i++ {
+		book := &models.Book{
+			Title:     fmt.Sprintf("Test Book %d", i),
+			Author:    "Test Author",
+			ISBN:      fmt.Sprintf("978045152%04d", i),
+			Published: time.Now().Add(-24 * time.Hour),
+		}
+		if err := repo.CreateBook(ctx, book); err != nil {
+			t.Fatalf("Failed to create test book: %v", err)
+		}
+	}
+
+	opts := DefaultListOptions()
+	opts.Page = 2
+	books, info, err := repo.ListBooks(ctx, opts)
+	if err != nil {
+		t.Fatalf("ListBooks() error = %v", err)
+	}
+	if len(books) != 5 {
+		t.Errorf("ListBooks() got %d books, want 5", len(books))
+	}
+	if info.Total != 15 {
+		t.Errorf("ListBooks() got total = %d, want 15", info.Total)
+	}
+
+	hits, info, err := repo.SearchBooks(ctx, "TEST book", PageOptions{Page: 2, PageSize: 10})
+	if err != nil {
+		t.Fatalf("SearchBooks() error = %v", err)
+	}
+	if info.Total != 15 || len(hits) != 5 {
+		t.Errorf("SearchBooks() got %d books (total %d), want 5 (total 15)", len(hits), info.Total)
+	}
+	if hits[0].Highlight == nil || !strings.HasPrefix(hits[0].Highlight.Title, "<mark>Test</mark>") {
+		t.Errorf("SearchBooks() highlight = %+v, want marked title", hits[0].Highlight)
+	}
+
+	hits, info, err = repo.SearchBooks(ctx, "missing", PageOptions{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("SearchBooks() error = %v", err)
+	}
+	if info.Total != 0 || len(hits) != 0 {
+		t.Errorf("SearchBooks() got %d books (total %d), want 0", len(hits), info.Total)
+	}
+}
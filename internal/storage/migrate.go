@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/migrations"
+)
+
+// Migration описывает одну версию схемы базы данных
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// ErrSchemaTooNew возвращается, если база данных уже мигрирована
+// до версии, о которой текущий бинарник ничего не знает
+type ErrSchemaTooNew struct {
+	Current int
+	Latest  int
+}
+
+func (e ErrSchemaTooNew) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than the latest known migration %d", e.Current, e.Latest)
+}
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// LoadMigrations читает миграции из файловой системы и сортирует их по версии
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("conflicting names for migration %d: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up script", m.Version, m.Name)
+		}
+		result = append(result, *m)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// Migrate применяет все встроенные миграции, которые еще не были применены
+func (d *Database) Migrate() error {
+	list, err := d.migrations()
+	if err != nil {
+		return err
+	}
+	return d.applyMigrations(list)
+}
+
+// MigrateDown откатывает указанное количество последних примененных миграций
+func (d *Database) MigrateDown(steps int) error {
+	list, err := d.migrations()
+	if err != nil {
+		return err
+	}
+	return d.rollbackMigrations(list, steps)
+}
+
+// migrations загружает встроенные миграции для диалекта базы данных
+func (d *Database) migrations() ([]Migration, error) {
+	fsys, err := fs.Sub(migrations.FS, d.dialect.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s migrations: %w", d.dialect.name, err)
+	}
+	return LoadMigrations(fsys)
+}
+
+// SchemaVersion возвращает текущую версию схемы базы данных (0 для пустой базы)
+func (d *Database) SchemaVersion() (int, error) {
+	if err := d.ensureMigrationsTable(); err != nil {
+		return 0, err
+	}
+	return d.currentVersion()
+}
+
+func (d *Database) ensureMigrationsTable() error {
+	query := `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name TEXT NOT NULL,
+            applied_at TIMESTAMP NOT NULL
+        )
+    `
+	if _, err := d.DB.Exec(query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) currentVersion() (int, error) {
+	var version sql.NullInt64
+	err := d.DB.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+func (d *Database) applyMigrations(list []Migration) error {
+	if err := d.ensureMigrationsTable(); err != nil {
+		return err
+	}
+
+	current, err := d.currentVersion()
+	if err != nil {
+		return err
+	}
+
+	if latest := latestVersion(list); current > latest {
+		return ErrSchemaTooNew{Current: current, Latest: latest}
+	}
+
+	for _, m := range list {
+		if m.Version <= current {
+			continue
+		}
+
+		log.Printf("Applying migration %03d_%s", m.Version, m.Name)
+		if err := d.runMigration(m.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(
+				d.dialect.rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+				m.Version, m.Name, time.Now(),
+			)
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) rollbackMigrations(list []Migration, steps int) error {
+	if err := d.ensureMigrationsTable(); err != nil {
+		return err
+	}
+
+	current, err := d.currentVersion()
+	if err != nil {
+		return err
+	}
+
+	byVersion := make(map[int]Migration, len(list))
+	for _, m := range list {
+		byVersion[m.Version] = m
+	}
+
+	for i := 0; i < steps && current > 0; i++ {
+		m, exists := byVersion[current]
+		if !exists {
+			return ErrSchemaTooNew{Current: current, Latest: latestVersion(list)}
+		}
+		if m.Down == "" {
+			return fmt.Errorf("migration %d (%s) has no down script", m.Version, m.Name)
+		}
+
+		log.Printf("Rolling back migration %03d_%s", m.Version, m.Name)
+		if err := d.runMigration(m.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(d.dialect.rebind("DELETE FROM schema_migrations WHERE version = ?"), m.Version)
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to roll back migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		current, err = d.currentVersion()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// latestVersion возвращает номер последней известной миграции
+func latestVersion(list []Migration) int {
+	if len(list) == 0 {
+		return 0
+	}
+	return list[len(list)-1].Version
+}
+
+// runMigration выполняет скрипт миграции и запись в schema_migrations в одной транзакции
+func (d *Database) runMigration(script string, record func(tx *sql.Tx) error) error {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if _, err := tx.Exec(script); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
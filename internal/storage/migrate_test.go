@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_second.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER);")},
+		"002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"001_first.up.sql":    {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"README.md":           {Data: []byte("not a migration")},
+	}
+
+	list, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("LoadMigrations() error = %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("LoadMigrations() got %d migrations, want 2", len(list))
+	}
+	if list[0].Version != 1 || list[1].Version != 2 {
+		t.Errorf("LoadMigrations() got versions %d, %d, want 1, 2", list[0].Version, list[1].Version)
+	}
+	if list[1].Down == "" {
+		t.Error("LoadMigrations() did not load down script")
+	}
+}
+
+func TestLoadMigrationsWithoutUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_first.down.sql": {Data: []byte("DROP TABLE a;")},
+	}
+
+	if _, err := LoadMigrations(fsys); err == nil {
+		t.Error("LoadMigrations() expected error for migration without up script")
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		list, err := db.migrations()
+		if err != nil {
+			t.Fatalf("LoadMigrations() error = %v", err)
+		}
+
+		version, err := db.SchemaVersion()
+		if err != nil {
+			t.Fatalf("SchemaVersion() error = %v", err)
+		}
+		if version != latestVersion(list) {
+			t.Errorf("SchemaVersion() got = %d, want %d", version, latestVersion(list))
+		}
+
+		// Повторный запуск не должен ничего менять
+		if err := db.Migrate(); err != nil {
+			t.Errorf("Migrate() second run error = %v", err)
+		}
+
+		if err := db.MigrateDown(len(list)); err != nil {
+			t.Fatalf("MigrateDown() error = %v", err)
+		}
+
+		version, err = db.SchemaVersion()
+		if err != nil {
+			t.Fatalf("SchemaVersion() error = %v", err)
+		}
+		if version != 0 {
+			t.Errorf("SchemaVersion() after rollback got = %d, want 0", version)
+		}
+
+		if tableExists(t, db, "books") {
+			t.Error("MigrateDown() did not drop books table")
+		}
+	})
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		_, err := db.DB.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'future', CURRENT_TIMESTAMP)")
+		if err != nil {
+			t.Fatalf("Failed to insert future migration: %v", err)
+		}
+
+		err = db.Migrate()
+		var tooNew ErrSchemaTooNew
+		if !errors.As(err, &tooNew) {
+			t.Fatalf("Migrate() error = %v, want ErrSchemaTooNew", err)
+		}
+		if tooNew.Current != 9999 {
+			t.Errorf("ErrSchemaTooNew.Current = %d, want 9999", tooNew.Current)
+		}
+	})
+}
+
+func TestMigrateRollsBackFailedMigration(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		list, err := db.migrations()
+		if err != nil {
+			t.Fatalf("LoadMigrations() error = %v", err)
+		}
+		broken := append(list, Migration{
+			Version: latestVersion(list) + 1,
+			Name:    "broken",
+			Up:      "CREATE TABLE broken (id INTEGER); SELECT * FROM missing_table;",
+		})
+
+		if err := db.applyMigrations(broken); err == nil {
+			t.Fatal("applyMigrations() expected error for broken migration")
+		}
+
+		version, err := db.SchemaVersion()
+		if err != nil {
+			t.Fatalf("SchemaVersion() error = %v", err)
+		}
+		if version != latestVersion(list) {
+			t.Errorf("SchemaVersion() got = %d, want %d", version, latestVersion(list))
+		}
+
+		if tableExists(t, db, "broken") {
+			t.Error("applyMigrations() left partially applied migration")
+		}
+	})
+}
+
+func TestDialectsShareMigrationVersions(t *testing.T) {
+	var versions [][]int
+	for _, d := range []dialect{sqliteDialect, postgresDialect} {
+		list, err := (&Database{dialect: d}).migrations()
+		if err != nil {
+			t.Fatalf("migrations() for %s error = %v", d.name, err)
+		}
+
+		var v []int
+		for _, m := range list {
+			if m.Down == "" {
+				t.Errorf("%s migration %d has no down script", d.name, m.Version)
+			}
+			v = append(v, m.Version)
+		}
+		versions = append(versions, v)
+	}
+
+	if fmt.Sprint(versions[0]) != fmt.Sprint(versions[1]) {
+		t.Errorf("migration versions differ: sqlite %v, postgres %v", versions[0], versions[1])
+	}
+}
+
+// tableExists проверяет наличие таблицы в схеме базы данных
+func tableExists(t *testing.T, db *Database, name string) bool {
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	if db.Dialect() == postgresDialect.name {
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'public' AND table_name = ?"
+	}
+
+	var count int
+	if err := db.queryRow(context.Background(), query, name).Scan(&count); err != nil {
+		t.Fatalf("Failed to inspect schema: %v", err)
+	}
+	return count > 0
+}
@@ -0,0 +1,362 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// ErrInvalidCursor возвращается, если курсор поврежден или выдан для другой сортировки
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageOptions задает страницу результата: по номеру (LIMIT/OFFSET) или по
+// курсору, полученному вместе с предыдущей страницей (keyset-пагинация)
+type PageOptions struct {
+	Page     int
+	PageSize int
+	// Cursor - значение PageInfo.NextCursor или PageInfo.PrevCursor.
+	// Если задан, Page не используется.
+	Cursor string
+	// SkipTotal отключает подсчет общего количества результатов
+	SkipTotal bool
+
+	// collapseWorks оставляет в результатах поиска одно издание
+	// произведения (SearchWorks)
+	collapseWorks bool
+}
+
+// PageInfo описывает положение полученной страницы в результате
+type PageInfo struct {
+	// Total - общее количество результатов или -1, если подсчет отключен
+	Total int
+	// NextCursor и PrevCursor указывают на соседние страницы и пусты,
+	// если соседней страницы нет
+	NextCursor string
+	PrevCursor string
+}
+
+// sortScore - сортировка результатов поиска по релевантности (по убыванию)
+const sortScore SortField = "score"
+
+// cursor - содержимое курсора: значение ключа сортировки и id книги,
+// на которой закончилась (или началась) страница
+type cursor struct {
+	SortBy SortField `json:"s"`
+	Desc   bool      `json:"d,omitempty"`
+	Value  string    `json:"v"`
+	ID     int64     `json:"i"`
+	// Before означает страницу перед книгой, а не после нее
+	Before bool `json:"b,omitempty"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// sortKey - ключ сортировки страницы; книги с равным значением ключа
+// упорядочиваются по id в том же направлении
+type sortKey struct {
+	field SortField
+	desc  bool
+}
+
+// decodeCursor разбирает курсор и проверяет, что он выдан для этой сортировки
+func (k sortKey) decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.SortBy != k.field || c.Desc != k.desc {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := k.parseValue(c.Value); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// value возвращает значение ключа для книги в виде строки для курсора
+func (k sortKey) value(b *models.Book, score float64) string {
+	switch k.field {
+	case SortByTitle:
+		return b.Title
+	case SortByAuthor:
+		return b.Author
+	case SortByPublished:
+		return b.Published.UTC().Format(time.RFC3339Nano)
+	case SortByUpdatedAt:
+		return b.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case sortByDeletedAt:
+		return deletedAt(b).UTC().Format(time.RFC3339Nano)
+	case sortScore:
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+	return b.CreatedAt.UTC().Format(time.RFC3339Nano)
+}
+
+// parseValue преобразует значение ключа из курсора в аргумент запроса
+func (k sortKey) parseValue(v string) (any, error) {
+	switch k.field {
+	case SortByTitle, SortByAuthor:
+		return v, nil
+	case sortScore:
+		return strconv.ParseFloat(v, 64)
+	}
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// cursorAt возвращает курсор на страницу после книги (или перед ней, если before)
+func (k sortKey) cursorAt(b *models.Book, score float64, before bool) string {
+	return cursor{SortBy: k.field, Desc: k.desc, Value: k.value(b, score), ID: b.ID, Before: before}.encode()
+}
+
+// pageQuery - части SQL-запроса, выбирающие одну страницу
+type pageQuery struct {
+	// where - условие курсора, пустое при выборке по номеру страницы
+	where string
+	args  []any
+	// tail - ORDER BY и LIMIT/OFFSET со своими аргументами
+	tail     string
+	tailArgs []any
+	cursor   *cursor
+}
+
+// pageQuery строит условие и сортировку для выборки страницы. expr - выражение
+// ключа сортировки, idColumn - колонка id книги. Запрашивается на одну строку
+// больше размера страницы, чтобы узнать, есть ли следующая.
+func (d *Database) pageQuery(key sortKey, expr, idColumn string, opts PageOptions) (*pageQuery, error) {
+	q := &pageQuery{}
+	placeholder := "?"
+	if key.field.isText() {
+		expr = fmt.Sprintf(d.dialect.noCase, expr)
+		placeholder = fmt.Sprintf(d.dialect.noCase, "CAST(? AS TEXT)")
+	}
+
+	desc := key.desc
+	offset := 0
+	if opts.Cursor != "" {
+		c, err := key.decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.cursor = c
+
+		// Страница перед курсором выбирается в обратном порядке
+		// и разворачивается после чтения
+		if c.Before {
+			desc = !desc
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		value, _ := key.parseValue(c.Value)
+		q.where = fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND %[4]s %[2]s ?))", expr, op, placeholder, idColumn)
+		q.args = []any{value, value, c.ID}
+	} else {
+		offset = (opts.Page - 1) * opts.PageSize
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	q.tail = fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT ? OFFSET ?", expr, direction, idColumn, direction)
+	q.tailArgs = []any{opts.PageSize + 1, offset}
+	return q, nil
+}
+
+// finishPage отбрасывает лишнюю строку, восстанавливает порядок страницы,
+// выбранной перед курсором, и заполняет курсоры соседних страниц.
+// keyOf возвращает книгу элемента и ее релевантность.
+func finishPage[T any](items []T, key sortKey, opts PageOptions, c *cursor, keyOf func(T) (*models.Book, float64)) ([]T, PageInfo) {
+	more := len(items) > opts.PageSize
+	if more {
+		items = items[:opts.PageSize]
+	}
+
+	var hasPrev, hasNext bool
+	switch {
+	case c == nil:
+		hasPrev, hasNext = opts.Page > 1, more
+	case c.Before:
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+		hasPrev, hasNext = more, true
+	default:
+		hasPrev, hasNext = true, more
+	}
+
+	info := PageInfo{Total: -1}
+	if len(items) == 0 {
+		// За пределами результата остается только вернуться туда, откуда пришли
+		if c != nil {
+			back := *c
+			back.Before = !c.Before
+			if c.Before {
+				info.NextCursor = back.encode()
+			} else {
+				info.PrevCursor = back.encode()
+			}
+		}
+		return items, info
+	}
+
+	if hasPrev {
+		book, score := keyOf(items[0])
+		info.PrevCursor = key.cursorAt(book, score, true)
+	}
+	if hasNext {
+		book, score := keyOf(items[len(items)-1])
+		info.NextCursor = key.cursorAt(book, score, false)
+	}
+	return items, info
+}
+
+// bookKey возвращает книгу для finishPage при выборке списка книг
+func bookKey(b *models.Book) (*models.Book, float64) {
+	return b, 0
+}
+
+// hitKey возвращает книгу и релевантность для finishPage при поиске
+func hitKey(h *models.SearchHit) (*models.Book, float64) {
+	return &h.Book, h.Score
+}
+
+// pageBooks выбирает страницу из отсортированного в памяти списка книг так же,
+// как это делает запрос к базе данных
+func pageBooks(books []*models.Book, key sortKey, opts PageOptions) ([]*models.Book, PageInfo, error) {
+	var c *cursor
+	start := (opts.Page - 1) * opts.PageSize
+	if opts.Cursor != "" {
+		var err error
+		if c, err = key.decodeCursor(opts.Cursor); err != nil {
+			return nil, PageInfo{}, err
+		}
+		value, _ := key.parseValue(c.Value)
+
+		if c.Before {
+			// Книги перед курсором берутся с конца, как при обратной сортировке в SQL
+			end := sort.Search(len(books), func(i int) bool {
+				return key.compareTo(books[i], value, c.ID) >= 0
+			})
+			start = max(end-opts.PageSize-1, 0)
+			window := make([]*models.Book, 0, end-start)
+			for i := end - 1; i >= start; i-- {
+				window = append(window, books[i])
+			}
+			page, info := finishPage(window, key, opts, c, bookKey)
+			if !opts.SkipTotal {
+				info.Total = len(books)
+			}
+			return page, info, nil
+		}
+
+		start = sort.Search(len(books), func(i int) bool {
+			return key.compareTo(books[i], value, c.ID) > 0
+		})
+	}
+
+	start = min(start, len(books))
+	end := min(start+opts.PageSize+1, len(books))
+	page, info := finishPage(books[start:end], key, opts, c, bookKey)
+	if !opts.SkipTotal {
+		info.Total = len(books)
+	}
+	return page, info, nil
+}
+
+// compareTo сравнивает положение книги в порядке сортировки с позицией курсора:
+// отрицательный результат означает, что книга идет раньше
+func (k sortKey) compareTo(b *models.Book, value any, id int64) int {
+	c := 0
+	switch k.field {
+	case SortByTitle:
+		c = compareNoCase(b.Title, value.(string))
+	case SortByAuthor:
+		c = compareNoCase(b.Author, value.(string))
+	default:
+		c = k.timeOf(b).Compare(value.(time.Time))
+	}
+	if c == 0 {
+		c = compareIDs(b.ID, id)
+	}
+	if k.desc {
+		return -c
+	}
+	return c
+}
+
+// timeOf возвращает значение поля-даты, по которому идет сортировка
+func (k sortKey) timeOf(b *models.Book) time.Time {
+	switch k.field {
+	case SortByPublished:
+		return b.Published
+	case SortByUpdatedAt:
+		return b.UpdatedAt
+	case sortByDeletedAt:
+		return deletedAt(b)
+	}
+	return b.CreatedAt
+}
+
+// exportPageSize - размер страницы при обходе всех книг в ForEachPage
+// и ForEachSearchPage
+const exportPageSize = 100
+
+// ForEachPage проходит по всем книгам списка opts страницами по курсору
+// и вызывает fn для каждой страницы. Номер и размер страницы из opts
+// не используются.
+func ForEachPage(ctx context.Context, repo BookRepository, opts ListOptions, fn func(books []*models.Book) error) error {
+	opts.PageOptions = PageOptions{Page: 1, PageSize: exportPageSize, SkipTotal: true}
+	for {
+		books, info, err := repo.ListBooks(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if len(books) > 0 {
+			if err := fn(books); err != nil {
+				return err
+			}
+		}
+		if info.NextCursor == "" {
+			return nil
+		}
+		opts.Cursor = info.NextCursor
+	}
+}
+
+// ForEachSearchPage проходит по всем результатам поиска query в порядке
+// релевантности и вызывает fn для каждой страницы
+func ForEachSearchPage(ctx context.Context, repo BookRepository, query string, fn func(hits []*models.SearchHit) error) error {
+	opts := PageOptions{Page: 1, PageSize: exportPageSize, SkipTotal: true}
+	for {
+		hits, info, err := repo.SearchBooks(ctx, query, opts)
+		if err != nil {
+			return err
+		}
+		if len(hits) > 0 {
+			if err := fn(hits); err != nil {
+				return err
+			}
+		}
+		if info.NextCursor == "" {
+			return nil
+		}
+		opts.Cursor = info.NextCursor
+	}
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// pageFixtures создает книги с совпадающими без учета регистра названиями
+// и одинаковыми датами публикации, чтобы порядок зависел от id
+func pageFixtures(t *testing.T, repo BookRepository) {
+	titles := []string{"Бесы", "Идиот", "бесы", "Анна Каренина", "Игрок", "Бесы", "Подросток"}
+	for i, title := range titles {
+		book := &models.Book{
+			Title:     title,
+			Author:    "Фёдор Достоевский",
+			ISBN:      fmt.Sprintf("978-5-00-00000%d-0", i),
+			Published: time.Date(1860+i%3, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		if err := repo.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("Failed to create test book: %v", err)
+		}
+	}
+}
+
+// bookIDs возвращает id книг по порядку
+func bookIDs(books []*models.Book) []int64 {
+	ids := make([]int64, 0, len(books))
+	for _, b := range books {
+		ids = append(ids, b.ID)
+	}
+	return ids
+}
+
+func testCursorPaging(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	pageFixtures(t, repo)
+
+	sorts := []ListOptions{
+		{SortBy: SortByTitle},
+		{SortBy: SortByTitle, SortDesc: true},
+		{SortBy: SortByPublished, SortDesc: true},
+		{SortBy: SortByCreatedAt, SortDesc: true},
+	}
+
+	for _, sortOpts := range sorts {
+		t.Run(fmt.Sprintf("%s desc=%v", sortOpts.SortBy, sortOpts.SortDesc), func(t *testing.T) {
+			all := sortOpts
+			all.PageOptions = PageOptions{Page: 1, PageSize: 100}
+			books, _, err := repo.ListBooks(ctx, all)
+			if err != nil {
+				t.Fatalf("ListBooks() error = %v", err)
+			}
+			want := bookIDs(books)
+
+			// Идем вперед по курсорам, начиная с первой страницы
+			opts := sortOpts
+			opts.PageOptions = PageOptions{Page: 1, PageSize: 3, SkipTotal: true}
+			var got []int64
+			var info PageInfo
+			for i := 0; ; i++ {
+				var page []*models.Book
+				page, info, err = repo.ListBooks(ctx, opts)
+				if err != nil {
+					t.Fatalf("ListBooks() error = %v", err)
+				}
+				if info.Total != -1 {
+					t.Errorf("ListBooks() total = %d, want -1 without count", info.Total)
+				}
+				if i == 0 && info.PrevCursor != "" {
+					t.Errorf("ListBooks() first page has prev cursor")
+				}
+				got = append(got, bookIDs(page)...)
+				if info.NextCursor == "" {
+					break
+				}
+				if i > len(want) {
+					t.Fatalf("ListBooks() cursors do not terminate, got %v", got)
+				}
+				opts.Cursor = info.NextCursor
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("ListBooks() forward by cursor = %v, want %v", got, want)
+			}
+
+			// Возвращаемся назад от последней страницы
+			got = nil
+			for info.PrevCursor != "" {
+				opts.Cursor = info.PrevCursor
+				var page []*models.Book
+				page, info, err = repo.ListBooks(ctx, opts)
+				if err != nil {
+					t.Fatalf("ListBooks() error = %v", err)
+				}
+				if info.NextCursor == "" {
+					t.Errorf("ListBooks() page before cursor has no next cursor")
+				}
+				got = append(bookIDs(page), got...)
+			}
+			if want := want[:len(want)-len(want)%3]; !slices.Equal(got, want) {
+				t.Fatalf("ListBooks() backward by cursor = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func testCursorStableOnInsert(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	pageFixtures(t, repo)
+
+	opts := DefaultListOptions()
+	opts.PageSize = 3
+	first, info, err := repo.ListBooks(ctx, opts)
+	if err != nil {
+		t.Fatalf("ListBooks() error = %v", err)
+	}
+
+	// Новая книга попадает в начало списка и не должна сдвигать следующую страницу
+	book := &models.Book{Title: "Бедные люди", Author: "Фёдор Достоевский", ISBN: "978-5-00-000010-0", Published: time.Now()}
+	if err := repo.CreateBook(ctx, book); err != nil {
+		t.Fatalf("Failed to create test book: %v", err)
+	}
+
+	opts.Cursor = info.NextCursor
+	second, info, err := repo.ListBooks(ctx, opts)
+	if err != nil {
+		t.Fatalf("ListBooks() error = %v", err)
+	}
+	if info.Total != 8 {
+		t.Errorf("ListBooks() total = %d, want 8", info.Total)
+	}
+
+	seen := make(map[int64]bool)
+	for _, b := range append(first, second...) {
+		if seen[b.ID] {
+			t.Fatalf("ListBooks() returned book %d twice", b.ID)
+		}
+		seen[b.ID] = true
+	}
+	if len(second) != 3 {
+		t.Errorf("ListBooks() second page has %d books, want 3", len(second))
+	}
+}
+
+func testInvalidCursor(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	pageFixtures(t, repo)
+
+	opts := ListOptions{PageOptions: PageOptions{Page: 1, PageSize: 2}, SortBy: SortByTitle}
+	_, info, err := repo.ListBooks(ctx, opts)
+	if err != nil {
+		t.Fatalf("ListBooks() error = %v", err)
+	}
+
+	for name, opts := range map[string]ListOptions{
+		"garbage":    {PageOptions: PageOptions{PageSize: 2, Cursor: "not a cursor"}, SortBy: SortByTitle},
+		"other sort": {PageOptions: PageOptions{PageSize: 2, Cursor: info.NextCursor}, SortBy: SortByAuthor},
+	} {
+		if _, _, err := repo.ListBooks(ctx, opts); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("ListBooks() with %s cursor error = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+func TestListBooksCursor(t *testing.T) {
+	for _, test := range []func(*testing.T, BookRepository){testCursorPaging, testCursorStableOnInsert, testInvalidCursor} {
+		forEachBackend(t, func(t *testing.T, db *Database) {
+			test(t, db)
+		})
+	}
+}
+
+func TestMemoryListBooksCursor(t *testing.T) {
+	testCursorPaging(t, NewMemoryRepository())
+	testCursorStableOnInsert(t, NewMemoryRepository())
+	testInvalidCursor(t, NewMemoryRepository())
+}
+
+func TestSearchBooksCursor(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		createSearchFixtures(t, db)
+
+		for _, query := range []string{"толстой", `author:толстой OR title:raquin`} {
+			hits, _, err := db.SearchBooks(context.Background(), query, firstPage)
+			if err != nil {
+				t.Fatalf("SearchBooks() error = %v", err)
+			}
+			var want []int64
+			for _, h := range hits {
+				want = append(want, h.ID)
+			}
+
+			opts := PageOptions{Page: 1, PageSize: 1}
+			var got []int64
+			for len(got) <= len(want) {
+				hits, info, err := db.SearchBooks(context.Background(), query, opts)
+				if err != nil {
+					t.Fatalf("SearchBooks() error = %v", err)
+				}
+				for _, h := range hits {
+					got = append(got, h.ID)
+				}
+				if info.NextCursor == "" {
+					break
+				}
+				opts.Cursor = info.NextCursor
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("SearchBooks(%q) by cursor = %v, want %v", query, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// relationsChunkSize ограничивает число книг в одном запросе к таблицам,
+// связанным с книгами
+const relationsChunkSize = 500
+
+// loadRelations заполняет идентификаторы, участников, теги, полки, серии
+// и сведения об изданиях книг
+func loadRelations(ctx context.Context, q querier, books []*models.Book) error {
+	if err := loadIdentifiers(ctx, q, books); err != nil {
+		return err
+	}
+	if err := loadContributors(ctx, q, books); err != nil {
+		return err
+	}
+	if err := loadTags(ctx, q, books); err != nil {
+		return err
+	}
+	if err := loadShelves(ctx, q, books); err != nil {
+		return err
+	}
+	if err := loadSeries(ctx, q, books); err != nil {
+		return err
+	}
+	return loadEditions(ctx, q, books)
+}
+
+// hitBooks возвращает книги из результатов поиска для loadRelations
+func hitBooks(hits []*models.SearchHit) []*models.Book {
+	books := make([]*models.Book, len(hits))
+	for i, hit := range hits {
+		books[i] = &hit.Book
+	}
+	return books
+}
+
+// queryByBooks выполняет query для каждых relationsChunkSize книг и передает
+// каждую строку результата в scan вместе с книгами по ID. Вместо %s
+// в query подставляются плейсхолдеры с ID книг.
+func queryByBooks(ctx context.Context, q querier, books []*models.Book, query string, scan func(rows *sql.Rows, byID map[int64]*models.Book) error) error {
+	byID := make(map[int64]*models.Book, len(books))
+	for _, b := range books {
+		byID[b.ID] = b
+	}
+
+	for start := 0; start < len(books); start += relationsChunkSize {
+		chunk := books[start:min(start+relationsChunkSize, len(books))]
+		args := make([]any, len(chunk))
+		for i, b := range chunk {
+			args[i] = b.ID
+		}
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ")
+		rows, err := q.query(ctx, fmt.Sprintf(query, placeholders), args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			if err := scan(rows, byID); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
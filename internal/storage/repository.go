@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+var (
+	// ErrBookNotFound возвращается, если книга с указанным ID не существует
+	ErrBookNotFound = errors.New("book not found")
+	// ErrNoFTS5 возвращается при открытии SQLite, если программа собрана
+	// без тега sqlite_fts5: без FTS5 не создать индекс books_fts
+	ErrNoFTS5 = errors.New("SQLite is built without FTS5: build with -tags sqlite_fts5")
+	// ErrDuplicateISBN возвращается при попытке сохранить книгу с уже существующим ISBN
+	ErrDuplicateISBN = errors.New("книга с таким ISBN уже существует")
+	// ErrDuplicateIdentifier возвращается, если идентификатор книги того же
+	// типа и с тем же значением уже есть у другой книги
+	ErrDuplicateIdentifier = errors.New("книга с таким идентификатором уже существует")
+	// ErrVersionConflict возвращается, если книга была изменена после того,
+	// как клиент получил ее версию
+	ErrVersionConflict = errors.New("book version conflict")
+
+	// ErrAuthorNotFound возвращается, если автор с указанным ID не существует
+	ErrAuthorNotFound = errors.New("author not found")
+	// ErrDuplicateAuthorName возвращается, если имя или другое написание
+	// имени автора уже принадлежит другому автору
+	ErrDuplicateAuthorName = errors.New("автор с таким именем уже существует")
+	// ErrAuthorHasBooks возвращается при удалении автора, у которого есть книги
+	ErrAuthorHasBooks = errors.New("author has books")
+	// ErrMergeSameAuthor возвращается при попытке объединить автора с самим собой
+	ErrMergeSameAuthor = errors.New("cannot merge an author into itself")
+
+	// ErrShelfNotFound возвращается, если полка с указанным ID не существует
+	ErrShelfNotFound = errors.New("shelf not found")
+	// ErrDuplicateShelfName возвращается, если полка с таким названием уже есть
+	ErrDuplicateShelfName = errors.New("полка с таким названием уже существует")
+	// ErrBookNotOnShelf возвращается при удалении с полки книги, которой на ней нет
+	ErrBookNotOnShelf = errors.New("book is not on the shelf")
+
+	// ErrWorkNotFound возвращается, если произведение с указанным ID не существует
+	ErrWorkNotFound = errors.New("work not found")
+	// ErrBookNotInWork возвращается при отвязке книги, которая не является
+	// изданием этого произведения
+	ErrBookNotInWork = errors.New("book is not an edition of the work")
+)
+
+// BookRepository описывает хранилище книг, с которым работают обработчики API
+type BookRepository interface {
+	CreateBook(ctx context.Context, book *models.Book) error
+	// GetBook возвращает nil без ошибки, если книга не найдена
+	GetBook(ctx context.Context, id int64) (*models.Book, error)
+	// UpdateBook увеличивает версию книги. Если book.Version не равна нулю,
+	// книга сохраняется, только если ее текущая версия совпадает с ней,
+	// иначе возвращается ErrVersionConflict.
+	UpdateBook(ctx context.Context, book *models.Book) error
+	// CreateBook, UpdateBook, DeleteBook и RestoreBook записывают изменения
+	// в историю книги от имени автора из контекста (см. WithActor).
+	// DeleteBook перемещает книгу в корзину; ненулевая version проверяется
+	// так же, как в UpdateBook. Книги в корзине не возвращаются
+	// GetBook, ListBooks и SearchBooks и не могут быть изменены.
+	DeleteBook(ctx context.Context, id int64, version int64) error
+	ListBooks(ctx context.Context, opts ListOptions) ([]*models.Book, PageInfo, error)
+	// SearchBooks возвращает книги, отсортированные по релевантности
+	SearchBooks(ctx context.Context, query string, opts PageOptions) ([]*models.SearchHit, PageInfo, error)
+
+	// ListTrash возвращает книги из корзины, сначала удаленные последними
+	ListTrash(ctx context.Context, opts PageOptions) ([]*models.Book, PageInfo, error)
+	// RestoreBook возвращает книгу из корзины
+	RestoreBook(ctx context.Context, id int64) error
+	// PurgeTrash окончательно удаляет книги, попавшие в корзину раньше
+	// указанного момента, и возвращает их количество
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+
+	// BooksByISBN возвращает книги с указанными ISBN по ключу ISBN, включая
+	// книги в корзине. ISBN, которых нет в библиотеке, в ответ не попадают.
+	BooksByISBN(ctx context.Context, isbns []string) (map[string]*models.Book, error)
+
+	// Batch выполняет операции над несколькими книгами в одной транзакции
+	// и возвращает результат каждой операции и признак того, что транзакция
+	// зафиксирована. Ошибка возвращается, только если пакет не удалось
+	// выполнить целиком.
+	Batch(ctx context.Context, ops []BatchOp, atomic bool) (results []BatchResult, committed bool, err error)
+
+	// BookHistory возвращает историю изменений книги, начиная с последнего,
+	// в том числе окончательно удаленной из корзины
+	BookHistory(ctx context.Context, id int64) ([]*models.Revision, error)
+	// RevertBook возвращает поля книги к состоянию после указанной ревизии
+	// и записывает откат в историю
+	RevertBook(ctx context.Context, id int64, revision int) (*models.Book, error)
+
+	// ListAuthors возвращает страницу авторов по алфавиту (по SortName)
+	// и общее количество авторов, подходящих под фильтр
+	ListAuthors(ctx context.Context, opts AuthorListOptions) ([]*models.Author, int, error)
+	// GetAuthor возвращает nil без ошибки, если автор не найден
+	GetAuthor(ctx context.Context, id int64) (*models.Author, error)
+	// CreateAuthor и UpdateAuthor возвращают ErrDuplicateAuthorName, если
+	// имя или одно из написаний имени уже принадлежит другому автору.
+	// UpdateAuthor переименовывает автора во всех его книгах, а прежнее
+	// имя оставляет среди других написаний.
+	CreateAuthor(ctx context.Context, author *models.Author) error
+	UpdateAuthor(ctx context.Context, author *models.Author) error
+	// DeleteAuthor удаляет автора, у которого нет книг (в том числе
+	// в корзине), иначе возвращает ErrAuthorHasBooks
+	DeleteAuthor(ctx context.Context, id int64) error
+	// MergeAuthors в одной транзакции переносит все книги автора sourceID
+	// к автору targetID, добавляет имена sourceID к его другим написаниям
+	// и удаляет sourceID. Возвращает автора targetID после объединения.
+	MergeAuthors(ctx context.Context, sourceID, targetID int64) (*models.Author, error)
+
+	// TagCounts возвращает теги книг вне корзины по алфавиту с количеством книг
+	TagCounts(ctx context.Context) ([]models.TagCount, error)
+
+	// ListShelves возвращает все полки по алфавиту
+	ListShelves(ctx context.Context) ([]*models.Shelf, error)
+	// GetShelf возвращает nil без ошибки, если полка не найдена
+	GetShelf(ctx context.Context, id int64) (*models.Shelf, error)
+	// CreateShelf и UpdateShelf возвращают ErrDuplicateShelfName, если
+	// название уже занято другой полкой
+	CreateShelf(ctx context.Context, shelf *models.Shelf) error
+	UpdateShelf(ctx context.Context, shelf *models.Shelf) error
+	// DeleteShelf удаляет полку; книги с нее остаются в библиотеке
+	DeleteShelf(ctx context.Context, id int64) error
+	// ShelfBooks возвращает страницу книг с полки вне корзины в порядке,
+	// заданном пользователем, и общее количество таких книг
+	ShelfBooks(ctx context.Context, shelfID int64, page, pageSize int) ([]*models.Book, int, error)
+	// AddToShelf кладет книгу на полку перед книгой с номером position
+	// (с 0) или в конец, если position отрицателен или больше числа книг.
+	// Книга, которая уже лежит на полке, перемещается.
+	// Изменение полок книги увеличивает ее версию, как и UpdateBook.
+	AddToShelf(ctx context.Context, shelfID, bookID int64, position int) error
+	// RemoveFromShelf убирает книгу с полки или возвращает ErrBookNotOnShelf
+	RemoveFromShelf(ctx context.Context, shelfID, bookID int64) error
+	// SetShelfBooks заменяет книги на полке книгами bookIDs в этом порядке.
+	// Книги в корзине, которых нет в списке, остаются на полке после них.
+	SetShelfBooks(ctx context.Context, shelfID int64, bookIDs []int64) error
+
+	// ListSeries возвращает все серии по алфавиту. Серии создаются при
+	// сохранении книг с новым названием серии.
+	ListSeries(ctx context.Context) ([]*models.Series, error)
+	// GetSeries возвращает nil без ошибки, если серия не найдена
+	GetSeries(ctx context.Context, id int64) (*models.Series, error)
+	// SeriesBooks возвращает книги серии вне корзины в порядке чтения:
+	// по номеру в серии, книги без номера - в конце по дате публикации
+	SeriesBooks(ctx context.Context, seriesID int64) ([]*models.Book, error)
+
+	// ListWorks возвращает все произведения по алфавиту
+	ListWorks(ctx context.Context) ([]*models.Work, error)
+	// GetWork возвращает nil без ошибки, если произведение не найдено
+	GetWork(ctx context.Context, id int64) (*models.Work, error)
+	CreateWork(ctx context.Context, work *models.Work) error
+	UpdateWork(ctx context.Context, work *models.Work) error
+	// DeleteWork удаляет произведение; его издания остаются в библиотеке
+	// отдельными книгами
+	DeleteWork(ctx context.Context, id int64) error
+	// WorkEditions возвращает издания произведения вне корзины по дате
+	// публикации или ErrWorkNotFound
+	WorkEditions(ctx context.Context, workID int64) ([]*models.Book, error)
+	// LinkEdition делает книгу изданием произведения. Издание другого
+	// произведения переходит к этому.
+	LinkEdition(ctx context.Context, workID, bookID int64) error
+	// UnlinkEdition отвязывает издание от произведения или возвращает
+	// ErrBookNotInWork
+	UnlinkEdition(ctx context.Context, workID, bookID int64) error
+	// SearchWorks ищет так же, как SearchBooks, но из изданий одного
+	// произведения оставляет только первое в выдаче. SearchHit.Editions
+	// содержит количество найденных изданий его произведения.
+	SearchWorks(ctx context.Context, query string, opts PageOptions) ([]*models.SearchHit, PageInfo, error)
+}
+
+var (
+	_ BookRepository = (*Database)(nil)
+	_ BookRepository = (*MemoryRepository)(nil)
+)
@@ -0,0 +1,290 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// ErrRevisionNotFound возвращается, если у книги нет ревизии с указанным номером
+var ErrRevisionNotFound = errors.New("revision not found")
+
+type actorKey struct{}
+
+// WithActor возвращает контекст, изменения в котором записываются
+// в историю от имени actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext возвращает автора изменений, сохраненного WithActor
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// bookFields возвращает поля книги, изменения которых попадают в историю,
+// в JSON-представлении API
+func bookFields(b *models.Book) map[string]json.RawMessage {
+	values := map[string]any{
+		"title":       b.Title,
+		"author":      b.Author,
+		"isbn":        b.ISBN,
+		"published":   b.Published.UTC(),
+		"identifiers": b.Identifiers,
+	}
+	// Пустой список идентификаторов не отличается от их отсутствия
+	if len(b.Identifiers) == 0 {
+		values["identifiers"] = nil
+	}
+	// Серия записывается по названию, как и участники
+	if b.Series != "" {
+		values["series"] = b.Series
+	} else {
+		values["series"] = nil
+	}
+	values["series_position"] = b.SeriesPosition
+	// Пустые сведения об издании не отличаются от их отсутствия
+	for name, value := range map[string]string{"publisher": b.Publisher, "language": b.Language, "format": b.Format} {
+		if value != "" {
+			values[name] = value
+		} else {
+			values[name] = nil
+		}
+	}
+	if len(b.Tags) > 0 {
+		values["tags"] = b.Tags
+	} else {
+		values["tags"] = nil
+	}
+	// Участники записываются без ID авторов: при откате они снова
+	// связываются с авторами по именам
+	if len(b.Contributors) > 0 {
+		contributors := make([]models.Contributor, len(b.Contributors))
+		for i, c := range b.Contributors {
+			contributors[i] = models.Contributor{Name: c.Name, Role: c.Role}
+		}
+		values["contributors"] = contributors
+	} else {
+		values["contributors"] = nil
+	}
+
+	fields := make(map[string]json.RawMessage, len(values))
+	for name, value := range values {
+		fields[name], _ = json.Marshal(value)
+	}
+	return fields
+}
+
+// diffBooks возвращает изменившиеся поля книги. old равен nil для новой книги.
+func diffBooks(old, new *models.Book) map[string]models.FieldChange {
+	oldFields := map[string]json.RawMessage{}
+	if old != nil {
+		oldFields = bookFields(old)
+	}
+
+	changes := make(map[string]models.FieldChange)
+	for name, value := range bookFields(new) {
+		before, ok := oldFields[name]
+		if !ok {
+			before = json.RawMessage("null")
+		}
+		if !bytes.Equal(before, value) {
+			changes[name] = models.FieldChange{Old: before, New: value}
+		}
+	}
+	return changes
+}
+
+// deletedAtChange описывает перемещение книги в корзину или из нее
+func deletedAtChange(old, new *time.Time) map[string]models.FieldChange {
+	oldValue, _ := json.Marshal(old)
+	newValue, _ := json.Marshal(new)
+	return map[string]models.FieldChange{"deleted_at": {Old: oldValue, New: newValue}}
+}
+
+// bookAtRevision восстанавливает поля книги на момент ревизии number,
+// последовательно применяя изменения из истории (revisions - по возрастанию
+// номеров). Поля, которых нет в истории, берутся из текущей книги.
+func bookAtRevision(current *models.Book, revisions []*models.Revision, number int) (*models.Book, error) {
+	found := false
+	state := bookFields(current)
+	for _, rev := range revisions {
+		if rev.Revision > number {
+			break
+		}
+		found = found || rev.Revision == number
+		if rev.Action == models.ActionCreate {
+			// Поля, не записанные при создании книги, были пустыми
+			for name := range state {
+				state[name] = json.RawMessage("null")
+			}
+		}
+		for name, change := range rev.Changes {
+			if _, tracked := state[name]; tracked {
+				state[name] = change.New
+			}
+		}
+	}
+	if !found {
+		return nil, ErrRevisionNotFound
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode book state: %w", err)
+	}
+
+	// null не меняет строку при разборе JSON, поэтому серия и сведения
+	// об издании очищаются заранее
+	book := *current
+	book.Series, book.Publisher, book.Language, book.Format = "", "", "", ""
+	if err := json.Unmarshal(data, &book); err != nil {
+		return nil, fmt.Errorf("failed to decode book state: %w", err)
+	}
+	return &book, nil
+}
+
+// recordRevision записывает ревизию в историю, назначая ей следующий номер,
+// автора из контекста и текущее время
+func (t *dbTx) recordRevision(ctx context.Context, rev *models.Revision) error {
+	changes, err := json.Marshal(rev.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode revision changes: %w", err)
+	}
+
+	err = t.queryRow(ctx, "SELECT COALESCE(MAX(revision), 0) + 1 FROM book_revisions WHERE book_id = ?", rev.BookID).Scan(&rev.Revision)
+	if err != nil {
+		return fmt.Errorf("failed to get next revision number: %w", err)
+	}
+
+	rev.Actor = ActorFromContext(ctx)
+	rev.CreatedAt = time.Now().UTC()
+
+	var revertedTo any
+	if rev.RevertedTo > 0 {
+		revertedTo = rev.RevertedTo
+	}
+
+	query := `
+        INSERT INTO book_revisions (book_id, revision, action, changes, actor, reverted_to, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+        RETURNING id
+    `
+	err = t.queryRow(ctx, query, rev.BookID, rev.Revision, rev.Action, string(changes), rev.Actor, revertedTo, rev.CreatedAt).Scan(&rev.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
+
+// BookHistory возвращает историю изменений книги, начиная с последнего.
+// История доступна и для книг в корзине, и для окончательно удаленных.
+func (d *Database) BookHistory(ctx context.Context, id int64) ([]*models.Revision, error) {
+	revisions, err := loadRevisions(ctx, d, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		// Книги, созданные до появления истории, могут не иметь ревизий
+		exists, err := bookExists(ctx, d, id)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrBookNotFound
+		}
+	}
+
+	// Сначала последние изменения
+	for i, j := 0, len(revisions)-1; i < j; i, j = i+1, j-1 {
+		revisions[i], revisions[j] = revisions[j], revisions[i]
+	}
+	return revisions, nil
+}
+
+// RevertBook возвращает поля книги к состоянию после указанной ревизии.
+// Откат записывается в историю как новая ревизия.
+func (d *Database) RevertBook(ctx context.Context, id int64, revision int) (*models.Book, error) {
+	log.Printf("Attempting to revert book %d to revision %d", id, revision)
+
+	var book *models.Book
+	err := d.inTx(ctx, func(tx *dbTx) error {
+		current, err := getBook(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return ErrBookNotFound
+		}
+
+		revisions, err := loadRevisions(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		book, err = bookAtRevision(current, revisions, revision)
+		if err != nil {
+			return err
+		}
+
+		return tx.updateBook(ctx, book, &models.Revision{Action: models.ActionRevert, RevertedTo: revision})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Successfully reverted book %d to revision %d", id, revision)
+	return book, nil
+}
+
+// bookExists проверяет, есть ли книга в базе, в том числе в корзине
+func bookExists(ctx context.Context, q querier, id int64) (bool, error) {
+	var count int
+	if err := q.queryRow(ctx, "SELECT COUNT(*) FROM books WHERE id = ?", id).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check book existence: %w", err)
+	}
+	return count > 0, nil
+}
+
+// loadRevisions читает историю книги по возрастанию номеров ревизий
+func loadRevisions(ctx context.Context, q querier, bookID int64) ([]*models.Revision, error) {
+	query := `
+        SELECT id, book_id, revision, action, changes, actor, reverted_to, created_at
+        FROM book_revisions
+        WHERE book_id = ?
+        ORDER BY revision
+    `
+	rows, err := q.query(ctx, query, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []*models.Revision
+	for rows.Next() {
+		var rev models.Revision
+		var changes []byte
+		var revertedTo sql.NullInt64
+		err := rows.Scan(&rev.ID, &rev.BookID, &rev.Revision, &rev.Action, &changes, &rev.Actor, &revertedTo, &rev.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan revision row: %w", err)
+		}
+		if err := json.Unmarshal(changes, &rev.Changes); err != nil {
+			return nil, fmt.Errorf("failed to decode revision %d changes: %w", rev.ID, err)
+		}
+		rev.RevertedTo = int(revertedTo.Int64)
+		revisions = append(revisions, &rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revision rows: %w", err)
+	}
+	return revisions, nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testBookHistory(t *testing.T, repo BookRepository) {
+	ctx := WithActor(context.Background(), "alice")
+
+	book := &models.Book{Title: "Война и мир", Author: "Лев Толстой", ISBN: "978-5-17-090335-2", Published: time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := repo.CreateBook(ctx, book); err != nil {
+		t.Fatalf("CreateBook() error = %v", err)
+	}
+
+	book.Title = "Война и мiръ"
+	if err := repo.UpdateBook(WithActor(ctx, "bob"), book); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	// Сохранение без изменений не попадает в историю
+	if err := repo.UpdateBook(ctx, book); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	if err := repo.DeleteBook(ctx, book.ID, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+	if _, err := repo.RevertBook(ctx, book.ID, 1); !errors.Is(err, ErrBookNotFound) {
+		t.Errorf("RevertBook() of trashed book error = %v, want ErrBookNotFound", err)
+	}
+	if err := repo.RestoreBook(ctx, book.ID); err != nil {
+		t.Fatalf("RestoreBook() error = %v", err)
+	}
+
+	history, err := repo.BookHistory(ctx, book.ID)
+	if err != nil {
+		t.Fatalf("BookHistory() error = %v", err)
+	}
+	actions := []string{models.ActionRestore, models.ActionDelete, models.ActionUpdate, models.ActionCreate}
+	if len(history) != len(actions) {
+		t.Fatalf("BookHistory() got %d revisions, want %d", len(history), len(actions))
+	}
+	for i, rev := range history {
+		if rev.Action != actions[i] || rev.Revision != len(actions)-i {
+			t.Errorf("BookHistory()[%d] = %s #%d, want %s #%d", i, rev.Action, rev.Revision, actions[i], len(actions)-i)
+		}
+	}
+
+	update := history[2]
+	if update.Actor != "bob" || history[3].Actor != "alice" {
+		t.Errorf("BookHistory() actors = %q, %q, want bob, alice", update.Actor, history[3].Actor)
+	}
+	change, ok := update.Changes["title"]
+	if len(update.Changes) != 1 || !ok {
+		t.Fatalf("BookHistory() update changes = %v, want only title", update.Changes)
+	}
+	var oldTitle, newTitle string
+	json.Unmarshal(change.Old, &oldTitle)
+	json.Unmarshal(change.New, &newTitle)
+	if oldTitle != "Война и мир" || newTitle != "Война и мiръ" {
+		t.Errorf("BookHistory() title change = %q -> %q", oldTitle, newTitle)
+	}
+	if len(history[3].Changes) != 5 {
+		t.Errorf("BookHistory() create changes = %v, want all fields with contributors", history[3].Changes)
+	}
+
+	// Откат к первой ревизии возвращает название и записывается в историю
+	reverted, err := repo.RevertBook(ctx, book.ID, 1)
+	if err != nil {
+		t.Fatalf("RevertBook() error = %v", err)
+	}
+	if reverted.Title != "Война и мир" {
+		t.Errorf("RevertBook() title = %q, want %q", reverted.Title, "Война и мир")
+	}
+	if stored, _ := repo.GetBook(ctx, book.ID); stored == nil || stored.Title != "Война и мир" || !stored.Published.Equal(book.Published) {
+		t.Errorf("GetBook() after revert = %+v", stored)
+	}
+
+	history, err = repo.BookHistory(ctx, book.ID)
+	if err != nil {
+		t.Fatalf("BookHistory() error = %v", err)
+	}
+	if history[0].Action != models.ActionRevert || history[0].RevertedTo != 1 || len(history[0].Changes) != 1 {
+		t.Errorf("BookHistory() last revision = %+v, want revert to 1 of title", history[0])
+	}
+
+	if _, err := repo.RevertBook(ctx, book.ID, 99); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("RevertBook() to missing revision error = %v, want ErrRevisionNotFound", err)
+	}
+	if _, err := repo.BookHistory(ctx, 9999); !errors.Is(err, ErrBookNotFound) {
+		t.Errorf("BookHistory() of missing book error = %v, want ErrBookNotFound", err)
+	}
+}
+
+func TestBookHistory(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testBookHistory(t, db)
+	})
+}
+
+func TestMemoryBookHistory(t *testing.T) {
+	testBookHistory(t, NewMemoryRepository())
+}
@@ -1,70 +1,292 @@
 package storage
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/NkvXness/GoBookshelf/internal/models"
+	"github.com/NkvXness/GoBookshelf/internal/search"
 )
 
-// SearchBooks выполняет поиск книг по заданному запросу
-func (d *Database) SearchBooks(query string, page, pageSize int) ([]*models.Book, int, error) {
-	log.Printf("Searching books with query=%s, page=%d, pageSize=%d", query, page, pageSize)
+// Веса колонок books_fts при ранжировании: название важнее автора, автор
+// важнее ISBN и других идентификаторов
+const searchWeights = "10.0, 5.0, 1.0, 1.0"
 
-	offset := (page - 1) * pageSize
-	searchQuery := "%" + query + "%"
+// Колонки книги в результатах поиска
+const searchColumns = "id, title, author, COALESCE(isbn, ''), published, created_at, updated_at, version"
+
+// SearchBooks выполняет поиск книг по заданному запросу.
+// Запрос разбирается пакетом search; ошибка разбора возвращается как *search.SyntaxError.
+func (d *Database) SearchBooks(ctx context.Context, query string, opts PageOptions) ([]*models.SearchHit, PageInfo, error) {
+	log.Printf("Searching books with query=%s, options %+v", query, opts)
+
+	node, err := search.Parse(query)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	// Запрос из одних слов выполняется с ранжированием по релевантности
+	if words, plain := search.PlainWords(node); plain {
+		text := strings.Join(words, " ")
+		if d.dialect.fullText {
+			return d.searchFullText(ctx, text, opts)
+		}
+		return d.searchLike(ctx, text, opts)
+	}
+
+	return d.searchStructured(ctx, node, opts)
+}
+
+// SearchWorks выполняет поиск так же, как SearchBooks, оставляя из изданий
+// одного произведения только первое в выдаче
+func (d *Database) SearchWorks(ctx context.Context, query string, opts PageOptions) ([]*models.SearchHit, PageInfo, error) {
+	opts.collapseWorks = true
+	return d.SearchBooks(ctx, query, opts)
+}
+
+// searchOptions возвращает параметры компиляции запроса для диалекта базы
+func (d *Database) searchOptions() search.SQLOptions {
+	opts := search.SQLOptions{Like: d.dialect.like, Lower: d.dialect.lower}
+	if d.dialect.fullText {
+		opts.FullTextTable = "books_fts"
+	}
+	return opts
+}
+
+// searchStructured выполняет запрос с полями и логическими операторами
+func (d *Database) searchStructured(ctx context.Context, node search.Node, opts PageOptions) ([]*models.SearchHit, PageInfo, error) {
+	where, args := search.ToSQL(node, d.searchOptions())
+	log.Printf("Compiled search query: %s", where)
+
+	hits, info, err := d.searchPage(ctx, searchQuery{
+		key:        sortKey{field: SortByCreatedAt, desc: true},
+		keyExpr:    "created_at",
+		idColumn:   "id",
+		columns:    searchColumns,
+		from:       "books",
+		conditions: []string{"deleted_at IS NULL", where},
+		args:       args,
+	}, opts)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	highlightHits(hits, strings.Join(search.HighlightTerms(node), " "))
+
+	return hits, info, nil
+}
+
+// searchFullText ищет книги через индекс FTS5 и сортирует их по bm25.
+// Встроенная bm25() тем меньше, чем релевантнее строка, поэтому в Score
+// попадает значение с обратным знаком. Фрагмент и подсветка возвращаются
+// с экранированным HTML.
+func (d *Database) searchFullText(ctx context.Context, query string, opts PageOptions) ([]*models.SearchHit, PageInfo, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, PageInfo{}, nil
+	}
+
+	hits, info, err := d.searchPage(ctx, searchQuery{
+		key:      sortKey{field: sortScore, desc: true},
+		keyExpr:  "-bm25(books_fts, " + searchWeights + ")",
+		idColumn: "b.id",
+		columns: `b.id, b.title, b.author, COALESCE(b.isbn, ''), b.published, b.created_at, b.updated_at, b.version,
+			-bm25(books_fts, ` + searchWeights + `),
+			snippet(books_fts, -1, ` + ftsMarks + `, '…', 15),
+			highlight(books_fts, 0, ` + ftsMarks + `),
+			highlight(books_fts, 1, ` + ftsMarks + `)`,
+		from:       "books_fts JOIN books b ON b.id = books_fts.rowid",
+		conditions: []string{"books_fts MATCH ?", "b.deleted_at IS NULL"},
+		args:       []any{match},
+		extra: func(hit *models.SearchHit) []any {
+			hit.Highlight = &models.SearchHighlight{}
+			return []any{&hit.Score, &hit.Snippet, &hit.Highlight.Title, &hit.Highlight.Author}
+		},
+	}, opts)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	for _, hit := range hits {
+		hit.Snippet = markHTML(hit.Snippet)
+		hit.Highlight.Title = markHTML(hit.Highlight.Title)
+		hit.Highlight.Author = markHTML(hit.Highlight.Author)
+	}
+
+	return hits, info, nil
+}
+
+// searchLike ищет книги по вхождению подстрок, если полнотекстовый индекс недоступен.
+// Каждое слово запроса должно встретиться в названии, авторе, ISBN или
+// одном из идентификаторов книги.
+func (d *Database) searchLike(ctx context.Context, query string, opts PageOptions) ([]*models.SearchHit, PageInfo, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, PageInfo{}, nil
+	}
+
+	conditions := make([]string, 0, len(terms)+1)
+	conditions = append(conditions, "deleted_at IS NULL")
+	args := make([]any, 0, 4*len(terms))
+	for _, term := range terms {
+		conditions = append(conditions, fmt.Sprintf(
+			`(title %[1]s ? ESCAPE '\' OR author %[1]s ? ESCAPE '\' OR replace(isbn, '-', '') %[1]s ? ESCAPE '\' OR %[2]s)`,
+			d.dialect.like, search.IdentifierCondition(d.dialect.like)))
+		pattern := "%" + search.EscapeLike(term) + "%"
+		args = append(args, pattern, pattern, pattern, pattern)
+	}
+
+	hits, info, err := d.searchPage(ctx, searchQuery{
+		key:        sortKey{field: SortByCreatedAt, desc: true},
+		keyExpr:    "created_at",
+		idColumn:   "id",
+		columns:    searchColumns,
+		from:       "books",
+		conditions: conditions,
+		args:       args,
+	}, opts)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	highlightHits(hits, query)
+
+	return hits, info, nil
+}
+
+// searchQuery описывает поисковый запрос для searchPage
+type searchQuery struct {
+	key      sortKey
+	keyExpr  string
+	idColumn string
+	// columns - поля книги и, возможно, дополнительные колонки для extra
+	columns    string
+	from       string
+	conditions []string
+	args       []any
+	// extra возвращает приемники для колонок, идущих после полей книги
+	extra func(hit *models.SearchHit) []any
+}
+
+// collapsed оборачивает запрос так, что из изданий одного произведения
+// остается первое в порядке выдачи, а количество найденных изданий
+// читается в SearchHit.Editions. Книги без произведения не группируются.
+// Оконные функции применяются к уже выбранным строкам: вызывать bm25
+// и другие функции FTS5 внутри них SQLite не позволяет.
+func (q searchQuery) collapsed() searchQuery {
+	direction := "ASC"
+	if q.key.desc {
+		direction = "DESC"
+	}
+	matches := fmt.Sprintf("SELECT %s, %s AS sort_key, COALESCE(e.work_id, -%s) AS work_key FROM %s LEFT JOIN book_editions e ON e.book_id = %s%s",
+		q.columns, q.keyExpr, q.idColumn, q.from, q.idColumn, whereClause(q.conditions))
+	ranked := fmt.Sprintf(`SELECT *,
+			ROW_NUMBER() OVER (PARTITION BY work_key ORDER BY sort_key %[1]s, id %[1]s) AS edition_rank,
+			COUNT(*) OVER (PARTITION BY work_key) AS editions
+		FROM (%[2]s) matches`, direction, matches)
+
+	extra := q.extra
+	return searchQuery{
+		key:        q.key,
+		keyExpr:    "sort_key",
+		idColumn:   "id",
+		columns:    "*",
+		from:       "(" + ranked + ") hits",
+		conditions: []string{"edition_rank = 1"},
+		args:       q.args,
+		extra: func(hit *models.SearchHit) []any {
+			var dest []any
+			if extra != nil {
+				dest = extra(hit)
+			}
+			var sortKey, workKey, rank any
+			return append(dest, &sortKey, &workKey, &rank, &hit.Editions)
+		},
+	}
+}
+
+// searchPage подсчитывает найденные книги и выбирает одну страницу результата
+func (d *Database) searchPage(ctx context.Context, q searchQuery, opts PageOptions) ([]*models.SearchHit, PageInfo, error) {
+	if opts.collapseWorks {
+		q = q.collapsed()
+	}
 
 	// Получаем общее количество найденных книг
-	var total int
-	err := d.DB.QueryRow(`
-		SELECT COUNT(*) FROM books 
-		WHERE title LIKE ? OR author LIKE ? OR isbn LIKE ?
-	`, searchQuery, searchQuery, searchQuery).Scan(&total)
+	total := -1
+	if !opts.SkipTotal {
+		err := d.queryRow(ctx, "SELECT COUNT(*) FROM "+q.from+whereClause(q.conditions), q.args...).Scan(&total)
+		if err != nil {
+			log.Printf("Error getting search results count: %v", err)
+			return nil, PageInfo{}, fmt.Errorf("failed to get search results count: %w", err)
+		}
+		log.Printf("Found total books: %d", total)
+	}
+
+	page, err := d.pageQuery(q.key, q.keyExpr, q.idColumn, opts)
 	if err != nil {
-		log.Printf("Error getting search results count: %v", err)
-		return nil, 0, fmt.Errorf("failed to get search results count: %w", err)
+		return nil, PageInfo{}, err
+	}
+	conditions, args := q.conditions, q.args
+	if page.where != "" {
+		conditions = append(conditions, page.where)
+		args = append(args, page.args...)
 	}
-	log.Printf("Found total books: %d", total)
 
 	// Получаем найденные книги для текущей страницы
-	query = `
-		SELECT id, title, author, isbn, published, created_at, updated_at
-		FROM books
-		WHERE title LIKE ? OR author LIKE ? OR isbn LIKE ?
-		ORDER BY created_at DESC
-		LIMIT ? OFFSET ?
-	`
-	rows, err := d.DB.Query(query, searchQuery, searchQuery, searchQuery, pageSize, offset)
+	sqlQuery := "SELECT " + q.columns + " FROM " + q.from + whereClause(conditions) + page.tail
+	rows, err := d.query(ctx, sqlQuery, append(args, page.tailArgs...)...)
 	if err != nil {
 		log.Printf("Error searching books: %v", err)
-		return nil, 0, fmt.Errorf("failed to search books: %w", err)
+		return nil, PageInfo{}, fmt.Errorf("failed to search books: %w", err)
 	}
 	defer rows.Close()
 
-	var books []*models.Book
+	hits, err := scanSearchHits(rows, q.extra)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	hits, info := finishPage(hits, q.key, opts, page.cursor, hitKey)
+	info.Total = total
+	if err := loadRelations(ctx, d, hitBooks(hits)); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	log.Printf("Successfully retrieved %d books from search", len(hits))
+	return hits, info, nil
+}
+
+// scanSearchHits читает строки результата поиска. Функция extra возвращает
+// приемники для колонок, идущих после полей книги.
+func scanSearchHits(rows *sql.Rows, extra func(hit *models.SearchHit) []any) ([]*models.SearchHit, error) {
+	var hits []*models.SearchHit
 	for rows.Next() {
-		var book models.Book
-		err := rows.Scan(
-			&book.ID,
-			&book.Title,
-			&book.Author,
-			&book.ISBN,
-			&book.Published,
-			&book.CreatedAt,
-			&book.UpdatedAt,
-		)
-		if err != nil {
+		var hit models.SearchHit
+		dest := []any{
+			&hit.ID,
+			&hit.Title,
+			&hit.Author,
+			&hit.ISBN,
+			&hit.Published,
+			&hit.CreatedAt,
+			&hit.UpdatedAt,
+			&hit.Version,
+		}
+		if extra != nil {
+			dest = append(dest, extra(&hit)...)
+		}
+
+		if err := rows.Scan(dest...); err != nil {
 			log.Printf("Error scanning book row: %v", err)
-			return nil, 0, fmt.Errorf("failed to scan book row: %w", err)
+			return nil, fmt.Errorf("failed to scan book row: %w", err)
 		}
-		books = append(books, &book)
+		hits = append(hits, &hit)
 	}
 
 	if err := rows.Err(); err != nil {
 		log.Printf("Error iterating book rows: %v", err)
-		return nil, 0, fmt.Errorf("error iterating book rows: %w", err)
+		return nil, fmt.Errorf("error iterating book rows: %w", err)
 	}
 
-	log.Printf("Successfully retrieved %d books from search", len(books))
-	return books, total, nil
+	return hits, nil
 }
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// firstPage - первая страница результатов поиска
+var firstPage = PageOptions{Page: 1, PageSize: 10}
+
+func createSearchFixtures(t *testing.T, db *Database) []*models.Book {
+	books := []*models.Book{
+		{Title: "Война и мир", Author: "Лев Толстой", ISBN: "978-5-17-090335-2"},
+		{Title: "Анна Каренина", Author: "Лев Толстой", ISBN: "978-5-389-07435-4"},
+		{Title: "Thérèse Raquin", Author: "Émile Zola", ISBN: "978-0-14-044944-8"},
+		{Title: "Толстой: биография", Author: "Павел Басинский", ISBN: "978-5-17-982216-0"},
+	}
+	for _, book := range books {
+		book.Published = time.Now().Add(-24 * time.Hour)
+		if err := db.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("Failed to create test book: %v", err)
+		}
+	}
+	return books
+}
+
+func TestSearchBooks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		createSearchFixtures(t, db)
+
+		tests := []struct {
+			name  string
+			query string
+			want  int
+		}{
+			{"cyrillic lower case", "толстой", 3},
+			{"cyrillic upper case", "ТОЛСТОЙ", 3},
+			{"several words", "лев каренина", 1},
+			{"isbn with hyphens", "978-5-17-090335-2", 1},
+			{"isbn-10", "5-389-07435-1", 1},
+			{"no matches", "Достоевский", 0},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				hits, info, err := db.SearchBooks(context.Background(), tt.query, firstPage)
+				if err != nil {
+					t.Fatalf("SearchBooks() error = %v", err)
+				}
+				if info.Total != tt.want || len(hits) != tt.want {
+					t.Errorf("SearchBooks(%q) got %d books (total %d), want %d", tt.query, len(hits), info.Total, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestSearchBooksStructured(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		books := createSearchFixtures(t, db)
+		ctx := context.Background()
+
+		// Даты публикации задаются отдельно, фикстуры создаются со вчерашней датой
+		for i, year := range []int{1869, 1878, 1867, 2010} {
+			books[i].Published = time.Date(year, 1, 15, 0, 0, 0, 0, time.UTC)
+			if err := db.UpdateBook(ctx, books[i]); err != nil {
+				t.Fatalf("UpdateBook() error = %v", err)
+			}
+		}
+
+		tests := []struct {
+			query string
+			want  int
+		}{
+			{`author:толстой`, 2},
+			{`author:"Лев Толстой" title:анна`, 1},
+			{`title:толстой`, 1},
+			{`author:толстой published:>=1870`, 1},
+			{`published:1869`, 1},
+			{`published:>1867 published:<2000`, 2},
+			{`isbn:978-5*`, 3},
+			{`isbn:978-5-17-090335-2`, 1},
+			{`isbn:538907435x`, 0},
+			{`isbn:5389074351`, 1},
+			{`толстой -isbn:978-5-17*`, 1},
+			{`zola OR (author:толстой AND published:<1870)`, 2},
+			{`NOT author:толстой`, 2},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				hits, info, err := db.SearchBooks(ctx, tt.query, firstPage)
+				if err != nil {
+					t.Fatalf("SearchBooks() error = %v", err)
+				}
+				if info.Total != tt.want || len(hits) != tt.want {
+					t.Errorf("SearchBooks(%s) got %d books (total %d), want %d", tt.query, len(hits), info.Total, tt.want)
+				}
+			})
+		}
+
+		if _, _, err := db.SearchBooks(ctx, `genre:poetry`, firstPage); err == nil {
+			t.Error("SearchBooks() expected syntax error for unknown field")
+		}
+	})
+}
+
+func TestSearchBooksFullText(t *testing.T) {
+	db, cleanup := setupTestDB(t, "test.db")
+	defer cleanup()
+
+	books := createSearchFixtures(t, db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"prefix", "толст", 3},
+		{"diacritics removed", "emile", 1},
+		{"isbn prefix without hyphens", "9785170", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, info, err := db.SearchBooks(ctx, tt.query, firstPage)
+			if err != nil {
+				t.Fatalf("SearchBooks() error = %v", err)
+			}
+			if info.Total != tt.want || len(hits) != tt.want {
+				t.Errorf("SearchBooks(%q) got %d books (total %d), want %d", tt.query, len(hits), info.Total, tt.want)
+			}
+		})
+	}
+
+	// Совпадение в названии весит больше, чем в имени автора
+	hits, _, err := db.SearchBooks(ctx, "толстой", firstPage)
+	if err != nil {
+		t.Fatalf("SearchBooks() error = %v", err)
+	}
+	if hits[0].ID != books[3].ID {
+		t.Errorf("SearchBooks() first hit = %q, want %q", hits[0].Title, books[3].Title)
+	}
+	if hits[0].Score <= hits[1].Score {
+		t.Errorf("SearchBooks() scores not in descending order: %v, %v", hits[0].Score, hits[1].Score)
+	}
+	if hits[0].Highlight == nil || !strings.Contains(hits[0].Highlight.Title, "<mark>Толстой</mark>") {
+		t.Errorf("SearchBooks() highlight = %+v, want marked title", hits[0].Highlight)
+	}
+
+	// Индекс обновляется триггерами при изменении и удалении книг
+	books[0].Title = "Севастопольские рассказы"
+	if err := db.UpdateBook(ctx, books[0]); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	if err := db.DeleteBook(ctx, books[1].ID, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+
+	for query, want := range map[string]int{"война": 0, "севастопольские": 1, "каренина": 0} {
+		_, info, err := db.SearchBooks(ctx, query, firstPage)
+		if err != nil {
+			t.Fatalf("SearchBooks() error = %v", err)
+		}
+		if info.Total != want {
+			t.Errorf("SearchBooks(%q) after changes got total = %d, want %d", query, info.Total, want)
+		}
+	}
+}
+
+// testSearchEscapesHTML проверяет, что в подсветке размечены только
+// совпадения, а HTML из названия и имени автора экранирован
+func testSearchEscapesHTML(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	book := &models.Book{Title: `<img src=x onerror="alert(1)"> Война & мир`, Author: "<b>Лев</b> Толстой"}
+	if err := repo.CreateBook(ctx, book); err != nil {
+		t.Fatalf("CreateBook() error = %v", err)
+	}
+
+	for _, query := range []string{"война толстой", "title:война author:толстой"} {
+		hits, _, err := repo.SearchBooks(ctx, query, firstPage)
+		if err != nil || len(hits) != 1 || hits[0].Highlight == nil {
+			t.Fatalf("SearchBooks(%q) = %d hits, %v", query, len(hits), err)
+		}
+		highlight := hits[0].Highlight
+		if want := `&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>Война</mark> &amp; мир`; highlight.Title != want {
+			t.Errorf("SearchBooks(%q) title highlight = %s, want %s", query, highlight.Title, want)
+		}
+		if want := `&lt;b&gt;Лев&lt;/b&gt; <mark>Толстой</mark>`; highlight.Author != want {
+			t.Errorf("SearchBooks(%q) author highlight = %s, want %s", query, highlight.Author, want)
+		}
+		if strings.Contains(hits[0].Snippet, "<img") || strings.Contains(hits[0].Snippet, "<b>") {
+			t.Errorf("SearchBooks(%q) snippet = %s, want escaped HTML", query, hits[0].Snippet)
+		}
+	}
+}
+
+func TestSearchEscapesHTML(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testSearchEscapesHTML(t, db)
+	})
+}
+
+func TestMemorySearchEscapesHTML(t *testing.T) {
+	testSearchEscapesHTML(t, NewMemoryRepository())
+}
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"Лев Толстой", `"Лев"* "Толстой"*`},
+		{"  war, peace! ", `"war"* "peace"*`},
+		{"978-5-17", `"978517"*`},
+		{"5-85270-001-X", `"585270001x"*`},
+		{`"; DROP`, `"DROP"*`},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ftsQuery(tt.query); got != tt.want {
+			t.Errorf("ftsQuery(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
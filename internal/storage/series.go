@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// seriesQuery выбирает серии вместе с количеством их книг вне корзины
+const seriesQuery = `
+    SELECT s.id, s.name, (
+        SELECT COUNT(*) FROM book_series bs JOIN books b ON b.id = bs.book_id
+        WHERE bs.series_id = s.id AND b.deleted_at IS NULL
+    )
+    FROM series s`
+
+func (d *Database) ListSeries(ctx context.Context) ([]*models.Series, error) {
+	rows, err := d.query(ctx, seriesQuery+" ORDER BY "+fmt.Sprintf(d.dialect.noCase, "s.name")+", s.id")
+	if err != nil {
+		log.Printf("Error querying series: %v", err)
+		return nil, fmt.Errorf("failed to query series: %w", err)
+	}
+	return scanSeries(rows)
+}
+
+func (d *Database) GetSeries(ctx context.Context, id int64) (*models.Series, error) {
+	rows, err := d.query(ctx, seriesQuery+" WHERE s.id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get series: %w", err)
+	}
+	series, err := scanSeries(rows)
+	if err != nil || len(series) == 0 {
+		return nil, err
+	}
+	return series[0], nil
+}
+
+// scanSeries читает серии из результата seriesQuery и закрывает rows
+func scanSeries(rows *sql.Rows) ([]*models.Series, error) {
+	defer rows.Close()
+
+	var result []*models.Series
+	for rows.Next() {
+		var s models.Series
+		if err := rows.Scan(&s.ID, &s.Name, &s.BookCount); err != nil {
+			return nil, fmt.Errorf("failed to scan series row: %w", err)
+		}
+		result = append(result, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating series rows: %w", err)
+	}
+	return result, nil
+}
+
+func (d *Database) SeriesBooks(ctx context.Context, seriesID int64) ([]*models.Book, error) {
+	// Книги без номера идут после пронумерованных, затем по дате публикации
+	query := `
+        SELECT b.id, b.title, b.author, COALESCE(b.isbn, ''), b.published, b.created_at, b.updated_at, b.version
+        FROM book_series bs
+        JOIN books b ON b.id = bs.book_id
+        WHERE bs.series_id = ? AND b.deleted_at IS NULL
+        ORDER BY CASE WHEN bs.position IS NULL THEN 1 ELSE 0 END, bs.position, b.published, b.id`
+	rows, err := d.query(ctx, query, seriesID)
+	if err != nil {
+		log.Printf("Error querying series books: %v", err)
+		return nil, fmt.Errorf("failed to query series books: %w", err)
+	}
+	defer rows.Close()
+
+	var books []*models.Book
+	for rows.Next() {
+		var book models.Book
+		err := rows.Scan(
+			&book.ID,
+			&book.Title,
+			&book.Author,
+			&book.ISBN,
+			&book.Published,
+			&book.CreatedAt,
+			&book.UpdatedAt,
+			&book.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		books = append(books, &book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book rows: %w", err)
+	}
+
+	if err := loadRelations(ctx, d, books); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// resolveSeries связывает книгу с серией по названию без учета регистра,
+// создавая серию, если ее еще нет, и заменяет название книги на название
+// серии
+func (t *dbTx) resolveSeries(ctx context.Context, book *models.Book) error {
+	book.SeriesID = 0
+	if book.Series == "" {
+		return nil
+	}
+
+	query := fmt.Sprintf("SELECT id, name FROM series WHERE %s(name) = ?", t.dialect.lower)
+	err := t.queryRow(ctx, query, models.NameKey(book.Series)).Scan(&book.SeriesID, &book.Series)
+	if err == sql.ErrNoRows {
+		err = t.queryRow(ctx, "INSERT INTO series (name) VALUES (?) RETURNING id", book.Series).Scan(&book.SeriesID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve series: %w", err)
+	}
+	return nil
+}
+
+// saveSeries заменяет серию книги и ее номер в серии
+func (t *dbTx) saveSeries(ctx context.Context, book *models.Book) error {
+	if _, err := t.exec(ctx, "DELETE FROM book_series WHERE book_id = ?", book.ID); err != nil {
+		return fmt.Errorf("failed to delete book series: %w", err)
+	}
+	if book.SeriesID == 0 {
+		return nil
+	}
+
+	_, err := t.exec(ctx, "INSERT INTO book_series (book_id, series_id, position) VALUES (?, ?, ?)",
+		book.ID, book.SeriesID, book.SeriesPosition)
+	if err != nil {
+		return fmt.Errorf("failed to save book series: %w", err)
+	}
+	return nil
+}
+
+// loadSeries заполняет серии книг и их номера в сериях
+func loadSeries(ctx context.Context, q querier, books []*models.Book) error {
+	for _, b := range books {
+		b.Series, b.SeriesID, b.SeriesPosition = "", 0, nil
+	}
+
+	query := `
+        SELECT bs.book_id, bs.series_id, s.name, bs.position
+        FROM book_series bs
+        JOIN series s ON s.id = bs.series_id
+        WHERE bs.book_id IN (%s)`
+	err := queryByBooks(ctx, q, books, query, func(rows *sql.Rows, byID map[int64]*models.Book) error {
+		var bookID, seriesID int64
+		var name string
+		var position sql.NullFloat64
+		if err := rows.Scan(&bookID, &seriesID, &name, &position); err != nil {
+			return err
+		}
+		if b, ok := byID[bookID]; ok {
+			b.Series, b.SeriesID = name, seriesID
+			if position.Valid {
+				b.SeriesPosition = &position.Float64
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load book series: %w", err)
+	}
+	return nil
+}
+
+// sameSeries проверяет, совпадают ли серия и номер в ней у двух книг
+func sameSeries(a, b *models.Book) bool {
+	if a.SeriesID != b.SeriesID || (a.SeriesPosition == nil) != (b.SeriesPosition == nil) {
+		return false
+	}
+	return a.SeriesPosition == nil || *a.SeriesPosition == *b.SeriesPosition
+}
+
+func (m *MemoryRepository) ListSeries(ctx context.Context) ([]*models.Series, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*models.Series, 0, len(m.series))
+	for _, s := range m.series {
+		result = append(result, m.seriesWithCount(s))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if c := compareNoCase(result[i].Name, result[j].Name); c != 0 {
+			return c < 0
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (m *MemoryRepository) GetSeries(ctx context.Context, id int64) (*models.Series, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, exists := m.series[id]
+	if !exists {
+		return nil, nil
+	}
+	return m.seriesWithCount(s), nil
+}
+
+func (m *MemoryRepository) SeriesBooks(ctx context.Context, seriesID int64) ([]*models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	books := m.sorted(func(b *models.Book) bool {
+		return b.DeletedAt == nil && b.SeriesID == seriesID
+	})
+	sort.SliceStable(books, func(i, j int) bool {
+		a, b := books[i], books[j]
+		switch {
+		case (a.SeriesPosition == nil) != (b.SeriesPosition == nil):
+			return a.SeriesPosition != nil
+		case a.SeriesPosition != nil && *a.SeriesPosition != *b.SeriesPosition:
+			return *a.SeriesPosition < *b.SeriesPosition
+		case !a.Published.Equal(b.Published):
+			return a.Published.Before(b.Published)
+		}
+		return a.ID < b.ID
+	})
+	return books, nil
+}
+
+// resolveSeries связывает книгу с серией так же, как dbTx.resolveSeries
+// (вызывается под блокировкой)
+func (m *MemoryRepository) resolveSeries(book *models.Book) {
+	book.SeriesID = 0
+	if book.Series == "" {
+		return
+	}
+
+	key := models.NameKey(book.Series)
+	for _, s := range m.series {
+		if models.NameKey(s.Name) == key {
+			book.SeriesID, book.Series = s.ID, s.Name
+			return
+		}
+	}
+	s := &models.Series{ID: m.nextSeriesID, Name: book.Series}
+	m.nextSeriesID++
+	m.series[s.ID] = s
+	book.SeriesID = s.ID
+}
+
+// seriesWithCount возвращает копию серии с количеством ее книг вне корзины
+// (вызывается под блокировкой)
+func (m *MemoryRepository) seriesWithCount(s *models.Series) *models.Series {
+	result := *s
+	result.BookCount = 0
+	for _, b := range m.books {
+		if b.DeletedAt == nil && b.SeriesID == s.ID {
+			result.BookCount++
+		}
+	}
+	return &result
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testSeries(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	position := func(p float64) *float64 { return &p }
+
+	newBook := func(title, series string, pos *float64, year int) *models.Book {
+		t.Helper()
+		book := &models.Book{
+			Title:          title,
+			Author:         "Стивен Кинг",
+			Published:      time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC),
+			Series:         series,
+			SeriesPosition: pos,
+		}
+		book.Normalize()
+		if err := repo.CreateBook(ctx, book); err != nil {
+			t.Fatalf("CreateBook(%q) error = %v", title, err)
+		}
+		return book
+	}
+
+	wizard := newBook("Колдун и кристалл", "Тёмная башня", position(4), 1997)
+	gunslinger := newBook("Стрелок", "Тёмная Башня", position(1), 1982)
+	wind := newBook("Ветер сквозь замочную скважину", "тёмная  башня", position(4.5), 2012)
+	newBook("Зал одиночества", "Тёмная башня", nil, 2000)
+	newBook("Оно", "", nil, 1986)
+
+	if gunslinger.Series != "Тёмная башня" || gunslinger.SeriesID != wizard.SeriesID || wind.SeriesID != wizard.SeriesID {
+		t.Errorf("CreateBook() series = %q %d, want linked to %d", gunslinger.Series, gunslinger.SeriesID, wizard.SeriesID)
+	}
+
+	got, _ := repo.GetBook(ctx, wind.ID)
+	if got.Series != "Тёмная башня" || got.SeriesPosition == nil || *got.SeriesPosition != 4.5 {
+		t.Errorf("GetBook() series = %q %v", got.Series, got.SeriesPosition)
+	}
+
+	series, err := repo.GetSeries(ctx, wizard.SeriesID)
+	if err != nil || series == nil || series.Name != "Тёмная башня" || series.BookCount != 4 {
+		t.Fatalf("GetSeries() = %+v, %v", series, err)
+	}
+	books, err := repo.SeriesBooks(ctx, series.ID)
+	if err != nil {
+		t.Fatalf("SeriesBooks() error = %v", err)
+	}
+	var titles []string
+	for _, b := range books {
+		titles = append(titles, b.Title)
+	}
+	want := []string{"Стрелок", "Колдун и кристалл", "Ветер сквозь замочную скважину", "Зал одиночества"}
+	if len(titles) != len(want) {
+		t.Fatalf("SeriesBooks() = %q, want %q", titles, want)
+	}
+	for i := range want {
+		if titles[i] != want[i] {
+			t.Errorf("SeriesBooks() = %q, want %q", titles, want)
+			break
+		}
+	}
+
+	hits, _, err := repo.SearchBooks(ctx, "series:башня -series:зал", firstPage)
+	if err != nil || len(hits) != 4 {
+		t.Errorf("SearchBooks(series:) = %d hits, %v; want 4", len(hits), err)
+	}
+
+	// Изменение номера записывается в историю, уход из серии оставляет серию
+	wind.SeriesPosition = position(8)
+	if err := repo.UpdateBook(ctx, wind); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	history, _ := repo.BookHistory(ctx, wind.ID)
+	if len(history) != 2 || string(history[0].Changes["series_position"].Old) != "4.5" {
+		t.Errorf("BookHistory() = %+v, want series_position change", history)
+	}
+	wind.Series, wind.SeriesPosition = "", nil
+	if err := repo.UpdateBook(ctx, wind); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	if got, _ = repo.GetBook(ctx, wind.ID); got.Series != "" || got.SeriesID != 0 || got.SeriesPosition != nil {
+		t.Errorf("GetBook() after leaving series = %q %d %v", got.Series, got.SeriesID, got.SeriesPosition)
+	}
+	if _, err := repo.RevertBook(ctx, wind.ID, 1); err != nil {
+		t.Fatalf("RevertBook() error = %v", err)
+	}
+	if got, _ = repo.GetBook(ctx, wind.ID); got.SeriesID != series.ID || *got.SeriesPosition != 4.5 {
+		t.Errorf("GetBook() after revert = %q %d %v", got.Series, got.SeriesID, got.SeriesPosition)
+	}
+	if _, err := repo.RevertBook(ctx, wind.ID, 3); err != nil {
+		t.Fatalf("RevertBook() error = %v", err)
+	}
+	if got, _ = repo.GetBook(ctx, wind.ID); got.Series != "" || got.SeriesPosition != nil {
+		t.Errorf("GetBook() after revert to no series = %q %v", got.Series, got.SeriesPosition)
+	}
+
+	if err := repo.DeleteBook(ctx, gunslinger.ID, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+	all, err := repo.ListSeries(ctx)
+	if err != nil || len(all) != 1 || all[0].BookCount != 2 {
+		t.Errorf("ListSeries() = %+v, %v; want one series with 2 books", all, err)
+	}
+	if missing, err := repo.GetSeries(ctx, 999); missing != nil || err != nil {
+		t.Errorf("GetSeries(999) = %+v, %v; want nil", missing, err)
+	}
+}
+
+func TestSeries(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testSeries(t, db)
+	})
+}
+
+func TestMemorySeries(t *testing.T) {
+	testSeries(t, NewMemoryRepository())
+}
@@ -0,0 +1,620 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// shelfQuery выбирает полки вместе с количеством книг на них вне корзины
+const shelfQuery = `
+    SELECT s.id, s.name, s.description, s.created_at, s.updated_at, (
+        SELECT COUNT(*) FROM shelf_books sb JOIN books b ON b.id = sb.book_id
+        WHERE sb.shelf_id = s.id AND b.deleted_at IS NULL
+    )
+    FROM shelves s`
+
+func (d *Database) ListShelves(ctx context.Context) ([]*models.Shelf, error) {
+	rows, err := d.query(ctx, shelfQuery+" ORDER BY "+fmt.Sprintf(d.dialect.noCase, "s.name")+", s.id")
+	if err != nil {
+		log.Printf("Error querying shelves: %v", err)
+		return nil, fmt.Errorf("failed to query shelves: %w", err)
+	}
+	return scanShelves(rows)
+}
+
+func (d *Database) GetShelf(ctx context.Context, id int64) (*models.Shelf, error) {
+	return getShelf(ctx, d, id)
+}
+
+// getShelf читает полку; возвращает nil без ошибки, если ее нет
+func getShelf(ctx context.Context, q querier, id int64) (*models.Shelf, error) {
+	rows, err := q.query(ctx, shelfQuery+" WHERE s.id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shelf: %w", err)
+	}
+	shelves, err := scanShelves(rows)
+	if err != nil || len(shelves) == 0 {
+		return nil, err
+	}
+	return shelves[0], nil
+}
+
+// scanShelves читает полки из результата shelfQuery и закрывает rows
+func scanShelves(rows *sql.Rows) ([]*models.Shelf, error) {
+	defer rows.Close()
+
+	var shelves []*models.Shelf
+	for rows.Next() {
+		var s models.Shelf
+		if err := rows.Scan(&s.ID, &s.Name, &s.Description, &s.CreatedAt, &s.UpdatedAt, &s.BookCount); err != nil {
+			return nil, fmt.Errorf("failed to scan shelf row: %w", err)
+		}
+		shelves = append(shelves, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shelf rows: %w", err)
+	}
+	return shelves, nil
+}
+
+func (d *Database) CreateShelf(ctx context.Context, shelf *models.Shelf) error {
+	now := time.Now().UTC()
+	err := d.queryRow(ctx, "INSERT INTO shelves (name, description, created_at, updated_at) VALUES (?, ?, ?, ?) RETURNING id",
+		shelf.Name, shelf.Description, now, now).Scan(&shelf.ID)
+	if err != nil {
+		if d.dialect.isUniqueViolation(err) {
+			return ErrDuplicateShelfName
+		}
+		log.Printf("Error creating shelf: %v", err)
+		return fmt.Errorf("failed to create shelf: %w", err)
+	}
+	shelf.CreatedAt = now
+	shelf.UpdatedAt = now
+	shelf.BookCount = 0
+
+	log.Printf("Created shelf %d %q", shelf.ID, shelf.Name)
+	return nil
+}
+
+func (d *Database) UpdateShelf(ctx context.Context, shelf *models.Shelf) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		result, err := tx.exec(ctx, "UPDATE shelves SET name = ?, description = ?, updated_at = ? WHERE id = ?",
+			shelf.Name, shelf.Description, time.Now().UTC(), shelf.ID)
+		if err != nil {
+			if tx.dialect.isUniqueViolation(err) {
+				return ErrDuplicateShelfName
+			}
+			return fmt.Errorf("failed to update shelf: %w", err)
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get update result: %w", err)
+		}
+		if updated == 0 {
+			return ErrShelfNotFound
+		}
+
+		saved, err := getShelf(ctx, tx, shelf.ID)
+		if err != nil {
+			return err
+		}
+		*shelf = *saved
+		return nil
+	})
+}
+
+func (d *Database) DeleteShelf(ctx context.Context, id int64) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		if err := tx.touchShelf(ctx, id); err != nil {
+			return err
+		}
+		order, _, err := tx.shelfOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.saveShelfOrder(ctx, id, order, nil); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, "DELETE FROM shelves WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete shelf: %w", err)
+		}
+
+		log.Printf("Deleted shelf %d", id)
+		return nil
+	})
+}
+
+func (d *Database) ShelfBooks(ctx context.Context, shelfID int64, page, pageSize int) ([]*models.Book, int, error) {
+	shelf, err := getShelf(ctx, d, shelfID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if shelf == nil {
+		return nil, 0, ErrShelfNotFound
+	}
+
+	query := `
+        SELECT b.id, b.title, b.author, COALESCE(b.isbn, ''), b.published, b.created_at, b.updated_at, b.version
+        FROM shelf_books sb
+        JOIN books b ON b.id = sb.book_id
+        WHERE sb.shelf_id = ? AND b.deleted_at IS NULL
+        ORDER BY sb.position
+        LIMIT ? OFFSET ?`
+	rows, err := d.query(ctx, query, shelfID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		log.Printf("Error querying shelf books: %v", err)
+		return nil, 0, fmt.Errorf("failed to query shelf books: %w", err)
+	}
+	defer rows.Close()
+
+	var books []*models.Book
+	for rows.Next() {
+		var book models.Book
+		err := rows.Scan(
+			&book.ID,
+			&book.Title,
+			&book.Author,
+			&book.ISBN,
+			&book.Published,
+			&book.CreatedAt,
+			&book.UpdatedAt,
+			&book.Version,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		books = append(books, &book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating book rows: %w", err)
+	}
+
+	if err := loadRelations(ctx, d, books); err != nil {
+		return nil, 0, err
+	}
+	return books, shelf.BookCount, nil
+}
+
+func (d *Database) AddToShelf(ctx context.Context, shelfID, bookID int64, position int) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		if err := tx.touchShelf(ctx, shelfID); err != nil {
+			return err
+		}
+		if err := tx.checkActiveBooks(ctx, []int64{bookID}); err != nil {
+			return err
+		}
+		order, trashed, err := tx.shelfOrder(ctx, shelfID)
+		if err != nil {
+			return err
+		}
+		return tx.saveShelfOrder(ctx, shelfID, order, insertOnShelf(order, trashed, bookID, position))
+	})
+}
+
+func (d *Database) RemoveFromShelf(ctx context.Context, shelfID, bookID int64) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		if err := tx.touchShelf(ctx, shelfID); err != nil {
+			return err
+		}
+		order, _, err := tx.shelfOrder(ctx, shelfID)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(order, bookID) {
+			return ErrBookNotOnShelf
+		}
+		return tx.saveShelfOrder(ctx, shelfID, order, withoutBook(order, bookID))
+	})
+}
+
+func (d *Database) SetShelfBooks(ctx context.Context, shelfID int64, bookIDs []int64) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		if err := tx.touchShelf(ctx, shelfID); err != nil {
+			return err
+		}
+		if err := tx.checkActiveBooks(ctx, bookIDs); err != nil {
+			return err
+		}
+		order, trashed, err := tx.shelfOrder(ctx, shelfID)
+		if err != nil {
+			return err
+		}
+		return tx.saveShelfOrder(ctx, shelfID, order, replaceOnShelf(order, trashed, bookIDs))
+	})
+}
+
+// touchShelf обновляет время изменения полки перед изменением ее книг
+// или возвращает ErrShelfNotFound. Заодно блокирует строку полки до конца
+// транзакции, и параллельные изменения одной полки не смешиваются.
+func (t *dbTx) touchShelf(ctx context.Context, shelfID int64) error {
+	result, err := t.exec(ctx, "UPDATE shelves SET updated_at = ? WHERE id = ?", time.Now().UTC(), shelfID)
+	if err != nil {
+		return fmt.Errorf("failed to update shelf: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get update result: %w", err)
+	}
+	if updated == 0 {
+		return ErrShelfNotFound
+	}
+	return nil
+}
+
+// checkActiveBooks проверяет, что все книги существуют и не лежат в корзине
+func (t *dbTx) checkActiveBooks(ctx context.Context, bookIDs []int64) error {
+	for _, id := range bookIDs {
+		var count int
+		err := t.queryRow(ctx, "SELECT COUNT(*) FROM books WHERE id = ? AND deleted_at IS NULL", id).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to check book existence: %w", err)
+		}
+		if count == 0 {
+			return ErrBookNotFound
+		}
+	}
+	return nil
+}
+
+// shelfOrder возвращает ID книг на полке по порядку, включая книги
+// в корзине, и множество книг в корзине
+func (t *dbTx) shelfOrder(ctx context.Context, shelfID int64) ([]int64, map[int64]bool, error) {
+	query := `
+        SELECT sb.book_id, b.deleted_at IS NOT NULL
+        FROM shelf_books sb
+        JOIN books b ON b.id = sb.book_id
+        WHERE sb.shelf_id = ?
+        ORDER BY sb.position`
+	rows, err := t.query(ctx, query, shelfID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query shelf books: %w", err)
+	}
+	defer rows.Close()
+
+	var order []int64
+	trashed := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		var deleted bool
+		if err := rows.Scan(&id, &deleted); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan shelf book: %w", err)
+		}
+		order = append(order, id)
+		trashed[id] = deleted
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating shelf books: %w", err)
+	}
+	return order, trashed, nil
+}
+
+// saveShelfOrder заменяет книги на полке порядком order и увеличивает
+// версию книг, которые появились на полке или пропали с нее
+func (t *dbTx) saveShelfOrder(ctx context.Context, shelfID int64, previous, order []int64) error {
+	if _, err := t.exec(ctx, "DELETE FROM shelf_books WHERE shelf_id = ?", shelfID); err != nil {
+		return fmt.Errorf("failed to delete shelf books: %w", err)
+	}
+	for i, id := range order {
+		_, err := t.exec(ctx, "INSERT INTO shelf_books (shelf_id, book_id, position) VALUES (?, ?, ?)", shelfID, id, i)
+		if err != nil {
+			return fmt.Errorf("failed to save shelf book: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, id := range movedBooks(previous, order) {
+		_, err := t.exec(ctx, "UPDATE books SET updated_at = ?, version = version + 1 WHERE id = ?", now, id)
+		if err != nil {
+			return fmt.Errorf("failed to update book %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// loadShelves заполняет полки, на которых лежат книги
+func loadShelves(ctx context.Context, q querier, books []*models.Book) error {
+	for _, b := range books {
+		b.Shelves = nil
+	}
+
+	query := `
+        SELECT book_id, shelf_id
+        FROM shelf_books
+        WHERE book_id IN (%s)
+        ORDER BY book_id, shelf_id`
+	err := queryByBooks(ctx, q, books, query, func(rows *sql.Rows, byID map[int64]*models.Book) error {
+		var bookID, shelfID int64
+		if err := rows.Scan(&bookID, &shelfID); err != nil {
+			return err
+		}
+		if b, ok := byID[bookID]; ok {
+			b.Shelves = append(b.Shelves, shelfID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load book shelves: %w", err)
+	}
+	return nil
+}
+
+// insertOnShelf возвращает порядок книг, в котором книга bookID стоит перед
+// книгой вне корзины с номером position (с 0) или в конце полки
+func insertOnShelf(order []int64, trashed map[int64]bool, bookID int64, position int) []int64 {
+	order = withoutBook(order, bookID)
+	at := len(order)
+	if position >= 0 {
+		visible := 0
+		for i, id := range order {
+			if trashed[id] {
+				continue
+			}
+			if visible == position {
+				at = i
+				break
+			}
+			visible++
+		}
+	}
+	return slices.Insert(order, at, bookID)
+}
+
+// replaceOnShelf возвращает порядок книг bookIDs без повторов, за которыми
+// следуют книги из order, лежащие в корзине
+func replaceOnShelf(order []int64, trashed map[int64]bool, bookIDs []int64) []int64 {
+	result := make([]int64, 0, len(bookIDs))
+	for _, id := range bookIDs {
+		if !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	for _, id := range order {
+		if trashed[id] && !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// withoutBook возвращает копию порядка книг без книги bookID
+func withoutBook(order []int64, bookID int64) []int64 {
+	return slices.DeleteFunc(slices.Clone(order), func(id int64) bool { return id == bookID })
+}
+
+// movedBooks возвращает книги, которые есть только в одном из порядков
+func movedBooks(previous, order []int64) []int64 {
+	var moved []int64
+	for _, id := range previous {
+		if !slices.Contains(order, id) {
+			moved = append(moved, id)
+		}
+	}
+	for _, id := range order {
+		if !slices.Contains(previous, id) {
+			moved = append(moved, id)
+		}
+	}
+	return moved
+}
+
+// memoryShelf - полка и ID книг на ней по порядку, включая книги в корзине
+type memoryShelf struct {
+	shelf models.Shelf
+	books []int64
+}
+
+func (m *MemoryRepository) ListShelves(ctx context.Context) ([]*models.Shelf, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	shelves := make([]*models.Shelf, 0, len(m.shelves))
+	for _, s := range m.shelves {
+		shelves = append(shelves, m.shelfWithCount(s))
+	}
+	sort.Slice(shelves, func(i, j int) bool {
+		if c := compareNoCase(shelves[i].Name, shelves[j].Name); c != 0 {
+			return c < 0
+		}
+		return shelves[i].ID < shelves[j].ID
+	})
+	return shelves, nil
+}
+
+func (m *MemoryRepository) GetShelf(ctx context.Context, id int64) (*models.Shelf, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, exists := m.shelves[id]
+	if !exists {
+		return nil, nil
+	}
+	return m.shelfWithCount(s), nil
+}
+
+func (m *MemoryRepository) CreateShelf(ctx context.Context, shelf *models.Shelf) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.shelfNameTaken(shelf.Name, 0) {
+		return ErrDuplicateShelfName
+	}
+
+	now := time.Now()
+	shelf.ID = m.nextShelfID
+	shelf.CreatedAt = now
+	shelf.UpdatedAt = now
+	shelf.BookCount = 0
+	m.nextShelfID++
+	m.shelves[shelf.ID] = &memoryShelf{shelf: *shelf}
+	return nil
+}
+
+func (m *MemoryRepository) UpdateShelf(ctx context.Context, shelf *models.Shelf) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.shelves[shelf.ID]
+	if !exists {
+		return ErrShelfNotFound
+	}
+	if m.shelfNameTaken(shelf.Name, shelf.ID) {
+		return ErrDuplicateShelfName
+	}
+
+	s.shelf.Name = shelf.Name
+	s.shelf.Description = shelf.Description
+	s.shelf.UpdatedAt = time.Now()
+	*shelf = *m.shelfWithCount(s)
+	return nil
+}
+
+func (m *MemoryRepository) DeleteShelf(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.shelves[id]
+	if !exists {
+		return ErrShelfNotFound
+	}
+	m.setShelfOrder(s, nil)
+	delete(m.shelves, id)
+	return nil
+}
+
+func (m *MemoryRepository) ShelfBooks(ctx context.Context, shelfID int64, page, pageSize int) ([]*models.Book, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, exists := m.shelves[shelfID]
+	if !exists {
+		return nil, 0, ErrShelfNotFound
+	}
+
+	var books []*models.Book
+	for _, id := range s.books {
+		if b := m.books[id]; b.DeletedAt == nil {
+			book := *b
+			books = append(books, &book)
+		}
+	}
+
+	start := min((page-1)*pageSize, len(books))
+	end := min(start+pageSize, len(books))
+	return books[start:end], len(books), nil
+}
+
+func (m *MemoryRepository) AddToShelf(ctx context.Context, shelfID, bookID int64, position int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.shelves[shelfID]
+	if !exists {
+		return ErrShelfNotFound
+	}
+	if !m.activeBooks([]int64{bookID}) {
+		return ErrBookNotFound
+	}
+	m.setShelfOrder(s, insertOnShelf(s.books, m.trashedBooks(s.books), bookID, position))
+	return nil
+}
+
+func (m *MemoryRepository) RemoveFromShelf(ctx context.Context, shelfID, bookID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.shelves[shelfID]
+	if !exists {
+		return ErrShelfNotFound
+	}
+	if !slices.Contains(s.books, bookID) {
+		return ErrBookNotOnShelf
+	}
+	m.setShelfOrder(s, withoutBook(s.books, bookID))
+	return nil
+}
+
+func (m *MemoryRepository) SetShelfBooks(ctx context.Context, shelfID int64, bookIDs []int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.shelves[shelfID]
+	if !exists {
+		return ErrShelfNotFound
+	}
+	if !m.activeBooks(bookIDs) {
+		return ErrBookNotFound
+	}
+	m.setShelfOrder(s, replaceOnShelf(s.books, m.trashedBooks(s.books), bookIDs))
+	return nil
+}
+
+// setShelfOrder заменяет книги на полке так же, как dbTx.saveShelfOrder,
+// и обновляет полки в самих книгах (вызывается под блокировкой)
+func (m *MemoryRepository) setShelfOrder(s *memoryShelf, order []int64) {
+	now := time.Now()
+	for _, id := range movedBooks(s.books, order) {
+		b := m.books[id]
+		if slices.Contains(order, id) {
+			b.Shelves = append(slices.Clone(b.Shelves), s.shelf.ID)
+			slices.Sort(b.Shelves)
+		} else {
+			b.Shelves = slices.DeleteFunc(slices.Clone(b.Shelves), func(shelfID int64) bool { return shelfID == s.shelf.ID })
+		}
+		b.UpdatedAt = now
+		b.Version++
+	}
+	s.books = order
+	s.shelf.UpdatedAt = now
+}
+
+// shelfNameTaken проверяет, занято ли название другой полкой
+// (вызывается под блокировкой)
+func (m *MemoryRepository) shelfNameTaken(name string, exceptID int64) bool {
+	for id, s := range m.shelves {
+		if id != exceptID && s.shelf.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// activeBooks проверяет, что все книги существуют и не лежат в корзине
+// (вызывается под блокировкой)
+func (m *MemoryRepository) activeBooks(ids []int64) bool {
+	for _, id := range ids {
+		if b, exists := m.books[id]; !exists || b.DeletedAt != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// trashedBooks возвращает множество книг в корзине из списка
+// (вызывается под блокировкой)
+func (m *MemoryRepository) trashedBooks(ids []int64) map[int64]bool {
+	trashed := make(map[int64]bool)
+	for _, id := range ids {
+		trashed[id] = m.books[id].DeletedAt != nil
+	}
+	return trashed
+}
+
+// shelfWithCount возвращает копию полки с количеством книг на ней вне
+// корзины (вызывается под блокировкой)
+func (m *MemoryRepository) shelfWithCount(s *memoryShelf) *models.Shelf {
+	shelf := s.shelf
+	shelf.BookCount = 0
+	for _, id := range s.books {
+		if m.books[id].DeletedAt == nil {
+			shelf.BookCount++
+		}
+	}
+	return &shelf
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testShelves(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	published := time.Date(1965, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var ids []int64
+	for _, title := range []string{"Дюна", "Солярис", "Пикник на обочине", "Гиперион"} {
+		book := &models.Book{Title: title, Author: "Автор", Published: published}
+		if err := repo.CreateBook(ctx, book); err != nil {
+			t.Fatalf("CreateBook(%q) error = %v", title, err)
+		}
+		ids = append(ids, book.ID)
+	}
+	dune, solaris, picnic, hyperion := ids[0], ids[1], ids[2], ids[3]
+
+	shelfBooks := func(shelfID int64) []int64 {
+		t.Helper()
+		books, total, err := repo.ShelfBooks(ctx, shelfID, 1, 10)
+		if err != nil || total != len(books) {
+			t.Fatalf("ShelfBooks() = %d books, total %d, %v", len(books), total, err)
+		}
+		var result []int64
+		for _, b := range books {
+			result = append(result, b.ID)
+		}
+		return result
+	}
+
+	toRead := &models.Shelf{Name: "Прочитать", Description: "Летом"}
+	if err := repo.CreateShelf(ctx, toRead); err != nil || toRead.ID == 0 {
+		t.Fatalf("CreateShelf() = %+v, %v", toRead, err)
+	}
+	favorites := &models.Shelf{Name: "Избранное"}
+	if err := repo.CreateShelf(ctx, favorites); err != nil {
+		t.Fatalf("CreateShelf() error = %v", err)
+	}
+	if err := repo.CreateShelf(ctx, &models.Shelf{Name: "Избранное"}); !errors.Is(err, ErrDuplicateShelfName) {
+		t.Errorf("CreateShelf() with taken name error = %v, want ErrDuplicateShelfName", err)
+	}
+
+	// Книги встают на указанное место, повторное добавление перемещает книгу
+	for _, add := range []struct {
+		book     int64
+		position int
+	}{{dune, -1}, {solaris, -1}, {picnic, 0}, {dune, 1}} {
+		if err := repo.AddToShelf(ctx, toRead.ID, add.book, add.position); err != nil {
+			t.Fatalf("AddToShelf(%d, %d) error = %v", add.book, add.position, err)
+		}
+	}
+	if got, want := shelfBooks(toRead.ID), []int64{picnic, dune, solaris}; !slices.Equal(got, want) {
+		t.Errorf("ShelfBooks() = %v, want %v", got, want)
+	}
+	if err := repo.AddToShelf(ctx, favorites.ID, dune, -1); err != nil {
+		t.Fatalf("AddToShelf() error = %v", err)
+	}
+
+	book, _ := repo.GetBook(ctx, dune)
+	if !slices.Equal(book.Shelves, []int64{toRead.ID, favorites.ID}) || book.Version == 1 {
+		t.Errorf("GetBook().Shelves = %v, version %d; want both shelves and new version", book.Shelves, book.Version)
+	}
+	opts := DefaultListOptions()
+	opts.ShelfID = favorites.ID
+	if books, _, err := repo.ListBooks(ctx, opts); err != nil || len(books) != 1 || books[0].ID != dune {
+		t.Errorf("ListBooks(shelf) = %d books, %v; want Дюна", len(books), err)
+	}
+	hits, _, err := repo.SearchBooks(ctx, "shelf:"+strconv.FormatInt(toRead.ID, 10), firstPage)
+	if err != nil || len(hits) != 3 {
+		t.Errorf("SearchBooks(shelf:) = %d hits, %v; want 3", len(hits), err)
+	}
+
+	// Сохранение книги не меняет ее полки
+	book.Title = "Дюна (перевод)"
+	if err := repo.UpdateBook(ctx, book); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	if book, _ = repo.GetBook(ctx, dune); len(book.Shelves) != 2 {
+		t.Errorf("GetBook().Shelves after update = %v", book.Shelves)
+	}
+
+	// Книга в корзине не видна на полке, но остается на ней
+	if err := repo.DeleteBook(ctx, solaris, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+	if err := repo.SetShelfBooks(ctx, toRead.ID, []int64{hyperion, dune}); err != nil {
+		t.Fatalf("SetShelfBooks() error = %v", err)
+	}
+	if got, want := shelfBooks(toRead.ID), []int64{hyperion, dune}; !slices.Equal(got, want) {
+		t.Errorf("ShelfBooks() after set = %v, want %v", got, want)
+	}
+	if err := repo.RestoreBook(ctx, solaris); err != nil {
+		t.Fatalf("RestoreBook() error = %v", err)
+	}
+	if got, want := shelfBooks(toRead.ID), []int64{hyperion, dune, solaris}; !slices.Equal(got, want) {
+		t.Errorf("ShelfBooks() after restore = %v, want %v", got, want)
+	}
+	if err := repo.SetShelfBooks(ctx, toRead.ID, []int64{picnic, 999}); !errors.Is(err, ErrBookNotFound) {
+		t.Errorf("SetShelfBooks() with missing book error = %v, want ErrBookNotFound", err)
+	}
+
+	if err := repo.RemoveFromShelf(ctx, toRead.ID, hyperion); err != nil {
+		t.Fatalf("RemoveFromShelf() error = %v", err)
+	}
+	if err := repo.RemoveFromShelf(ctx, toRead.ID, hyperion); !errors.Is(err, ErrBookNotOnShelf) {
+		t.Errorf("RemoveFromShelf() twice error = %v, want ErrBookNotOnShelf", err)
+	}
+
+	shelves, err := repo.ListShelves(ctx)
+	if err != nil || len(shelves) != 2 || shelves[0].Name != "Избранное" || shelves[1].BookCount != 2 {
+		t.Errorf("ListShelves() = %+v, %v", shelves, err)
+	}
+
+	toRead.Name = "Прочитать летом"
+	if err := repo.UpdateShelf(ctx, toRead); err != nil || toRead.BookCount != 2 || toRead.Description != "Летом" {
+		t.Errorf("UpdateShelf() = %+v, %v", toRead, err)
+	}
+	toRead.Name = "Избранное"
+	if err := repo.UpdateShelf(ctx, toRead); !errors.Is(err, ErrDuplicateShelfName) {
+		t.Errorf("UpdateShelf() with taken name error = %v, want ErrDuplicateShelfName", err)
+	}
+
+	// Удаление полки убирает ее из книг, сами книги остаются
+	if err := repo.DeleteShelf(ctx, favorites.ID); err != nil {
+		t.Fatalf("DeleteShelf() error = %v", err)
+	}
+	if book, _ = repo.GetBook(ctx, dune); !slices.Equal(book.Shelves, []int64{toRead.ID}) {
+		t.Errorf("GetBook().Shelves after shelf delete = %v", book.Shelves)
+	}
+	if err := repo.DeleteShelf(ctx, favorites.ID); !errors.Is(err, ErrShelfNotFound) {
+		t.Errorf("DeleteShelf() twice error = %v, want ErrShelfNotFound", err)
+	}
+	if _, _, err := repo.ShelfBooks(ctx, favorites.ID, 1, 10); !errors.Is(err, ErrShelfNotFound) {
+		t.Errorf("ShelfBooks() of deleted shelf error = %v, want ErrShelfNotFound", err)
+	}
+	if err := repo.AddToShelf(ctx, favorites.ID, dune, -1); !errors.Is(err, ErrShelfNotFound) {
+		t.Errorf("AddToShelf() to deleted shelf error = %v, want ErrShelfNotFound", err)
+	}
+
+	// Окончательно удаленная книга пропадает с полки
+	if err := repo.DeleteBook(ctx, dune, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+	if _, err := repo.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("PurgeTrash() error = %v", err)
+	}
+	if got, want := shelfBooks(toRead.ID), []int64{solaris}; !slices.Equal(got, want) {
+		t.Errorf("ShelfBooks() after purge = %v, want %v", got, want)
+	}
+}
+
+func TestShelves(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testShelves(t, db)
+	})
+}
+
+func TestMemoryShelves(t *testing.T) {
+	testShelves(t, NewMemoryRepository())
+}
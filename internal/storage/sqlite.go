@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriverName - драйвер SQLite с дополнительными функциями и сортировками
+const sqliteDriverName = "sqlite3_bookshelf"
+
+// unicodeLower - функция lower, учитывающая любые буквы Unicode.
+// Встроенная lower в SQLite меняет регистр только у ASCII.
+const unicodeLower = "unicode_lower"
+
+// unicodeNoCase - сортировка без учета регистра для любых букв Unicode.
+// Встроенная NOCASE в SQLite учитывает только ASCII.
+const unicodeNoCase = "UNICODE_NOCASE"
+
+func init() {
+	sql.Register(sqliteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc(unicodeLower, strings.ToLower, true); err != nil {
+				return err
+			}
+			return conn.RegisterCollation(unicodeNoCase, compareNoCase)
+		},
+	})
+}
+
+// compareNoCase сравнивает строки без учета регистра так же, как lower() в PostgreSQL
+func compareNoCase(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
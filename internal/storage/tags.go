@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// TagCounts возвращает теги книг вне корзины с количеством книг
+func (d *Database) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	query := `
+        SELECT t.name, COUNT(*)
+        FROM tags t
+        JOIN book_tags bt ON bt.tag_id = t.id
+        JOIN books b ON b.id = bt.book_id
+        WHERE b.deleted_at IS NULL
+        GROUP BY t.name`
+	rows, err := d.query(ctx, query)
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []models.TagCount
+	for rows.Next() {
+		var tag models.TagCount
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag row: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag rows: %w", err)
+	}
+
+	// Порядок задается здесь, а не в запросе: правила сравнения строк
+	// в PostgreSQL зависят от настроек базы
+	slices.SortFunc(tags, func(a, b models.TagCount) int { return strings.Compare(a.Name, b.Name) })
+	return tags, nil
+}
+
+// saveTags заменяет теги книги на book.Tags, создавая теги, которых еще нет
+func (t *dbTx) saveTags(ctx context.Context, book *models.Book) error {
+	if _, err := t.exec(ctx, "DELETE FROM book_tags WHERE book_id = ?", book.ID); err != nil {
+		return fmt.Errorf("failed to delete book tags: %w", err)
+	}
+
+	for _, name := range book.Tags {
+		var tagID int64
+		err := t.queryRow(ctx, "SELECT id FROM tags WHERE name = ?", name).Scan(&tagID)
+		if err == sql.ErrNoRows {
+			err = t.queryRow(ctx, "INSERT INTO tags (name) VALUES (?) RETURNING id", name).Scan(&tagID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save tag %q: %w", name, err)
+		}
+
+		if _, err := t.exec(ctx, "INSERT INTO book_tags (book_id, tag_id) VALUES (?, ?)", book.ID, tagID); err != nil {
+			return fmt.Errorf("failed to save book tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadTags заполняет теги книг
+func loadTags(ctx context.Context, q querier, books []*models.Book) error {
+	for _, b := range books {
+		b.Tags = nil
+	}
+
+	query := `
+        SELECT bt.book_id, t.name
+        FROM book_tags bt
+        JOIN tags t ON t.id = bt.tag_id
+        WHERE bt.book_id IN (%s)`
+	err := queryByBooks(ctx, q, books, query, func(rows *sql.Rows, byID map[int64]*models.Book) error {
+		var bookID int64
+		var name string
+		if err := rows.Scan(&bookID, &name); err != nil {
+			return err
+		}
+		if b, ok := byID[bookID]; ok {
+			b.Tags = append(b.Tags, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load book tags: %w", err)
+	}
+
+	// Теги сортируются так же, как в Book.FormatTags, чтобы сохраненная
+	// книга не отличалась от прочитанной
+	for _, b := range books {
+		slices.Sort(b.Tags)
+	}
+	return nil
+}
+
+func (m *MemoryRepository) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, b := range m.books {
+		if b.DeletedAt != nil {
+			continue
+		}
+		for _, tag := range b.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]models.TagCount, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, models.TagCount{Name: name, Count: count})
+	}
+	slices.SortFunc(tags, func(a, b models.TagCount) int { return strings.Compare(a.Name, b.Name) })
+	return tags, nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testTags(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	published := time.Date(1965, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newBook := func(title string, tags ...string) *models.Book {
+		t.Helper()
+		book := &models.Book{Title: title, Author: "Автор", Published: published, Tags: tags}
+		book.Normalize()
+		if err := repo.CreateBook(ctx, book); err != nil {
+			t.Fatalf("CreateBook(%q) error = %v", title, err)
+		}
+		return book
+	}
+
+	dune := newBook("Дюна", "Фантастика", "  любимое ", "фантастика")
+	if !slices.Equal(dune.Tags, []string{"любимое", "фантастика"}) {
+		t.Errorf("CreateBook() tags = %q", dune.Tags)
+	}
+	solaris := newBook("Солярис", "фантастика")
+	newBook("Без тегов")
+
+	got, _ := repo.GetBook(ctx, dune.ID)
+	if !slices.Equal(got.Tags, dune.Tags) {
+		t.Errorf("GetBook() tags = %q, want %q", got.Tags, dune.Tags)
+	}
+
+	counts, err := repo.TagCounts(ctx)
+	want := []models.TagCount{{Name: "любимое", Count: 1}, {Name: "фантастика", Count: 2}}
+	if err != nil || !slices.Equal(counts, want) {
+		t.Errorf("TagCounts() = %+v, %v; want %+v", counts, err, want)
+	}
+
+	// Фильтр по нескольким тегам оставляет книги со всеми тегами
+	opts := DefaultListOptions()
+	opts.Tags = []string{"фантастика", "любимое"}
+	books, info, err := repo.ListBooks(ctx, opts)
+	if err != nil || info.Total != 1 || books[0].ID != dune.ID {
+		t.Errorf("ListBooks(tags) = %d books, total %d, %v; want Дюна", len(books), info.Total, err)
+	}
+	hits, _, err := repo.SearchBooks(ctx, "tag:фантаст*", firstPage)
+	if err != nil || len(hits) != 2 {
+		t.Errorf("SearchBooks(tag:фантаст*) = %d hits, %v; want 2", len(hits), err)
+	}
+
+	// Изменение тегов записывается в историю, пустой список удаляет теги
+	solaris.Tags = []string{}
+	if err := repo.UpdateBook(ctx, solaris); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	got, _ = repo.GetBook(ctx, solaris.ID)
+	if len(got.Tags) != 0 {
+		t.Errorf("GetBook() after clearing tags = %q", got.Tags)
+	}
+	history, _ := repo.BookHistory(ctx, solaris.ID)
+	if len(history) != 2 || string(history[0].Changes["tags"].Old) != `["фантастика"]` {
+		t.Errorf("BookHistory() = %+v, want tags change", history)
+	}
+	if _, err := repo.RevertBook(ctx, solaris.ID, 1); err != nil {
+		t.Fatalf("RevertBook() error = %v", err)
+	}
+	if got, _ = repo.GetBook(ctx, solaris.ID); !slices.Equal(got.Tags, []string{"фантастика"}) {
+		t.Errorf("GetBook() after revert tags = %q", got.Tags)
+	}
+
+	// Книги в корзине не учитываются
+	if err := repo.DeleteBook(ctx, dune.ID, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+	counts, _ = repo.TagCounts(ctx)
+	if want := []models.TagCount{{Name: "фантастика", Count: 1}}; !slices.Equal(counts, want) {
+		t.Errorf("TagCounts() after delete = %+v, want %+v", counts, want)
+	}
+
+	// % и _ в запросе ищутся буквально, а не как символы шаблона LIKE
+	newBook("Гиперион", "sci_fi")
+	newBook("Нейромант", "scifi")
+	newBook("Скидки", "100%")
+	newBook("Тысяча", "1000")
+	for query, want := range map[string]string{"tag:sci_*": "Гиперион", "tag:100%*": "Скидки"} {
+		hits, _, err := repo.SearchBooks(ctx, query, firstPage)
+		if err != nil || len(hits) != 1 || hits[0].Title != want {
+			t.Errorf("SearchBooks(%s) = %d hits, %v; want %s", query, len(hits), err, want)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testTags(t, db)
+	})
+}
+
+func TestMemoryTags(t *testing.T) {
+	testTags(t, NewMemoryRepository())
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// ListTrash возвращает книги из корзины, сначала удаленные последними
+func (d *Database) ListTrash(ctx context.Context, opts PageOptions) ([]*models.Book, PageInfo, error) {
+	return d.ListBooks(ctx, trashListOptions(opts))
+}
+
+// RestoreBook возвращает книгу из корзины
+func (d *Database) RestoreBook(ctx context.Context, id int64) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		return tx.restoreBook(ctx, id)
+	})
+}
+
+func (t *dbTx) restoreBook(ctx context.Context, id int64) error {
+	log.Printf("Attempting to restore book with ID: %d", id)
+
+	var deletedAt time.Time
+	err := t.queryRow(ctx, "SELECT deleted_at FROM books WHERE id = ? AND deleted_at IS NOT NULL", id).Scan(&deletedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("Book with ID %d not found in trash", id)
+			return ErrBookNotFound
+		}
+		log.Printf("Error querying trashed book: %v", err)
+		return fmt.Errorf("failed to get trashed book: %w", err)
+	}
+
+	if _, err := t.exec(ctx, "UPDATE books SET deleted_at = NULL WHERE id = ?", id); err != nil {
+		log.Printf("Error executing restore query: %v", err)
+		return fmt.Errorf("failed to restore book: %w", err)
+	}
+
+	log.Printf("Successfully restored book %d", id)
+	return t.recordRevision(ctx, &models.Revision{
+		BookID:  id,
+		Action:  models.ActionRestore,
+		Changes: deletedAtChange(&deletedAt, nil),
+	})
+}
+
+// PurgeTrash окончательно удаляет книги, попавшие в корзину раньше before,
+// вместе с их идентификаторами, участниками, тегами, местами на полках
+// и в сериях. История изменений остается и завершается ревизией purge.
+func (d *Database) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	var purged int64
+	err := d.inTx(ctx, func(tx *dbTx) error {
+		ids, err := tx.trashedBefore(ctx, before)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := tx.recordRevision(ctx, &models.Revision{
+				BookID:  id,
+				Action:  models.ActionPurge,
+				Changes: map[string]models.FieldChange{},
+			}); err != nil {
+				return err
+			}
+		}
+
+		for _, table := range []string{"book_identifiers", "book_contributors", "book_tags", "shelf_books", "book_series", "book_editions"} {
+			_, err := tx.exec(ctx, `
+                DELETE FROM `+table+` WHERE book_id IN (
+                    SELECT id FROM books WHERE deleted_at IS NOT NULL AND deleted_at < ?
+                )`, before.UTC())
+			if err != nil {
+				return fmt.Errorf("failed to purge %s: %w", table, err)
+			}
+		}
+
+		result, err := tx.exec(ctx, "DELETE FROM books WHERE deleted_at IS NOT NULL AND deleted_at < ?", before.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to purge trash: %w", err)
+		}
+
+		purged, err = result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get purge result: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error purging trash: %v", err)
+		return 0, err
+	}
+
+	log.Printf("Purged %d books deleted before %s", purged, before.Format(time.RFC3339))
+	return int(purged), nil
+}
+
+// trashedBefore возвращает ID книг, попавших в корзину раньше before
+func (t *dbTx) trashedBefore(ctx context.Context, before time.Time) ([]int64, error) {
+	rows, err := t.query(ctx, "SELECT id FROM books WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY id", before.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trashed books: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan trashed book ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trashed books: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testTrash(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+	listFixtures(t, repo)
+
+	books, _, err := repo.ListBooks(ctx, DefaultListOptions())
+	if err != nil {
+		t.Fatalf("ListBooks() error = %v", err)
+	}
+	trashed := books[0]
+
+	if err := repo.DeleteBook(ctx, trashed.ID, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+
+	// Книга в корзине не видна и не изменяется
+	if book, err := repo.GetBook(ctx, trashed.ID); err != nil || book != nil {
+		t.Errorf("GetBook() after delete = %+v, %v, want nil", book, err)
+	}
+	if _, info, _ := repo.ListBooks(ctx, DefaultListOptions()); info.Total != 3 {
+		t.Errorf("ListBooks() after delete total = %d, want 3", info.Total)
+	}
+	if hits, _, _ := repo.SearchBooks(ctx, "isbn:"+trashed.ISBN, PageOptions{Page: 1, PageSize: 10}); len(hits) != 0 {
+		t.Errorf("SearchBooks() found trashed book: %+v", hits)
+	}
+	if err := repo.DeleteBook(ctx, trashed.ID, 0); !errors.Is(err, ErrBookNotFound) {
+		t.Errorf("DeleteBook() twice error = %v, want ErrBookNotFound", err)
+	}
+	if err := repo.UpdateBook(ctx, trashed); !errors.Is(err, ErrBookNotFound) {
+		t.Errorf("UpdateBook() of trashed book error = %v, want ErrBookNotFound", err)
+	}
+
+	trash, info, err := repo.ListTrash(ctx, PageOptions{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListTrash() error = %v", err)
+	}
+	if info.Total != 1 || len(trash) != 1 || trash[0].ID != trashed.ID || trash[0].DeletedAt == nil {
+		t.Fatalf("ListTrash() = %+v (total %d), want trashed book", trash, info.Total)
+	}
+
+	// Восстановление возвращает книгу в список
+	if err := repo.RestoreBook(ctx, trashed.ID); err != nil {
+		t.Fatalf("RestoreBook() error = %v", err)
+	}
+	if book, err := repo.GetBook(ctx, trashed.ID); err != nil || book == nil || book.DeletedAt != nil {
+		t.Errorf("GetBook() after restore = %+v, %v", book, err)
+	}
+	if err := repo.RestoreBook(ctx, trashed.ID); !errors.Is(err, ErrBookNotFound) {
+		t.Errorf("RestoreBook() of book outside trash error = %v, want ErrBookNotFound", err)
+	}
+
+	// Очистка удаляет только книги старше срока хранения
+	if err := repo.DeleteBook(ctx, trashed.ID, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+	if purged, err := repo.PurgeTrash(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("PurgeTrash() of fresh trash = %d, %v, want 0", purged, err)
+	}
+	if purged, err := repo.PurgeTrash(ctx, time.Now().Add(time.Second)); err != nil || purged != 1 {
+		t.Errorf("PurgeTrash() = %d, %v, want 1", purged, err)
+	}
+	if err := repo.RestoreBook(ctx, trashed.ID); !errors.Is(err, ErrBookNotFound) {
+		t.Errorf("RestoreBook() of purged book error = %v, want ErrBookNotFound", err)
+	}
+
+	// История удаленной книги остается и заканчивается ревизией purge
+	history, err := repo.BookHistory(ctx, trashed.ID)
+	if err != nil {
+		t.Fatalf("BookHistory() of purged book error = %v", err)
+	}
+	if len(history) < 4 || history[0].Action != models.ActionPurge || history[1].Action != models.ActionDelete ||
+		history[len(history)-1].Action != models.ActionCreate {
+		t.Errorf("BookHistory() of purged book = %d revisions starting with %+v, want create ... delete, purge", len(history), history[0])
+	}
+	if trash, _, _ := repo.ListTrash(ctx, PageOptions{Page: 1, PageSize: 10}); len(trash) != 0 {
+		t.Errorf("ListTrash() after purge = %+v, want empty", trash)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testTrash(t, db)
+	})
+}
+
+func TestMemoryTrash(t *testing.T) {
+	testTrash(t, NewMemoryRepository())
+}
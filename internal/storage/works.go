@@ -0,0 +1,504 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+// workQuery выбирает произведения вместе с количеством их изданий вне корзины
+const workQuery = `
+    SELECT w.id, w.title, w.created_at, w.updated_at, (
+        SELECT COUNT(*) FROM book_editions e JOIN books b ON b.id = e.book_id
+        WHERE e.work_id = w.id AND b.deleted_at IS NULL
+    )
+    FROM works w`
+
+func (d *Database) ListWorks(ctx context.Context) ([]*models.Work, error) {
+	rows, err := d.query(ctx, workQuery+" ORDER BY "+fmt.Sprintf(d.dialect.noCase, "w.title")+", w.id")
+	if err != nil {
+		log.Printf("Error querying works: %v", err)
+		return nil, fmt.Errorf("failed to query works: %w", err)
+	}
+	return scanWorks(rows)
+}
+
+func (d *Database) GetWork(ctx context.Context, id int64) (*models.Work, error) {
+	return getWork(ctx, d, id)
+}
+
+// getWork читает произведение; возвращает nil без ошибки, если его нет
+func getWork(ctx context.Context, q querier, id int64) (*models.Work, error) {
+	rows, err := q.query(ctx, workQuery+" WHERE w.id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get work: %w", err)
+	}
+	works, err := scanWorks(rows)
+	if err != nil || len(works) == 0 {
+		return nil, err
+	}
+	return works[0], nil
+}
+
+// scanWorks читает произведения из результата workQuery и закрывает rows
+func scanWorks(rows *sql.Rows) ([]*models.Work, error) {
+	defer rows.Close()
+
+	var works []*models.Work
+	for rows.Next() {
+		var w models.Work
+		if err := rows.Scan(&w.ID, &w.Title, &w.CreatedAt, &w.UpdatedAt, &w.EditionCount); err != nil {
+			return nil, fmt.Errorf("failed to scan work row: %w", err)
+		}
+		works = append(works, &w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating work rows: %w", err)
+	}
+	return works, nil
+}
+
+func (d *Database) CreateWork(ctx context.Context, work *models.Work) error {
+	now := time.Now().UTC()
+	err := d.queryRow(ctx, "INSERT INTO works (title, created_at, updated_at) VALUES (?, ?, ?) RETURNING id",
+		work.Title, now, now).Scan(&work.ID)
+	if err != nil {
+		log.Printf("Error creating work: %v", err)
+		return fmt.Errorf("failed to create work: %w", err)
+	}
+	work.CreatedAt = now
+	work.UpdatedAt = now
+	work.EditionCount = 0
+
+	log.Printf("Created work %d %q", work.ID, work.Title)
+	return nil
+}
+
+func (d *Database) UpdateWork(ctx context.Context, work *models.Work) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		result, err := tx.exec(ctx, "UPDATE works SET title = ?, updated_at = ? WHERE id = ?",
+			work.Title, time.Now().UTC(), work.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update work: %w", err)
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get update result: %w", err)
+		}
+		if updated == 0 {
+			return ErrWorkNotFound
+		}
+
+		saved, err := getWork(ctx, tx, work.ID)
+		if err != nil {
+			return err
+		}
+		*work = *saved
+		return nil
+	})
+}
+
+func (d *Database) DeleteWork(ctx context.Context, id int64) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		if err := tx.touchWork(ctx, id); err != nil {
+			return err
+		}
+
+		// Издания, в том числе лежащие в корзине, остаются без произведения
+		rows, err := tx.query(ctx, "SELECT book_id FROM book_editions WHERE work_id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to query work editions: %w", err)
+		}
+		var editions []int64
+		for rows.Next() {
+			var bookID int64
+			if err := rows.Scan(&bookID); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan work edition: %w", err)
+			}
+			editions = append(editions, bookID)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("error iterating work editions: %w", err)
+		}
+		for _, bookID := range editions {
+			if err := tx.setWork(ctx, bookID, 0); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.exec(ctx, "DELETE FROM works WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete work: %w", err)
+		}
+
+		log.Printf("Deleted work %d", id)
+		return nil
+	})
+}
+
+func (d *Database) WorkEditions(ctx context.Context, workID int64) ([]*models.Book, error) {
+	work, err := getWork(ctx, d, workID)
+	if err != nil {
+		return nil, err
+	}
+	if work == nil {
+		return nil, ErrWorkNotFound
+	}
+
+	query := `
+        SELECT b.id, b.title, b.author, COALESCE(b.isbn, ''), b.published, b.created_at, b.updated_at, b.version
+        FROM book_editions e
+        JOIN books b ON b.id = e.book_id
+        WHERE e.work_id = ? AND b.deleted_at IS NULL
+        ORDER BY b.published, b.id`
+	rows, err := d.query(ctx, query, workID)
+	if err != nil {
+		log.Printf("Error querying work editions: %v", err)
+		return nil, fmt.Errorf("failed to query work editions: %w", err)
+	}
+	defer rows.Close()
+
+	var books []*models.Book
+	for rows.Next() {
+		var book models.Book
+		err := rows.Scan(
+			&book.ID,
+			&book.Title,
+			&book.Author,
+			&book.ISBN,
+			&book.Published,
+			&book.CreatedAt,
+			&book.UpdatedAt,
+			&book.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book row: %w", err)
+		}
+		books = append(books, &book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book rows: %w", err)
+	}
+
+	if err := loadRelations(ctx, d, books); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+func (d *Database) LinkEdition(ctx context.Context, workID, bookID int64) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		if err := tx.touchWork(ctx, workID); err != nil {
+			return err
+		}
+		if err := tx.checkActiveBooks(ctx, []int64{bookID}); err != nil {
+			return err
+		}
+		return tx.setWork(ctx, bookID, workID)
+	})
+}
+
+func (d *Database) UnlinkEdition(ctx context.Context, workID, bookID int64) error {
+	return d.inTx(ctx, func(tx *dbTx) error {
+		if err := tx.touchWork(ctx, workID); err != nil {
+			return err
+		}
+		current, err := tx.bookWork(ctx, bookID)
+		if err != nil {
+			return err
+		}
+		if current != workID {
+			return ErrBookNotInWork
+		}
+		return tx.setWork(ctx, bookID, 0)
+	})
+}
+
+// touchWork обновляет время изменения произведения перед изменением его
+// изданий или возвращает ErrWorkNotFound
+func (t *dbTx) touchWork(ctx context.Context, workID int64) error {
+	result, err := t.exec(ctx, "UPDATE works SET updated_at = ? WHERE id = ?", time.Now().UTC(), workID)
+	if err != nil {
+		return fmt.Errorf("failed to update work: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get update result: %w", err)
+	}
+	if updated == 0 {
+		return ErrWorkNotFound
+	}
+	return nil
+}
+
+// bookWork возвращает ID произведения книги или 0
+func (t *dbTx) bookWork(ctx context.Context, bookID int64) (int64, error) {
+	var workID sql.NullInt64
+	err := t.queryRow(ctx, "SELECT work_id FROM book_editions WHERE book_id = ?", bookID).Scan(&workID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get book work: %w", err)
+	}
+	return workID.Int64, nil
+}
+
+// setWork связывает книгу с произведением workID (0 - отвязывает) и, если
+// произведение изменилось, увеличивает версию книги
+func (t *dbTx) setWork(ctx context.Context, bookID, workID int64) error {
+	current, err := t.bookWork(ctx, bookID)
+	if err != nil {
+		return err
+	}
+	if current == workID {
+		return nil
+	}
+
+	var work any
+	if workID != 0 {
+		work = workID
+	}
+	result, err := t.exec(ctx, "UPDATE book_editions SET work_id = ? WHERE book_id = ?", work, bookID)
+	if err != nil {
+		return fmt.Errorf("failed to update book work: %w", err)
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get update result: %w", err)
+	} else if updated == 0 {
+		if _, err := t.exec(ctx, "INSERT INTO book_editions (book_id, work_id) VALUES (?, ?)", bookID, work); err != nil {
+			return fmt.Errorf("failed to save book work: %w", err)
+		}
+	}
+
+	_, err = t.exec(ctx, "UPDATE books SET updated_at = ?, version = version + 1 WHERE id = ?", time.Now().UTC(), bookID)
+	if err != nil {
+		return fmt.Errorf("failed to update book %d: %w", bookID, err)
+	}
+	return nil
+}
+
+// saveEdition заменяет сведения об издании книги, сохраняя ее произведение
+func (t *dbTx) saveEdition(ctx context.Context, book *models.Book) error {
+	if _, err := t.exec(ctx, "DELETE FROM book_editions WHERE book_id = ?", book.ID); err != nil {
+		return fmt.Errorf("failed to delete book edition: %w", err)
+	}
+	if book.Publisher == "" && book.Language == "" && book.Format == "" && book.WorkID == 0 {
+		return nil
+	}
+
+	var work any
+	if book.WorkID != 0 {
+		work = book.WorkID
+	}
+	_, err := t.exec(ctx, "INSERT INTO book_editions (book_id, work_id, publisher, language, format) VALUES (?, ?, ?, ?, ?)",
+		book.ID, work, book.Publisher, book.Language, book.Format)
+	if err != nil {
+		return fmt.Errorf("failed to save book edition: %w", err)
+	}
+	return nil
+}
+
+// loadEditions заполняет сведения об изданиях книг и их произведения
+func loadEditions(ctx context.Context, q querier, books []*models.Book) error {
+	for _, b := range books {
+		b.Publisher, b.Language, b.Format, b.WorkID = "", "", "", 0
+	}
+
+	query := `
+        SELECT book_id, work_id, publisher, language, format
+        FROM book_editions
+        WHERE book_id IN (%s)`
+	err := queryByBooks(ctx, q, books, query, func(rows *sql.Rows, byID map[int64]*models.Book) error {
+		var bookID int64
+		var workID sql.NullInt64
+		var publisher, language, format string
+		if err := rows.Scan(&bookID, &workID, &publisher, &language, &format); err != nil {
+			return err
+		}
+		if b, ok := byID[bookID]; ok {
+			b.Publisher, b.Language, b.Format, b.WorkID = publisher, language, format, workID.Int64
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load book editions: %w", err)
+	}
+	return nil
+}
+
+// sameEdition проверяет, совпадают ли сведения об издании у двух книг
+func sameEdition(a, b *models.Book) bool {
+	return a.Publisher == b.Publisher && a.Language == b.Language && a.Format == b.Format
+}
+
+func (m *MemoryRepository) ListWorks(ctx context.Context) ([]*models.Work, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	works := make([]*models.Work, 0, len(m.works))
+	for _, w := range m.works {
+		works = append(works, m.workWithCount(w))
+	}
+	sort.Slice(works, func(i, j int) bool {
+		if c := compareNoCase(works[i].Title, works[j].Title); c != 0 {
+			return c < 0
+		}
+		return works[i].ID < works[j].ID
+	})
+	return works, nil
+}
+
+func (m *MemoryRepository) GetWork(ctx context.Context, id int64) (*models.Work, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	w, exists := m.works[id]
+	if !exists {
+		return nil, nil
+	}
+	return m.workWithCount(w), nil
+}
+
+func (m *MemoryRepository) CreateWork(ctx context.Context, work *models.Work) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	work.ID = m.nextWorkID
+	work.CreatedAt = now
+	work.UpdatedAt = now
+	work.EditionCount = 0
+	m.nextWorkID++
+
+	stored := *work
+	m.works[work.ID] = &stored
+	return nil
+}
+
+func (m *MemoryRepository) UpdateWork(ctx context.Context, work *models.Work) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, exists := m.works[work.ID]
+	if !exists {
+		return ErrWorkNotFound
+	}
+	w.Title = work.Title
+	w.UpdatedAt = time.Now()
+	*work = *m.workWithCount(w)
+	return nil
+}
+
+func (m *MemoryRepository) DeleteWork(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.works[id]; !exists {
+		return ErrWorkNotFound
+	}
+	for _, b := range m.books {
+		if b.WorkID == id {
+			m.setWork(b, 0)
+		}
+	}
+	delete(m.works, id)
+	return nil
+}
+
+func (m *MemoryRepository) WorkEditions(ctx context.Context, workID int64) ([]*models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, exists := m.works[workID]; !exists {
+		return nil, ErrWorkNotFound
+	}
+	books := m.sorted(func(b *models.Book) bool {
+		return b.DeletedAt == nil && b.WorkID == workID
+	})
+	sort.SliceStable(books, func(i, j int) bool {
+		if !books[i].Published.Equal(books[j].Published) {
+			return books[i].Published.Before(books[j].Published)
+		}
+		return books[i].ID < books[j].ID
+	})
+	return books, nil
+}
+
+func (m *MemoryRepository) LinkEdition(ctx context.Context, workID, bookID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, exists := m.works[workID]
+	if !exists {
+		return ErrWorkNotFound
+	}
+	if !m.activeBooks([]int64{bookID}) {
+		return ErrBookNotFound
+	}
+	w.UpdatedAt = time.Now()
+	m.setWork(m.books[bookID], workID)
+	return nil
+}
+
+func (m *MemoryRepository) UnlinkEdition(ctx context.Context, workID, bookID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, exists := m.works[workID]
+	if !exists {
+		return ErrWorkNotFound
+	}
+	b, exists := m.books[bookID]
+	if !exists || b.WorkID != workID {
+		return ErrBookNotInWork
+	}
+	w.UpdatedAt = time.Now()
+	m.setWork(b, 0)
+	return nil
+}
+
+// setWork связывает книгу с произведением так же, как dbTx.setWork
+// (вызывается под блокировкой)
+func (m *MemoryRepository) setWork(b *models.Book, workID int64) {
+	if b.WorkID == workID {
+		return
+	}
+	b.WorkID = workID
+	b.UpdatedAt = time.Now()
+	b.Version++
+}
+
+// workWithCount возвращает копию произведения с количеством его изданий вне
+// корзины (вызывается под блокировкой)
+func (m *MemoryRepository) workWithCount(w *models.Work) *models.Work {
+	work := *w
+	work.EditionCount = 0
+	for _, b := range m.books {
+		if b.DeletedAt == nil && b.WorkID == w.ID {
+			work.EditionCount++
+		}
+	}
+	return &work
+}
+
+// collapseWorks оставляет из изданий одного произведения первое в списке
+// и возвращает количество изданий каждого произведения в списке
+func collapseWorks(books []*models.Book) ([]*models.Book, map[int64]int) {
+	editions := make(map[int64]int)
+	result := make([]*models.Book, 0, len(books))
+	for _, b := range books {
+		if b.WorkID != 0 {
+			editions[b.WorkID]++
+			if editions[b.WorkID] > 1 {
+				continue
+			}
+		}
+		result = append(result, b)
+	}
+	return result, editions
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NkvXness/GoBookshelf/internal/models"
+)
+
+func testWorks(t *testing.T, repo BookRepository) {
+	ctx := context.Background()
+
+	newBook := func(title, language, format string, year int) *models.Book {
+		t.Helper()
+		book := &models.Book{
+			Title:     title,
+			Author:    "Лев Толстой",
+			Published: time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC),
+			Publisher: "Издательство",
+			Language:  language,
+			Format:    format,
+		}
+		book.Normalize()
+		if err := repo.CreateBook(ctx, book); err != nil {
+			t.Fatalf("CreateBook(%q) error = %v", title, err)
+		}
+		return book
+	}
+
+	russian := newBook("Анна Каренина", "ru", models.EditionHardcover, 1878)
+	english := newBook("Anna Karenina", "en", models.EditionPaperback, 1901)
+	audio := newBook("Анна Каренина", "ru", models.EditionAudiobook, 2010)
+	newBook("Анна на шее", "ru", "", 1895)
+
+	got, _ := repo.GetBook(ctx, english.ID)
+	if got.Publisher != "Издательство" || got.Language != "en" || got.Format != models.EditionPaperback || got.WorkID != 0 {
+		t.Errorf("GetBook() edition = %q %q %q %d", got.Publisher, got.Language, got.Format, got.WorkID)
+	}
+
+	work := &models.Work{Title: "Анна Каренина"}
+	if err := repo.CreateWork(ctx, work); err != nil || work.ID == 0 {
+		t.Fatalf("CreateWork() = %+v, %v", work, err)
+	}
+	for _, b := range []*models.Book{audio, russian, english} {
+		if err := repo.LinkEdition(ctx, work.ID, b.ID); err != nil {
+			t.Fatalf("LinkEdition(%d) error = %v", b.ID, err)
+		}
+	}
+	if err := repo.LinkEdition(ctx, work.ID, 999); !errors.Is(err, ErrBookNotFound) {
+		t.Errorf("LinkEdition() with missing book error = %v, want ErrBookNotFound", err)
+	}
+	if err := repo.LinkEdition(ctx, 999, russian.ID); !errors.Is(err, ErrWorkNotFound) {
+		t.Errorf("LinkEdition() to missing work error = %v, want ErrWorkNotFound", err)
+	}
+
+	// Связь с произведением меняет версию книги, но не попадает в историю
+	got, _ = repo.GetBook(ctx, russian.ID)
+	if got.WorkID != work.ID || got.Version != 2 {
+		t.Errorf("GetBook() after link = work %d, version %d", got.WorkID, got.Version)
+	}
+	if history, _ := repo.BookHistory(ctx, russian.ID); len(history) != 1 {
+		t.Errorf("BookHistory() after link = %d revisions, want 1", len(history))
+	}
+
+	editions, err := repo.WorkEditions(ctx, work.ID)
+	if err != nil || len(editions) != 3 || editions[0].ID != russian.ID || editions[2].ID != audio.ID {
+		t.Fatalf("WorkEditions() = %d editions, %v", len(editions), err)
+	}
+	if w, _ := repo.GetWork(ctx, work.ID); w == nil || w.EditionCount != 3 {
+		t.Errorf("GetWork() = %+v, want 3 editions", w)
+	}
+
+	// Поиск с группировкой оставляет одно издание произведения
+	workEditions := func(query string) map[int64]int {
+		t.Helper()
+		hits, info, err := repo.SearchWorks(ctx, query, firstPage)
+		if err != nil || info.Total != len(hits) {
+			t.Fatalf("SearchWorks(%q) = %d hits, total %d, %v", query, len(hits), info.Total, err)
+		}
+		editions := make(map[int64]int)
+		for _, hit := range hits {
+			editions[hit.WorkID] += hit.Editions
+		}
+		return editions
+	}
+	for _, query := range []string{"толстой", "author:толстой"} {
+		if got := workEditions(query); len(got) != 2 || got[0] != 1 || got[work.ID] != 3 {
+			t.Errorf("SearchWorks(%q) editions by work = %v, want one book and 3 editions", query, got)
+		}
+		if hits, _, _ := repo.SearchBooks(ctx, query, firstPage); len(hits) != 4 {
+			t.Errorf("SearchBooks(%q) = %d hits, want 4", query, len(hits))
+		}
+	}
+	if got := workEditions("анна"); len(got) != 2 || got[0] != 1 || got[work.ID] != 2 {
+		t.Errorf("SearchWorks(анна) editions by work = %v, want the Russian editions together", got)
+	}
+
+	// Сохранение книги не меняет ее произведение, изменения издания
+	// записываются в историю
+	got.Publisher = "Азбука"
+	if err := repo.UpdateBook(ctx, got); err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	if got, _ = repo.GetBook(ctx, russian.ID); got.WorkID != work.ID || got.Publisher != "Азбука" {
+		t.Errorf("GetBook() after update = work %d, publisher %q", got.WorkID, got.Publisher)
+	}
+	history, _ := repo.BookHistory(ctx, russian.ID)
+	if len(history) != 2 || string(history[0].Changes["publisher"].New) != `"Азбука"` {
+		t.Errorf("BookHistory() = %+v, want publisher change", history)
+	}
+
+	if err := repo.UnlinkEdition(ctx, work.ID, english.ID); err != nil {
+		t.Fatalf("UnlinkEdition() error = %v", err)
+	}
+	if err := repo.UnlinkEdition(ctx, work.ID, english.ID); !errors.Is(err, ErrBookNotInWork) {
+		t.Errorf("UnlinkEdition() twice error = %v, want ErrBookNotInWork", err)
+	}
+	if got, _ = repo.GetBook(ctx, english.ID); got.WorkID != 0 || got.Language != "en" {
+		t.Errorf("GetBook() after unlink = work %d, language %q", got.WorkID, got.Language)
+	}
+
+	work.Title = "Анна Каренина (роман)"
+	if err := repo.UpdateWork(ctx, work); err != nil || work.EditionCount != 2 {
+		t.Errorf("UpdateWork() = %+v, %v", work, err)
+	}
+
+	// Удаление произведения оставляет его издания отдельными книгами
+	if err := repo.DeleteBook(ctx, audio.ID, 0); err != nil {
+		t.Fatalf("DeleteBook() error = %v", err)
+	}
+	works, err := repo.ListWorks(ctx)
+	if err != nil || len(works) != 1 || works[0].EditionCount != 1 {
+		t.Errorf("ListWorks() = %+v, %v; want one work with 1 edition", works, err)
+	}
+	if err := repo.DeleteWork(ctx, work.ID); err != nil {
+		t.Fatalf("DeleteWork() error = %v", err)
+	}
+	if got, _ = repo.GetBook(ctx, russian.ID); got == nil || got.WorkID != 0 {
+		t.Errorf("GetBook() after work delete = %+v", got)
+	}
+	if _, err := repo.WorkEditions(ctx, work.ID); !errors.Is(err, ErrWorkNotFound) {
+		t.Errorf("WorkEditions() of deleted work error = %v, want ErrWorkNotFound", err)
+	}
+	if err := repo.DeleteWork(ctx, work.ID); !errors.Is(err, ErrWorkNotFound) {
+		t.Errorf("DeleteWork() twice error = %v, want ErrWorkNotFound", err)
+	}
+}
+
+func TestWorks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *Database) {
+		testWorks(t, db)
+	})
+}
+
+func TestMemoryWorks(t *testing.T) {
+	testWorks(t, NewMemoryRepository())
+}
@@ -0,0 +1,15 @@
+// Package migrations содержит SQL-миграции схемы базы данных.
+//
+// Для каждого поддерживаемого диалекта есть отдельный каталог (sqlite,
+// postgres) с одинаковым набором версий. Файлы именуются по шаблону
+// NNN_описание.up.sql и NNN_описание.down.sql, где NNN - номер версии схемы.
+// Файлы встраиваются в бинарник и применяются пакетом storage при старте
+// сервера.
+package migrations
+
+import "embed"
+
+// FS содержит все файлы миграций, сгруппированные по диалектам
+//
+//go:embed sqlite/*.sql postgres/*.sql
+var FS embed.FS
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_books_isbn;
+DROP INDEX IF EXISTS idx_books_author;
+DROP INDEX IF EXISTS idx_books_title;
+
+DROP TABLE IF EXISTS books;
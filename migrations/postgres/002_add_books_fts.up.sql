@@ -0,0 +1,4 @@
+-- В PostgreSQL поиск выполняется через ILIKE, который учитывает регистр
+-- букв Unicode, поэтому отдельный полнотекстовый индекс не создается.
+-- Миграция нужна, чтобы версии схемы совпадали с SQLite.
+SELECT 1;
@@ -0,0 +1,3 @@
+-- version увеличивается при каждом изменении книги и служит ETag
+-- для оптимистичной блокировки
+ALTER TABLE books ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+-- Идентификаторы книг помимо ISBN (ISSN, LCCN, OCLC, DOI, ASIN, штрихкод).
+-- Значение хранится в нормализованном виде и уникально в пределах типа;
+-- position сохраняет порядок идентификаторов книги.
+CREATE TABLE IF NOT EXISTS book_identifiers (
+    book_id BIGINT NOT NULL,
+    position INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY (book_id, position),
+    UNIQUE (type, value)
+);
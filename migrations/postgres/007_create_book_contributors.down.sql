@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_book_contributors_author;
+DROP TABLE IF EXISTS book_contributors;
+DROP TABLE IF EXISTS authors;
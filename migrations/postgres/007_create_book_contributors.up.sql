@@ -0,0 +1,47 @@
+-- Авторы книг. Один человек хранится один раз, книги ссылаются на него
+-- через book_contributors с ролью (author, translator, editor,
+-- illustrator); position сохраняет порядок участников книги.
+CREATE TABLE IF NOT EXISTS authors (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS book_contributors (
+    book_id BIGINT NOT NULL,
+    position INTEGER NOT NULL,
+    author_id BIGINT NOT NULL REFERENCES authors(id),
+    role TEXT NOT NULL,
+    PRIMARY KEY (book_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_contributors_author ON book_contributors(author_id);
+
+-- Поле books.author становится списком имен авторов через запятую.
+-- Существующие книги получают авторов из этого списка; имена, которые
+-- отличаются только регистром, относятся к одному автору. Если хотя бы
+-- одна часть списка - одно слово ("Толстой, Лев", "King, Jr."), запятая
+-- разделяет части одного имени, и поле остается одним автором.
+CREATE TEMP TABLE legacy_authors AS
+WITH parts AS (
+    SELECT b.id AS book_id, n.position - 1 AS position, trim(n.name) AS name
+    FROM books b, unnest(string_to_array(b.author, ', ')) WITH ORDINALITY AS n (name, position)
+    WHERE trim(n.name) <> ''
+),
+single_names AS (
+    SELECT DISTINCT book_id FROM parts WHERE strpos(name, ' ') = 0
+)
+SELECT book_id, position, name FROM parts
+WHERE book_id NOT IN (SELECT book_id FROM single_names)
+UNION ALL
+SELECT id, 0, trim(author) FROM books
+WHERE id IN (SELECT book_id FROM single_names);
+
+INSERT INTO authors (name)
+SELECT MIN(name) FROM legacy_authors GROUP BY lower(name);
+
+INSERT INTO book_contributors (book_id, position, author_id, role)
+SELECT l.book_id, MIN(l.position), a.id, 'author'
+FROM legacy_authors l JOIN authors a ON lower(a.name) = lower(l.name)
+GROUP BY l.book_id, a.id;
+
+DROP TABLE legacy_authors;
@@ -0,0 +1,12 @@
+-- sort_name - имя автора для сортировки ("Толстой, Лев"); у авторов,
+-- созданных до этой миграции, оно пустое и строится из имени при чтении.
+ALTER TABLE authors ADD COLUMN sort_name TEXT NOT NULL DEFAULT '';
+
+-- Другие написания имени автора по порядку. Имя принадлежит только одному
+-- автору: по нему участник книги связывается с автором.
+CREATE TABLE IF NOT EXISTS author_names (
+    author_id BIGINT NOT NULL REFERENCES authors(id),
+    position INTEGER NOT NULL,
+    name TEXT NOT NULL UNIQUE,
+    PRIMARY KEY (author_id, position)
+);
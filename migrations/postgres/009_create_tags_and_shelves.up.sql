@@ -0,0 +1,31 @@
+-- Теги книг. Имя тега хранится в нижнем регистре.
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS book_tags (
+    book_id BIGINT NOT NULL,
+    tag_id BIGINT NOT NULL REFERENCES tags(id),
+    PRIMARY KEY (book_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_tags_tag ON book_tags(tag_id);
+
+-- Полки (подборки) книг. position задает порядок книг на полке.
+CREATE TABLE IF NOT EXISTS shelves (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS shelf_books (
+    shelf_id BIGINT NOT NULL REFERENCES shelves(id),
+    book_id BIGINT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (shelf_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shelf_books_book ON shelf_books(book_id);
@@ -0,0 +1,15 @@
+-- Серии книг (собрания сочинений, трилогии, циклы)
+CREATE TABLE IF NOT EXISTS series (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+-- Книга входит не более чем в одну серию. position - номер книги в серии,
+-- может быть дробным (2.5) или отсутствовать.
+CREATE TABLE IF NOT EXISTS book_series (
+    book_id BIGINT PRIMARY KEY,
+    series_id BIGINT NOT NULL REFERENCES series(id),
+    position DOUBLE PRECISION
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_series_series ON book_series(series_id);
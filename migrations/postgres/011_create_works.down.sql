@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_book_editions_work;
+DROP TABLE IF EXISTS book_editions;
+DROP TABLE IF EXISTS works;
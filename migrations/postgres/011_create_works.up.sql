@@ -0,0 +1,20 @@
+-- Произведения: одно произведение объединяет несколько изданий
+-- (переводы, переиздания, электронные и аудиоверсии)
+CREATE TABLE IF NOT EXISTS works (
+    id BIGSERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Сведения об издании книги и произведение, к которому оно относится.
+-- Строка есть только у книг, для которых заполнено хотя бы одно поле.
+CREATE TABLE IF NOT EXISTS book_editions (
+    book_id BIGINT PRIMARY KEY,
+    work_id BIGINT REFERENCES works(id),
+    publisher TEXT NOT NULL DEFAULT '',
+    language TEXT NOT NULL DEFAULT '',
+    format TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_editions_work ON book_editions(work_id);
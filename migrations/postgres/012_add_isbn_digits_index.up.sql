@@ -0,0 +1,3 @@
+-- ISBN уникален по цифрам: один и тот же номер может быть записан с дефисами
+-- по разным версиям таблицы диапазонов ISBN
+CREATE UNIQUE INDEX IF NOT EXISTS idx_books_isbn_digits ON books (replace(isbn, '-', ''));
@@ -0,0 +1,29 @@
+-- Полнотекстовый индекс FTS5 по названию, автору и ISBN.
+-- unicode61 приводит к нижнему регистру любые буквы Unicode (в том числе
+-- кириллицу) и с remove_diacritics 2 убирает диакритику: "Émile" ищется
+-- по "emile". ISBN хранится без дефисов, чтобы искать по префиксу цифр.
+CREATE VIRTUAL TABLE IF NOT EXISTS books_fts USING fts5(
+    title,
+    author,
+    isbn,
+    tokenize="unicode61 remove_diacritics 2",
+    prefix="2 3"
+);
+
+INSERT INTO books_fts (rowid, title, author, isbn)
+SELECT id, title, author, replace(isbn, '-', '') FROM books;
+
+CREATE TRIGGER IF NOT EXISTS books_fts_insert AFTER INSERT ON books BEGIN
+    INSERT INTO books_fts (rowid, title, author, isbn)
+    VALUES (new.id, new.title, new.author, replace(new.isbn, '-', ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS books_fts_update AFTER UPDATE ON books BEGIN
+    DELETE FROM books_fts WHERE rowid = old.id;
+    INSERT INTO books_fts (rowid, title, author, isbn)
+    VALUES (new.id, new.title, new.author, replace(new.isbn, '-', ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS books_fts_delete AFTER DELETE ON books BEGIN
+    DELETE FROM books_fts WHERE rowid = old.id;
+END;
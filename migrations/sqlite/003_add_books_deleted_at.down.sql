@@ -0,0 +1,6 @@
+-- Книги из корзины при откате удаляются окончательно
+DELETE FROM books WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_books_deleted_at;
+
+ALTER TABLE books DROP COLUMN deleted_at;
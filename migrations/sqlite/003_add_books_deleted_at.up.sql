@@ -0,0 +1,5 @@
+-- Книги удаляются в корзину: deleted_at содержит момент удаления,
+-- NULL означает, что книга не удалена
+ALTER TABLE books ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_books_deleted_at ON books(deleted_at);
@@ -0,0 +1,13 @@
+-- История изменений книг. changes - JSON с изменившимися полями
+-- вида {"title": {"old": "...", "new": "..."}}.
+CREATE TABLE IF NOT EXISTS book_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    reverted_to INTEGER,
+    created_at DATETIME NOT NULL,
+    UNIQUE (book_id, revision)
+);
@@ -0,0 +1,35 @@
+DROP TRIGGER IF EXISTS book_identifiers_fts_delete;
+DROP TRIGGER IF EXISTS book_identifiers_fts_insert;
+DROP TABLE IF EXISTS book_identifiers;
+
+-- Полнотекстовый индекс возвращается к колонкам из миграции 002
+DROP TRIGGER IF EXISTS books_fts_delete;
+DROP TRIGGER IF EXISTS books_fts_update;
+DROP TRIGGER IF EXISTS books_fts_insert;
+DROP TABLE IF EXISTS books_fts;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS books_fts USING fts5(
+    title,
+    author,
+    isbn,
+    tokenize="unicode61 remove_diacritics 2",
+    prefix="2 3"
+);
+
+INSERT INTO books_fts (rowid, title, author, isbn)
+SELECT id, title, author, replace(isbn, '-', '') FROM books;
+
+CREATE TRIGGER IF NOT EXISTS books_fts_insert AFTER INSERT ON books BEGIN
+    INSERT INTO books_fts (rowid, title, author, isbn)
+    VALUES (new.id, new.title, new.author, replace(new.isbn, '-', ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS books_fts_update AFTER UPDATE ON books BEGIN
+    DELETE FROM books_fts WHERE rowid = old.id;
+    INSERT INTO books_fts (rowid, title, author, isbn)
+    VALUES (new.id, new.title, new.author, replace(new.isbn, '-', ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS books_fts_delete AFTER DELETE ON books BEGIN
+    DELETE FROM books_fts WHERE rowid = old.id;
+END;
@@ -0,0 +1,64 @@
+-- Идентификаторы книг помимо ISBN (ISSN, LCCN, OCLC, DOI, ASIN, штрихкод).
+-- Значение хранится в нормализованном виде и уникально в пределах типа;
+-- position сохраняет порядок идентификаторов книги.
+CREATE TABLE IF NOT EXISTS book_identifiers (
+    book_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY (book_id, position),
+    UNIQUE (type, value)
+);
+
+-- Полнотекстовый индекс пересоздается с колонкой identifiers, чтобы книгу
+-- без ISBN можно было найти по ее идентификаторам. Значения индексируются
+-- как есть и без дефисов: "0028-0836" ищется и по "00280836".
+DROP TRIGGER IF EXISTS books_fts_delete;
+DROP TRIGGER IF EXISTS books_fts_update;
+DROP TRIGGER IF EXISTS books_fts_insert;
+DROP TABLE IF EXISTS books_fts;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS books_fts USING fts5(
+    title,
+    author,
+    isbn,
+    identifiers,
+    tokenize="unicode61 remove_diacritics 2",
+    prefix="2 3"
+);
+
+INSERT INTO books_fts (rowid, title, author, isbn, identifiers)
+SELECT id, title, author, replace(isbn, '-', ''), NULL FROM books;
+
+CREATE TRIGGER IF NOT EXISTS books_fts_insert AFTER INSERT ON books BEGIN
+    INSERT INTO books_fts (rowid, title, author, isbn, identifiers)
+    VALUES (new.id, new.title, new.author, replace(new.isbn, '-', ''),
+        (SELECT group_concat(value || ' ' || replace(value, '-', ''), ' ') FROM book_identifiers WHERE book_id = new.id));
+END;
+
+CREATE TRIGGER IF NOT EXISTS books_fts_update AFTER UPDATE ON books BEGIN
+    DELETE FROM books_fts WHERE rowid = old.id;
+    INSERT INTO books_fts (rowid, title, author, isbn, identifiers)
+    VALUES (new.id, new.title, new.author, replace(new.isbn, '-', ''),
+        (SELECT group_concat(value || ' ' || replace(value, '-', ''), ' ') FROM book_identifiers WHERE book_id = new.id));
+END;
+
+CREATE TRIGGER IF NOT EXISTS books_fts_delete AFTER DELETE ON books BEGIN
+    DELETE FROM books_fts WHERE rowid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS book_identifiers_fts_insert AFTER INSERT ON book_identifiers BEGIN
+    DELETE FROM books_fts WHERE rowid = new.book_id;
+    INSERT INTO books_fts (rowid, title, author, isbn, identifiers)
+    SELECT id, title, author, replace(isbn, '-', ''),
+        (SELECT group_concat(value || ' ' || replace(value, '-', ''), ' ') FROM book_identifiers WHERE book_id = new.book_id)
+    FROM books WHERE id = new.book_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS book_identifiers_fts_delete AFTER DELETE ON book_identifiers BEGIN
+    DELETE FROM books_fts WHERE rowid = old.book_id;
+    INSERT INTO books_fts (rowid, title, author, isbn, identifiers)
+    SELECT id, title, author, replace(isbn, '-', ''),
+        (SELECT group_concat(value || ' ' || replace(value, '-', ''), ' ') FROM book_identifiers WHERE book_id = old.book_id)
+    FROM books WHERE id = old.book_id;
+END;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS author_names;
+ALTER TABLE authors DROP COLUMN sort_name;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_shelf_books_book;
+DROP TABLE IF EXISTS shelf_books;
+DROP TABLE IF EXISTS shelves;
+DROP INDEX IF EXISTS idx_book_tags_tag;
+DROP TABLE IF EXISTS book_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_book_series_series;
+DROP TABLE IF EXISTS book_series;
+DROP TABLE IF EXISTS series;
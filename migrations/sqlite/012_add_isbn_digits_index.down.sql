@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_books_isbn_digits;